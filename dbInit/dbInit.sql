@@ -23,17 +23,216 @@ CREATE EXTENSION "uuid-ossp" SCHEMA public;
 create table if not exists public.users(
     id uuid not null primary key default uuid_generate_v4(),
     login varchar(24) not null,
-    password text not null,
-    refresh_token text,
-    balance real not null default 0
+    password text not null
 );
 
+-- Сессии входа aus. Строка - один refresh токен(id - его jti), токены одного входа образуют семью family_id.
+-- Обмен токена помечает его rotated_at и выдает новый той же семьи. Повторный обмен уже обмененного токена - признак
+-- кражи: вся семья отзывается(revoked_at). access_id - jti access токена, выданного вместе с refresh: при отзыве
+-- сессии он попадает в denylist до своего истечения
+create table if not exists public.sessions(
+    id uuid not null primary key,
+    family_id uuid not null,
+    user_id uuid not null references public.users(id),
+    user_agent text not null default '',
+    ip text not null default '',
+    access_id uuid,
+    access_expires_at timestamptz,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+    rotated_at timestamptz,
+    revoked_at timestamptz
+);
+create index if not exists sessions_user_idx on public.sessions(user_id);
+create index if not exists sessions_family_idx on public.sessions(family_id);
+
+-- Роли и их права. Права встраиваются в access токен, gateway сверяет их с таблицей политик рутов.
+-- market:read - просмотр управления рынком, market:write - изменение рынка
+create table if not exists public.roles(
+    name text not null primary key,
+    permissions text[] not null default '{}'
+);
+
+insert into public.roles(name, permissions) values
+    ('admin', '{market:read, market:write}'),
+    ('auditor', '{market:read}')
+on conflict do nothing;
+
+-- Роли пользователей. Пользователь без ролей обычный: ему доступны только его собственные данные.
+-- Роль выдается вставкой строки и попадает в токены при следующем обновлении
+create table if not exists public.user_roles(
+    user_id uuid not null references public.users(id),
+    role text not null references public.roles(name),
+    primary key (user_id, role)
+);
+
+-- TOTP(RFC 6238) пользователей. secret - base32 секрет приложения, activated_at - когда 2FA включена первым кодом
+-- (до этого секрет ждет подтверждения), last_step - последний принятый шаг времени: один код дважды не принимается
+create table if not exists public.totp(
+    user_id uuid not null primary key references public.users(id),
+    secret text not null,
+    created_at timestamptz not null default now(),
+    activated_at timestamptz,
+    last_step bigint not null default 0
+);
+
+-- Одноразовые коды восстановления 2FA, хранятся только bcrypt хэши. Выдаются заново при каждом включении 2FA
+create table if not exists public.recovery_codes(
+    id uuid not null primary key default uuid_generate_v4(),
+    user_id uuid not null references public.users(id),
+    code_hash text not null,
+    used_at timestamptz
+);
+create index if not exists recovery_codes_user_idx on public.recovery_codes(user_id);
+
+-- Ключи подписи токенов aus(id - kid из заголовка токена, private_key - PKCS1 DER). Подписывает ключ с retired_at is null,
+-- при ротации прежний ключ отставляется, но публикуется в JWKS до expires_at, пока живут подписанные им токены
+create table if not exists public.signing_keys(
+    id text not null primary key,
+    private_key bytea not null,
+    created_at timestamptz not null default now(),
+    retired_at timestamptz,
+    expires_at timestamptz
+);
+
+-- Валюты кошельков и листинга бумаг. rate - цена единицы валюты в базовой(USD), ее двигает market
+create table if not exists public.currencies(
+    code text not null primary key,
+    rate numeric(20, 8) not null check (rate > 0),
+    updated_at timestamptz not null default now()
+);
+
+insert into public.currencies(code, rate) values('USD', 1), ('EUR', 1.08), ('RUB', 0.011);
+
+-- Журнал двойной записи. Деньги пользователя лежат на его счете cash, резерв под заявки - на hold.
+-- Системные счета: external - деньги за пределами биржи(пополнения и выводы), market - встречная сторона сделок по цене market, fees - комиссии,
+-- fx - встречная сторона обмена валют, interest - источник процентов на остаток. У каждой валюты свои счета
+create table if not exists public.accounts(
+    id uuid not null primary key default uuid_generate_v4(),
+    kind text not null check (kind in ('cash', 'hold', 'external', 'market', 'fees', 'fx', 'interest')),
+    user_id uuid references public.users(id),
+    currency text not null default 'USD' references public.currencies(code),
+    -- Кэш суммы проводок по счету, меняется только вместе с ними
+    balance numeric(20, 2) not null default 0,
+    check ((user_id is null) = (kind in ('external', 'market', 'fees', 'fx', 'interest'))),
+    unique nulls not distinct (kind, user_id, currency)
+);
+
+create table if not exists public.entries(
+    id uuid not null primary key default uuid_generate_v4(),
+    kind text not null check (kind in ('opening', 'deposit', 'withdrawal', 'trade', 'reserve', 'release', 'transfer', 'exchange', 'interest')),
+    -- Сделка или заявка, породившая запись
+    reference uuid,
+    -- Комментарий отправителя перевода
+    memo text,
+    created_at timestamptz not null default now()
+);
+
+create table if not exists public.postings(
+    id bigserial primary key,
+    entry_id uuid not null references public.entries(id),
+    account_id uuid not null references public.accounts(id),
+    amount numeric(20, 2) not null check (amount <> 0)
+);
+
+create index if not exists postings_entry_idx on public.postings(entry_id);
+create index if not exists postings_account_idx on public.postings(account_id, id);
+
+-- Проводки записи в каждой валюте должны давать в сумме ноль. Проверяется при коммите, когда вставлены все проводки записи
+create or replace function public.check_entry_balanced() returns trigger language plpgsql as $$
+begin
+    if exists (select 1 from public.postings p join public.accounts a on a.id = p.account_id
+        where p.entry_id = new.entry_id group by a.currency having sum(p.amount) <> 0) then
+        raise exception 'postings of entry % do not sum to zero', new.entry_id;
+    end if;
+    return null;
+end $$;
+
+create constraint trigger postings_balanced after insert on public.postings
+    deferrable initially deferred for each row execute function public.check_entry_balanced();
+
+insert into public.accounts(kind) values('external'), ('market'), ('fees');
+
+-- Резервы balance под сделки и заявки papers. amount - еще не списанный остаток, пустой user_id - резерв счета market
+create table if not exists public.holds(
+    id uuid not null primary key default uuid_generate_v4(),
+    user_id uuid references public.users(id),
+    amount numeric(20, 2) not null check (amount >= 0),
+    currency text not null default 'USD' references public.currencies(code),
+    reference uuid,
+    created_at timestamptz not null default now()
+);
+
+-- Пополнения через платежного провайдера. Деньги зачисляются только после подтвержденного callback провайдера,
+-- запись журнала пополнения ссылается на платеж
+create table if not exists public.payments(
+    id uuid not null primary key default uuid_generate_v4(),
+    user_id uuid not null references public.users(id),
+    amount numeric(20, 2) not null check (amount > 0),
+    currency text not null references public.currencies(code),
+    provider text not null,
+    status text not null default 'pending' check (status in ('pending', 'settled', 'failed')),
+    reason text,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+create index if not exists payments_user_created_idx on public.payments(user_id, created_at desc);
+
+-- Проценты на остаток. За каждый день в interest_days начисляется доля годовой ставки на остаток cash на конец дня,
+-- накопленное за месяц выплачивается одной записью журнала на счет и запоминается в interest_payouts
+create table if not exists public.interest_days(
+    day date not null primary key,
+    created_at timestamptz not null default now()
+);
+
+create table if not exists public.interest_accruals(
+    account_id uuid not null references public.accounts(id),
+    day date not null references public.interest_days(day),
+    balance numeric(20, 2) not null,
+    amount numeric(20, 8) not null,
+    primary key (account_id, day)
+);
+
+create table if not exists public.interest_payouts(
+    account_id uuid not null references public.accounts(id),
+    month date not null,
+    amount numeric(20, 2) not null,
+    primary key (account_id, month)
+);
+
+-- Ключи идемпотентности изменяющих запросов balance и papers. scope - сервис и метод, user_id - пользователь запроса
+-- (нулевой - системные вызовы): ключи разных пользователей не пересекаются. request_hash - sha256 запроса: тот же ключ
+-- с другим запросом отклоняется. Пока response пуст, запрос выполняется. После expires_at ключ можно занять снова
+create table if not exists public.idempotency_keys(
+    scope text not null,
+    user_id uuid not null default '00000000-0000-0000-0000-000000000000',
+    key text not null,
+    request_hash text not null,
+    response bytea,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+    primary key (scope, user_id, key)
+);
+create index if not exists idempotency_keys_expires_idx on public.idempotency_keys(expires_at);
+
 create table if not exists public.papers(
     name text not null primary key,
-    price real not null,
-    past_prices real[]
+    -- Цена в валюте листинга
+    price numeric(20, 2) not null,
+    currency text not null default 'USD' references public.currencies(code),
+    listed boolean not null default true,
+    halted boolean not null default false,
+    halted_until timestamptz
+);
+
+create table if not exists public.price_ticks(
+    paper_name text not null references public.papers(name),
+    price numeric(20, 2) not null,
+    created_at timestamptz not null default now()
 );
 
+create index if not exists price_ticks_paper_created_idx on public.price_ticks(paper_name, created_at);
+
 create table if not exists public.storage(
     id uuid not null references public.users(id),
     paper_name text not null references public.papers(name),
@@ -41,6 +240,53 @@ create table if not exists public.storage(
     primary key (id, paper_name)
 );
 
+create table if not exists public.orders(
+    id uuid not null primary key default uuid_generate_v4(),
+    user_id uuid not null references public.users(id),
+    paper_name text not null references public.papers(name),
+    side text not null check (side in ('buy', 'sell')),
+    type text not null check (type in ('limit', 'market')),
+    price numeric(20, 2) not null default 0,
+    amount int not null check (amount > 0),
+    filled int not null default 0,
+    status text not null check (status in ('open', 'partial', 'filled', 'cancelled')),
+    -- Резерв денег лимитной покупки в balance
+    hold_id uuid references public.holds(id),
+    created_at timestamptz not null default now()
+);
+
+create index if not exists orders_open_idx on public.orders(paper_name, created_at) where status in ('open', 'partial');
+
+create table if not exists public.trades(
+    id uuid not null primary key default uuid_generate_v4(),
+    user_id uuid not null references public.users(id),
+    paper_name text not null references public.papers(name),
+    side text not null check (side in ('buy', 'sell')),
+    amount int not null,
+    price numeric(20, 2) not null,
+    fee numeric(20, 2) not null default 0,
+    status text not null,
+    order_id uuid references public.orders(id),
+    created_at timestamptz not null default now()
+);
+
+create index if not exists trades_user_created_idx on public.trades(user_id, created_at desc, id desc);
+
+-- Денежные расчеты papers по записанным сделкам, заявкам и отменам, еще не проведенные в balance. Пишутся в транзакции
+-- сделки и удаляются после проведения. С выплатами(payouts) - списание резерва hold_id(пустой - сначала резерв market
+-- на cash в currency), без них - возврат cash из резерва владельцу(0 - всего остатка)
+create table if not exists public.settlements(
+    id uuid not null primary key default uuid_generate_v4(),
+    hold_id uuid references public.holds(id),
+    currency text references public.currencies(code),
+    cash numeric(20, 2) not null default 0,
+    payouts jsonb not null default '[]',
+    reference uuid,
+    attempts int not null default 0,
+    last_error text,
+    created_at timestamptz not null default now()
+);
+
 insert into public.papers(name, price) values('Dogecoin', '100');
 insert into public.papers(name, price) values('Amogus', '12.5');
 insert into public.papers(name, price) values('Ichor', '666666');
\ No newline at end of file
@@ -0,0 +1,50 @@
+-- Таблицы и колонки торгов для БД, созданных до них(сделки, заявки, история цен, листинг и остановка торгов).
+-- Идет перед 0001: цены создаются real, как в dbInit.sql того времени, и переводятся в numeric следующей миграцией
+begin;
+
+create table if not exists public.price_ticks(
+    paper_name text not null references public.papers(name),
+    price real not null,
+    created_at timestamptz not null default now()
+);
+
+create index if not exists price_ticks_paper_created_idx on public.price_ticks(paper_name, created_at);
+
+-- past_prices хранил цены без времени, свечи по ним не построить
+alter table public.papers drop column if exists past_prices;
+alter table public.papers add column if not exists listed boolean not null default true;
+alter table public.papers add column if not exists halted boolean not null default false;
+alter table public.papers add column if not exists halted_until timestamptz;
+
+create table if not exists public.orders(
+    id uuid not null primary key default uuid_generate_v4(),
+    user_id uuid not null references public.users(id),
+    paper_name text not null references public.papers(name),
+    side text not null check (side in ('buy', 'sell')),
+    type text not null check (type in ('limit', 'market')),
+    price real not null default 0,
+    amount int not null check (amount > 0),
+    filled int not null default 0,
+    status text not null check (status in ('open', 'partial', 'filled', 'cancelled')),
+    created_at timestamptz not null default now()
+);
+
+create index if not exists orders_open_idx on public.orders(paper_name, created_at) where status in ('open', 'partial');
+
+create table if not exists public.trades(
+    id uuid not null primary key default uuid_generate_v4(),
+    user_id uuid not null references public.users(id),
+    paper_name text not null references public.papers(name),
+    side text not null check (side in ('buy', 'sell')),
+    amount int not null,
+    price real not null,
+    fee real not null default 0,
+    status text not null,
+    created_at timestamptz not null default now()
+);
+
+alter table public.trades add column if not exists order_id uuid references public.orders(id);
+
+create index if not exists trades_user_created_idx on public.trades(user_id, created_at desc, id desc);
+
+commit;
@@ -0,0 +1,11 @@
+-- Перевод денег и цен из real в numeric для уже созданных БД(новые создаются dbInit.sql сразу с numeric)
+begin;
+
+alter table public.users alter column balance type numeric(20, 2) using round(balance::numeric, 2);
+alter table public.papers alter column price type numeric(20, 2) using round(price::numeric, 2);
+alter table public.price_ticks alter column price type numeric(20, 2) using round(price::numeric, 2);
+alter table public.orders alter column price type numeric(20, 2) using round(price::numeric, 2);
+alter table public.trades alter column price type numeric(20, 2) using round(price::numeric, 2);
+alter table public.trades alter column fee type numeric(20, 2) using round(fee::numeric, 2);
+
+commit;
@@ -0,0 +1,59 @@
+-- Перевод балансов на журнал двойной записи для уже созданных БД. Текущие балансы и резервы открытых лимитных покупок
+-- становятся одной записью opening со встречной проводкой на external, после чего users.balance удаляется
+begin;
+
+create table if not exists public.accounts(
+    id uuid not null primary key default uuid_generate_v4(),
+    kind text not null check (kind in ('cash', 'hold', 'external', 'market', 'fees')),
+    user_id uuid references public.users(id),
+    balance numeric(20, 2) not null default 0,
+    check ((user_id is null) = (kind in ('external', 'market', 'fees'))),
+    unique nulls not distinct (kind, user_id)
+);
+
+create table if not exists public.entries(
+    id uuid not null primary key default uuid_generate_v4(),
+    kind text not null check (kind in ('opening', 'deposit', 'withdrawal', 'trade', 'reserve', 'release')),
+    reference uuid,
+    created_at timestamptz not null default now()
+);
+
+create table if not exists public.postings(
+    id bigserial primary key,
+    entry_id uuid not null references public.entries(id),
+    account_id uuid not null references public.accounts(id),
+    amount numeric(20, 2) not null check (amount <> 0)
+);
+
+create index if not exists postings_entry_idx on public.postings(entry_id);
+create index if not exists postings_account_idx on public.postings(account_id, id);
+
+create or replace function public.check_entry_balanced() returns trigger language plpgsql as $$
+begin
+    if (select sum(amount) from public.postings where entry_id = new.entry_id) <> 0 then
+        raise exception 'postings of entry % do not sum to zero', new.entry_id;
+    end if;
+    return null;
+end $$;
+
+create constraint trigger postings_balanced after insert on public.postings
+    deferrable initially deferred for each row execute function public.check_entry_balanced();
+
+insert into public.accounts(kind) values('external'), ('market'), ('fees') on conflict do nothing;
+
+create temporary table opening on commit drop as
+    select 'cash' as kind, id as user_id, balance as amount from public.users where balance <> 0
+    union all
+    select 'hold', user_id, sum(price * (amount - filled)) from public.orders
+        where side = 'buy' and type = 'limit' and status in ('open', 'partial') group by user_id;
+
+insert into public.accounts(kind, user_id, balance) select kind, user_id, amount from opening;
+update public.accounts set balance = -(select coalesce(sum(amount), 0) from opening) where kind = 'external';
+
+with entry as (insert into public.entries(kind) select 'opening' where exists (select 1 from opening) returning id)
+insert into public.postings(entry_id, account_id, amount)
+    select entry.id, accounts.id, accounts.balance from entry, public.accounts where accounts.balance <> 0;
+
+alter table public.users drop column balance;
+
+commit;
@@ -0,0 +1,21 @@
+-- Резервы balance для уже созданных БД. Деньги открытых лимитных покупок уже лежат на счетах hold(0002_ledger.sql),
+-- каждая такая заявка получает свой резерв на неисполненный остаток
+begin;
+
+create table if not exists public.holds(
+    id uuid not null primary key default uuid_generate_v4(),
+    user_id uuid references public.users(id),
+    amount numeric(20, 2) not null check (amount >= 0),
+    reference uuid,
+    created_at timestamptz not null default now()
+);
+
+alter table public.orders add column if not exists hold_id uuid references public.holds(id);
+
+insert into public.holds(user_id, amount, reference)
+    select user_id, price * (amount - filled), id from public.orders
+    where side = 'buy' and type = 'limit' and status in ('open', 'partial') and hold_id is null;
+
+update public.orders set hold_id = holds.id from public.holds where holds.reference = orders.id and orders.hold_id is null;
+
+commit;
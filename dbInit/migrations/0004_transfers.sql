@@ -0,0 +1,10 @@
+-- Переводы между пользователями для уже созданных БД: новый вид записей журнала и комментарий к переводу
+begin;
+
+alter table public.entries drop constraint if exists entries_kind_check;
+alter table public.entries add constraint entries_kind_check
+    check (kind in ('opening', 'deposit', 'withdrawal', 'trade', 'reserve', 'release', 'transfer'));
+
+alter table public.entries add column if not exists memo text;
+
+commit;
@@ -0,0 +1,37 @@
+-- Мультивалютные кошельки для уже созданных БД. Все существующие деньги, резервы и бумаги становятся долларовыми:
+-- у счетов, резервов и бумаг появляется валюта, у каждой валюты - свои системные счета
+begin;
+
+create table if not exists public.currencies(
+    code text not null primary key,
+    rate numeric(20, 8) not null check (rate > 0),
+    updated_at timestamptz not null default now()
+);
+
+insert into public.currencies(code, rate) values('USD', 1), ('EUR', 1.08), ('RUB', 0.011) on conflict (code) do nothing;
+
+alter table public.accounts add column if not exists currency text not null default 'USD' references public.currencies(code);
+alter table public.accounts drop constraint if exists accounts_kind_check;
+alter table public.accounts add constraint accounts_kind_check check (kind in ('cash', 'hold', 'external', 'market', 'fees', 'fx'));
+alter table public.accounts drop constraint if exists accounts_check;
+alter table public.accounts add constraint accounts_check check ((user_id is null) = (kind in ('external', 'market', 'fees', 'fx')));
+alter table public.accounts drop constraint if exists accounts_kind_user_id_key;
+alter table public.accounts add constraint accounts_kind_user_id_currency_key unique nulls not distinct (kind, user_id, currency);
+
+alter table public.entries drop constraint if exists entries_kind_check;
+alter table public.entries add constraint entries_kind_check
+    check (kind in ('opening', 'deposit', 'withdrawal', 'trade', 'reserve', 'release', 'transfer', 'exchange'));
+
+create or replace function public.check_entry_balanced() returns trigger language plpgsql as $$
+begin
+    if exists (select 1 from public.postings p join public.accounts a on a.id = p.account_id
+        where p.entry_id = new.entry_id group by a.currency having sum(p.amount) <> 0) then
+        raise exception 'postings of entry % do not sum to zero', new.entry_id;
+    end if;
+    return null;
+end $$;
+
+alter table public.holds add column if not exists currency text not null default 'USD' references public.currencies(code);
+alter table public.papers add column if not exists currency text not null default 'USD' references public.currencies(code);
+
+commit;
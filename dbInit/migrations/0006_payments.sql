@@ -0,0 +1,17 @@
+-- Пополнения через платежного провайдера для уже созданных БД
+begin;
+
+create table if not exists public.payments(
+    id uuid not null primary key default uuid_generate_v4(),
+    user_id uuid not null references public.users(id),
+    amount numeric(20, 2) not null check (amount > 0),
+    currency text not null references public.currencies(code),
+    provider text not null,
+    status text not null default 'pending' check (status in ('pending', 'settled', 'failed')),
+    reason text,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+create index if not exists payments_user_created_idx on public.payments(user_id, created_at desc);
+
+commit;
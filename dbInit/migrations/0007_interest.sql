@@ -0,0 +1,33 @@
+-- Проценты на остаток для уже созданных БД: системный счет interest, новый вид записей журнала и таблицы начислений
+begin;
+
+alter table public.accounts drop constraint if exists accounts_kind_check;
+alter table public.accounts add constraint accounts_kind_check check (kind in ('cash', 'hold', 'external', 'market', 'fees', 'fx', 'interest'));
+alter table public.accounts drop constraint if exists accounts_check;
+alter table public.accounts add constraint accounts_check check ((user_id is null) = (kind in ('external', 'market', 'fees', 'fx', 'interest')));
+
+alter table public.entries drop constraint if exists entries_kind_check;
+alter table public.entries add constraint entries_kind_check
+    check (kind in ('opening', 'deposit', 'withdrawal', 'trade', 'reserve', 'release', 'transfer', 'exchange', 'interest'));
+
+create table if not exists public.interest_days(
+    day date not null primary key,
+    created_at timestamptz not null default now()
+);
+
+create table if not exists public.interest_accruals(
+    account_id uuid not null references public.accounts(id),
+    day date not null references public.interest_days(day),
+    balance numeric(20, 2) not null,
+    amount numeric(20, 8) not null,
+    primary key (account_id, day)
+);
+
+create table if not exists public.interest_payouts(
+    account_id uuid not null references public.accounts(id),
+    month date not null,
+    amount numeric(20, 2) not null,
+    primary key (account_id, month)
+);
+
+commit;
@@ -0,0 +1,17 @@
+-- Ключи идемпотентности для уже созданных БД
+begin;
+
+-- Ключи идемпотентности изменяющих запросов balance и papers. scope - сервис и метод, request_hash - sha256 запроса:
+-- тот же ключ с другим запросом отклоняется. Пока response пуст, запрос выполняется. После expires_at ключ можно занять снова
+create table if not exists public.idempotency_keys(
+    scope text not null,
+    key text not null,
+    request_hash text not null,
+    response bytea,
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+    primary key (scope, key)
+);
+create index if not exists idempotency_keys_expires_idx on public.idempotency_keys(expires_at);
+
+commit;
@@ -0,0 +1,24 @@
+-- Сессии с ротацией refresh токенов для уже созданных БД. Старые refresh токены не переносятся: после миграции
+-- нужно войти заново
+begin;
+
+-- Сессии входа aus. Строка - один refresh токен(id - его jti), токены одного входа образуют семью family_id.
+-- Обмен токена помечает его rotated_at и выдает новый той же семьи. Повторный обмен уже обмененного токена - признак
+-- кражи: вся семья отзывается(revoked_at)
+create table if not exists public.sessions(
+    id uuid not null primary key,
+    family_id uuid not null,
+    user_id uuid not null references public.users(id),
+    user_agent text not null default '',
+    ip text not null default '',
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+    rotated_at timestamptz,
+    revoked_at timestamptz
+);
+create index if not exists sessions_user_idx on public.sessions(user_id);
+create index if not exists sessions_family_idx on public.sessions(family_id);
+
+alter table public.users drop column if exists refresh_token;
+
+commit;
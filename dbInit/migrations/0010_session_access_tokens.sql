@@ -0,0 +1,7 @@
+-- jti access токенов сессий для denylist при выходе для уже созданных БД
+begin;
+
+alter table public.sessions add column if not exists access_id uuid;
+alter table public.sessions add column if not exists access_expires_at timestamptz;
+
+commit;
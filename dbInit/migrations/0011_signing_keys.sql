@@ -0,0 +1,12 @@
+-- Ключи подписи токенов в aus вместо приватного ключа в конфигах сервисов
+begin;
+
+create table if not exists public.signing_keys(
+    id text not null primary key,
+    private_key bytea not null,
+    created_at timestamptz not null default now(),
+    retired_at timestamptz,
+    expires_at timestamptz
+);
+
+commit;
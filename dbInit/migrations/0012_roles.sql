@@ -0,0 +1,25 @@
+-- Роли пользователей вместо admin_ids в конфиге gateway. Администраторов из admin_ids нужно перенести:
+-- insert into public.user_roles(user_id, role) values('<id>', 'admin');
+begin;
+
+-- Роли и их права. Права встраиваются в access токен, gateway сверяет их с таблицей политик рутов.
+-- market:read - просмотр управления рынком, market:write - изменение рынка
+create table if not exists public.roles(
+    name text not null primary key,
+    permissions text[] not null default '{}'
+);
+
+insert into public.roles(name, permissions) values
+    ('admin', '{market:read, market:write}'),
+    ('auditor', '{market:read}')
+on conflict do nothing;
+
+-- Роли пользователей. Пользователь без ролей обычный: ему доступны только его собственные данные.
+-- Роль выдается вставкой строки и попадает в токены при следующем обновлении
+create table if not exists public.user_roles(
+    user_id uuid not null references public.users(id),
+    role text not null references public.roles(name),
+    primary key (user_id, role)
+);
+
+commit;
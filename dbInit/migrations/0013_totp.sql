@@ -0,0 +1,23 @@
+-- Двухфакторная аутентификация TOTP для уже созданных БД
+begin;
+
+-- TOTP(RFC 6238) пользователей. secret - base32 секрет приложения, activated_at - когда 2FA включена первым кодом
+-- (до этого секрет ждет подтверждения), last_step - последний принятый шаг времени: один код дважды не принимается
+create table if not exists public.totp(
+    user_id uuid not null primary key references public.users(id),
+    secret text not null,
+    created_at timestamptz not null default now(),
+    activated_at timestamptz,
+    last_step bigint not null default 0
+);
+
+-- Одноразовые коды восстановления 2FA, хранятся только bcrypt хэши. Выдаются заново при каждом включении 2FA
+create table if not exists public.recovery_codes(
+    id uuid not null primary key default uuid_generate_v4(),
+    user_id uuid not null references public.users(id),
+    code_hash text not null,
+    used_at timestamptz
+);
+create index if not exists recovery_codes_user_idx on public.recovery_codes(user_id);
+
+commit;
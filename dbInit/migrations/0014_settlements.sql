@@ -0,0 +1,19 @@
+-- Очередь денежных расчетов papers для уже созданных БД
+begin;
+
+-- Денежные расчеты papers по записанным сделкам, заявкам и отменам, еще не проведенные в balance. Пишутся в транзакции
+-- сделки и удаляются после проведения. С выплатами(payouts) - списание резерва hold_id(пустой - сначала резерв market
+-- на cash в currency), без них - возврат cash из резерва владельцу(0 - всего остатка)
+create table if not exists public.settlements(
+    id uuid not null primary key default uuid_generate_v4(),
+    hold_id uuid references public.holds(id),
+    currency text references public.currencies(code),
+    cash numeric(20, 2) not null default 0,
+    payouts jsonb not null default '[]',
+    reference uuid,
+    attempts int not null default 0,
+    last_error text,
+    created_at timestamptz not null default now()
+);
+
+commit;
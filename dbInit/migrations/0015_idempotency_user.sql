@@ -0,0 +1,9 @@
+-- Ключи идемпотентности в пределах пользователя для уже созданных БД. Старые ключи остаются за нулевым пользователем
+-- и истекают как обычно
+begin;
+
+alter table public.idempotency_keys add column if not exists user_id uuid not null default '00000000-0000-0000-0000-000000000000';
+alter table public.idempotency_keys drop constraint if exists idempotency_keys_pkey;
+alter table public.idempotency_keys add primary key (scope, user_id, key);
+
+commit;
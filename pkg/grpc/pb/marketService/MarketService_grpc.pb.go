@@ -0,0 +1,311 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.3
+// source: MarketService.proto
+
+package __
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	MarketAdmin_ListPapers_FullMethodName  = "/MarketService.MarketAdmin/ListPapers"
+	MarketAdmin_CreatePaper_FullMethodName = "/MarketService.MarketAdmin/CreatePaper"
+	MarketAdmin_DelistPaper_FullMethodName = "/MarketService.MarketAdmin/DelistPaper"
+	MarketAdmin_HaltPaper_FullMethodName   = "/MarketService.MarketAdmin/HaltPaper"
+	MarketAdmin_ResumePaper_FullMethodName = "/MarketService.MarketAdmin/ResumePaper"
+	MarketAdmin_SetPrice_FullMethodName    = "/MarketService.MarketAdmin/SetPrice"
+)
+
+// MarketAdminClient is the client API for MarketAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MarketAdminClient interface {
+	ListPapers(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Papers, error)
+	CreatePaper(ctx context.Context, in *Paper, opts ...grpc.CallOption) (*Paper, error)
+	DelistPaper(ctx context.Context, in *PaperName, opts ...grpc.CallOption) (*Paper, error)
+	HaltPaper(ctx context.Context, in *PaperName, opts ...grpc.CallOption) (*Paper, error)
+	ResumePaper(ctx context.Context, in *PaperName, opts ...grpc.CallOption) (*Paper, error)
+	SetPrice(ctx context.Context, in *Paper, opts ...grpc.CallOption) (*Paper, error)
+}
+
+type marketAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMarketAdminClient(cc grpc.ClientConnInterface) MarketAdminClient {
+	return &marketAdminClient{cc}
+}
+
+func (c *marketAdminClient) ListPapers(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Papers, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Papers)
+	err := c.cc.Invoke(ctx, MarketAdmin_ListPapers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketAdminClient) CreatePaper(ctx context.Context, in *Paper, opts ...grpc.CallOption) (*Paper, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Paper)
+	err := c.cc.Invoke(ctx, MarketAdmin_CreatePaper_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketAdminClient) DelistPaper(ctx context.Context, in *PaperName, opts ...grpc.CallOption) (*Paper, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Paper)
+	err := c.cc.Invoke(ctx, MarketAdmin_DelistPaper_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketAdminClient) HaltPaper(ctx context.Context, in *PaperName, opts ...grpc.CallOption) (*Paper, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Paper)
+	err := c.cc.Invoke(ctx, MarketAdmin_HaltPaper_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketAdminClient) ResumePaper(ctx context.Context, in *PaperName, opts ...grpc.CallOption) (*Paper, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Paper)
+	err := c.cc.Invoke(ctx, MarketAdmin_ResumePaper_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketAdminClient) SetPrice(ctx context.Context, in *Paper, opts ...grpc.CallOption) (*Paper, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Paper)
+	err := c.cc.Invoke(ctx, MarketAdmin_SetPrice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MarketAdminServer is the server API for MarketAdmin service.
+// All implementations must embed UnimplementedMarketAdminServer
+// for forward compatibility.
+type MarketAdminServer interface {
+	ListPapers(context.Context, *Request) (*Papers, error)
+	CreatePaper(context.Context, *Paper) (*Paper, error)
+	DelistPaper(context.Context, *PaperName) (*Paper, error)
+	HaltPaper(context.Context, *PaperName) (*Paper, error)
+	ResumePaper(context.Context, *PaperName) (*Paper, error)
+	SetPrice(context.Context, *Paper) (*Paper, error)
+	mustEmbedUnimplementedMarketAdminServer()
+}
+
+// UnimplementedMarketAdminServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMarketAdminServer struct{}
+
+func (UnimplementedMarketAdminServer) ListPapers(context.Context, *Request) (*Papers, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPapers not implemented")
+}
+func (UnimplementedMarketAdminServer) CreatePaper(context.Context, *Paper) (*Paper, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePaper not implemented")
+}
+func (UnimplementedMarketAdminServer) DelistPaper(context.Context, *PaperName) (*Paper, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DelistPaper not implemented")
+}
+func (UnimplementedMarketAdminServer) HaltPaper(context.Context, *PaperName) (*Paper, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HaltPaper not implemented")
+}
+func (UnimplementedMarketAdminServer) ResumePaper(context.Context, *PaperName) (*Paper, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumePaper not implemented")
+}
+func (UnimplementedMarketAdminServer) SetPrice(context.Context, *Paper) (*Paper, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPrice not implemented")
+}
+func (UnimplementedMarketAdminServer) mustEmbedUnimplementedMarketAdminServer() {}
+func (UnimplementedMarketAdminServer) testEmbeddedByValue()                     {}
+
+// UnsafeMarketAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MarketAdminServer will
+// result in compilation errors.
+type UnsafeMarketAdminServer interface {
+	mustEmbedUnimplementedMarketAdminServer()
+}
+
+func RegisterMarketAdminServer(s grpc.ServiceRegistrar, srv MarketAdminServer) {
+	// If the following call pancis, it indicates UnimplementedMarketAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MarketAdmin_ServiceDesc, srv)
+}
+
+func _MarketAdmin_ListPapers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketAdminServer).ListPapers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketAdmin_ListPapers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketAdminServer).ListPapers(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketAdmin_CreatePaper_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Paper)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketAdminServer).CreatePaper(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketAdmin_CreatePaper_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketAdminServer).CreatePaper(ctx, req.(*Paper))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketAdmin_DelistPaper_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaperName)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketAdminServer).DelistPaper(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketAdmin_DelistPaper_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketAdminServer).DelistPaper(ctx, req.(*PaperName))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketAdmin_HaltPaper_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaperName)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketAdminServer).HaltPaper(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketAdmin_HaltPaper_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketAdminServer).HaltPaper(ctx, req.(*PaperName))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketAdmin_ResumePaper_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaperName)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketAdminServer).ResumePaper(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketAdmin_ResumePaper_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketAdminServer).ResumePaper(ctx, req.(*PaperName))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketAdmin_SetPrice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Paper)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketAdminServer).SetPrice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketAdmin_SetPrice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketAdminServer).SetPrice(ctx, req.(*Paper))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MarketAdmin_ServiceDesc is the grpc.ServiceDesc for MarketAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MarketAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "MarketService.MarketAdmin",
+	HandlerType: (*MarketAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListPapers",
+			Handler:    _MarketAdmin_ListPapers_Handler,
+		},
+		{
+			MethodName: "CreatePaper",
+			Handler:    _MarketAdmin_CreatePaper_Handler,
+		},
+		{
+			MethodName: "DelistPaper",
+			Handler:    _MarketAdmin_DelistPaper_Handler,
+		},
+		{
+			MethodName: "HaltPaper",
+			Handler:    _MarketAdmin_HaltPaper_Handler,
+		},
+		{
+			MethodName: "ResumePaper",
+			Handler:    _MarketAdmin_ResumePaper_Handler,
+		},
+		{
+			MethodName: "SetPrice",
+			Handler:    _MarketAdmin_SetPrice_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "MarketService.proto",
+}
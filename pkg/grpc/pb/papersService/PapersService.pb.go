@@ -101,13 +101,16 @@ func (*Request) Descriptor() ([]byte, []int) {
 	return file_PapersService_proto_rawDescGZIP(), []int{1}
 }
 
+// Цены и суммы в минорных единицах(копейках) передаются в *Minor полях. Float поля оставлены для старых клиентов:
+// сервер читает их, только если *Minor поле пустое, и заполняет оба поля в ответах
 type Paper struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Name  string  `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Price float32 `protobuf:"fixed32,2,opt,name=price,proto3" json:"price,omitempty"`
+	Name       string  `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Price      float32 `protobuf:"fixed32,2,opt,name=price,proto3" json:"price,omitempty"`
+	PriceMinor int64   `protobuf:"varint,3,opt,name=priceMinor,proto3" json:"priceMinor,omitempty"`
 }
 
 func (x *Paper) Reset() {
@@ -154,14 +157,25 @@ func (x *Paper) GetPrice() float32 {
 	return 0
 }
 
+func (x *Paper) GetPriceMinor() int64 {
+	if x != nil {
+		return x.PriceMinor
+	}
+	return 0
+}
+
+// idempotencyKey - ключ идемпотентности клиента для BuyPaper и SellPaper: повтор с тем же ключом возвращает первый ответ,
+// не проводя сделку снова. Тот же ключ с другим запросом - ошибка AlreadyExists, пока первый запрос выполняется - Aborted
 type PaperRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	UserId      []byte `protobuf:"bytes,1,opt,name=userId,proto3" json:"userId,omitempty"`
-	PaperName   string `protobuf:"bytes,2,opt,name=paperName,proto3" json:"paperName,omitempty"`
-	PaperAmount int32  `protobuf:"varint,3,opt,name=paperAmount,proto3" json:"paperAmount,omitempty"`
+	UserId         []byte   `protobuf:"bytes,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	PaperName      string   `protobuf:"bytes,2,opt,name=paperName,proto3" json:"paperName,omitempty"`
+	PaperAmount    int32    `protobuf:"varint,3,opt,name=paperAmount,proto3" json:"paperAmount,omitempty"`
+	PaperNames     []string `protobuf:"bytes,4,rep,name=paperNames,proto3" json:"paperNames,omitempty"`
+	IdempotencyKey string   `protobuf:"bytes,5,opt,name=idempotencyKey,proto3" json:"idempotencyKey,omitempty"`
 }
 
 func (x *PaperRequest) Reset() {
@@ -215,6 +229,20 @@ func (x *PaperRequest) GetPaperAmount() int32 {
 	return 0
 }
 
+func (x *PaperRequest) GetPaperNames() []string {
+	if x != nil {
+		return x.PaperNames
+	}
+	return nil
+}
+
+func (x *PaperRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
 type AvailablePapers struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -305,6 +333,955 @@ func (x *Status) GetResponse() string {
 	return ""
 }
 
+type TradeHistoryRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UserId    []byte `protobuf:"bytes,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	PaperName string `protobuf:"bytes,2,opt,name=paperName,proto3" json:"paperName,omitempty"`
+	From      int64  `protobuf:"varint,3,opt,name=from,proto3" json:"from,omitempty"`
+	To        int64  `protobuf:"varint,4,opt,name=to,proto3" json:"to,omitempty"`
+	Cursor    string `protobuf:"bytes,5,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Limit     int32  `protobuf:"varint,6,opt,name=limit,proto3" json:"limit,omitempty"`
+}
+
+func (x *TradeHistoryRequest) Reset() {
+	*x = TradeHistoryRequest{}
+	mi := &file_PapersService_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TradeHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TradeHistoryRequest) ProtoMessage() {}
+
+func (x *TradeHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_PapersService_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TradeHistoryRequest.ProtoReflect.Descriptor instead.
+func (*TradeHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_PapersService_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TradeHistoryRequest) GetUserId() []byte {
+	if x != nil {
+		return x.UserId
+	}
+	return nil
+}
+
+func (x *TradeHistoryRequest) GetPaperName() string {
+	if x != nil {
+		return x.PaperName
+	}
+	return ""
+}
+
+func (x *TradeHistoryRequest) GetFrom() int64 {
+	if x != nil {
+		return x.From
+	}
+	return 0
+}
+
+func (x *TradeHistoryRequest) GetTo() int64 {
+	if x != nil {
+		return x.To
+	}
+	return 0
+}
+
+func (x *TradeHistoryRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *TradeHistoryRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type Trade struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id         []byte  `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	PaperName  string  `protobuf:"bytes,2,opt,name=paperName,proto3" json:"paperName,omitempty"`
+	Side       string  `protobuf:"bytes,3,opt,name=side,proto3" json:"side,omitempty"`
+	Amount     int32   `protobuf:"varint,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	Price      float32 `protobuf:"fixed32,5,opt,name=price,proto3" json:"price,omitempty"`
+	Fee        float32 `protobuf:"fixed32,6,opt,name=fee,proto3" json:"fee,omitempty"`
+	Status     string  `protobuf:"bytes,7,opt,name=status,proto3" json:"status,omitempty"`
+	ExecutedAt int64   `protobuf:"varint,8,opt,name=executedAt,proto3" json:"executedAt,omitempty"`
+	PriceMinor int64   `protobuf:"varint,9,opt,name=priceMinor,proto3" json:"priceMinor,omitempty"`
+	FeeMinor   int64   `protobuf:"varint,10,opt,name=feeMinor,proto3" json:"feeMinor,omitempty"`
+}
+
+func (x *Trade) Reset() {
+	*x = Trade{}
+	mi := &file_PapersService_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Trade) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Trade) ProtoMessage() {}
+
+func (x *Trade) ProtoReflect() protoreflect.Message {
+	mi := &file_PapersService_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Trade.ProtoReflect.Descriptor instead.
+func (*Trade) Descriptor() ([]byte, []int) {
+	return file_PapersService_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Trade) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *Trade) GetPaperName() string {
+	if x != nil {
+		return x.PaperName
+	}
+	return ""
+}
+
+func (x *Trade) GetSide() string {
+	if x != nil {
+		return x.Side
+	}
+	return ""
+}
+
+func (x *Trade) GetAmount() int32 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *Trade) GetPrice() float32 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Trade) GetFee() float32 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+func (x *Trade) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Trade) GetExecutedAt() int64 {
+	if x != nil {
+		return x.ExecutedAt
+	}
+	return 0
+}
+
+func (x *Trade) GetPriceMinor() int64 {
+	if x != nil {
+		return x.PriceMinor
+	}
+	return 0
+}
+
+func (x *Trade) GetFeeMinor() int64 {
+	if x != nil {
+		return x.FeeMinor
+	}
+	return 0
+}
+
+type TradeHistory struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Trades     []*Trade `protobuf:"bytes,1,rep,name=trades,proto3" json:"trades,omitempty"`
+	NextCursor string   `protobuf:"bytes,2,opt,name=nextCursor,proto3" json:"nextCursor,omitempty"`
+}
+
+func (x *TradeHistory) Reset() {
+	*x = TradeHistory{}
+	mi := &file_PapersService_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TradeHistory) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TradeHistory) ProtoMessage() {}
+
+func (x *TradeHistory) ProtoReflect() protoreflect.Message {
+	mi := &file_PapersService_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TradeHistory.ProtoReflect.Descriptor instead.
+func (*TradeHistory) Descriptor() ([]byte, []int) {
+	return file_PapersService_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *TradeHistory) GetTrades() []*Trade {
+	if x != nil {
+		return x.Trades
+	}
+	return nil
+}
+
+func (x *TradeHistory) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+// idempotencyKey - ключ идемпотентности для PlaceOrder и ReplaceOrder, как в PaperRequest
+type OrderRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UserId         []byte  `protobuf:"bytes,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	OrderId        []byte  `protobuf:"bytes,2,opt,name=orderId,proto3" json:"orderId,omitempty"`
+	PaperName      string  `protobuf:"bytes,3,opt,name=paperName,proto3" json:"paperName,omitempty"`
+	Side           string  `protobuf:"bytes,4,opt,name=side,proto3" json:"side,omitempty"`
+	Type           string  `protobuf:"bytes,5,opt,name=type,proto3" json:"type,omitempty"`
+	Price          float32 `protobuf:"fixed32,6,opt,name=price,proto3" json:"price,omitempty"`
+	Amount         int32   `protobuf:"varint,7,opt,name=amount,proto3" json:"amount,omitempty"`
+	PriceMinor     int64   `protobuf:"varint,8,opt,name=priceMinor,proto3" json:"priceMinor,omitempty"`
+	IdempotencyKey string  `protobuf:"bytes,9,opt,name=idempotencyKey,proto3" json:"idempotencyKey,omitempty"`
+}
+
+func (x *OrderRequest) Reset() {
+	*x = OrderRequest{}
+	mi := &file_PapersService_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderRequest) ProtoMessage() {}
+
+func (x *OrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_PapersService_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderRequest.ProtoReflect.Descriptor instead.
+func (*OrderRequest) Descriptor() ([]byte, []int) {
+	return file_PapersService_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *OrderRequest) GetUserId() []byte {
+	if x != nil {
+		return x.UserId
+	}
+	return nil
+}
+
+func (x *OrderRequest) GetOrderId() []byte {
+	if x != nil {
+		return x.OrderId
+	}
+	return nil
+}
+
+func (x *OrderRequest) GetPaperName() string {
+	if x != nil {
+		return x.PaperName
+	}
+	return ""
+}
+
+func (x *OrderRequest) GetSide() string {
+	if x != nil {
+		return x.Side
+	}
+	return ""
+}
+
+func (x *OrderRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *OrderRequest) GetPrice() float32 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *OrderRequest) GetAmount() int32 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *OrderRequest) GetPriceMinor() int64 {
+	if x != nil {
+		return x.PriceMinor
+	}
+	return 0
+}
+
+func (x *OrderRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type Order struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id         []byte  `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	PaperName  string  `protobuf:"bytes,2,opt,name=paperName,proto3" json:"paperName,omitempty"`
+	Side       string  `protobuf:"bytes,3,opt,name=side,proto3" json:"side,omitempty"`
+	Type       string  `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`
+	Price      float32 `protobuf:"fixed32,5,opt,name=price,proto3" json:"price,omitempty"`
+	Amount     int32   `protobuf:"varint,6,opt,name=amount,proto3" json:"amount,omitempty"`
+	Filled     int32   `protobuf:"varint,7,opt,name=filled,proto3" json:"filled,omitempty"`
+	Status     string  `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"`
+	CreatedAt  int64   `protobuf:"varint,9,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	Response   string  `protobuf:"bytes,10,opt,name=response,proto3" json:"response,omitempty"`
+	PriceMinor int64   `protobuf:"varint,11,opt,name=priceMinor,proto3" json:"priceMinor,omitempty"`
+}
+
+func (x *Order) Reset() {
+	*x = Order{}
+	mi := &file_PapersService_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Order) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Order) ProtoMessage() {}
+
+func (x *Order) ProtoReflect() protoreflect.Message {
+	mi := &file_PapersService_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Order.ProtoReflect.Descriptor instead.
+func (*Order) Descriptor() ([]byte, []int) {
+	return file_PapersService_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Order) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *Order) GetPaperName() string {
+	if x != nil {
+		return x.PaperName
+	}
+	return ""
+}
+
+func (x *Order) GetSide() string {
+	if x != nil {
+		return x.Side
+	}
+	return ""
+}
+
+func (x *Order) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Order) GetPrice() float32 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Order) GetAmount() int32 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *Order) GetFilled() int32 {
+	if x != nil {
+		return x.Filled
+	}
+	return 0
+}
+
+func (x *Order) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Order) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *Order) GetResponse() string {
+	if x != nil {
+		return x.Response
+	}
+	return ""
+}
+
+func (x *Order) GetPriceMinor() int64 {
+	if x != nil {
+		return x.PriceMinor
+	}
+	return 0
+}
+
+type Orders struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Orders []*Order `protobuf:"bytes,1,rep,name=orders,proto3" json:"orders,omitempty"`
+}
+
+func (x *Orders) Reset() {
+	*x = Orders{}
+	mi := &file_PapersService_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Orders) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Orders) ProtoMessage() {}
+
+func (x *Orders) ProtoReflect() protoreflect.Message {
+	mi := &file_PapersService_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Orders.ProtoReflect.Descriptor instead.
+func (*Orders) Descriptor() ([]byte, []int) {
+	return file_PapersService_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Orders) GetOrders() []*Order {
+	if x != nil {
+		return x.Orders
+	}
+	return nil
+}
+
+type OrderBookRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PaperName string `protobuf:"bytes,1,opt,name=paperName,proto3" json:"paperName,omitempty"`
+	Depth     int32  `protobuf:"varint,2,opt,name=depth,proto3" json:"depth,omitempty"`
+}
+
+func (x *OrderBookRequest) Reset() {
+	*x = OrderBookRequest{}
+	mi := &file_PapersService_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderBookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderBookRequest) ProtoMessage() {}
+
+func (x *OrderBookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_PapersService_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderBookRequest.ProtoReflect.Descriptor instead.
+func (*OrderBookRequest) Descriptor() ([]byte, []int) {
+	return file_PapersService_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *OrderBookRequest) GetPaperName() string {
+	if x != nil {
+		return x.PaperName
+	}
+	return ""
+}
+
+func (x *OrderBookRequest) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+type PriceLevel struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Price      float32 `protobuf:"fixed32,1,opt,name=price,proto3" json:"price,omitempty"`
+	Amount     int32   `protobuf:"varint,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	PriceMinor int64   `protobuf:"varint,3,opt,name=priceMinor,proto3" json:"priceMinor,omitempty"`
+}
+
+func (x *PriceLevel) Reset() {
+	*x = PriceLevel{}
+	mi := &file_PapersService_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PriceLevel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PriceLevel) ProtoMessage() {}
+
+func (x *PriceLevel) ProtoReflect() protoreflect.Message {
+	mi := &file_PapersService_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PriceLevel.ProtoReflect.Descriptor instead.
+func (*PriceLevel) Descriptor() ([]byte, []int) {
+	return file_PapersService_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PriceLevel) GetPrice() float32 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *PriceLevel) GetAmount() int32 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *PriceLevel) GetPriceMinor() int64 {
+	if x != nil {
+		return x.PriceMinor
+	}
+	return 0
+}
+
+type OrderBook struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PaperName string        `protobuf:"bytes,1,opt,name=paperName,proto3" json:"paperName,omitempty"`
+	Bids      []*PriceLevel `protobuf:"bytes,2,rep,name=bids,proto3" json:"bids,omitempty"`
+	Asks      []*PriceLevel `protobuf:"bytes,3,rep,name=asks,proto3" json:"asks,omitempty"`
+}
+
+func (x *OrderBook) Reset() {
+	*x = OrderBook{}
+	mi := &file_PapersService_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderBook) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderBook) ProtoMessage() {}
+
+func (x *OrderBook) ProtoReflect() protoreflect.Message {
+	mi := &file_PapersService_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderBook.ProtoReflect.Descriptor instead.
+func (*OrderBook) Descriptor() ([]byte, []int) {
+	return file_PapersService_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *OrderBook) GetPaperName() string {
+	if x != nil {
+		return x.PaperName
+	}
+	return ""
+}
+
+func (x *OrderBook) GetBids() []*PriceLevel {
+	if x != nil {
+		return x.Bids
+	}
+	return nil
+}
+
+func (x *OrderBook) GetAsks() []*PriceLevel {
+	if x != nil {
+		return x.Asks
+	}
+	return nil
+}
+
+type PriceHistoryRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PaperName string `protobuf:"bytes,1,opt,name=paperName,proto3" json:"paperName,omitempty"`
+	Interval  string `protobuf:"bytes,2,opt,name=interval,proto3" json:"interval,omitempty"`
+	From      int64  `protobuf:"varint,3,opt,name=from,proto3" json:"from,omitempty"`
+	To        int64  `protobuf:"varint,4,opt,name=to,proto3" json:"to,omitempty"`
+}
+
+func (x *PriceHistoryRequest) Reset() {
+	*x = PriceHistoryRequest{}
+	mi := &file_PapersService_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PriceHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PriceHistoryRequest) ProtoMessage() {}
+
+func (x *PriceHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_PapersService_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PriceHistoryRequest.ProtoReflect.Descriptor instead.
+func (*PriceHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_PapersService_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *PriceHistoryRequest) GetPaperName() string {
+	if x != nil {
+		return x.PaperName
+	}
+	return ""
+}
+
+func (x *PriceHistoryRequest) GetInterval() string {
+	if x != nil {
+		return x.Interval
+	}
+	return ""
+}
+
+func (x *PriceHistoryRequest) GetFrom() int64 {
+	if x != nil {
+		return x.From
+	}
+	return 0
+}
+
+func (x *PriceHistoryRequest) GetTo() int64 {
+	if x != nil {
+		return x.To
+	}
+	return 0
+}
+
+type Candle struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Time       int64   `protobuf:"varint,1,opt,name=time,proto3" json:"time,omitempty"`
+	Open       float32 `protobuf:"fixed32,2,opt,name=open,proto3" json:"open,omitempty"`
+	High       float32 `protobuf:"fixed32,3,opt,name=high,proto3" json:"high,omitempty"`
+	Low        float32 `protobuf:"fixed32,4,opt,name=low,proto3" json:"low,omitempty"`
+	Close      float32 `protobuf:"fixed32,5,opt,name=close,proto3" json:"close,omitempty"`
+	OpenMinor  int64   `protobuf:"varint,6,opt,name=openMinor,proto3" json:"openMinor,omitempty"`
+	HighMinor  int64   `protobuf:"varint,7,opt,name=highMinor,proto3" json:"highMinor,omitempty"`
+	LowMinor   int64   `protobuf:"varint,8,opt,name=lowMinor,proto3" json:"lowMinor,omitempty"`
+	CloseMinor int64   `protobuf:"varint,9,opt,name=closeMinor,proto3" json:"closeMinor,omitempty"`
+}
+
+func (x *Candle) Reset() {
+	*x = Candle{}
+	mi := &file_PapersService_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Candle) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Candle) ProtoMessage() {}
+
+func (x *Candle) ProtoReflect() protoreflect.Message {
+	mi := &file_PapersService_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Candle.ProtoReflect.Descriptor instead.
+func (*Candle) Descriptor() ([]byte, []int) {
+	return file_PapersService_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *Candle) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *Candle) GetOpen() float32 {
+	if x != nil {
+		return x.Open
+	}
+	return 0
+}
+
+func (x *Candle) GetHigh() float32 {
+	if x != nil {
+		return x.High
+	}
+	return 0
+}
+
+func (x *Candle) GetLow() float32 {
+	if x != nil {
+		return x.Low
+	}
+	return 0
+}
+
+func (x *Candle) GetClose() float32 {
+	if x != nil {
+		return x.Close
+	}
+	return 0
+}
+
+func (x *Candle) GetOpenMinor() int64 {
+	if x != nil {
+		return x.OpenMinor
+	}
+	return 0
+}
+
+func (x *Candle) GetHighMinor() int64 {
+	if x != nil {
+		return x.HighMinor
+	}
+	return 0
+}
+
+func (x *Candle) GetLowMinor() int64 {
+	if x != nil {
+		return x.LowMinor
+	}
+	return 0
+}
+
+func (x *Candle) GetCloseMinor() int64 {
+	if x != nil {
+		return x.CloseMinor
+	}
+	return 0
+}
+
+type PriceHistory struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PaperName string    `protobuf:"bytes,1,opt,name=paperName,proto3" json:"paperName,omitempty"`
+	Interval  string    `protobuf:"bytes,2,opt,name=interval,proto3" json:"interval,omitempty"`
+	Candles   []*Candle `protobuf:"bytes,3,rep,name=candles,proto3" json:"candles,omitempty"`
+}
+
+func (x *PriceHistory) Reset() {
+	*x = PriceHistory{}
+	mi := &file_PapersService_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PriceHistory) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PriceHistory) ProtoMessage() {}
+
+func (x *PriceHistory) ProtoReflect() protoreflect.Message {
+	mi := &file_PapersService_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PriceHistory.ProtoReflect.Descriptor instead.
+func (*PriceHistory) Descriptor() ([]byte, []int) {
+	return file_PapersService_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *PriceHistory) GetPaperName() string {
+	if x != nil {
+		return x.PaperName
+	}
+	return ""
+}
+
+func (x *PriceHistory) GetInterval() string {
+	if x != nil {
+		return x.Interval
+	}
+	return ""
+}
+
+func (x *PriceHistory) GetCandles() []*Candle {
+	if x != nil {
+		return x.Candles
+	}
+	return nil
+}
+
 var File_PapersService_proto protoreflect.FileDescriptor
 
 var file_PapersService_proto_rawDesc = []byte{
@@ -312,50 +1289,205 @@ var file_PapersService_proto_rawDesc = []byte{
 	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0d, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72,
 	0x76, 0x69, 0x63, 0x65, 0x22, 0x16, 0x0a, 0x04, 0x55, 0x73, 0x65, 0x72, 0x12, 0x0e, 0x0a, 0x02,
 	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x69, 0x64, 0x22, 0x09, 0x0a, 0x07,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x31, 0x0a, 0x05, 0x50, 0x61, 0x70, 0x65, 0x72,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x51, 0x0a, 0x05, 0x50, 0x61, 0x70, 0x65, 0x72,
 	0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
 	0x6e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x02, 0x52, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x22, 0x66, 0x0a, 0x0c, 0x50, 0x61,
-	0x70, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x75, 0x73,
-	0x65, 0x72, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x75, 0x73, 0x65, 0x72,
-	0x49, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65,
-	0x12, 0x20, 0x0a, 0x0b, 0x70, 0x61, 0x70, 0x65, 0x72, 0x41, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18,
-	0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x70, 0x61, 0x70, 0x65, 0x72, 0x41, 0x6d, 0x6f, 0x75,
-	0x6e, 0x74, 0x22, 0x29, 0x0a, 0x0f, 0x41, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x50,
-	0x61, 0x70, 0x65, 0x72, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x61, 0x70, 0x65, 0x72, 0x73, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x70, 0x61, 0x70, 0x65, 0x72, 0x73, 0x22, 0x24, 0x0a,
-	0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x32, 0xad, 0x03, 0x0a, 0x10, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x4d, 0x61,
-	0x6e, 0x61, 0x67, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x4e, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x41,
+	0x01, 0x28, 0x02, 0x52, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x70, 0x72,
+	0x69, 0x63, 0x65, 0x4d, 0x69, 0x6e, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a,
+	0x70, 0x72, 0x69, 0x63, 0x65, 0x4d, 0x69, 0x6e, 0x6f, 0x72, 0x22, 0xae, 0x01, 0x0a, 0x0c, 0x50,
+	0x61, 0x70, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x75,
+	0x73, 0x65, 0x72, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x75, 0x73, 0x65,
+	0x72, 0x49, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d,
+	0x65, 0x12, 0x20, 0x0a, 0x0b, 0x70, 0x61, 0x70, 0x65, 0x72, 0x41, 0x6d, 0x6f, 0x75, 0x6e, 0x74,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x70, 0x61, 0x70, 0x65, 0x72, 0x41, 0x6d, 0x6f,
+	0x75, 0x6e, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65,
+	0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0a, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61,
+	0x6d, 0x65, 0x73, 0x12, 0x26, 0x0a, 0x0e, 0x69, 0x64, 0x65, 0x6d, 0x70, 0x6f, 0x74, 0x65, 0x6e,
+	0x63, 0x79, 0x4b, 0x65, 0x79, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x69, 0x64, 0x65,
+	0x6d, 0x70, 0x6f, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x4b, 0x65, 0x79, 0x22, 0x29, 0x0a, 0x0f, 0x41,
 	0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x12, 0x16,
-	0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53,
-	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x41, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65,
-	0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x22, 0x00, 0x12, 0x46, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x55,
-	0x73, 0x65, 0x72, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x12, 0x13, 0x2e, 0x50, 0x61, 0x70, 0x65,
-	0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x1a, 0x1e,
-	0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x41,
-	0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x22, 0x00,
-	0x12, 0x40, 0x0a, 0x08, 0x42, 0x75, 0x79, 0x50, 0x61, 0x70, 0x65, 0x72, 0x12, 0x1b, 0x2e, 0x50,
-	0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x61, 0x70,
-	0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x50, 0x61, 0x70, 0x65,
-	0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
-	0x22, 0x00, 0x12, 0x41, 0x0a, 0x09, 0x53, 0x65, 0x6c, 0x6c, 0x50, 0x61, 0x70, 0x65, 0x72, 0x12,
-	0x1b, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e,
-	0x50, 0x61, 0x70, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x50,
-	0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x74, 0x61,
-	0x74, 0x75, 0x73, 0x22, 0x00, 0x12, 0x40, 0x0a, 0x09, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69,
-	0x62, 0x65, 0x12, 0x1b, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69,
-	0x63, 0x65, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x0a, 0x06, 0x70, 0x61, 0x70, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06,
+	0x70, 0x61, 0x70, 0x65, 0x72, 0x73, 0x22, 0x24, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x9d, 0x01, 0x0a,
+	0x13, 0x54, 0x72, 0x61, 0x64, 0x65, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x12, 0x1c, 0x0a, 0x09,
+	0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x09, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72,
+	0x6f, 0x6d, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x0e,
+	0x0a, 0x02, 0x74, 0x6f, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x74, 0x6f, 0x12, 0x16,
+	0x0a, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x22, 0xfd, 0x01, 0x0a,
+	0x05, 0x54, 0x72, 0x61, 0x64, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e,
+	0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x61, 0x70, 0x65, 0x72,
+	0x4e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x64, 0x65, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x73, 0x69, 0x64, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x6d, 0x6f, 0x75,
+	0x6e, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74,
+	0x12, 0x14, 0x0a, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x02, 0x52,
+	0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x66, 0x65, 0x65, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x02, 0x52, 0x03, 0x66, 0x65, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x12, 0x1e, 0x0a, 0x0a, 0x65, 0x78, 0x65, 0x63, 0x75, 0x74, 0x65, 0x64, 0x41, 0x74, 0x18, 0x08,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x65, 0x78, 0x65, 0x63, 0x75, 0x74, 0x65, 0x64, 0x41, 0x74,
+	0x12, 0x1e, 0x0a, 0x0a, 0x70, 0x72, 0x69, 0x63, 0x65, 0x4d, 0x69, 0x6e, 0x6f, 0x72, 0x18, 0x09,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x70, 0x72, 0x69, 0x63, 0x65, 0x4d, 0x69, 0x6e, 0x6f, 0x72,
+	0x12, 0x1a, 0x0a, 0x08, 0x66, 0x65, 0x65, 0x4d, 0x69, 0x6e, 0x6f, 0x72, 0x18, 0x0a, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x08, 0x66, 0x65, 0x65, 0x4d, 0x69, 0x6e, 0x6f, 0x72, 0x22, 0x5c, 0x0a, 0x0c,
+	0x54, 0x72, 0x61, 0x64, 0x65, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x12, 0x2c, 0x0a, 0x06,
+	0x74, 0x72, 0x61, 0x64, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x50,
+	0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x54, 0x72, 0x61,
+	0x64, 0x65, 0x52, 0x06, 0x74, 0x72, 0x61, 0x64, 0x65, 0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x6e, 0x65,
+	0x78, 0x74, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a,
+	0x6e, 0x65, 0x78, 0x74, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x22, 0xfc, 0x01, 0x0a, 0x0c, 0x4f,
+	0x72, 0x64, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x75,
+	0x73, 0x65, 0x72, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x75, 0x73, 0x65,
+	0x72, 0x49, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x49, 0x64, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x49, 0x64, 0x12, 0x1c, 0x0a,
+	0x09, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x73,
+	0x69, 0x64, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x73, 0x69, 0x64, 0x65, 0x12,
+	0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74,
+	0x79, 0x70, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x02, 0x52, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x6d, 0x6f,
+	0x75, 0x6e, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e,
+	0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x70, 0x72, 0x69, 0x63, 0x65, 0x4d, 0x69, 0x6e, 0x6f, 0x72, 0x18,
+	0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x70, 0x72, 0x69, 0x63, 0x65, 0x4d, 0x69, 0x6e, 0x6f,
+	0x72, 0x12, 0x26, 0x0a, 0x0e, 0x69, 0x64, 0x65, 0x6d, 0x70, 0x6f, 0x74, 0x65, 0x6e, 0x63, 0x79,
+	0x4b, 0x65, 0x79, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x69, 0x64, 0x65, 0x6d, 0x70,
+	0x6f, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x4b, 0x65, 0x79, 0x22, 0x95, 0x02, 0x0a, 0x05, 0x4f, 0x72,
+	0x64, 0x65, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x02, 0x69, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d,
+	0x65, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x64, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x73, 0x69, 0x64, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x72, 0x69,
+	0x63, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x02, 0x52, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x12,
+	0x16, 0x0a, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x66, 0x69, 0x6c, 0x6c, 0x65,
+	0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x66, 0x69, 0x6c, 0x6c, 0x65, 0x64, 0x12,
+	0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x72, 0x65, 0x61, 0x74,
+	0x65, 0x64, 0x41, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x63, 0x72, 0x65, 0x61,
+	0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x70, 0x72, 0x69, 0x63, 0x65, 0x4d, 0x69, 0x6e, 0x6f, 0x72, 0x18,
+	0x0b, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x70, 0x72, 0x69, 0x63, 0x65, 0x4d, 0x69, 0x6e, 0x6f,
+	0x72, 0x22, 0x36, 0x0a, 0x06, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x12, 0x2c, 0x0a, 0x06, 0x6f,
+	0x72, 0x64, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x50, 0x61,
+	0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x4f, 0x72, 0x64, 0x65,
+	0x72, 0x52, 0x06, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x22, 0x46, 0x0a, 0x10, 0x4f, 0x72, 0x64,
+	0x65, 0x72, 0x42, 0x6f, 0x6f, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1c, 0x0a,
+	0x09, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x64,
+	0x65, 0x70, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x64, 0x65, 0x70, 0x74,
+	0x68, 0x22, 0x5a, 0x0a, 0x0a, 0x50, 0x72, 0x69, 0x63, 0x65, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x12,
+	0x14, 0x0a, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x02, 0x52, 0x05,
+	0x70, 0x72, 0x69, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x1e, 0x0a,
+	0x0a, 0x70, 0x72, 0x69, 0x63, 0x65, 0x4d, 0x69, 0x6e, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x0a, 0x70, 0x72, 0x69, 0x63, 0x65, 0x4d, 0x69, 0x6e, 0x6f, 0x72, 0x22, 0x87, 0x01,
+	0x0a, 0x09, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x42, 0x6f, 0x6f, 0x6b, 0x12, 0x1c, 0x0a, 0x09, 0x70,
+	0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09,
+	0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x2d, 0x0a, 0x04, 0x62, 0x69, 0x64,
+	0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73,
+	0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x72, 0x69, 0x63, 0x65, 0x4c, 0x65, 0x76,
+	0x65, 0x6c, 0x52, 0x04, 0x62, 0x69, 0x64, 0x73, 0x12, 0x2d, 0x0a, 0x04, 0x61, 0x73, 0x6b, 0x73,
+	0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x72, 0x69, 0x63, 0x65, 0x4c, 0x65, 0x76, 0x65,
+	0x6c, 0x52, 0x04, 0x61, 0x73, 0x6b, 0x73, 0x22, 0x73, 0x0a, 0x13, 0x50, 0x72, 0x69, 0x63, 0x65,
+	0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1c,
+	0x0a, 0x09, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x09, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08,
+	0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08,
+	0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02,
+	0x74, 0x6f, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x74, 0x6f, 0x22, 0xe4, 0x01, 0x0a,
+	0x06, 0x43, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6f,
+	0x70, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x02, 0x52, 0x04, 0x6f, 0x70, 0x65, 0x6e, 0x12,
+	0x12, 0x0a, 0x04, 0x68, 0x69, 0x67, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x02, 0x52, 0x04, 0x68,
+	0x69, 0x67, 0x68, 0x12, 0x10, 0x0a, 0x03, 0x6c, 0x6f, 0x77, 0x18, 0x04, 0x20, 0x01, 0x28, 0x02,
+	0x52, 0x03, 0x6c, 0x6f, 0x77, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x02, 0x52, 0x05, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x6f,
+	0x70, 0x65, 0x6e, 0x4d, 0x69, 0x6e, 0x6f, 0x72, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09,
+	0x6f, 0x70, 0x65, 0x6e, 0x4d, 0x69, 0x6e, 0x6f, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x68, 0x69, 0x67,
+	0x68, 0x4d, 0x69, 0x6e, 0x6f, 0x72, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x68, 0x69,
+	0x67, 0x68, 0x4d, 0x69, 0x6e, 0x6f, 0x72, 0x12, 0x1a, 0x0a, 0x08, 0x6c, 0x6f, 0x77, 0x4d, 0x69,
+	0x6e, 0x6f, 0x72, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x6c, 0x6f, 0x77, 0x4d, 0x69,
+	0x6e, 0x6f, 0x72, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x4d, 0x69, 0x6e, 0x6f,
+	0x72, 0x18, 0x09, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x4d, 0x69,
+	0x6e, 0x6f, 0x72, 0x22, 0x79, 0x0a, 0x0c, 0x50, 0x72, 0x69, 0x63, 0x65, 0x48, 0x69, 0x73, 0x74,
+	0x6f, 0x72, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d,
+	0x65, 0x12, 0x1a, 0x0a, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x12, 0x2f, 0x0a,
+	0x07, 0x63, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15,
+	0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x43,
+	0x61, 0x6e, 0x64, 0x6c, 0x65, 0x52, 0x07, 0x63, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x73, 0x32, 0xb1,
+	0x07, 0x0a, 0x10, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x4d, 0x61, 0x6e, 0x61, 0x67, 0x65, 0x6d,
+	0x65, 0x6e, 0x74, 0x12, 0x4e, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x41, 0x76, 0x61, 0x69, 0x6c, 0x61,
+	0x62, 0x6c, 0x65, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x12, 0x16, 0x2e, 0x50, 0x61, 0x70, 0x65,
+	0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x1e, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x2e, 0x41, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x50, 0x61, 0x70, 0x65, 0x72,
+	0x73, 0x22, 0x00, 0x12, 0x46, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x50, 0x61,
+	0x70, 0x65, 0x72, 0x73, 0x12, 0x13, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x1a, 0x1e, 0x2e, 0x50, 0x61, 0x70, 0x65,
+	0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x41, 0x76, 0x61, 0x69, 0x6c, 0x61,
+	0x62, 0x6c, 0x65, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x22, 0x00, 0x12, 0x40, 0x0a, 0x08, 0x42,
+	0x75, 0x79, 0x50, 0x61, 0x70, 0x65, 0x72, 0x12, 0x1b, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73,
+	0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x22, 0x00, 0x12, 0x41, 0x0a,
+	0x09, 0x53, 0x65, 0x6c, 0x6c, 0x50, 0x61, 0x70, 0x65, 0x72, 0x12, 0x1b, 0x2e, 0x50, 0x61, 0x70,
+	0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73,
+	0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x22, 0x00,
+	0x12, 0x40, 0x0a, 0x09, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x12, 0x1b, 0x2e,
+	0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x61,
+	0x70, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x50, 0x61, 0x70,
+	0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72,
+	0x30, 0x01, 0x12, 0x3a, 0x0a, 0x0b, 0x55, 0x6e, 0x73, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62,
+	0x65, 0x12, 0x14, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x1a, 0x15, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73,
+	0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x54,
+	0x0a, 0x0f, 0x47, 0x65, 0x74, 0x54, 0x72, 0x61, 0x64, 0x65, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72,
+	0x79, 0x12, 0x22, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x2e, 0x54, 0x72, 0x61, 0x64, 0x65, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x54, 0x72, 0x61, 0x64, 0x65, 0x48, 0x69, 0x73, 0x74, 0x6f,
+	0x72, 0x79, 0x22, 0x00, 0x12, 0x41, 0x0a, 0x0a, 0x50, 0x6c, 0x61, 0x63, 0x65, 0x4f, 0x72, 0x64,
+	0x65, 0x72, 0x12, 0x1b, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69,
+	0x63, 0x65, 0x2e, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
 	0x14, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e,
-	0x50, 0x61, 0x70, 0x65, 0x72, 0x30, 0x01, 0x12, 0x3a, 0x0a, 0x0b, 0x55, 0x6e, 0x73, 0x75, 0x62,
-	0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x12, 0x14, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53,
-	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x1a, 0x15, 0x2e, 0x50,
-	0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x74, 0x61,
-	0x74, 0x75, 0x73, 0x42, 0x04, 0x5a, 0x02, 0x2e, 0x2f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x33,
+	0x4f, 0x72, 0x64, 0x65, 0x72, 0x22, 0x00, 0x12, 0x42, 0x0a, 0x0b, 0x43, 0x61, 0x6e, 0x63, 0x65,
+	0x6c, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x12, 0x1b, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x2e, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x22, 0x00, 0x12, 0x43, 0x0a, 0x0c, 0x52,
+	0x65, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x12, 0x1b, 0x2e, 0x50, 0x61,
+	0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x4f, 0x72, 0x64, 0x65,
+	0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72,
+	0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x22, 0x00,
+	0x12, 0x3d, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x55, 0x73, 0x65, 0x72, 0x4f, 0x72, 0x64, 0x65, 0x72,
+	0x73, 0x12, 0x13, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x1a, 0x15, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x22, 0x00, 0x12,
+	0x4b, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x42, 0x6f, 0x6f, 0x6b, 0x12,
+	0x1f, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e,
+	0x4f, 0x72, 0x64, 0x65, 0x72, 0x42, 0x6f, 0x6f, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x18, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x2e, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x42, 0x6f, 0x6f, 0x6b, 0x22, 0x00, 0x12, 0x54, 0x0a, 0x0f,
+	0x47, 0x65, 0x74, 0x50, 0x72, 0x69, 0x63, 0x65, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x12,
+	0x22, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e,
+	0x50, 0x72, 0x69, 0x63, 0x65, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x53, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x2e, 0x50, 0x72, 0x69, 0x63, 0x65, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79,
+	0x22, 0x00, 0x42, 0x04, 0x5a, 0x02, 0x2e, 0x2f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -370,33 +1502,64 @@ func file_PapersService_proto_rawDescGZIP() []byte {
 	return file_PapersService_proto_rawDescData
 }
 
-var file_PapersService_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_PapersService_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
 var file_PapersService_proto_goTypes = []any{
-	(*User)(nil),            // 0: PapersService.User
-	(*Request)(nil),         // 1: PapersService.Request
-	(*Paper)(nil),           // 2: PapersService.Paper
-	(*PaperRequest)(nil),    // 3: PapersService.PaperRequest
-	(*AvailablePapers)(nil), // 4: PapersService.AvailablePapers
-	(*Status)(nil),          // 5: PapersService.Status
+	(*User)(nil),                // 0: PapersService.User
+	(*Request)(nil),             // 1: PapersService.Request
+	(*Paper)(nil),               // 2: PapersService.Paper
+	(*PaperRequest)(nil),        // 3: PapersService.PaperRequest
+	(*AvailablePapers)(nil),     // 4: PapersService.AvailablePapers
+	(*Status)(nil),              // 5: PapersService.Status
+	(*TradeHistoryRequest)(nil), // 6: PapersService.TradeHistoryRequest
+	(*Trade)(nil),               // 7: PapersService.Trade
+	(*TradeHistory)(nil),        // 8: PapersService.TradeHistory
+	(*OrderRequest)(nil),        // 9: PapersService.OrderRequest
+	(*Order)(nil),               // 10: PapersService.Order
+	(*Orders)(nil),              // 11: PapersService.Orders
+	(*OrderBookRequest)(nil),    // 12: PapersService.OrderBookRequest
+	(*PriceLevel)(nil),          // 13: PapersService.PriceLevel
+	(*OrderBook)(nil),           // 14: PapersService.OrderBook
+	(*PriceHistoryRequest)(nil), // 15: PapersService.PriceHistoryRequest
+	(*Candle)(nil),              // 16: PapersService.Candle
+	(*PriceHistory)(nil),        // 17: PapersService.PriceHistory
 }
 var file_PapersService_proto_depIdxs = []int32{
-	1, // 0: PapersService.PapersManagement.GetAvailablePapers:input_type -> PapersService.Request
-	0, // 1: PapersService.PapersManagement.GetUserPapers:input_type -> PapersService.User
-	3, // 2: PapersService.PapersManagement.BuyPaper:input_type -> PapersService.PaperRequest
-	3, // 3: PapersService.PapersManagement.SellPaper:input_type -> PapersService.PaperRequest
-	3, // 4: PapersService.PapersManagement.Subscribe:input_type -> PapersService.PaperRequest
-	2, // 5: PapersService.PapersManagement.Unsubscribe:input_type -> PapersService.Paper
-	4, // 6: PapersService.PapersManagement.GetAvailablePapers:output_type -> PapersService.AvailablePapers
-	4, // 7: PapersService.PapersManagement.GetUserPapers:output_type -> PapersService.AvailablePapers
-	5, // 8: PapersService.PapersManagement.BuyPaper:output_type -> PapersService.Status
-	5, // 9: PapersService.PapersManagement.SellPaper:output_type -> PapersService.Status
-	2, // 10: PapersService.PapersManagement.Subscribe:output_type -> PapersService.Paper
-	5, // 11: PapersService.PapersManagement.Unsubscribe:output_type -> PapersService.Status
-	6, // [6:12] is the sub-list for method output_type
-	0, // [0:6] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	7,  // 0: PapersService.TradeHistory.trades:type_name -> PapersService.Trade
+	10, // 1: PapersService.Orders.orders:type_name -> PapersService.Order
+	13, // 2: PapersService.OrderBook.bids:type_name -> PapersService.PriceLevel
+	13, // 3: PapersService.OrderBook.asks:type_name -> PapersService.PriceLevel
+	16, // 4: PapersService.PriceHistory.candles:type_name -> PapersService.Candle
+	1,  // 5: PapersService.PapersManagement.GetAvailablePapers:input_type -> PapersService.Request
+	0,  // 6: PapersService.PapersManagement.GetUserPapers:input_type -> PapersService.User
+	3,  // 7: PapersService.PapersManagement.BuyPaper:input_type -> PapersService.PaperRequest
+	3,  // 8: PapersService.PapersManagement.SellPaper:input_type -> PapersService.PaperRequest
+	3,  // 9: PapersService.PapersManagement.Subscribe:input_type -> PapersService.PaperRequest
+	2,  // 10: PapersService.PapersManagement.Unsubscribe:input_type -> PapersService.Paper
+	6,  // 11: PapersService.PapersManagement.GetTradeHistory:input_type -> PapersService.TradeHistoryRequest
+	9,  // 12: PapersService.PapersManagement.PlaceOrder:input_type -> PapersService.OrderRequest
+	9,  // 13: PapersService.PapersManagement.CancelOrder:input_type -> PapersService.OrderRequest
+	9,  // 14: PapersService.PapersManagement.ReplaceOrder:input_type -> PapersService.OrderRequest
+	0,  // 15: PapersService.PapersManagement.GetUserOrders:input_type -> PapersService.User
+	12, // 16: PapersService.PapersManagement.GetOrderBook:input_type -> PapersService.OrderBookRequest
+	15, // 17: PapersService.PapersManagement.GetPriceHistory:input_type -> PapersService.PriceHistoryRequest
+	4,  // 18: PapersService.PapersManagement.GetAvailablePapers:output_type -> PapersService.AvailablePapers
+	4,  // 19: PapersService.PapersManagement.GetUserPapers:output_type -> PapersService.AvailablePapers
+	5,  // 20: PapersService.PapersManagement.BuyPaper:output_type -> PapersService.Status
+	5,  // 21: PapersService.PapersManagement.SellPaper:output_type -> PapersService.Status
+	2,  // 22: PapersService.PapersManagement.Subscribe:output_type -> PapersService.Paper
+	5,  // 23: PapersService.PapersManagement.Unsubscribe:output_type -> PapersService.Status
+	8,  // 24: PapersService.PapersManagement.GetTradeHistory:output_type -> PapersService.TradeHistory
+	10, // 25: PapersService.PapersManagement.PlaceOrder:output_type -> PapersService.Order
+	10, // 26: PapersService.PapersManagement.CancelOrder:output_type -> PapersService.Order
+	10, // 27: PapersService.PapersManagement.ReplaceOrder:output_type -> PapersService.Order
+	11, // 28: PapersService.PapersManagement.GetUserOrders:output_type -> PapersService.Orders
+	14, // 29: PapersService.PapersManagement.GetOrderBook:output_type -> PapersService.OrderBook
+	17, // 30: PapersService.PapersManagement.GetPriceHistory:output_type -> PapersService.PriceHistory
+	18, // [18:31] is the sub-list for method output_type
+	5,  // [5:18] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_PapersService_proto_init() }
@@ -410,7 +1573,7 @@ func file_PapersService_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_PapersService_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   18,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
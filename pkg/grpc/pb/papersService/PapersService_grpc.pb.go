@@ -25,6 +25,13 @@ const (
 	PapersManagement_SellPaper_FullMethodName          = "/PapersService.PapersManagement/SellPaper"
 	PapersManagement_Subscribe_FullMethodName          = "/PapersService.PapersManagement/Subscribe"
 	PapersManagement_Unsubscribe_FullMethodName        = "/PapersService.PapersManagement/Unsubscribe"
+	PapersManagement_GetTradeHistory_FullMethodName    = "/PapersService.PapersManagement/GetTradeHistory"
+	PapersManagement_PlaceOrder_FullMethodName         = "/PapersService.PapersManagement/PlaceOrder"
+	PapersManagement_CancelOrder_FullMethodName        = "/PapersService.PapersManagement/CancelOrder"
+	PapersManagement_ReplaceOrder_FullMethodName       = "/PapersService.PapersManagement/ReplaceOrder"
+	PapersManagement_GetUserOrders_FullMethodName      = "/PapersService.PapersManagement/GetUserOrders"
+	PapersManagement_GetOrderBook_FullMethodName       = "/PapersService.PapersManagement/GetOrderBook"
+	PapersManagement_GetPriceHistory_FullMethodName    = "/PapersService.PapersManagement/GetPriceHistory"
 )
 
 // PapersManagementClient is the client API for PapersManagement service.
@@ -37,6 +44,13 @@ type PapersManagementClient interface {
 	SellPaper(ctx context.Context, in *PaperRequest, opts ...grpc.CallOption) (*Status, error)
 	Subscribe(ctx context.Context, in *PaperRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Paper], error)
 	Unsubscribe(ctx context.Context, in *Paper, opts ...grpc.CallOption) (*Status, error)
+	GetTradeHistory(ctx context.Context, in *TradeHistoryRequest, opts ...grpc.CallOption) (*TradeHistory, error)
+	PlaceOrder(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*Order, error)
+	CancelOrder(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*Order, error)
+	ReplaceOrder(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*Order, error)
+	GetUserOrders(ctx context.Context, in *User, opts ...grpc.CallOption) (*Orders, error)
+	GetOrderBook(ctx context.Context, in *OrderBookRequest, opts ...grpc.CallOption) (*OrderBook, error)
+	GetPriceHistory(ctx context.Context, in *PriceHistoryRequest, opts ...grpc.CallOption) (*PriceHistory, error)
 }
 
 type papersManagementClient struct {
@@ -116,6 +130,76 @@ func (c *papersManagementClient) Unsubscribe(ctx context.Context, in *Paper, opt
 	return out, nil
 }
 
+func (c *papersManagementClient) GetTradeHistory(ctx context.Context, in *TradeHistoryRequest, opts ...grpc.CallOption) (*TradeHistory, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TradeHistory)
+	err := c.cc.Invoke(ctx, PapersManagement_GetTradeHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *papersManagementClient) PlaceOrder(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*Order, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Order)
+	err := c.cc.Invoke(ctx, PapersManagement_PlaceOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *papersManagementClient) CancelOrder(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*Order, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Order)
+	err := c.cc.Invoke(ctx, PapersManagement_CancelOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *papersManagementClient) ReplaceOrder(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*Order, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Order)
+	err := c.cc.Invoke(ctx, PapersManagement_ReplaceOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *papersManagementClient) GetUserOrders(ctx context.Context, in *User, opts ...grpc.CallOption) (*Orders, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Orders)
+	err := c.cc.Invoke(ctx, PapersManagement_GetUserOrders_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *papersManagementClient) GetOrderBook(ctx context.Context, in *OrderBookRequest, opts ...grpc.CallOption) (*OrderBook, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OrderBook)
+	err := c.cc.Invoke(ctx, PapersManagement_GetOrderBook_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *papersManagementClient) GetPriceHistory(ctx context.Context, in *PriceHistoryRequest, opts ...grpc.CallOption) (*PriceHistory, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PriceHistory)
+	err := c.cc.Invoke(ctx, PapersManagement_GetPriceHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PapersManagementServer is the server API for PapersManagement service.
 // All implementations must embed UnimplementedPapersManagementServer
 // for forward compatibility.
@@ -126,6 +210,13 @@ type PapersManagementServer interface {
 	SellPaper(context.Context, *PaperRequest) (*Status, error)
 	Subscribe(*PaperRequest, grpc.ServerStreamingServer[Paper]) error
 	Unsubscribe(context.Context, *Paper) (*Status, error)
+	GetTradeHistory(context.Context, *TradeHistoryRequest) (*TradeHistory, error)
+	PlaceOrder(context.Context, *OrderRequest) (*Order, error)
+	CancelOrder(context.Context, *OrderRequest) (*Order, error)
+	ReplaceOrder(context.Context, *OrderRequest) (*Order, error)
+	GetUserOrders(context.Context, *User) (*Orders, error)
+	GetOrderBook(context.Context, *OrderBookRequest) (*OrderBook, error)
+	GetPriceHistory(context.Context, *PriceHistoryRequest) (*PriceHistory, error)
 	mustEmbedUnimplementedPapersManagementServer()
 }
 
@@ -154,6 +245,27 @@ func (UnimplementedPapersManagementServer) Subscribe(*PaperRequest, grpc.ServerS
 func (UnimplementedPapersManagementServer) Unsubscribe(context.Context, *Paper) (*Status, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Unsubscribe not implemented")
 }
+func (UnimplementedPapersManagementServer) GetTradeHistory(context.Context, *TradeHistoryRequest) (*TradeHistory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTradeHistory not implemented")
+}
+func (UnimplementedPapersManagementServer) PlaceOrder(context.Context, *OrderRequest) (*Order, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PlaceOrder not implemented")
+}
+func (UnimplementedPapersManagementServer) CancelOrder(context.Context, *OrderRequest) (*Order, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelOrder not implemented")
+}
+func (UnimplementedPapersManagementServer) ReplaceOrder(context.Context, *OrderRequest) (*Order, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplaceOrder not implemented")
+}
+func (UnimplementedPapersManagementServer) GetUserOrders(context.Context, *User) (*Orders, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserOrders not implemented")
+}
+func (UnimplementedPapersManagementServer) GetOrderBook(context.Context, *OrderBookRequest) (*OrderBook, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrderBook not implemented")
+}
+func (UnimplementedPapersManagementServer) GetPriceHistory(context.Context, *PriceHistoryRequest) (*PriceHistory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPriceHistory not implemented")
+}
 func (UnimplementedPapersManagementServer) mustEmbedUnimplementedPapersManagementServer() {}
 func (UnimplementedPapersManagementServer) testEmbeddedByValue()                          {}
 
@@ -276,6 +388,132 @@ func _PapersManagement_Unsubscribe_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PapersManagement_GetTradeHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TradeHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PapersManagementServer).GetTradeHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PapersManagement_GetTradeHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PapersManagementServer).GetTradeHistory(ctx, req.(*TradeHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PapersManagement_PlaceOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PapersManagementServer).PlaceOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PapersManagement_PlaceOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PapersManagementServer).PlaceOrder(ctx, req.(*OrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PapersManagement_CancelOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PapersManagementServer).CancelOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PapersManagement_CancelOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PapersManagementServer).CancelOrder(ctx, req.(*OrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PapersManagement_ReplaceOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PapersManagementServer).ReplaceOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PapersManagement_ReplaceOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PapersManagementServer).ReplaceOrder(ctx, req.(*OrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PapersManagement_GetUserOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(User)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PapersManagementServer).GetUserOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PapersManagement_GetUserOrders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PapersManagementServer).GetUserOrders(ctx, req.(*User))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PapersManagement_GetOrderBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OrderBookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PapersManagementServer).GetOrderBook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PapersManagement_GetOrderBook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PapersManagementServer).GetOrderBook(ctx, req.(*OrderBookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PapersManagement_GetPriceHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PriceHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PapersManagementServer).GetPriceHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PapersManagement_GetPriceHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PapersManagementServer).GetPriceHistory(ctx, req.(*PriceHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // PapersManagement_ServiceDesc is the grpc.ServiceDesc for PapersManagement service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -303,6 +541,34 @@ var PapersManagement_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Unsubscribe",
 			Handler:    _PapersManagement_Unsubscribe_Handler,
 		},
+		{
+			MethodName: "GetTradeHistory",
+			Handler:    _PapersManagement_GetTradeHistory_Handler,
+		},
+		{
+			MethodName: "PlaceOrder",
+			Handler:    _PapersManagement_PlaceOrder_Handler,
+		},
+		{
+			MethodName: "CancelOrder",
+			Handler:    _PapersManagement_CancelOrder_Handler,
+		},
+		{
+			MethodName: "ReplaceOrder",
+			Handler:    _PapersManagement_ReplaceOrder_Handler,
+		},
+		{
+			MethodName: "GetUserOrders",
+			Handler:    _PapersManagement_GetUserOrders_Handler,
+		},
+		{
+			MethodName: "GetOrderBook",
+			Handler:    _PapersManagement_GetOrderBook_Handler,
+		},
+		{
+			MethodName: "GetPriceHistory",
+			Handler:    _PapersManagement_GetPriceHistory_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -0,0 +1,242 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	KeyReused  = "idempotency key is already used for another request"
+	KeyPending = "request with this idempotency key is in progress"
+)
+
+var (
+	ErrKeyReused  = errors.New(KeyReused)
+	ErrKeyPending = errors.New(KeyPending)
+)
+
+// Ошибка после того, как операция уже проведена(см. Committed)
+type committedError struct {
+	err error
+}
+
+func (e *committedError) Error() string { return e.err.Error() }
+func (e *committedError) Unwrap() error { return e.err }
+
+// Отмечает ошибку run, возникшую после проведения операции: Do не освобождает ключ, а сохраняет ответ run,
+// чтобы повтор с тем же ключом не провел операцию второй раз
+func Committed(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &committedError{err: err}
+}
+
+// Пул соединений сервиса с таблицей public.idempotency_keys
+type IDB interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Ключи идемпотентности изменяющих запросов balance и papers. Ключ действует в пределах scope(сервис и метод)
+// и пользователя: одинаковые ключи разных пользователей не пересекаются. Нулевой пользователь - системные вызовы
+type Keys struct {
+	db  IDB
+	ttl time.Duration
+}
+
+// ttl - сколько хранится ключ выполненного запроса
+func New(db IDB, ttl time.Duration) *Keys {
+	return &Keys{db: db, ttl: ttl}
+}
+
+// Выполняет run один раз на ключ идемпотентности key пользователя userID в scope: повтор того же запроса с тем же ключом
+// возвращает сохраненный ответ первого, не выполняя run. Пустой ключ - запрос выполняется без проверки. Ответ с ошибкой
+// до проведения операции не сохраняется, ключ освобождается и повтор выполнит запрос заново. Ошибка, отмеченная
+// Committed, возвращается клиенту, но ключ остается за проведенной операцией
+func Do[T proto.Message](ctx context.Context, k *Keys, scope string, userID uuid.UUID, key string, req proto.Message, run func() (T, error)) (T, error) {
+	var zero T
+	if key == "" {
+		return run()
+	}
+	hash, err := requestHash(req)
+	if err != nil {
+		return zero, err
+	}
+	stored, claimed, err := k.Claim(ctx, scope, userID, key, hash)
+	if err != nil {
+		return zero, grpcError(err)
+	}
+	if !claimed {
+		log.Printf("Replayed %v with idempotency key %v\n", scope, key)
+		return unmarshal[T](stored)
+	}
+	// Ключ освобождается и ответ сохраняется, даже если клиент уже отключился
+	ctx = context.WithoutCancel(ctx)
+	resp, runErr := run()
+	var committed *committedError
+	if runErr != nil && !errors.As(runErr, &committed) {
+		k.Release(ctx, scope, userID, key)
+		return resp, runErr
+	}
+	response, err := proto.Marshal(resp)
+	if err == nil {
+		err = k.Save(ctx, scope, userID, key, response)
+	}
+	// Операция уже проведена: ключ остается занятым до истечения, чтобы повтор не провел ее второй раз
+	if err != nil {
+		log.Printf("Cant save %v response for idempotency key %v: %v\n", scope, key, err)
+	}
+	if committed != nil {
+		return resp, committed.err
+	}
+	return resp, nil
+}
+
+// Сохраненный ответ запроса req с ключом key пользователя userID в scope без выполнения запроса и без занятия ключа.
+// Нет ответа по ключу - NotFound, ключ с другим запросом - AlreadyExists, запрос с ключом еще выполняется - Aborted
+func Replay[T proto.Message](ctx context.Context, k *Keys, scope string, userID uuid.UUID, key string, req proto.Message) (T, error) {
+	var zero T
+	hash, err := requestHash(req)
+	if err != nil {
+		return zero, err
+	}
+	stored, found, err := k.Lookup(ctx, scope, userID, key, hash)
+	if err != nil {
+		return zero, grpcError(err)
+	}
+	if !found {
+		return zero, status.Errorf(codes.NotFound, "no response for idempotency key %v", key)
+	}
+	return unmarshal[T](stored)
+}
+
+// Занимает ключ под запрос с хешем hash на ttl. Свободный ключ занимается: claimed = true, запрос нужно выполнить
+// и сохранить ответ через Save. Если ключ уже занят тем же запросом, возвращается сохраненный ответ, пока ответа нет -
+// ErrKeyPending. Ключ, занятый другим запросом, - ErrKeyReused
+func (k *Keys) Claim(ctx context.Context, scope string, userID uuid.UUID, key, hash string) (response []byte, claimed bool, err error) {
+	_, err = k.db.Exec(ctx, `delete from public.idempotency_keys where scope = $1 and user_id = $2 and key = $3 and expires_at < now()`, scope, userID, key)
+	if err != nil {
+		log.Println("Cant drop expired idempotency key:", err)
+		return nil, false, err
+	}
+	res, err := k.db.Exec(ctx, `insert into public.idempotency_keys(scope, user_id, key, request_hash, expires_at) values($1, $2, $3, $4, $5)
+		on conflict do nothing`, scope, userID, key, hash, time.Now().Add(k.ttl))
+	if err != nil {
+		log.Println("Cant claim idempotency key:", err)
+		return nil, false, err
+	}
+	if res.RowsAffected() == 1 {
+		return nil, true, nil
+	}
+	response, found, err := k.Lookup(ctx, scope, userID, key, hash)
+	if err == nil && !found {
+		// Ключ истек между insert и select: повтор займет его заново
+		err = ErrKeyPending
+	}
+	return response, false, err
+}
+
+// Сохраненный ответ запроса с хешем hash по неистекшему ключу: found = false, если ключа нет. Ключ, занятый
+// другим запросом, - ErrKeyReused, пока ответа нет - ErrKeyPending
+func (k *Keys) Lookup(ctx context.Context, scope string, userID uuid.UUID, key, hash string) (response []byte, found bool, err error) {
+	var stored string
+	var done bool
+	err = k.db.QueryRow(ctx, `select request_hash, response is not null, coalesce(response, '') from public.idempotency_keys
+		where scope = $1 and user_id = $2 and key = $3 and expires_at >= now()`, scope, userID, key).Scan(&stored, &done, &response)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, false, nil
+	case err != nil:
+		log.Println("Cant get idempotency key:", err)
+		return nil, false, err
+	case stored != hash:
+		return nil, false, ErrKeyReused
+	case !done:
+		return nil, false, ErrKeyPending
+	}
+	return response, true, nil
+}
+
+// Сохраняет ответ запроса, занявшего ключ
+func (k *Keys) Save(ctx context.Context, scope string, userID uuid.UUID, key string, response []byte) error {
+	if response == nil {
+		response = []byte{}
+	}
+	_, err := k.db.Exec(ctx, `update public.idempotency_keys set response = $4 where scope = $1 and user_id = $2 and key = $3`, scope, userID, key, response)
+	if err != nil {
+		log.Println("Cant save idempotent response:", err)
+	}
+	return err
+}
+
+// Освобождает ключ запроса, завершившегося ошибкой: повтор выполнит его заново
+func (k *Keys) Release(ctx context.Context, scope string, userID uuid.UUID, key string) error {
+	_, err := k.db.Exec(ctx, `delete from public.idempotency_keys where scope = $1 and user_id = $2 and key = $3 and response is null`, scope, userID, key)
+	if err != nil {
+		log.Println("Cant release idempotency key:", err)
+	}
+	return err
+}
+
+// Удаляет истекшие ключи всех сервисов
+func (k *Keys) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := k.db.Exec(ctx, `delete from public.idempotency_keys where expires_at < now()`)
+	if err != nil {
+		log.Println("Cant delete expired idempotency keys:", err)
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// Раз в interval удаляет истекшие ключи, чтобы таблица не росла с каждым запросом
+func (k *Keys) Cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if n, err := k.DeleteExpired(context.Background()); err == nil && n > 0 {
+			log.Printf("Deleted %v expired idempotency keys\n", n)
+		}
+	}
+}
+
+func requestHash(req proto.Message) (string, error) {
+	payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(payload)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func unmarshal[T proto.Message](stored []byte) (T, error) {
+	var zero T
+	resp := zero.ProtoReflect().New().Interface().(T)
+	if err := proto.Unmarshal(stored, resp); err != nil {
+		log.Println("Cant unmarshal idempotent response:", err)
+		return zero, err
+	}
+	return resp, nil
+}
+
+// Ключ с другим запросом - AlreadyExists, запрос с ключом еще выполняется - Aborted
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrKeyReused):
+		return status.Errorf(codes.AlreadyExists, "%v", err)
+	case errors.Is(err, ErrKeyPending):
+		return status.Errorf(codes.Aborted, "%v", err)
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestKeys(t *testing.T) {
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, "postgres://user:123@db:5432/papersdb")
+	if err == nil {
+		err = db.Ping(ctx)
+	}
+	if err != nil {
+		t.Skipf("Database is not available: %v", err)
+	}
+	defer db.Close()
+
+	keys := New(db, time.Hour)
+	scope, key := "test/Idempotency", uuid.NewString()
+	user, other := uuid.New(), uuid.New()
+	defer db.Exec(ctx, `delete from public.idempotency_keys where scope = $1`, scope)
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tKey is claimed once and replays the saved response", testID)
+		_, claimed, err := keys.Claim(ctx, scope, user, key, "hash")
+		require.NoError(t, err)
+		require.True(t, claimed)
+		_, _, err = keys.Claim(ctx, scope, user, key, "hash")
+		require.ErrorIs(t, err, ErrKeyPending, "Key must stay busy until the response is saved")
+		require.NoError(t, keys.Save(ctx, scope, user, key, []byte("response")))
+		response, claimed, err := keys.Claim(ctx, scope, user, key, "hash")
+		require.NoError(t, err)
+		require.False(t, claimed)
+		require.Equal(t, []byte("response"), response)
+		_, _, err = keys.Claim(ctx, scope, user, key, "other")
+		require.ErrorIs(t, err, ErrKeyReused, "Key must not be reused for another request")
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\tThe same key of another user is a separate key", testID)
+		_, claimed, err := keys.Claim(ctx, scope, other, key, "other")
+		require.NoError(t, err)
+		require.True(t, claimed)
+	}
+	{
+		testID := 3
+		t.Logf("\tTest %d:\tReleased and expired keys can be claimed again", testID)
+		failed := uuid.NewString()
+		_, claimed, err := keys.Claim(ctx, scope, user, failed, "hash")
+		require.NoError(t, err)
+		require.True(t, claimed)
+		require.NoError(t, keys.Release(ctx, scope, user, failed))
+		_, claimed, err = New(db, -time.Second).Claim(ctx, scope, user, failed, "other")
+		require.NoError(t, err)
+		require.True(t, claimed, "Released key must be free")
+		_, claimed, err = keys.Claim(ctx, scope, user, failed, "hash")
+		require.NoError(t, err)
+		require.True(t, claimed, "Expired key must be free")
+	}
+	{
+		testID := 4
+		t.Logf("\tTest %d:\tCleanup deletes expired keys", testID)
+		expired := uuid.NewString()
+		_, _, err := New(db, -time.Second).Claim(ctx, scope, user, expired, "hash")
+		require.NoError(t, err)
+		deleted, err := keys.DeleteExpired(ctx)
+		require.NoError(t, err)
+		require.Positive(t, deleted)
+		_, claimed, err := keys.Claim(ctx, scope, user, expired, "other")
+		require.NoError(t, err)
+		require.True(t, claimed)
+	}
+	{
+		testID := 5
+		t.Logf("\tTest %d:\tLookup returns the saved response without claiming the key", testID)
+		_, found, err := keys.Lookup(ctx, scope, user, uuid.NewString(), "hash")
+		require.NoError(t, err)
+		require.False(t, found)
+		response, found, err := keys.Lookup(ctx, scope, user, key, "hash")
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, []byte("response"), response)
+		_, _, err = keys.Lookup(ctx, scope, user, key, "other")
+		require.ErrorIs(t, err, ErrKeyReused)
+	}
+	{
+		testID := 6
+		t.Logf("\tTest %d:\tDo runs again after a failure, but not after a failure of a committed operation", testID)
+		req, runs := wrapperspb.String("request"), 0
+		fail := func(err error) func() (*wrapperspb.StringValue, error) {
+			return func() (*wrapperspb.StringValue, error) {
+				runs++
+				return wrapperspb.String("done"), err
+			}
+		}
+		failed, committed := uuid.NewString(), uuid.NewString()
+		_, err := Do(ctx, keys, scope, user, failed, req, fail(errors.New("not committed")))
+		require.Error(t, err)
+		_, err = Do(ctx, keys, scope, user, failed, req, fail(nil))
+		require.NoError(t, err)
+		require.Equal(t, 2, runs, "Key must be released after a failure before commit")
+		_, err = Do(ctx, keys, scope, user, committed, req, fail(Committed(errors.New("cache is down"))))
+		require.EqualError(t, err, "cache is down")
+		resp, err := Do(ctx, keys, scope, user, committed, req, fail(nil))
+		require.NoError(t, err)
+		require.Equal(t, "done", resp.Value)
+		require.Equal(t, 3, runs, "Committed operation must not run again")
+	}
+}
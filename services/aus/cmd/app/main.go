@@ -3,6 +3,7 @@ package main
 import (
 	pg "aus/internal/db"
 	"aus/internal/pkg/jwt"
+	"aus/internal/redis"
 	server "aus/internal/server"
 	"log"
 
@@ -13,6 +14,7 @@ type Config struct {
 	JWTConfig        *jwt.Config    `yaml:"jwt" env-prefix:"JWT_"`
 	DBConfig         *pg.Config     `yaml:"db" env-prefix:"DB_"`
 	AuthServerConfig *server.Config `yaml:"aus" env-prefix:"AS_"`
+	RDSConfig        *redis.Config  `yaml:"rds"`
 }
 
 func readConfig(filename string) (*Config, error) {
@@ -35,15 +37,27 @@ func main() {
 		log.Fatalln(err)
 	}
 	log.Println("DB connected successfully")
-	jwt, err := jwt.New(cfg.JWTConfig)
+	jwt, err := jwt.New(cfg.JWTConfig, db)
 	if err != nil {
 		log.Fatalln(err)
 	}
 	log.Println("JWT created successfully")
-	service, err := server.New(cfg.AuthServerConfig, &jwt, db)
+	go jwt.StartRotation()
+	rds, err := redis.New(cfg.RDSConfig)
 	if err != nil {
 		log.Fatalln(err)
 	}
+	log.Println("Redis connected successfully")
+	service, err := server.New(cfg.AuthServerConfig, jwt, db, rds, rds)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	go func() {
+		log.Printf("JWKS is served on %v%v\n", service.JWKS.Addr, "/.well-known/jwks.json")
+		if err := service.JWKS.ListenAndServe(); err != nil {
+			log.Fatalln(err)
+		}
+	}()
 	if err := service.AuthServer.Serve(*service.Listener); err != nil {
 		log.Fatalln(err)
 	}
@@ -97,25 +97,10 @@ func (d *DB) CheckSameLogin(login string) (bool, error) {
 	return true, nil
 }
 
-// Возвращает рефреш токен пользователя для сравнения
-func (d *DB) GetRefreshToken(id uuid.UUID) (string, error) {
-	var pgtoken pgtype.Text
-	err := d.db.QueryRow(context.Background(), `select refresh_token from public.users where id=$1`, id).Scan(&pgtoken)
-	if err != nil {
-		if err.Error() == pgx.ErrNoRows.Error() {
-			return "", nil
-		}
-		return "", err
-	}
-	return pgtoken.String, nil
-}
-
-// Добавляет/меняет рефреш токен пользователя
-func (d *DB) InsertRefreshToken(token string, id uuid.UUID) error {
-	_, err := d.db.Exec(context.Background(), `update public.users set refresh_token=$1 where id=$2`, token, id)
-	return err
-}
-
 func (d *DB) DeleteUser(login string) {
+	_, _ = d.db.Exec(context.Background(), `delete from public.sessions where user_id in (select id from public.users where login=$1)`, login)
+	_, _ = d.db.Exec(context.Background(), `delete from public.user_roles where user_id in (select id from public.users where login=$1)`, login)
+	_, _ = d.db.Exec(context.Background(), `delete from public.recovery_codes where user_id in (select id from public.users where login=$1)`, login)
+	_, _ = d.db.Exec(context.Background(), `delete from public.totp where user_id in (select id from public.users where login=$1)`, login)
 	_, _ = d.db.Exec(context.Background(), `delete from public.users where login=$1`, login)
 }
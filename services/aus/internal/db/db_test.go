@@ -1,9 +1,13 @@
 package db
 
 import (
+	problems "aus/internal/pkg/customErrors"
 	"aus/internal/pkg/models"
+	"context"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -21,3 +25,135 @@ func TestDB(t *testing.T) {
 		require.NoError(t, err, "Cannot add user %v")
 	}
 }
+
+func TestSessions(t *testing.T) {
+	cfg := Config{Host: "db", Port: "5432", User: "user", Password: "123", DBName: "papersdb"}
+	db, err := New(&cfg)
+	if err != nil {
+		t.Skipf("Database is not available: %v", err)
+	}
+	defer db.Close()
+
+	userID, err := db.AddUser(models.User{Login: "sessiontest", Password: "fakehash"})
+	require.NoError(t, err, "Cannot add test user")
+	defer db.DeleteUser("sessiontest")
+	token := func(family uuid.UUID, agent string) models.RefreshToken {
+		return models.RefreshToken{ID: uuid.New(), FamilyID: family, UserID: *userID, Device: models.Device{UserAgent: agent}, ExpiresAt: time.Now().Add(time.Hour),
+			Access: models.AccessToken{ID: uuid.New(), ExpiresAt: time.Now().Add(time.Minute)}}
+	}
+	phone, laptop := token(uuid.New(), "phone"), token(uuid.New(), "laptop")
+	require.NoError(t, db.CreateSession(phone))
+	require.NoError(t, db.CreateSession(laptop))
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tLogin on a second device keeps the first session", testID)
+		sessions, err := db.GetSessions(*userID)
+		require.NoError(t, err)
+		require.Len(t, sessions, 2)
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\tRefresh token is rotated once, reuse revokes the whole session", testID)
+		next := token(phone.FamilyID, "phone")
+		require.NoError(t, db.RotateRefreshToken(phone.ID, next))
+		require.ErrorIs(t, db.RotateRefreshToken(phone.ID, token(phone.FamilyID, "thief")), problems.ErrRefreshTokenReused)
+		require.ErrorIs(t, db.RotateRefreshToken(next.ID, token(phone.FamilyID, "phone")), problems.ErrBadRefreshToken,
+			"Token issued before the reuse must be revoked too")
+		sessions, err := db.GetSessions(*userID)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		require.Equal(t, laptop.FamilyID, sessions[0].ID, "Other sessions must survive")
+	}
+	{
+		testID := 3
+		t.Logf("\tTest %d:\tLogout revokes the session and returns its live access tokens", testID)
+		revoked, tokens, err := db.RevokeSessions(*userID, &laptop.FamilyID)
+		require.NoError(t, err)
+		require.Equal(t, 1, revoked)
+		require.Len(t, tokens, 1)
+		require.Equal(t, laptop.Access.ID, tokens[0].ID)
+		require.ErrorIs(t, db.RotateRefreshToken(laptop.ID, token(laptop.FamilyID, "laptop")), problems.ErrBadRefreshToken)
+		revoked, tokens, err = db.RevokeSessions(*userID, nil)
+		require.NoError(t, err)
+		require.Zero(t, revoked, "All sessions are already revoked")
+		require.Len(t, tokens, 3, "Access tokens of every session must be denied again")
+	}
+}
+
+func TestRoles(t *testing.T) {
+	cfg := Config{Host: "db", Port: "5432", User: "user", Password: "123", DBName: "papersdb"}
+	db, err := New(&cfg)
+	if err != nil {
+		t.Skipf("Database is not available: %v", err)
+	}
+	defer db.Close()
+
+	userID, err := db.AddUser(models.User{Login: "roletest", Password: "fakehash"})
+	require.NoError(t, err, "Cannot add test user")
+	defer db.DeleteUser("roletest")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tUser without roles has no permissions", testID)
+		roles, permissions, err := db.GetUserRoles(*userID)
+		require.NoError(t, err)
+		require.Empty(t, roles)
+		require.Empty(t, permissions)
+	}
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tPermissions of several roles are merged", testID)
+		_, err := db.db.Exec(context.Background(), `insert into public.user_roles(user_id, role) values($1, 'admin'), ($1, 'auditor')`, *userID)
+		require.NoError(t, err)
+		roles, permissions, err := db.GetUserRoles(*userID)
+		require.NoError(t, err)
+		require.Equal(t, []string{"admin", "auditor"}, roles)
+		require.Equal(t, []string{"market:read", "market:write"}, permissions)
+	}
+}
+
+func TestTOTP(t *testing.T) {
+	cfg := Config{Host: "db", Port: "5432", User: "user", Password: "123", DBName: "papersdb"}
+	db, err := New(&cfg)
+	if err != nil {
+		t.Skipf("Database is not available: %v", err)
+	}
+	defer db.Close()
+
+	userID, err := db.AddUser(models.User{Login: "totptest", Password: "fakehash"})
+	require.NoError(t, err, "Cannot add test user")
+	defer db.DeleteUser("totptest")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tSecret waits for the first code, enrollment can be restarted", testID)
+		_, err := db.GetTOTP(*userID)
+		require.ErrorIs(t, err, problems.ErrTOTPNotEnrolled)
+		require.NoError(t, db.SaveTOTPSecret(*userID, "FIRST"))
+		require.NoError(t, db.SaveTOTPSecret(*userID, "SECOND"))
+		secret, err := db.GetTOTP(*userID)
+		require.NoError(t, err)
+		require.Equal(t, "SECOND", secret.Secret)
+		require.False(t, secret.Active)
+		require.ErrorIs(t, db.UseTOTPStep(*userID, 1), problems.ErrBadTOTPCode, "Pending secret must not accept codes")
+	}
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tActivated secret accepts each step once", testID)
+		require.NoError(t, db.ActivateTOTP(*userID, 100, []string{"hash1", "hash2"}))
+		require.ErrorIs(t, db.SaveTOTPSecret(*userID, "THIRD"), problems.ErrTOTPActive)
+		require.ErrorIs(t, db.UseTOTPStep(*userID, 100), problems.ErrBadTOTPCode, "Activation code must not be reused")
+		require.NoError(t, db.UseTOTPStep(*userID, 101))
+		require.ErrorIs(t, db.UseTOTPStep(*userID, 101), problems.ErrBadTOTPCode)
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\tRecovery code is used once", testID)
+		codes, err := db.GetRecoveryCodes(*userID)
+		require.NoError(t, err)
+		require.Len(t, codes, 2)
+		require.NoError(t, db.UseRecoveryCode(codes[0].ID))
+		require.ErrorIs(t, db.UseRecoveryCode(codes[0].ID), problems.ErrBadTOTPCode)
+		codes, err = db.GetRecoveryCodes(*userID)
+		require.NoError(t, err)
+		require.Len(t, codes, 1)
+	}
+}
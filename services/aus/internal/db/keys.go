@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"aus/internal/pkg/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Ключи подписи, которые еще публикуются: текущий и отставленные, от новых к старым
+func (d *DB) GetSigningKeys(ctx context.Context) ([]models.SigningKey, error) {
+	res, err := d.db.Query(ctx, `select id, private_key, created_at, retired_at, expires_at from public.signing_keys
+		where expires_at is null or expires_at > now()
+		order by created_at desc`)
+	if err != nil {
+		log.Println("Cant get signing keys:", err)
+		return nil, err
+	}
+	keys, err := pgx.CollectRows(res, func(row pgx.CollectableRow) (models.SigningKey, error) {
+		var key models.SigningKey
+		err := row.Scan(&key.ID, &key.PrivateKey, &key.CreatedAt, &key.RetiredAt, &key.ExpiresAt)
+		return key, err
+	})
+	if err != nil {
+		log.Println("Cant scan signing keys:", err)
+	}
+	return keys, err
+}
+
+// Делает key текущим ключом подписи, если текущий создан не позже since(при нулевом since - только если текущего нет).
+// Прежний ключ отставляется и публикуется еще overlap. Возвращает false, если ротация не понадобилась: так
+// несколько экземпляров aus не выпускают по ключу каждый
+func (d *DB) RotateSigningKey(ctx context.Context, key models.SigningKey, since time.Time, overlap time.Duration) (bool, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		log.Println("Cant begin transaction:", err)
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `lock table public.signing_keys in share row exclusive mode`); err != nil {
+		log.Println("Cant lock signing keys:", err)
+		return false, err
+	}
+	var fresh bool
+	err = tx.QueryRow(ctx, `select exists(select 1 from public.signing_keys where retired_at is null and created_at > $1)`, since).Scan(&fresh)
+	if err != nil {
+		log.Println("Cant check current signing key:", err)
+		return false, err
+	}
+	if fresh {
+		return false, nil
+	}
+	_, err = tx.Exec(ctx, `update public.signing_keys set retired_at = now(), expires_at = now() + $1 where retired_at is null`, overlap)
+	if err != nil {
+		log.Println("Cant retire signing key:", err)
+		return false, err
+	}
+	_, err = tx.Exec(ctx, `insert into public.signing_keys(id, private_key) values($1, $2)`, key.ID, key.PrivateKey)
+	if err != nil {
+		log.Println("Cant insert signing key:", err)
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
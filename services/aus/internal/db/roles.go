@@ -0,0 +1,22 @@
+package db
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// Роли пользователя и объединение их прав, отсортированные. У обычного пользователя оба списка пустые
+func (d *DB) GetUserRoles(userID uuid.UUID) ([]string, []string, error) {
+	roles, permissions := []string{}, []string{}
+	err := d.db.QueryRow(context.Background(), `select coalesce(array_agg(ur.role order by ur.role), '{}'),
+			coalesce((select array_agg(distinct p order by p) from public.roles r, unnest(r.permissions) p
+				where r.name in (select role from public.user_roles where user_id = $1)), '{}')
+		from public.user_roles ur where ur.user_id = $1`, userID).Scan(&roles, &permissions)
+	if err != nil {
+		log.Println("Cant get user roles:", err)
+		return nil, nil, err
+	}
+	return roles, permissions, nil
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"log"
+
+	problems "aus/internal/pkg/customErrors"
+	"aus/internal/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const insertSession = `insert into public.sessions(id, family_id, user_id, user_agent, ip, expires_at, access_id, access_expires_at)
+	values($1, $2, $3, $4, $5, $6, $7, $8)`
+
+func sessionArgs(token models.RefreshToken) []any {
+	return []any{token.ID, token.FamilyID, token.UserID, token.Device.UserAgent, token.Device.IP, token.ExpiresAt, token.Access.ID, token.Access.ExpiresAt}
+}
+
+// Запоминает refresh токен новой сессии(или следующий токен сессии, см. RotateRefreshToken)
+func (d *DB) CreateSession(token models.RefreshToken) error {
+	_, err := d.db.Exec(context.Background(), insertSession, sessionArgs(token)...)
+	if err != nil {
+		log.Println("Cant create session:", err)
+	}
+	return err
+}
+
+// Обменивает refresh токен oldID на next той же сессии. Токен обменивается один раз: повторный обмен уже обмененного
+// токена отзывает всю сессию и возвращает problems.ErrRefreshTokenReused. Неизвестный, отозванный или истекший
+// токен - problems.ErrBadRefreshToken
+func (d *DB) RotateRefreshToken(oldID uuid.UUID, next models.RefreshToken) error {
+	ctx := context.Background()
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		log.Println("Cant begin transaction:", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+	res, err := tx.Exec(ctx, `update public.sessions set rotated_at = now()
+		where id = $1 and family_id = $2 and user_id = $3 and rotated_at is null and revoked_at is null and expires_at > now()`,
+		oldID, next.FamilyID, next.UserID)
+	if err != nil {
+		log.Println("Cant rotate refresh token:", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		var rotated bool
+		err := tx.QueryRow(ctx, `select rotated_at is not null from public.sessions where id = $1 and family_id = $2`, oldID, next.FamilyID).Scan(&rotated)
+		if err == pgx.ErrNoRows || err == nil && !rotated {
+			return problems.ErrBadRefreshToken
+		}
+		if err != nil {
+			log.Println("Cant get refresh token:", err)
+			return err
+		}
+		log.Printf("Refresh token %v of session %v reused, revoking the session\n", oldID, next.FamilyID)
+		if _, err := tx.Exec(ctx, `update public.sessions set revoked_at = now() where family_id = $1 and revoked_at is null`, next.FamilyID); err != nil {
+			log.Println("Cant revoke session:", err)
+			return err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return err
+		}
+		return problems.ErrRefreshTokenReused
+	}
+	_, err = tx.Exec(ctx, insertSession, sessionArgs(next)...)
+	if err != nil {
+		log.Println("Cant insert next refresh token:", err)
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Активные сессии пользователя от последних обновленных: по текущему refresh токену каждой сессии
+func (d *DB) GetSessions(userID uuid.UUID) ([]models.Session, error) {
+	res, err := d.db.Query(context.Background(), `select s.family_id, s.user_agent, s.ip, f.created_at, s.created_at, s.expires_at
+		from public.sessions s
+		cross join lateral (select min(created_at) as created_at from public.sessions where family_id = s.family_id) f
+		where s.user_id = $1 and s.rotated_at is null and s.revoked_at is null and s.expires_at > now()
+		order by s.created_at desc`, userID)
+	if err != nil {
+		log.Println("Cant get sessions:", err)
+		return nil, err
+	}
+	sessions, err := pgx.CollectRows(res, func(row pgx.CollectableRow) (models.Session, error) {
+		var s models.Session
+		err := row.Scan(&s.ID, &s.Device.UserAgent, &s.Device.IP, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt)
+		return s, err
+	})
+	if err != nil {
+		log.Println("Cant scan sessions:", err)
+	}
+	return sessions, err
+}
+
+// Отзывает сессию familyID пользователя или, если familyID nil, все его сессии. Возвращает число отозванных сессий
+// и еще действующие access токены этих сессий, которые нужно запретить до истечения
+func (d *DB) RevokeSessions(userID uuid.UUID, familyID *uuid.UUID) (int, []models.AccessToken, error) {
+	ctx := context.Background()
+	var revoked int
+	err := d.db.QueryRow(ctx, `with revoked as (update public.sessions set revoked_at = now()
+			where user_id = $1 and family_id = coalesce($2, family_id) and revoked_at is null returning family_id)
+		select count(distinct family_id) from revoked`, userID, familyID).Scan(&revoked)
+	if err != nil {
+		log.Println("Cant revoke sessions:", err)
+		return 0, nil, err
+	}
+	res, err := d.db.Query(ctx, `select access_id, access_expires_at from public.sessions
+		where user_id = $1 and family_id = coalesce($2, family_id) and access_expires_at > now()`, userID, familyID)
+	if err != nil {
+		log.Println("Cant get session access tokens:", err)
+		return revoked, nil, err
+	}
+	tokens, err := pgx.CollectRows(res, func(row pgx.CollectableRow) (models.AccessToken, error) {
+		token := models.AccessToken{UserID: userID}
+		err := row.Scan(&token.ID, &token.ExpiresAt)
+		return token, err
+	})
+	if err != nil {
+		log.Println("Cant scan session access tokens:", err)
+	}
+	return revoked, tokens, err
+}
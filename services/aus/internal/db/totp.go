@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"log"
+
+	problems "aus/internal/pkg/customErrors"
+	"aus/internal/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Запоминает новый секрет TOTP, ожидающий подтверждения первым кодом. Незавершенное подключение заменяется,
+// включенная 2FA - problems.ErrTOTPActive
+func (d *DB) SaveTOTPSecret(userID uuid.UUID, secret string) error {
+	res, err := d.db.Exec(context.Background(), `insert into public.totp(user_id, secret) values($1, $2)
+		on conflict (user_id) do update set secret = excluded.secret, created_at = now(), last_step = 0
+		where public.totp.activated_at is null`, userID, secret)
+	if err != nil {
+		log.Println("Cant save totp secret:", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return problems.ErrTOTPActive
+	}
+	return nil
+}
+
+// TOTP пользователя. Если 2FA не подключалась - problems.ErrTOTPNotEnrolled
+func (d *DB) GetTOTP(userID uuid.UUID) (models.TOTP, error) {
+	totp := models.TOTP{UserID: userID}
+	err := d.db.QueryRow(context.Background(), `select secret, activated_at is not null, last_step from public.totp where user_id = $1`,
+		userID).Scan(&totp.Secret, &totp.Active, &totp.LastStep)
+	if err == pgx.ErrNoRows {
+		return totp, problems.ErrTOTPNotEnrolled
+	}
+	if err != nil {
+		log.Println("Cant get totp:", err)
+	}
+	return totp, err
+}
+
+// Включает 2FA по первому принятому шагу и заменяет коды восстановления хэшами новых
+func (d *DB) ActivateTOTP(userID uuid.UUID, step int64, recoveryHashes []string) error {
+	ctx := context.Background()
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		log.Println("Cant begin transaction:", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+	res, err := tx.Exec(ctx, `update public.totp set activated_at = now(), last_step = $2 where user_id = $1 and activated_at is null`, userID, step)
+	if err != nil {
+		log.Println("Cant activate totp:", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return problems.ErrTOTPActive
+	}
+	if _, err := tx.Exec(ctx, `delete from public.recovery_codes where user_id = $1`, userID); err != nil {
+		log.Println("Cant delete recovery codes:", err)
+		return err
+	}
+	for _, hash := range recoveryHashes {
+		if _, err := tx.Exec(ctx, `insert into public.recovery_codes(user_id, code_hash) values($1, $2)`, userID, hash); err != nil {
+			log.Println("Cant insert recovery code:", err)
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// Запоминает принятый шаг времени. Шаг не новее последнего принятого(повтор кода) - problems.ErrBadTOTPCode
+func (d *DB) UseTOTPStep(userID uuid.UUID, step int64) error {
+	res, err := d.db.Exec(context.Background(), `update public.totp set last_step = $2
+		where user_id = $1 and activated_at is not null and last_step < $2`, userID, step)
+	if err != nil {
+		log.Println("Cant use totp step:", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return problems.ErrBadTOTPCode
+	}
+	return nil
+}
+
+// Неиспользованные коды восстановления пользователя
+func (d *DB) GetRecoveryCodes(userID uuid.UUID) ([]models.RecoveryCode, error) {
+	res, err := d.db.Query(context.Background(), `select id, code_hash from public.recovery_codes where user_id = $1 and used_at is null`, userID)
+	if err != nil {
+		log.Println("Cant get recovery codes:", err)
+		return nil, err
+	}
+	codes, err := pgx.CollectRows(res, func(row pgx.CollectableRow) (models.RecoveryCode, error) {
+		var code models.RecoveryCode
+		err := row.Scan(&code.ID, &code.Hash)
+		return code, err
+	})
+	if err != nil {
+		log.Println("Cant scan recovery codes:", err)
+	}
+	return codes, err
+}
+
+// Гасит код восстановления. Уже использованный - problems.ErrBadTOTPCode
+func (d *DB) UseRecoveryCode(id uuid.UUID) error {
+	res, err := d.db.Exec(context.Background(), `update public.recovery_codes set used_at = now() where id = $1 and used_at is null`, id)
+	if err != nil {
+		log.Println("Cant use recovery code:", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return problems.ErrBadTOTPCode
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package problems
+
+import "errors"
+
+var (
+	BadRefreshToken    = "refresh token is invalid or expired, consider relogin"
+	RefreshTokenReused = "refresh token was already used, the session is revoked, consider relogin"
+	NotRefreshToken    = "token is not a refresh token"
+	TOTPNotEnrolled    = "two-factor authentication is not enabled"
+	TOTPActive         = "two-factor authentication is already enabled"
+	BadTOTPCode        = "invalid or already used two-factor code"
+	TOTPLocked         = "too many wrong two-factor codes, try again later"
+)
+
+var (
+	ErrBadRefreshToken    = errors.New(BadRefreshToken)
+	ErrRefreshTokenReused = errors.New(RefreshTokenReused)
+	ErrNotRefreshToken    = errors.New(NotRefreshToken)
+	ErrTOTPNotEnrolled    = errors.New(TOTPNotEnrolled)
+	ErrTOTPActive         = errors.New(TOTPActive)
+	ErrBadTOTPCode        = errors.New(BadTOTPCode)
+	ErrTOTPLocked         = errors.New(TOTPLocked)
+)
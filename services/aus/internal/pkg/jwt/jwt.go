@@ -1,32 +1,61 @@
 package jwt
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/base64"
 	"errors"
 	"log"
-	"regexp"
-	"strconv"
-	"strings"
+	"math/big"
+	"sync"
 	"time"
 
-	"github.com/gofiber/fiber/v2"
+	problems "aus/internal/pkg/customErrors"
+	"aus/internal/pkg/models"
+
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+// Подписывает токены текущим ключом подписи и проверяет их по kid любым еще опубликованным ключом.
+// Ключи живут в базе: aus - единственный, у кого есть приватные ключи, остальные сервисы берут публичные из JWKS
 type JWT struct {
-	PrivateKey             *rsa.PrivateKey
-	PublicKey              *rsa.PublicKey
 	AccessTokenExpiration  time.Duration
 	RefreshTokenExpiration time.Duration
+	cfg                    *Config
+	store                  IKeyStore
+
+	mu      sync.RWMutex
+	signing *signingKey
+	public  map[string]*rsa.PublicKey
+	jwks    []models.JWK
 }
 
 type Config struct {
-	Key                    string `yaml:"private_key" env-prefix:"PRIVATEKEY" env-default:""`
-	AccessTokenExpiration  int    `yaml:"access_token_expiration" env-prefix:"ACCESSTOKENEXPIRATION" env-default:"3600"`
-	RefreshTokenExpiration int    `yaml:"refresh_token_expiration" env-prefix:"PRIVATEKEY" env-default:"36000"`
+	AccessTokenExpiration  int `yaml:"access_token_expiration" env-prefix:"ACCESSTOKENEXPIRATION" env-default:"3600"`
+	RefreshTokenExpiration int `yaml:"refresh_token_expiration" env-prefix:"PRIVATEKEY" env-default:"36000"`
+	// Раз в сколько секунд выпускать новый ключ подписи. 0 - без ротации
+	KeyRotation int `yaml:"key_rotation" env:"KEY_ROTATION" env-default:"604800"`
+	// Сколько секунд отставленный ключ еще публикуется. 0 - срок жизни refresh токена: меньше нельзя, иначе
+	// подписанные прежним ключом токены перестанут приниматься раньше срока
+	KeyOverlap int `yaml:"key_overlap" env:"KEY_OVERLAP"`
+	// Как часто в секундах проверять, не пора ли ротировать ключ, и перечитывать ключи из базы
+	KeyCheckInterval int `yaml:"key_check_interval" env-default:"60"`
+	// Сколько секунд действует challenge токен между паролем и кодом 2FA
+	ChallengeTokenExpiration int `yaml:"challenge_token_expiration" env-default:"300"`
+}
+
+type IKeyStore interface {
+	GetSigningKeys(ctx context.Context) ([]models.SigningKey, error)
+	RotateSigningKey(ctx context.Context, key models.SigningKey, since time.Time, overlap time.Duration) (bool, error)
+}
+
+type signingKey struct {
+	id        string
+	key       *rsa.PrivateKey
+	createdAt time.Time
 }
 
 const (
@@ -34,162 +63,269 @@ const (
 	ExpiredToken = "expired token"
 )
 
-var (
-	needToProvideAuthTokenURLs = []*regexp.Regexp{
-		regexp.MustCompile("^/buypaper$"),
-		regexp.MustCompile("^/sellpaper$"),
-		regexp.MustCompile("^/mypapers$"),
-		regexp.MustCompile("^/getbalance$"),
-		regexp.MustCompile("^/takebalance$"),
-		regexp.MustCompile("^/addbalance$"),
-	}
-	needToProvideRefreshTokenURLs = []*regexp.Regexp{
-		regexp.MustCompile("^/refresh$"),
-	}
+// Тип токена в клейме typ. challenge выдается после пароля пользователю с 2FA и годится только для LoginTOTP
+const (
+	AccessTokenType    = "access"
+	RefreshTokenType   = "refresh"
+	ChallengeTokenType = "challenge"
 )
 
-// Создает jwt объект с ключами и сроком действия токенов
-func New(cfg *Config) (JWT, error) {
-	jwt := JWT{}
-	var err error
-	privateKeyString := cfg.Key
-	jwt.AccessTokenExpiration = time.Second * time.Duration(cfg.AccessTokenExpiration)
-	jwt.RefreshTokenExpiration = time.Second * time.Duration(cfg.RefreshTokenExpiration)
-	if privateKeyString == "" {
-		jwt.PrivateKey, err = rsa.GenerateKey(rand.Reader, 2048)
-		if err != nil {
-			return JWT{}, err
+// Клеймы токенов: sub - пользователь, sid - сессия входа, jti - id токена(у refresh - строка таблицы сессий),
+// typ - access или refresh, чтобы refresh токен нельзя было предъявить вместо access.
+// roles и perms - роли пользователя и их права, только в access токене: по perms gateway пускает на руты
+type Claims struct {
+	jwt.RegisteredClaims
+	SessionID   string   `json:"sid,omitempty"`
+	Type        string   `json:"typ,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"perms,omitempty"`
+}
+
+// Создает jwt объект со сроками действия токенов и загружает ключи. Если ключей еще нет, выпускает первый
+func New(cfg *Config, store IKeyStore) (*JWT, error) {
+	j := &JWT{
+		AccessTokenExpiration:  time.Second * time.Duration(cfg.AccessTokenExpiration),
+		RefreshTokenExpiration: time.Second * time.Duration(cfg.RefreshTokenExpiration),
+		cfg:                    cfg,
+		store:                  store,
+	}
+	ctx := context.Background()
+	if err := j.load(ctx); err != nil {
+		return nil, err
+	}
+	if j.signing == nil {
+		if err := j.rotate(ctx, time.Time{}); err != nil {
+			return nil, err
 		}
-		jwt.PublicKey = &jwt.PrivateKey.PublicKey
-		return jwt, nil
 	}
-	keyBytes := convertStringToBytesSlice(privateKeyString)
-	jwt.PrivateKey, err = x509.ParsePKCS1PrivateKey(keyBytes)
-	jwt.PublicKey = &jwt.PrivateKey.PublicKey
-	if err != nil {
-		return JWT{}, err
+	return j, nil
+}
+
+// Раз в KeyCheckInterval ротирует ключ, если пора, и подхватывает ключи, выпущенные другими экземплярами aus
+func (j *JWT) StartRotation() {
+	for range time.Tick(time.Duration(j.cfg.KeyCheckInterval) * time.Second) {
+		if err := j.Rotate(context.Background()); err != nil {
+			log.Println("Signing key rotation failed:", err)
+		}
 	}
-	return jwt, nil
 }
 
-// Создаёт jwt объект, если ключ уже существует(это нужно, потому что при отсутствии ключа в cfg файле сервисы пытаются создать его сами, и создают разные ключи)
-// Это не очень хорошо, так как gateway зависит от authService, и это стоило бы как-то исправить
-func NewWithKey(cfg *Config, key *rsa.PrivateKey) (JWT, error) {
-	jwt := JWT{PrivateKey: key, PublicKey: &key.PublicKey,
-		AccessTokenExpiration: time.Second * time.Duration(cfg.AccessTokenExpiration), RefreshTokenExpiration: time.Second * time.Duration(cfg.RefreshTokenExpiration)}
-	return jwt, nil
+// Выпускает новый ключ подписи, если текущему больше KeyRotation, и перечитывает ключи
+func (j *JWT) Rotate(ctx context.Context) error {
+	rotation := time.Duration(j.cfg.KeyRotation) * time.Second
+	j.mu.RLock()
+	due := rotation > 0 && time.Since(j.signing.createdAt) >= rotation
+	j.mu.RUnlock()
+	if !due {
+		return j.load(ctx)
+	}
+	return j.rotate(ctx, time.Now().Add(-rotation))
 }
 
-// Создаёт токены для пользователя по его id. В access лежит id, в refresh ничего
-func (j *JWT) CreateTokens(user_id uuid.UUID) (string, string, error) {
-	subj := user_id.String()
-	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.AccessTokenExpiration)),
-		Subject:   subj,
-	}).SignedString(j.PrivateKey)
+func (j *JWT) rotate(ctx context.Context, since time.Time) error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
-		return "", "", err
+		return err
+	}
+	overlap := time.Duration(j.cfg.KeyOverlap) * time.Second
+	if overlap < j.RefreshTokenExpiration {
+		overlap = j.RefreshTokenExpiration
 	}
-	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.RefreshTokenExpiration)),
-	}).SignedString(j.PrivateKey)
+	kid := uuid.NewString()
+	rotated, err := j.store.RotateSigningKey(ctx, models.SigningKey{ID: kid, PrivateKey: x509.MarshalPKCS1PrivateKey(key)}, since, overlap)
 	if err != nil {
-		return "", "", err
+		return err
 	}
-	return accessToken, refreshToken, nil
+	if rotated {
+		log.Println("New signing key:", kid)
+	}
+	return j.load(ctx)
 }
 
-// Проверяет валидность токенов
-func (j *JWT) ValidateToken(c *fiber.Ctx, token string) (bool, error) {
-	claims := jwt.MapClaims{}
-	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return j.PublicKey, nil
-	})
+// Перечитывает опубликованные ключи из базы. Подписывает самый новый неотставленный
+func (j *JWT) load(ctx context.Context) error {
+	keys, err := j.store.GetSigningKeys(ctx)
 	if err != nil {
-		switch {
-		case errors.Is(err, jwt.ErrTokenExpired):
-			log.Println("Should expire at:", time.Unix(0, int64(claims["exp"].(float64))))
-			return false, err
-		case errors.Is(err, jwt.ErrSignatureInvalid) || errors.Is(err, jwt.ErrTokenUnverifiable):
-			return false, err
-		default:
-			return false, err
+		return err
+	}
+	var signing *signingKey
+	public := make(map[string]*rsa.PublicKey, len(keys))
+	jwks := make([]models.JWK, 0, len(keys))
+	for _, key := range keys {
+		private, err := x509.ParsePKCS1PrivateKey(key.PrivateKey)
+		if err != nil {
+			log.Printf("Cant parse signing key %v: %v\n", key.ID, err)
+			return err
 		}
+		if signing == nil && key.RetiredAt == nil {
+			signing = &signingKey{id: key.ID, key: private, createdAt: key.CreatedAt}
+		}
+		public[key.ID] = &private.PublicKey
+		jwks = append(jwks, toJWK(key.ID, &private.PublicKey))
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if signing != nil {
+		j.signing = signing
 	}
-	return true, nil
+	j.public = public
+	j.jwks = jwks
+	return nil
 }
 
-// Фильтрует руты, на которых access или refresh токены не понадобятся
-func (j *JWT) AuthFilter(c *fiber.Ctx) bool { //TODO: переделать чтобы была одна функция фильтра вместо двух
-	originalURL := strings.ToLower(c.OriginalURL())
-	for _, pattern := range needToProvideAuthTokenURLs {
-		if pattern.MatchString(originalURL) {
-			return false
-		}
-	}
-	return true
+// Опубликованные публичные ключи: ими проверяются все еще действующие токены
+func (j *JWT) JWKS() []models.JWK {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return append([]models.JWK(nil), j.jwks...)
 }
 
-// -||-
-func (j *JWT) RefreshFilter(c *fiber.Ctx) bool {
-	originalURL := strings.ToLower(c.OriginalURL())
-	for _, pattern := range needToProvideRefreshTokenURLs {
-		if pattern.MatchString(originalURL) {
-			return false
-		}
+func toJWK(kid string, key *rsa.PublicKey) models.JWK {
+	return models.JWK{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		Alg: jwt.SigningMethodRS256.Alg(),
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
 	}
-	return true
 }
 
-// Получает id пользователя из access токена
-func (j *JWT) GetIDFromToken(token string) (*uuid.UUID, error) {
-	claims := jwt.MapClaims{}
-	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return j.PublicKey, nil
+// Подписывает клеймы текущим ключом, его id кладется в заголовок kid
+func (j *JWT) sign(claims Claims) (string, error) {
+	j.mu.RLock()
+	signing := j.signing
+	j.mu.RUnlock()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = signing.id
+	return token.SignedString(signing.key)
+}
+
+// Создаёт пару токенов сессии пользователя. В обоих лежат id пользователя и сессии, jti и срок действия токены
+// получают из строки сессии, роли и права access токена - из refresh.Access
+func (j *JWT) CreateTokens(refresh models.RefreshToken) (string, string, error) {
+	subj := refresh.UserID.String()
+	accessToken, err := j.sign(Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(refresh.Access.ExpiresAt),
+			Subject:   subj,
+			ID:        refresh.Access.ID.String(),
+		},
+		SessionID:   refresh.FamilyID.String(),
+		Type:        AccessTokenType,
+		Roles:       refresh.Access.Roles,
+		Permissions: refresh.Access.Permissions,
 	})
 	if err != nil {
-		return nil, err
+		return "", "", err
 	}
-	id, err := getIdFromClaims(claims)
-	log.Printf("Got id: %v\n", id)
+	refreshToken, err := j.sign(Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(refresh.ExpiresAt),
+			Subject:   subj,
+			ID:        refresh.ID.String(),
+		},
+		SessionID: refresh.FamilyID.String(),
+		Type:      RefreshTokenType,
+	})
 	if err != nil {
-		return nil, err
+		return "", "", err
 	}
-	return id, nil
+	return accessToken, refreshToken, nil
 }
 
-// Получение ключей из jwt
-func (j *JWT) GetPrivateKey() *rsa.PrivateKey {
-	return j.PrivateKey
+// Проверяет refresh токен и возвращает его строку сессии(без устройства и срока). Access токен - problems.ErrNotRefreshToken
+func (j *JWT) ParseRefreshToken(token string) (models.RefreshToken, error) {
+	claims, err := j.parse(token)
+	if err != nil {
+		return models.RefreshToken{}, err
+	}
+	if claims.Type != RefreshTokenType {
+		return models.RefreshToken{}, problems.ErrNotRefreshToken
+	}
+	var refresh models.RefreshToken
+	if refresh.ID, err = uuid.Parse(claims.ID); err != nil {
+		return refresh, err
+	}
+	if refresh.FamilyID, err = uuid.Parse(claims.SessionID); err != nil {
+		return refresh, err
+	}
+	if refresh.UserID, err = uuid.Parse(claims.Subject); err != nil {
+		return refresh, err
+	}
+	return refresh, nil
 }
 
-// -||-
-func (j *JWT) GetPublicKey() *rsa.PublicKey {
-	return j.PublicKey
+// Challenge токен входа с 2FA: подтверждает, что пароль пользователя уже проверен
+func (j *JWT) CreateChallengeToken(userID uuid.UUID) (string, error) {
+	return j.sign(Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(j.cfg.ChallengeTokenExpiration) * time.Second)),
+			Subject:   userID.String(),
+			ID:        uuid.NewString(),
+		},
+		Type: ChallengeTokenType,
+	})
 }
 
-// Получение id из клеймов токена
-func getIdFromClaims(claims jwt.MapClaims) (*uuid.UUID, error) {
-	subj := claims["sub"].(string)
-	user_id, err := uuid.Parse(subj)
+// Проверяет challenge токен и возвращает его пользователя
+func (j *JWT) ParseChallengeToken(token string) (uuid.UUID, error) {
+	claims, err := j.parse(token)
 	if err != nil {
-		log.Println("Cannot atoi Id from claims")
-		return nil, err
+		return uuid.Nil, err
 	}
-	return &user_id, nil
+	if claims.Type != ChallengeTokenType {
+		return uuid.Nil, errors.New(InvalidToken)
+	}
+	return uuid.Parse(claims.Subject)
 }
 
-// Перевод застрингованного слайса байтов в слайс байтов "[1 2 3]" -> []byte{1, 2, 3}
-func convertStringToBytesSlice(line string) []byte {
-	line = strings.Trim(line, "[]")
-	parts := strings.Split(line, " ")
-	var bytes []byte
-	for _, part := range parts {
-		num, err := strconv.Atoi(part)
-		if err != nil {
-			panic(err)
-		}
-		bytes = append(bytes, byte(num))
+// Проверяет access токен и возвращает его пользователя, сессию и jti
+func (j *JWT) ParseAccessToken(token string) (models.AccessToken, error) {
+	claims, err := j.parse(token)
+	if err != nil {
+		return models.AccessToken{}, err
+	}
+	if claims.Type != AccessTokenType {
+		return models.AccessToken{}, errors.New(InvalidToken)
 	}
-	return bytes
+	access := models.AccessToken{}
+	if access.UserID, err = uuid.Parse(claims.Subject); err != nil {
+		return access, err
+	}
+	access.ID, _ = uuid.Parse(claims.ID)
+	access.SessionID, _ = uuid.Parse(claims.SessionID)
+	if claims.ExpiresAt != nil {
+		access.ExpiresAt = claims.ExpiresAt.Time
+	}
+	access.Roles, access.Permissions = claims.Roles, claims.Permissions
+	return access, nil
+}
+
+func (j *JWT) parse(token string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, j.publicKey, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
+	return claims, err
+}
+
+// Публичный ключ из kid заголовка токена. Токены без kid или с неопубликованным ключом не принимаются
+func (j *JWT) publicKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	key, ok := j.public[kid]
+	if !ok {
+		return nil, errors.New(InvalidToken)
+	}
+	return key, nil
+}
+
+// Сроки действия токенов
+func (j *JWT) GetAccessTokenExpiration() time.Duration {
+	return j.AccessTokenExpiration
+}
+
+// -||-
+func (j *JWT) GetRefreshTokenExpiration() time.Duration {
+	return j.RefreshTokenExpiration
 }
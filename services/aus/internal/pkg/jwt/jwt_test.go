@@ -0,0 +1,99 @@
+package jwt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"aus/internal/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// Хранит ключи в памяти так же, как public.signing_keys
+type fakeStore struct {
+	keys []models.SigningKey
+}
+
+func (s *fakeStore) GetSigningKeys(context.Context) ([]models.SigningKey, error) {
+	keys := []models.SigningKey{}
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		if s.keys[i].ExpiresAt == nil || s.keys[i].ExpiresAt.After(time.Now()) {
+			keys = append(keys, s.keys[i])
+		}
+	}
+	return keys, nil
+}
+
+func (s *fakeStore) RotateSigningKey(_ context.Context, key models.SigningKey, since time.Time, overlap time.Duration) (bool, error) {
+	now := time.Now()
+	for i := range s.keys {
+		if s.keys[i].RetiredAt == nil {
+			if s.keys[i].CreatedAt.After(since) {
+				return false, nil
+			}
+			expires := now.Add(overlap)
+			s.keys[i].RetiredAt, s.keys[i].ExpiresAt = &now, &expires
+		}
+	}
+	key.CreatedAt = now
+	s.keys = append(s.keys, key)
+	return true, nil
+}
+
+func session() models.RefreshToken {
+	return models.RefreshToken{ID: uuid.New(), FamilyID: uuid.New(), UserID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour),
+		Access: models.AccessToken{ID: uuid.New(), ExpiresAt: time.Now().Add(time.Minute), Roles: []string{"auditor"}, Permissions: []string{"market:read"}}}
+}
+
+func TestRotation(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeStore{}
+	j, err := New(&Config{AccessTokenExpiration: 60, RefreshTokenExpiration: 3600, KeyRotation: 3600}, store)
+	require.NoError(t, err)
+	refresh := session()
+	_, oldRefresh, err := j.CreateTokens(refresh)
+	require.NoError(t, err)
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tfirst key is created and published", testID)
+		require.Len(t, store.keys, 1)
+		require.Len(t, j.JWKS(), 1)
+		require.Equal(t, store.keys[0].ID, j.JWKS()[0].Kid)
+	}
+	{
+		testID := 1
+		t.Logf("\tTest %d:\trotation before the interval does nothing", testID)
+		require.NoError(t, j.Rotate(ctx))
+		require.Len(t, store.keys, 1)
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\tafter rotation new tokens use the new key, old ones are still accepted", testID)
+		store.keys[0].CreatedAt = time.Now().Add(-2 * time.Hour)
+		j.signing.createdAt = store.keys[0].CreatedAt
+		require.NoError(t, j.Rotate(ctx))
+		require.Len(t, store.keys, 2)
+		require.Len(t, j.JWKS(), 2)
+		require.Equal(t, store.keys[1].ID, j.signing.id)
+		parsed, err := j.ParseRefreshToken(oldRefresh)
+		require.NoError(t, err)
+		require.Equal(t, refresh.ID, parsed.ID)
+		access, _, err := j.CreateTokens(session())
+		require.NoError(t, err)
+		parsedAccess, err := j.ParseAccessToken(access)
+		require.NoError(t, err)
+		require.Equal(t, []string{"market:read"}, parsedAccess.Permissions, "Permissions must be carried in the access token")
+	}
+	{
+		testID := 3
+		t.Logf("\tTest %d:\ttokens of a key past its overlap are rejected", testID)
+		expired := time.Now().Add(-time.Second)
+		store.keys[0].ExpiresAt = &expired
+		require.NoError(t, j.Rotate(ctx))
+		require.Len(t, j.JWKS(), 1)
+		_, err := j.ParseRefreshToken(oldRefresh)
+		require.Error(t, err)
+	}
+}
@@ -1,6 +1,10 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // Данные о пользователе
 type User struct {
@@ -25,3 +29,75 @@ type Money struct {
 	ID   uuid.UUID `json:"id"`
 	Cash float32   `json:"cash"`
 }
+
+// Устройство, с которого выполнен вход
+type Device struct {
+	UserAgent string `json:"user_agent"`
+	IP        string `json:"ip"`
+}
+
+// Refresh токен сессии: ID - его jti, FamilyID - сессия входа, общая для всех токенов, выданных обменом.
+// Access - access токен, выданный вместе с ним
+type RefreshToken struct {
+	ID        uuid.UUID
+	FamilyID  uuid.UUID
+	UserID    uuid.UUID
+	Device    Device
+	ExpiresAt time.Time
+	Access    AccessToken
+}
+
+// Access токен: ID - его jti, SessionID - сессия входа(нулевая у токенов, выданных до сессий).
+// Roles и Permissions - роли пользователя и их права на момент выдачи токена
+type AccessToken struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	SessionID   uuid.UUID
+	ExpiresAt   time.Time
+	Roles       []string
+	Permissions []string
+}
+
+// Активная сессия входа: CreatedAt - время входа, LastUsedAt - последнего обмена refresh токена
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	Device     Device    `json:"device"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// Ключ подписи токенов: ID - kid, PrivateKey - PKCS1 DER. RetiredAt - когда ключ перестал подписывать(nil у текущего),
+// ExpiresAt - до какого времени он публикуется в JWKS
+type SigningKey struct {
+	ID         string
+	PrivateKey []byte
+	CreatedAt  time.Time
+	RetiredAt  *time.Time
+	ExpiresAt  *time.Time
+}
+
+// Публичный ключ в формате JWK(RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// TOTP пользователя: Active - 2FA включена первым кодом, LastStep - последний принятый шаг времени
+type TOTP struct {
+	UserID   uuid.UUID
+	Secret   string
+	Active   bool
+	LastStep int64
+}
+
+// Код восстановления 2FA: хранится только bcrypt хэш
+type RecoveryCode struct {
+	ID   uuid.UUID
+	Hash string
+}
@@ -0,0 +1,98 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Параметры кодов(RFC 6238): HMAC-SHA1, шаг 30 секунд, 6 цифр. Их же понимают все приложения-аутентификаторы
+const (
+	Period = 30
+	Digits = 6
+	// Сколько соседних шагов принимать из-за расхождения часов телефона и сервера
+	skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Новый случайный секрет в base32(160 бит, как рекомендует RFC 4226)
+func NewSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(secret), nil
+}
+
+// Provisioning URI для QR кода приложения: otpauth://totp/<issuer>:<account>?secret=...
+func URI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(Digits))
+	params.Set("period", fmt.Sprint(Period))
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+account) + "?" + params.Encode()
+}
+
+// Шаг времени t
+func Step(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// Код секрета на шаге step
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", Digits, value%1000000), nil
+}
+
+// Проверяет код на момент t с допуском в skew шагов. Возвращает шаг совпавшего кода: принятый шаг нужно запомнить,
+// чтобы тот же код нельзя было использовать повторно
+func Validate(secret, code string, t time.Time) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+	now := Step(t)
+	for step := now - skew; step <= now+skew; step++ {
+		expected, err := Code(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// n одноразовых кодов восстановления вида xxxxx-xxxxx
+func NewRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for range n {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(raw))[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+	return codes, nil
+}
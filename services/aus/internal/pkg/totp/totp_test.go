@@ -0,0 +1,40 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCode(t *testing.T) {
+	// Векторы RFC 6238(SHA1), последние 6 цифр
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{59: "287082", 1111111109: "081804", 1111111111: "050471", 1234567890: "005924", 2000000000: "279037"}
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tcodes match RFC 6238 test vectors", testID)
+		for unix, want := range vectors {
+			code, err := Code(secret, Step(time.Unix(unix, 0)))
+			require.NoError(t, err)
+			require.Equal(t, want, code, "time %v", unix)
+		}
+	}
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tcode of the neighbouring step is accepted, older codes are not", testID)
+		now := time.Unix(1111111111, 0)
+		previous, err := Code(secret, Step(now)-1)
+		require.NoError(t, err)
+		step, ok := Validate(secret, previous, now)
+		require.True(t, ok)
+		require.Equal(t, Step(now)-1, step)
+		old, err := Code(secret, Step(now)-2)
+		require.NoError(t, err)
+		_, ok = Validate(secret, old, now)
+		require.False(t, ok)
+		_, ok = Validate(secret, "12345", now)
+		require.False(t, ok)
+	}
+}
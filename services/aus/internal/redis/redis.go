@@ -0,0 +1,72 @@
+package redis
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+type Config struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"dbnum"`
+}
+
+// Префикс ключей denylist access токенов: denied:<jti>. Gateway не пропускает токены с таким ключом
+const DenylistPrefix = "denied:"
+
+type Redis struct {
+	client *redis.Client
+}
+
+func New(cfg *Config) (*Redis, error) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     cfg.Host + cfg.Port,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	ret := Redis{rdb}
+	return &ret, nil
+}
+
+// Запрещает access токен jti до его истечения: ключ живет ровно столько, сколько токен еще действителен
+func (r *Redis) DenyToken(ctx context.Context, jti uuid.UUID, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	if err := r.client.Set(ctx, DenylistPrefix+jti.String(), 1, ttl).Err(); err != nil {
+		log.Println("Cant deny access token:", err)
+		return err
+	}
+	return nil
+}
+
+// Префикс счетчиков попыток кода 2FA с последнего верного: totp_failures:<user>
+const TOTPFailuresPrefix = "totp_failures:"
+
+// Считает попытку ввести код 2FA до его проверки и возвращает номер попытки в текущем окне. Счетчик создается
+// вместе со сроком окна в одной транзакции, поэтому параллельные попытки получают разные номера, а окно всегда истекает
+func (r *Redis) AddTOTPAttempt(ctx context.Context, userID uuid.UUID, window time.Duration) (int64, error) {
+	key := TOTPFailuresPrefix + userID.String()
+	var attempt *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SetNX(ctx, key, 0, window)
+		attempt = pipe.Incr(ctx, key)
+		return nil
+	})
+	if err != nil {
+		log.Println("Cant count totp attempt:", err)
+		return 0, err
+	}
+	return attempt.Val(), nil
+}
+
+// Сбрасывает счетчик после верного кода
+func (r *Redis) ResetTOTPFailures(ctx context.Context, userID uuid.UUID) error {
+	return r.client.Del(ctx, TOTPFailuresPrefix+userID.String()).Err()
+}
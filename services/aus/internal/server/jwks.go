@@ -0,0 +1,27 @@
+package authserver
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"aus/internal/pkg/models"
+)
+
+const jwksPath = "/.well-known/jwks.json"
+
+// Отдает опубликованные публичные ключи в формате JWKS(RFC 7517). Кэшировать ответ дольше минуты нельзя:
+// после ротации токены подписываются новым ключом
+func jwksHandler(jwt IJWTManager) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+jwksPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		if err := json.NewEncoder(w).Encode(struct {
+			Keys []models.JWK `json:"keys"`
+		}{jwt.JWKS()}); err != nil {
+			log.Println("Cant write jwks:", err)
+		}
+	})
+	return mux
+}
@@ -2,12 +2,14 @@ package authserver
 
 import (
 	"context"
-	"crypto/rsa"
-	"crypto/x509"
+	"errors"
 	"log"
 	"net"
+	"net/http"
+	"time"
 
 	"aus/internal/pkg/crypt"
+	problems "aus/internal/pkg/customErrors"
 	pb "aus/internal/pkg/grpc/pb/authService"
 	"aus/internal/pkg/models"
 
@@ -20,12 +22,21 @@ import (
 
 type Config struct {
 	Port string `yaml:"auth_port" env-prefix:"AUTHPORT"`
+	// Порт HTTP сервера с JWKS(/.well-known/jwks.json)
+	JWKSPort string `yaml:"jwks_port" env:"JWKS_PORT" env-default:":8081"`
+	// Издатель в provisioning URI 2FA, его название видно в приложении-аутентификаторе
+	TOTPIssuer string `yaml:"totp_issuer" env-default:"Papers"`
 }
 
 type IJWTManager interface {
-	GetPrivateKey() *rsa.PrivateKey
-	CreateTokens(user_id uuid.UUID) (string, string, error)
-	GetIDFromToken(token string) (*uuid.UUID, error)
+	JWKS() []models.JWK
+	GetAccessTokenExpiration() time.Duration
+	GetRefreshTokenExpiration() time.Duration
+	CreateTokens(refresh models.RefreshToken) (string, string, error)
+	ParseRefreshToken(token string) (models.RefreshToken, error)
+	ParseAccessToken(token string) (models.AccessToken, error)
+	CreateChallengeToken(userID uuid.UUID) (string, error)
+	ParseChallengeToken(token string) (uuid.UUID, error)
 }
 
 type IDBManager interface {
@@ -33,35 +44,60 @@ type IDBManager interface {
 	AddUser(user models.User) (*uuid.UUID, error)
 	GetUserByID(id uuid.UUID) (models.User, error)
 	GetUserByLogin(login string) (models.User, error)
-	InsertRefreshToken(token string, id uuid.UUID) error
-	GetRefreshToken(id uuid.UUID) (string, error)
+	CreateSession(token models.RefreshToken) error
+	RotateRefreshToken(oldID uuid.UUID, next models.RefreshToken) error
+	GetSessions(userID uuid.UUID) ([]models.Session, error)
+	RevokeSessions(userID uuid.UUID, familyID *uuid.UUID) (int, []models.AccessToken, error)
+	GetUserRoles(userID uuid.UUID) ([]string, []string, error)
+	SaveTOTPSecret(userID uuid.UUID, secret string) error
+	GetTOTP(userID uuid.UUID) (models.TOTP, error)
+	ActivateTOTP(userID uuid.UUID, step int64, recoveryHashes []string) error
+	UseTOTPStep(userID uuid.UUID, step int64) error
+	GetRecoveryCodes(userID uuid.UUID) ([]models.RecoveryCode, error)
+	UseRecoveryCode(id uuid.UUID) error
+}
+
+// Denylist access токенов, который gateway проверяет на каждом запросе
+type IDenylist interface {
+	DenyToken(ctx context.Context, jti uuid.UUID, expiresAt time.Time) error
+}
+
+// Счетчик неверных кодов 2FA против перебора
+type ITOTPLimiter interface {
+	AddTOTPAttempt(ctx context.Context, userID uuid.UUID, window time.Duration) (int64, error)
+	ResetTOTPFailures(ctx context.Context, userID uuid.UUID) error
 }
 
 type server struct {
 	pb.UnimplementedAuthentificationServer
-	jwt IJWTManager
-	db  IDBManager
+	cfg      *Config
+	jwt      IJWTManager
+	db       IDBManager
+	denylist IDenylist
+	limiter  ITOTPLimiter
 }
 
 type Service struct {
 	AuthServer *grpc.Server
 	Listener   *net.Listener
+	JWKS       *http.Server
 	cfg        *Config
 	jwt        IJWTManager
 	db         IDBManager
 }
 
 // Создание сервера сервиса аутентификации
-func New(cfg *Config, jwt IJWTManager, db IDBManager) (*Service, error) {
+func New(cfg *Config, jwt IJWTManager, db IDBManager, denylist IDenylist, limiter ITOTPLimiter) (*Service, error) {
 	log.Println(cfg.Port)
 	lis, err := net.Listen("tcp", cfg.Port)
 	if err != nil {
 		return nil, err
 	}
 	s := grpc.NewServer()
-	pb.RegisterAuthentificationServer(s, &server{jwt: jwt, db: db})
+	pb.RegisterAuthentificationServer(s, &server{cfg: cfg, jwt: jwt, db: db, denylist: denylist, limiter: limiter})
 	log.Printf("Auth server listening at %v\n", lis.Addr())
-	return &Service{AuthServer: s, Listener: &lis, cfg: cfg, jwt: jwt, db: db}, nil
+	jwks := &http.Server{Addr: cfg.JWKSPort, Handler: jwksHandler(jwt)}
+	return &Service{AuthServer: s, Listener: &lis, JWKS: jwks, cfg: cfg, jwt: jwt, db: db}, nil
 }
 
 // Регистрация пользователя(в т.ч. валидация ника и пароля, проверка на наличие пользователя в БД, добавление его в БД и возврат токенов)
@@ -86,15 +122,7 @@ func (s *server) Register(_ context.Context, in *pb.User) (*pb.AuthData, error)
 		log.Println("Something went wrong when added user: " + err.Error())
 		return nil, status.Errorf(codes.Internal, "%v", err)
 	}
-	access, refresh, err := s.jwt.CreateTokens(*id)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "%v", err)
-	}
-	err = s.db.InsertRefreshToken(refresh, *id)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "%v", err)
-	}
-	return &pb.AuthData{AccessToken: access, RefreshToken: refresh}, nil
+	return s.startSession(*id, in.Device)
 }
 
 // Проверка данных аутентификации и создание и возвращение токенов в случае успеха
@@ -119,42 +147,164 @@ func (s *server) Login(_ context.Context, in *pb.User) (*pb.AuthData, error) {
 		}
 		return nil, status.Errorf(codes.Internal, err.Error())
 	}
-	access, refresh, err := s.jwt.CreateTokens(user.ID)
+	// С включенной 2FA токены выдаст LoginTOTP после кода
+	secret, err := s.db.GetTOTP(user.ID)
+	switch {
+	case err == nil && secret.Active:
+		challenge, err := s.jwt.CreateChallengeToken(user.ID)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "%v", err)
+		}
+		return &pb.AuthData{ChallengeToken: challenge}, nil
+	case err != nil && !errors.Is(err, problems.ErrTOTPNotEnrolled):
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+	return s.startSession(user.ID, in.Device)
+}
+
+// Начинает новую сессию входа с устройства и выдает ее первую пару токенов. Другие сессии пользователя не затрагиваются
+func (s *server) startSession(userID uuid.UUID, device *pb.Device) (*pb.AuthData, error) {
+	refresh := s.refreshToken(models.RefreshToken{FamilyID: uuid.New(), UserID: userID}, device)
+	if err := s.db.CreateSession(refresh); err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+	return s.tokens(refresh)
+}
+
+// Следующий refresh токен сессии prev вместе с новым access токеном: новые jti, устройство запроса и полные сроки действия
+func (s *server) refreshToken(prev models.RefreshToken, device *pb.Device) models.RefreshToken {
+	now := time.Now()
+	return models.RefreshToken{
+		ID: uuid.New(), FamilyID: prev.FamilyID, UserID: prev.UserID, ExpiresAt: now.Add(s.jwt.GetRefreshTokenExpiration()),
+		Device: models.Device{UserAgent: device.GetUserAgent(), IP: device.GetIp()},
+		Access: models.AccessToken{ID: uuid.New(), ExpiresAt: now.Add(s.jwt.GetAccessTokenExpiration())},
+	}
+}
+
+// Выпускает пару токенов строки сессии. Роли читаются заново, поэтому выданная или снятая роль
+// действует с ближайшего обновления токенов
+func (s *server) tokens(refresh models.RefreshToken) (*pb.AuthData, error) {
+	roles, permissions, err := s.db.GetUserRoles(refresh.UserID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "%v", err)
 	}
-	err = s.db.InsertRefreshToken(refresh, user.ID)
+	refresh.Access.Roles, refresh.Access.Permissions = roles, permissions
+	access, refreshToken, err := s.jwt.CreateTokens(refresh)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "%v", err)
 	}
-	return &pb.AuthData{AccessToken: access, RefreshToken: refresh}, nil
+	return &pb.AuthData{AccessToken: access, RefreshToken: refreshToken}, nil
 }
 
-// Создание новых токенов(нужно для обновления access токена при его истекшем сроке годности)
-func (s *server) UpdateTokens(_ context.Context, in *pb.AuthData) (*pb.AuthData, error) {
-	user_id, err := s.jwt.GetIDFromToken(in.AccessToken)
+// Обмен refresh токена на новую пару(нужно для обновления access токена при его истекшем сроке годности).
+// Access токен не нужен: пользователь и сессия берутся из refresh токена
+func (s *server) UpdateTokens(ctx context.Context, in *pb.AuthData) (*pb.AuthData, error) {
+	prev, err := s.jwt.ParseRefreshToken(in.RefreshToken)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, err.Error())
+		log.Println("Bad refresh token:", err)
+		return nil, status.Errorf(codes.Unauthenticated, "%v", problems.ErrBadRefreshToken)
+	}
+	next := s.refreshToken(prev, in.Device)
+	err = s.db.RotateRefreshToken(prev.ID, next)
+	switch {
+	case errors.Is(err, problems.ErrRefreshTokenReused):
+		// Токен, скорее всего, украден: access токены отозванной сессии тоже больше не действуют
+		if _, err := s.revoke(ctx, prev.UserID, &prev.FamilyID); err != nil {
+			log.Println("Cant deny access tokens of reused session:", err)
+		}
+		return nil, status.Errorf(codes.Unauthenticated, "%v", problems.ErrRefreshTokenReused)
+	case errors.Is(err, problems.ErrBadRefreshToken):
+		return nil, status.Errorf(codes.Unauthenticated, "%v", err)
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "%v", err)
 	}
-	realRefreshToken, err := s.db.GetRefreshToken(*user_id)
+	return s.tokens(next)
+}
+
+// Активные сессии пользователя access токена, его сессия отмечена current
+func (s *server) GetSessions(_ context.Context, in *pb.SessionsRequest) (*pb.Sessions, error) {
+	access, err := s.jwt.ParseAccessToken(in.AccessToken)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "%v", err)
+	}
+	sessions, err := s.db.GetSessions(access.UserID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "%v", err)
 	}
-	if realRefreshToken != in.RefreshToken {
-		return nil, status.Errorf(codes.InvalidArgument, "Refresh token was changed, consider relogin")
+	resp := &pb.Sessions{Sessions: make([]*pb.Session, 0, len(sessions))}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, &pb.Session{
+			Id: session.ID[:], Device: &pb.Device{UserAgent: session.Device.UserAgent, Ip: session.Device.IP},
+			CreatedAt: session.CreatedAt.Unix(), LastUsedAt: session.LastUsedAt.Unix(), ExpiresAt: session.ExpiresAt.Unix(),
+			Current: session.ID == access.SessionID,
+		})
 	}
-	access, refresh, err := s.jwt.CreateTokens(*user_id)
+	return resp, nil
+}
+
+// Завершает сессию access токена. Сам токен запрещается, даже если выдан до сессий
+func (s *server) Logout(ctx context.Context, in *pb.LogoutRequest) (*pb.LogoutResult, error) {
+	access, err := s.jwt.ParseAccessToken(in.AccessToken)
 	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "%v", err)
+	}
+	revoked := 0
+	if access.SessionID != uuid.Nil {
+		if revoked, err = s.revoke(ctx, access.UserID, &access.SessionID); err != nil {
+			return nil, status.Errorf(codes.Internal, "%v", err)
+		}
+	}
+	if err := s.deny(ctx, access); err != nil {
 		return nil, status.Errorf(codes.Internal, "%v", err)
 	}
-	err = s.db.InsertRefreshToken(access, *user_id)
+	log.Printf("User %v logged out of session %v\n", access.UserID, access.SessionID)
+	return &pb.LogoutResult{Sessions: int32(revoked)}, nil
+}
+
+// Завершает все сессии пользователя access токена
+func (s *server) LogoutAll(ctx context.Context, in *pb.LogoutRequest) (*pb.LogoutResult, error) {
+	access, err := s.jwt.ParseAccessToken(in.AccessToken)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "%v", err)
+	}
+	revoked, err := s.revoke(ctx, access.UserID, nil)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "%v", err)
 	}
-	return &pb.AuthData{AccessToken: access, RefreshToken: refresh}, nil
+	if err := s.deny(ctx, access); err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+	log.Printf("User %v logged out of %v sessions\n", access.UserID, revoked)
+	return &pb.LogoutResult{Sessions: int32(revoked)}, nil
+}
+
+// Отзывает сессию(или все сессии, если sessionID nil) и запрещает их еще действующие access токены
+func (s *server) revoke(ctx context.Context, userID uuid.UUID, sessionID *uuid.UUID) (int, error) {
+	revoked, tokens, err := s.db.RevokeSessions(userID, sessionID)
+	if err != nil {
+		return 0, err
+	}
+	for _, token := range tokens {
+		if err := s.deny(ctx, token); err != nil {
+			return revoked, err
+		}
+	}
+	return revoked, nil
+}
+
+func (s *server) deny(ctx context.Context, token models.AccessToken) error {
+	if token.ID == uuid.Nil {
+		return nil
+	}
+	return s.denylist.DenyToken(ctx, token.ID, token.ExpiresAt)
 }
 
-// Получение приватного ключа(см jwt.NewWithKey())
-func (s *server) GetPrivateKey(_ context.Context, in *pb.KeyRequest) (*pb.PrivateKey, error) {
-	return &pb.PrivateKey{Key: x509.MarshalPKCS1PrivateKey(s.jwt.GetPrivateKey())}, nil
+// Публичные ключи проверки токенов
+func (s *server) GetJWKS(_ context.Context, in *pb.KeyRequest) (*pb.JWKS, error) {
+	keys := s.jwt.JWKS()
+	resp := &pb.JWKS{Keys: make([]*pb.JWK, 0, len(keys))}
+	for _, key := range keys {
+		resp.Keys = append(resp.Keys, &pb.JWK{Kty: key.Kty, Kid: key.Kid, Use: key.Use, Alg: key.Alg, N: key.N, E: key.E})
+	}
+	return resp, nil
 }
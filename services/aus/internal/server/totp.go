@@ -0,0 +1,179 @@
+package authserver
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"aus/internal/pkg/crypt"
+	problems "aus/internal/pkg/customErrors"
+	pb "aus/internal/pkg/grpc/pb/authService"
+	"aus/internal/pkg/totp"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// После totpMaxFailures попыток без верного кода проверки 2FA отклоняются до конца окна totpLockout
+const (
+	totpMaxFailures    = 5
+	totpLockout        = 15 * time.Minute
+	recoveryCodesCount = 10
+)
+
+// Подключение 2FA: новый секрет и provisioning URI для приложения. 2FA включится после ActivateTOTP
+func (s *server) EnrollTOTP(_ context.Context, in *pb.TOTPRequest) (*pb.TOTPEnrollment, error) {
+	access, err := s.jwt.ParseAccessToken(in.AccessToken)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "%v", err)
+	}
+	user, err := s.db.GetUserByID(access.UserID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+	if err := s.db.SaveTOTPSecret(access.UserID, secret); err != nil {
+		return nil, totpError(err)
+	}
+	return &pb.TOTPEnrollment{Secret: secret, Uri: totp.URI(s.cfg.TOTPIssuer, user.Login, secret)}, nil
+}
+
+// Включение 2FA первым кодом из приложения: так пользователь подтверждает, что секрет сохранен
+func (s *server) ActivateTOTP(ctx context.Context, in *pb.TOTPRequest) (*pb.RecoveryCodes, error) {
+	access, err := s.jwt.ParseAccessToken(in.AccessToken)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "%v", err)
+	}
+	secret, err := s.db.GetTOTP(access.UserID)
+	if err != nil {
+		return nil, totpError(err)
+	}
+	if secret.Active {
+		return nil, totpError(problems.ErrTOTPActive)
+	}
+	// Неверные коды при включении считаются так же, как при входе: иначе код можно подбирать здесь без ограничений
+	var step int64
+	err = s.limitFailures(ctx, access.UserID, func() error {
+		var ok bool
+		if step, ok = totp.Validate(secret.Secret, in.Code, time.Now()); !ok {
+			return problems.ErrBadTOTPCode
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, totpError(err)
+	}
+	recovery, err := totp.NewRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+	hashes := make([]string, 0, len(recovery))
+	for _, code := range recovery {
+		hash, err := crypt.CryptPassword(code)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "%v", err)
+		}
+		hashes = append(hashes, string(hash))
+	}
+	if err := s.db.ActivateTOTP(access.UserID, step, hashes); err != nil {
+		return nil, totpError(err)
+	}
+	log.Printf("User %v enabled two-factor authentication\n", access.UserID)
+	return &pb.RecoveryCodes{Codes: recovery}, nil
+}
+
+// Второй шаг входа: код из приложения или код восстановления к challenge токену из Login
+func (s *server) LoginTOTP(ctx context.Context, in *pb.TOTPLogin) (*pb.AuthData, error) {
+	userID, err := s.jwt.ParseChallengeToken(in.ChallengeToken)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "%v", err)
+	}
+	if err := s.verifyCode(ctx, userID, in.Code, true); err != nil {
+		return nil, totpError(err)
+	}
+	return s.startSession(userID, in.Device)
+}
+
+// Подтверждение операции кодом из приложения. Коды восстановления здесь не принимаются
+func (s *server) VerifyTOTP(ctx context.Context, in *pb.TOTPRequest) (*pb.TOTPResult, error) {
+	access, err := s.jwt.ParseAccessToken(in.AccessToken)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "%v", err)
+	}
+	if err := s.verifyCode(ctx, access.UserID, in.Code, false); err != nil {
+		return nil, totpError(err)
+	}
+	return &pb.TOTPResult{}, nil
+}
+
+// Проверяет код включенной 2FA пользователя и считает неверные коды
+func (s *server) verifyCode(ctx context.Context, userID uuid.UUID, code string, allowRecovery bool) error {
+	return s.limitFailures(ctx, userID, func() error { return s.checkCode(userID, code, allowRecovery) })
+}
+
+// Проверка кода check под ограничением: попытка считается до проверки, поэтому параллельные запросы не получат
+// больше totpMaxFailures попыток за окно totpLockout. Верный код сбрасывает счетчик
+func (s *server) limitFailures(ctx context.Context, userID uuid.UUID, check func() error) error {
+	attempt, err := s.limiter.AddTOTPAttempt(ctx, userID, totpLockout)
+	if err != nil {
+		return err
+	}
+	if attempt > totpMaxFailures {
+		return problems.ErrTOTPLocked
+	}
+	if err := check(); err != nil {
+		return err
+	}
+	if err := s.limiter.ResetTOTPFailures(ctx, userID); err != nil {
+		log.Println("Cant reset totp failures:", err)
+	}
+	return nil
+}
+
+// Код приложения принимается один раз(запоминается его шаг), код восстановления гасится
+func (s *server) checkCode(userID uuid.UUID, code string, allowRecovery bool) error {
+	secret, err := s.db.GetTOTP(userID)
+	if err != nil {
+		return err
+	}
+	if !secret.Active {
+		return problems.ErrTOTPNotEnrolled
+	}
+	if step, ok := totp.Validate(secret.Secret, code, time.Now()); ok {
+		return s.db.UseTOTPStep(userID, step)
+	}
+	if !allowRecovery {
+		return problems.ErrBadTOTPCode
+	}
+	recovery, err := s.db.GetRecoveryCodes(userID)
+	if err != nil {
+		return err
+	}
+	code = strings.ToLower(strings.TrimSpace(code))
+	for _, rc := range recovery {
+		if bcrypt.CompareHashAndPassword([]byte(rc.Hash), []byte(code)) == nil {
+			log.Printf("User %v logged in with a recovery code\n", userID)
+			return s.db.UseRecoveryCode(rc.ID)
+		}
+	}
+	return problems.ErrBadTOTPCode
+}
+
+func totpError(err error) error {
+	switch {
+	case errors.Is(err, problems.ErrBadTOTPCode):
+		return status.Errorf(codes.PermissionDenied, "%v", err)
+	case errors.Is(err, problems.ErrTOTPLocked):
+		return status.Errorf(codes.ResourceExhausted, "%v", err)
+	case errors.Is(err, problems.ErrTOTPNotEnrolled), errors.Is(err, problems.ErrTOTPActive):
+		return status.Errorf(codes.FailedPrecondition, "%v", err)
+	}
+	return status.Errorf(codes.Internal, "%v", err)
+}
@@ -0,0 +1,74 @@
+package authserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	problems "aus/internal/pkg/customErrors"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// Счетчик попыток в памяти, как в redis: номер попытки выдается атомарно
+type fakeLimiter struct {
+	mu       sync.Mutex
+	attempts map[uuid.UUID]int64
+}
+
+func (l *fakeLimiter) AddTOTPAttempt(_ context.Context, userID uuid.UUID, _ time.Duration) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.attempts[userID]++
+	return l.attempts[userID], nil
+}
+
+func (l *fakeLimiter) ResetTOTPFailures(_ context.Context, userID uuid.UUID) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.attempts, userID)
+	return nil
+}
+
+func TestLimitFailures(t *testing.T) {
+	s := &server{limiter: &fakeLimiter{attempts: map[uuid.UUID]int64{}}}
+	ctx := context.Background()
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tParallel wrong codes get no more than %d checks", testID, totpMaxFailures)
+		userID := uuid.New()
+		var checks, locked atomic.Int64
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := s.limitFailures(ctx, userID, func() error {
+					checks.Add(1)
+					return problems.ErrBadTOTPCode
+				})
+				if errors.Is(err, problems.ErrTOTPLocked) {
+					locked.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		require.EqualValues(t, totpMaxFailures, checks.Load())
+		require.EqualValues(t, 20-totpMaxFailures, locked.Load())
+	}
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tCorrect code resets the counter", testID)
+		userID := uuid.New()
+		for range totpMaxFailures - 1 {
+			require.ErrorIs(t, s.limitFailures(ctx, userID, func() error { return problems.ErrBadTOTPCode }), problems.ErrBadTOTPCode)
+		}
+		require.NoError(t, s.limitFailures(ctx, userID, func() error { return nil }))
+		require.ErrorIs(t, s.limitFailures(ctx, userID, func() error { return problems.ErrBadTOTPCode }), problems.ErrBadTOTPCode,
+			"Counter must start over after a correct code")
+	}
+}
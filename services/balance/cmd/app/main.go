@@ -2,15 +2,23 @@ package main
 
 import (
 	pg "balance/internal/db"
+	"balance/internal/interest"
+	"balance/internal/provider"
+	"balance/internal/rules"
 	server "balance/internal/server"
+	"idempotency"
 	"log"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	DBConfig            *pg.Config     `yaml:"db" env-prefix:"DB_"`
-	BalanceServerConfig *server.Config `yaml:"balance"`
+	DBConfig            *pg.Config       `yaml:"db" env-prefix:"DB_"`
+	BalanceServerConfig *server.Config   `yaml:"balance"`
+	RulesConfig         *rules.Config    `yaml:"rules"`
+	PaymentsConfig      *provider.Config `yaml:"payments"`
+	InterestConfig      *interest.Config `yaml:"interest"`
 }
 
 func readConfig(filename string) (*Config, error) {
@@ -33,10 +41,14 @@ func main() {
 		log.Fatalln(err)
 	}
 	log.Println("DB connected successfully")
-	server, err := server.New(cfg.BalanceServerConfig, db)
+	keys := idempotency.New(db.Pool(), time.Duration(cfg.BalanceServerConfig.IdempotencyTTL)*time.Second)
+	go keys.Cleanup(time.Duration(cfg.BalanceServerConfig.IdempotencyCleanupInterval) * time.Second)
+	server, err := server.New(cfg.BalanceServerConfig, db, rules.New(cfg.RulesConfig, db), provider.New(cfg.PaymentsConfig), keys)
 	if err != nil {
 		log.Fatalln(err)
 	}
+	go server.WatchLedger()
+	go interest.New(cfg.InterestConfig, db).Start()
 	if err := server.Server.Serve(*server.Listener); err != nil {
 		log.Fatalln(err)
 	}
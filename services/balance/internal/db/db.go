@@ -4,10 +4,15 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
+
+	problems "balance/internal/pkg/customErrors"
+	"balance/internal/pkg/models"
+	"balance/internal/pkg/money"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Config struct {
@@ -20,40 +25,138 @@ type Config struct {
 
 type DB struct {
 	config *Config
-	db     *pgx.Conn
+	db     *pgxpool.Pool
 }
 
-// Создает соединение с существующей БД
+// Создает пул соединений с существующей БД(запросы и проверка журнала идут параллельно)
 func New(cfg *Config) (*DB, error) {
 	d := &DB{config: cfg}
 	connection := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DBName)
-	db, err := pgx.Connect(context.Background(), connection)
+	db, err := pgxpool.New(context.Background(), connection)
 	log.Println("Connecting to: " + connection)
 	if err != nil {
 		return nil, err
 	}
+	if err := db.Ping(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
 	d.db = db
 	return d, nil
 }
 
-// Закрывает соединение с БД
+// Пул соединений для пакетов со своими таблицами в той же БД(idempotency)
+func (d *DB) Pool() *pgxpool.Pool {
+	return d.db
+}
+
+// Закрывает соединения с БД
 func (d *DB) Close() error {
-	return d.db.Close(context.Background())
+	d.db.Close()
+	return nil
 }
 
-func (d *DB) GetUserBalance(userID uuid.UUID) (float32, error) {
-	var balance pgtype.Float4
-	err := d.db.QueryRow(context.Background(), `select balance from public.users where id=$1`, userID).Scan(&balance)
+// Баланс пользователя в валюте currency - кэш его счета cash в журнале. Пока проводок не было, он нулевой
+func (d *DB) GetUserBalance(userID uuid.UUID, currency string) (money.Amount, error) {
+	var balance money.Amount
+	err := d.db.QueryRow(context.Background(), `select coalesce((select balance from public.accounts where kind = $1 and user_id = $2 and currency = $3), 0)`,
+		models.AccountCash, userID, currency).Scan(&balance)
 	if err != nil {
 		return -1, err
 	}
-	return balance.Float32, nil
+	return balance, nil
 }
 
-func (d *DB) ChangeBalance(userID uuid.UUID, cash float32) error {
-	_, err := d.db.Exec(context.Background(), `update public.users set balance = balance + $1 where id = $2`, cash, userID)
+// Кошельки пользователя: деньги на счетах cash во всех валютах, в которых были проводки. Базовая валюта есть всегда
+func (d *DB) GetWallets(userID uuid.UUID) ([]models.Wallet, error) {
+	res, err := d.db.Query(context.Background(), `select c.code, coalesce(a.balance, 0) from public.currencies c
+		left join public.accounts a on a.currency = c.code and a.kind = $1 and a.user_id = $2
+		where a.id is not null or c.code = $3 order by c.code`, models.AccountCash, userID, models.BaseCurrency)
 	if err != nil {
-		log.Println("Cant change user balance")
+		log.Println("Cant get user wallets:", err)
+		return nil, err
+	}
+	defer res.Close()
+	wallets := []models.Wallet{}
+	for res.Next() {
+		var wallet models.Wallet
+		if err := res.Scan(&wallet.Currency, &wallet.Amount); err != nil {
+			log.Println("Cant scan wallet:", err)
+			return nil, err
+		}
+		wallets = append(wallets, wallet)
+	}
+	return wallets, res.Err()
+}
+
+// Пополнение: деньги приходят на счет пользователя в валюте currency извне биржи
+func (d *DB) Deposit(userID uuid.UUID, currency string, cash money.Amount) error {
+	ctx := context.Background()
+	entry := models.Entry{Kind: models.EntryDeposit}
+	entry.Move(models.SystemAccount(models.AccountExternal, currency), models.CashAccount(userID, currency), cash)
+	return d.inTx(ctx, func(tx pgx.Tx) error {
+		if _, err := rate(ctx, tx, currency); err != nil {
+			return err
+		}
+		return post(ctx, tx, entry)
+	})
+}
+
+// Вывод денег за пределы биржи. Если денег не хватает - problems.ErrLowBalance
+func (d *DB) Withdraw(userID uuid.UUID, currency string, cash money.Amount) error {
+	ctx := context.Background()
+	entry := models.Entry{Kind: models.EntryWithdrawal}
+	entry.Move(models.CashAccount(userID, currency), models.SystemAccount(models.AccountExternal, currency), cash)
+	return d.inTx(ctx, func(tx pgx.Tx) error {
+		if _, err := rate(ctx, tx, currency); err != nil {
+			return err
+		}
+		balance, err := lock(ctx, tx, models.CashAccount(userID, currency))
+		if err != nil {
+			return err
+		}
+		if cash > balance {
+			return problems.ErrLowBalance
+		}
+		return post(ctx, tx, entry)
+	})
+}
+
+// Пополнения или выводы(kind) пользователя: суммы в базовой валюте по текущим курсам с day и с month и время последнего
+func (d *DB) GetActivity(ctx context.Context, userID uuid.UUID, kind string, day, month time.Time) (models.Activity, error) {
+	var activity models.Activity
+	var last *time.Time
+	err := d.db.QueryRow(ctx, `select coalesce(round(sum(abs(p.amount) * c.rate) filter (where e.created_at >= $3), 2), 0),
+			coalesce(round(sum(abs(p.amount) * c.rate) filter (where e.created_at >= $4), 2), 0), max(e.created_at)
+		from public.postings p
+		join public.entries e on e.id = p.entry_id
+		join public.accounts a on a.id = p.account_id
+		join public.currencies c on c.code = a.currency
+		where e.kind = $1 and a.kind = $5 and a.user_id = $2`,
+		kind, userID, day, month, models.AccountCash).Scan(&activity.Day, &activity.Month, &last)
+	if err != nil {
+		log.Println("Cant get user activity:", err)
+		return activity, err
+	}
+	if last != nil {
+		activity.Last = *last
+	}
+	return activity, nil
+}
+
+// Выполняет fn в транзакции и коммитит ее, если fn не вернула ошибку
+func (d *DB) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		log.Println("Cant begin transaction:", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Println("Cant commit transaction:", err)
 		return err
 	}
 	return nil
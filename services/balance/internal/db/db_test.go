@@ -0,0 +1,449 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	problems "balance/internal/pkg/customErrors"
+	"balance/internal/pkg/models"
+	"balance/internal/pkg/money"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallelReservesNeverOverdraw(t *testing.T) {
+	cfg := Config{Host: "db", Port: "5432", User: "user", Password: "123", DBName: "papersdb"}
+	db, err := New(&cfg)
+	if err != nil {
+		t.Skipf("Database is not available: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	const (
+		price     = money.Amount(1000)
+		balance   = money.Amount(5500)
+		buyers    = 20
+		canAfford = 5
+	)
+	var userID uuid.UUID
+	err = db.db.QueryRow(ctx, `insert into public.users(login, password) values($1, 'fakehash') returning id`, "reservetest").Scan(&userID)
+	require.NoError(t, err, "Cannot add test user")
+	defer dropUser(ctx, db, userID)
+	require.NoError(t, db.Deposit(userID, models.BaseCurrency, balance), "Cannot deposit")
+
+	t.Log("Testing parallel reserves in process...")
+	var wg sync.WaitGroup
+	holds := make(chan uuid.UUID, buyers)
+	rejected := make(chan error, buyers)
+	for range buyers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hold, err := db.Reserve(ctx, userID, models.BaseCurrency, price, nil)
+			if err != nil {
+				rejected <- err
+				return
+			}
+			holds <- hold
+		}()
+	}
+	wg.Wait()
+	close(holds)
+	close(rejected)
+	for err := range rejected {
+		require.ErrorIs(t, err, problems.ErrLowBalance, "Unexpected reserve error")
+	}
+	require.Len(t, holds, canAfford, "Wrong amount of placed holds")
+
+	left, err := db.GetUserBalance(userID, models.BaseCurrency)
+	require.NoError(t, err, "Cannot get test user balance")
+	require.Equal(t, balance-price.Mul(canAfford), left, "Balance does not match placed holds")
+
+	var committed, released bool
+	for hold := range holds {
+		switch {
+		case !committed:
+			err := db.Commit(ctx, hold, []models.Payout{{Account: models.AccountMarket, Amount: price - 10}, {Account: models.AccountFees, Amount: 10}}, nil)
+			require.NoError(t, err, "Cannot commit hold")
+			require.ErrorIs(t, db.Commit(ctx, hold, []models.Payout{{Account: models.AccountMarket, Amount: 1}}, nil), problems.ErrHoldExceeded)
+			committed = true
+		case !released:
+			require.NoError(t, db.Release(ctx, hold, 0), "Cannot release hold")
+			released = true
+		}
+	}
+	left, err = db.GetUserBalance(userID, models.BaseCurrency)
+	require.NoError(t, err, "Cannot get test user balance")
+	require.Equal(t, balance-price.Mul(canAfford-1), left, "Released hold must return to balance")
+
+	drifts, err := db.CheckLedger(ctx)
+	require.NoError(t, err, "Cannot check ledger")
+	require.Empty(t, drifts, "Ledger has drifted")
+}
+
+func TestTransactionHistory(t *testing.T) {
+	cfg := Config{Host: "db", Port: "5432", User: "user", Password: "123", DBName: "papersdb"}
+	db, err := New(&cfg)
+	if err != nil {
+		t.Skipf("Database is not available: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	var userID uuid.UUID
+	err = db.db.QueryRow(ctx, `insert into public.users(login, password) values($1, 'fakehash') returning id`, "historytest").Scan(&userID)
+	require.NoError(t, err, "Cannot add test user")
+	defer dropUser(ctx, db, userID)
+
+	require.NoError(t, db.Deposit(userID, models.BaseCurrency, 10000), "Cannot deposit")
+	buy := uuid.New()
+	hold, err := db.Reserve(ctx, userID, models.BaseCurrency, 1100, &buy)
+	require.NoError(t, err, "Cannot reserve")
+	require.NoError(t, db.Commit(ctx, hold, []models.Payout{{Account: models.AccountMarket, Amount: 1000}, {Account: models.AccountFees, Amount: 100}}, &buy))
+	sell := uuid.New()
+	hold, err = db.Reserve(ctx, uuid.Nil, models.BaseCurrency, 500, &sell)
+	require.NoError(t, err, "Cannot reserve market")
+	require.NoError(t, db.Commit(ctx, hold, []models.Payout{{UserID: userID, Amount: 450}, {Account: models.AccountFees, Amount: 50}}, &sell))
+	require.NoError(t, db.Withdraw(userID, models.BaseCurrency, 2000), "Cannot withdraw")
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tTrades are split from their fees, reserves are hidden", testID)
+		history, err := db.GetTransactions(ctx, models.TransactionFilter{UserID: userID, Limit: 10})
+		require.NoError(t, err)
+		expected := []struct {
+			kind    string
+			amount  money.Amount
+			balance money.Amount
+		}{
+			{models.TransactionWithdrawal, -2000, 7350},
+			{models.TransactionFee, -50, 9350},
+			{models.TransactionTradeCredit, 500, 9400},
+			{models.TransactionFee, -100, 8900},
+			{models.TransactionTradeDebit, -1000, 9000},
+			{models.TransactionDeposit, 10000, 10000},
+		}
+		require.Len(t, history, len(expected))
+		for i, e := range expected {
+			require.Equal(t, e.kind, history[i].Type, "Wrong type of transaction %d", i)
+			require.Equal(t, e.amount, history[i].Amount, "Wrong amount of transaction %d", i)
+			require.Equal(t, e.balance, history[i].Balance, "Wrong running balance of transaction %d", i)
+		}
+		require.Equal(t, sell, *history[1].Reference, "Fee must reference its trade")
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\tFilters keep running balance of the whole history, pages continue after the cursor", testID)
+		fees, err := db.GetTransactions(ctx, models.TransactionFilter{UserID: userID, Types: []string{models.TransactionFee}, Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, fees, 1)
+		require.Equal(t, money.Amount(9350), fees[0].Balance)
+		fees, err = db.GetTransactions(ctx, models.TransactionFilter{UserID: userID, Types: []string{models.TransactionFee}, After: &fees[0], Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, fees, 1)
+		require.Equal(t, money.Amount(8900), fees[0].Balance)
+	}
+}
+
+func TestTransfers(t *testing.T) {
+	cfg := Config{Host: "db", Port: "5432", User: "user", Password: "123", DBName: "papersdb"}
+	db, err := New(&cfg)
+	if err != nil {
+		t.Skipf("Database is not available: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	users := map[string]uuid.UUID{"transfersender": uuid.Nil, "transferrecipient": uuid.Nil}
+	for login := range users {
+		var id uuid.UUID
+		err = db.db.QueryRow(ctx, `insert into public.users(login, password) values($1, 'fakehash') returning id`, login).Scan(&id)
+		require.NoError(t, err, "Cannot add test user")
+		defer dropUser(ctx, db, id)
+		users[login] = id
+	}
+	sender, recipient := users["transfersender"], users["transferrecipient"]
+	require.NoError(t, db.Deposit(sender, models.BaseCurrency, 10000), "Cannot deposit")
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tTransfer moves money between users and shows up in both histories", testID)
+		transfer, err := db.Transfer(ctx, models.Transfer{From: sender, Currency: models.BaseCurrency, ToLogin: "transferrecipient", Amount: 3000, Memo: "dinner"}, 0, 0)
+		require.NoError(t, err)
+		require.Equal(t, recipient, transfer.To)
+		require.Equal(t, "transfersender", transfer.FromLogin)
+		for user, expected := range map[uuid.UUID]struct {
+			kind            string
+			amount, balance money.Amount
+		}{
+			sender:    {models.TransactionTransferOut, -3000, 7000},
+			recipient: {models.TransactionTransferIn, 3000, 3000},
+		} {
+			history, err := db.GetTransactions(ctx, models.TransactionFilter{UserID: user, Limit: 1})
+			require.NoError(t, err)
+			require.Len(t, history, 1)
+			require.Equal(t, expected.kind, history[0].Type)
+			require.Equal(t, expected.amount, history[0].Amount)
+			require.Equal(t, expected.balance, history[0].Balance)
+			require.Equal(t, "dinner", history[0].Memo)
+			require.Equal(t, transfer.ID, *history[0].Reference)
+		}
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\tUnknown recipient, self transfer, low balance and daily limit are refused", testID)
+		_, err := db.Transfer(ctx, models.Transfer{From: sender, Currency: models.BaseCurrency, ToLogin: "nosuchtransferuser", Amount: 100}, 0, 0)
+		require.ErrorIs(t, err, problems.ErrNoRecipient)
+		_, err = db.Transfer(ctx, models.Transfer{From: sender, Currency: models.BaseCurrency, ToLogin: "transfersender", Amount: 100}, 0, 0)
+		require.ErrorIs(t, err, problems.ErrSelfTransfer)
+		_, err = db.Transfer(ctx, models.Transfer{From: sender, Currency: models.BaseCurrency, ToLogin: "transferrecipient", Amount: 7001}, 0, 0)
+		require.ErrorIs(t, err, problems.ErrLowBalance)
+		_, err = db.Transfer(ctx, models.Transfer{From: sender, Currency: models.BaseCurrency, ToLogin: "transferrecipient", Amount: 1000}, 0, 3500)
+		require.ErrorIs(t, err, problems.ErrOverLimit)
+		_, err = db.Transfer(ctx, models.Transfer{From: sender, Currency: models.BaseCurrency, ToLogin: "transferrecipient", Amount: 500}, 0, 3500)
+		require.NoError(t, err)
+	}
+	drifts, err := db.CheckLedger(ctx)
+	require.NoError(t, err, "Cannot check ledger")
+	require.Empty(t, drifts, "Ledger has drifted")
+}
+
+func TestExchange(t *testing.T) {
+	cfg := Config{Host: "db", Port: "5432", User: "user", Password: "123", DBName: "papersdb"}
+	db, err := New(&cfg)
+	if err != nil {
+		t.Skipf("Database is not available: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	var userID uuid.UUID
+	err = db.db.QueryRow(ctx, `insert into public.users(login, password) values($1, 'fakehash') returning id`, "exchangetest").Scan(&userID)
+	require.NoError(t, err, "Cannot add test user")
+	defer dropUser(ctx, db, userID)
+	require.NoError(t, db.Deposit(userID, models.BaseCurrency, 100000), "Cannot deposit")
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tExchange moves money between wallets at the current rate", testID)
+		ex, err := db.Exchange(ctx, models.Exchange{UserID: userID, From: models.BaseCurrency, To: "EUR", Amount: 20000})
+		require.NoError(t, err)
+		require.Equal(t, money.Amount(20000).MulRate(ex.Rate), ex.Received)
+		wallets, err := db.GetWallets(userID)
+		require.NoError(t, err)
+		require.Contains(t, wallets, models.Wallet{Currency: "EUR", Amount: ex.Received})
+		require.Contains(t, wallets, models.Wallet{Currency: models.BaseCurrency, Amount: 80000})
+		_, err = db.Exchange(ctx, models.Exchange{UserID: userID, From: models.BaseCurrency, To: "XXX", Amount: 100})
+		require.ErrorIs(t, err, problems.ErrNoCurrency)
+		_, err = db.Exchange(ctx, models.Exchange{UserID: userID, From: "EUR", To: models.BaseCurrency, Amount: ex.Received + 1})
+		require.ErrorIs(t, err, problems.ErrLowBalance)
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\tReserve in listing currency buys the shortfall for base currency", testID)
+		eur, err := db.GetUserBalance(userID, "EUR")
+		require.NoError(t, err)
+		available, err := db.GetAvailable(ctx, userID, "EUR")
+		require.NoError(t, err)
+		_, err = db.Reserve(ctx, userID, "EUR", eur+1000, nil)
+		require.NoError(t, err)
+		left, err := db.GetUserBalance(userID, "EUR")
+		require.NoError(t, err)
+		require.Zero(t, left, "Reserve must take the whole wallet and the converted shortfall")
+		base, err := db.GetUserBalance(userID, models.BaseCurrency)
+		require.NoError(t, err)
+		require.Less(t, base, money.Amount(80000), "Shortfall must be paid in base currency")
+		_, err = db.Reserve(ctx, userID, "EUR", available, nil)
+		require.ErrorIs(t, err, problems.ErrLowBalance)
+	}
+	{
+		testID := 3
+		t.Logf("\tTest %d:\tDeposit activity is summed in base currency", testID)
+		require.NoError(t, db.Deposit(userID, "EUR", 1000), "Cannot deposit")
+		inBase, err := db.ToBase(ctx, "EUR", 1000)
+		require.NoError(t, err)
+		now := time.Now()
+		activity, err := db.GetActivity(ctx, userID, models.EntryDeposit, now.Add(-24*time.Hour), now.Add(-30*24*time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, 100000+inBase, activity.Day)
+		require.Equal(t, activity.Day, activity.Month)
+		require.WithinDuration(t, now, activity.Last, time.Minute)
+		activity, err = db.GetActivity(ctx, userID, models.EntryWithdrawal, now.Add(-24*time.Hour), now.Add(-30*24*time.Hour))
+		require.NoError(t, err)
+		require.Zero(t, activity.Day)
+		require.True(t, activity.Last.IsZero())
+	}
+	drifts, err := db.CheckLedger(ctx)
+	require.NoError(t, err, "Cannot check ledger")
+	require.Empty(t, drifts, "Ledger has drifted")
+}
+
+func TestPayments(t *testing.T) {
+	cfg := Config{Host: "db", Port: "5432", User: "user", Password: "123", DBName: "papersdb"}
+	db, err := New(&cfg)
+	if err != nil {
+		t.Skipf("Database is not available: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	var userID uuid.UUID
+	err = db.db.QueryRow(ctx, `insert into public.users(login, password) values($1, 'fakehash') returning id`, "paymenttest").Scan(&userID)
+	require.NoError(t, err, "Cannot add test user")
+	defer dropUser(ctx, db, userID)
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tSettled callback credits the payment exactly once", testID)
+		payment, err := db.CreatePayment(ctx, models.Payment{UserID: userID, Currency: models.BaseCurrency, Amount: 5000, Provider: "test"})
+		require.NoError(t, err)
+		require.Equal(t, models.PaymentPending, payment.Status)
+		balance, err := db.GetUserBalance(userID, models.BaseCurrency)
+		require.NoError(t, err)
+		require.Zero(t, balance, "Pending payment must not be credited")
+		callback := models.PaymentCallback{PaymentID: payment.ID, Status: models.PaymentSettled, Amount: 5000, Currency: models.BaseCurrency}
+		_, err = db.CompletePayment(ctx, models.PaymentCallback{PaymentID: payment.ID, Status: models.PaymentSettled, Amount: 50000, Currency: models.BaseCurrency})
+		require.ErrorIs(t, err, problems.ErrBadCallback, "Callback amount must match the payment")
+		for range 3 {
+			settled, err := db.CompletePayment(ctx, callback)
+			require.NoError(t, err)
+			require.Equal(t, models.PaymentSettled, settled.Status)
+		}
+		balance, err = db.GetUserBalance(userID, models.BaseCurrency)
+		require.NoError(t, err)
+		require.Equal(t, money.Amount(5000), balance, "Repeated callbacks must not credit again")
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\tFailed payment keeps the reason and credits nothing", testID)
+		payment, err := db.CreatePayment(ctx, models.Payment{UserID: userID, Currency: "EUR", Amount: 700, Provider: "test"})
+		require.NoError(t, err)
+		failed, err := db.CompletePayment(ctx, models.PaymentCallback{PaymentID: payment.ID, Status: models.PaymentFailed, Amount: 700, Currency: "EUR", Reason: "card declined"})
+		require.NoError(t, err)
+		require.Equal(t, "card declined", failed.Reason)
+		late, err := db.CompletePayment(ctx, models.PaymentCallback{PaymentID: payment.ID, Status: models.PaymentSettled, Amount: 700, Currency: "EUR"})
+		require.NoError(t, err)
+		require.Equal(t, models.PaymentFailed, late.Status, "Completed payment must not change")
+		payments, err := db.GetPayments(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, payments, 2)
+		require.Equal(t, payment.ID, payments[0].ID, "Newest payment must be first")
+		_, err = db.GetPayment(ctx, uuid.New(), payment.ID)
+		require.ErrorIs(t, err, problems.ErrNoPayment, "Payment of another user must not be visible")
+	}
+	drifts, err := db.CheckLedger(ctx)
+	require.NoError(t, err, "Cannot check ledger")
+	require.Empty(t, drifts, "Ledger has drifted")
+}
+
+func TestInterest(t *testing.T) {
+	cfg := Config{Host: "db", Port: "5432", User: "user", Password: "123", DBName: "papersdb"}
+	db, err := New(&cfg)
+	if err != nil {
+		t.Skipf("Database is not available: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	var userID uuid.UUID
+	err = db.db.QueryRow(ctx, `insert into public.users(login, password) values($1, 'fakehash') returning id`, "interesttest").Scan(&userID)
+	require.NoError(t, err, "Cannot add test user")
+	defer dropUser(ctx, db, userID)
+	require.NoError(t, db.Deposit(userID, models.BaseCurrency, 36500), "Cannot deposit")
+	// Дни далеко в будущем, чтобы не пересечься с настоящими начислениями. Начисления других счетов за эти дни
+	// удаляются до выплаты, иначе тест выплатил бы проценты всем
+	first := time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+	defer db.db.Exec(ctx, `delete from public.interest_days where day >= $1`, first)
+	dropOthers := func() {
+		db.db.Exec(ctx, `delete from public.interest_accruals where day >= $1
+			and account_id not in (select id from public.accounts where user_id = $2)`, first, userID)
+	}
+	defer dropOthers()
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tDay is accrued once on the end of day balance", testID)
+		for range 2 {
+			require.NoError(t, db.AccrueInterest(ctx, first, 0.01))
+		}
+		require.NoError(t, db.AccrueInterest(ctx, first.AddDate(0, 0, 1), 0.01))
+		var total float64
+		err := db.db.QueryRow(ctx, `select sum(i.amount) from public.interest_accruals i join public.accounts a on a.id = i.account_id
+			where a.user_id = $1`, userID).Scan(&total)
+		require.NoError(t, err)
+		require.InDelta(t, 7.3, total, 1e-9, "Two days of 1% on 365.00")
+		last, err := db.LastInterestDay(ctx)
+		require.NoError(t, err)
+		require.Equal(t, first.AddDate(0, 0, 1), last.UTC())
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\tClosed month is paid once into the balance history", testID)
+		dropOthers()
+		for range 2 {
+			_, err := db.PayInterest(ctx, first.AddDate(0, 1, 0))
+			require.NoError(t, err)
+		}
+		balance, err := db.GetUserBalance(userID, models.BaseCurrency)
+		require.NoError(t, err)
+		require.Equal(t, money.Amount(37230), balance, "Interest must be paid exactly once")
+		history, err := db.GetTransactions(ctx, models.TransactionFilter{UserID: userID, Types: []string{models.TransactionInterest}})
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+	}
+}
+
+func TestLedgerEntries(t *testing.T) {
+	user, usd := uuid.New(), models.BaseCurrency
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tBalanced entries pass validation", testID)
+		entry := models.Entry{Kind: models.EntryTrade}
+		entry.Move(models.HoldAccount(user, usd), models.SystemAccount(models.AccountMarket, usd), 1000)
+		entry.Move(models.HoldAccount(user, usd), models.CashAccount(user, usd), 50)
+		entry.Move(models.CashAccount(user, usd), models.SystemAccount(models.AccountFees, usd), 0)
+		require.Len(t, entry.Postings, 4, "Zero moves must be skipped")
+		require.NoError(t, validate(entry))
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\tUnbalanced, empty and zero postings are refused", testID)
+		unbalanced := models.Entry{Kind: models.EntryDeposit, Postings: []models.Posting{
+			{Account: models.SystemAccount(models.AccountExternal, usd), Amount: -100}, {Account: models.CashAccount(user, usd), Amount: 101},
+		}}
+		require.ErrorIs(t, validate(unbalanced), problems.ErrUnbalanced)
+		require.ErrorIs(t, validate(models.Entry{Kind: models.EntryDeposit}), problems.ErrUnbalanced)
+		zero := models.Entry{Kind: models.EntryDeposit, Postings: []models.Posting{
+			{Account: models.SystemAccount(models.AccountExternal, usd), Amount: 0}, {Account: models.CashAccount(user, usd), Amount: 0},
+		}}
+		require.ErrorIs(t, validate(zero), problems.ErrUnbalanced)
+		crossCurrency := models.Entry{Kind: models.EntryExchange, Postings: []models.Posting{
+			{Account: models.CashAccount(user, usd), Amount: -100}, {Account: models.SystemAccount(models.AccountFX, "EUR"), Amount: 100},
+		}}
+		require.ErrorIs(t, validate(crossCurrency), problems.ErrUnbalanced, "Each currency must balance on its own")
+	}
+	{
+		testID := 3
+		t.Logf("\tTest %d:\tPayouts go to user cash or to the named system account", testID)
+		require.Equal(t, models.CashAccount(user, usd), models.Payout{UserID: user, Account: models.AccountFees}.Target(usd))
+		require.Equal(t, models.SystemAccount(models.AccountFees, usd), models.Payout{Account: models.AccountFees}.Target(usd))
+	}
+}
+
+// Удаляет тестового пользователя вместе с его резервами и записями журнала. Проводки тех же записей по системным счетам
+// тоже удаляются, их кэш балансов откатывается, поэтому проверка журнала после теста расхождений не находит
+func dropUser(ctx context.Context, db *DB, userID uuid.UUID) {
+	var entries []uuid.UUID
+	db.db.QueryRow(ctx, `select coalesce(array_agg(distinct p.entry_id), '{}') from public.postings p
+		join public.accounts a on a.id = p.account_id where a.user_id = $1`, userID).Scan(&entries)
+	db.db.Exec(ctx, `update public.accounts a set balance = a.balance - p.total
+		from (select account_id, sum(amount) as total from public.postings where entry_id = any($1) group by account_id) p
+		where a.id = p.account_id`, entries)
+	db.db.Exec(ctx, `delete from public.postings where entry_id = any($1)`, entries)
+	db.db.Exec(ctx, `delete from public.entries where id = any($1)`, entries)
+	db.db.Exec(ctx, `delete from public.interest_accruals where account_id in (select id from public.accounts where user_id = $1)`, userID)
+	db.db.Exec(ctx, `delete from public.interest_payouts where account_id in (select id from public.accounts where user_id = $1)`, userID)
+	db.db.Exec(ctx, `delete from public.accounts where user_id = $1`, userID)
+	db.db.Exec(ctx, `delete from public.holds where user_id = $1`, userID)
+	db.db.Exec(ctx, `delete from public.payments where user_id = $1`, userID)
+	db.db.Exec(ctx, `delete from public.users where id = $1`, userID)
+}
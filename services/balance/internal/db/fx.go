@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"log"
+	"math"
+	"slices"
+
+	problems "balance/internal/pkg/customErrors"
+	"balance/internal/pkg/models"
+	"balance/internal/pkg/money"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Меняет деньги пользователя из валюты From в валюту To по текущему курсу. Обмен - одна запись журнала: проданная
+// сумма уходит на счет fx валюты From, купленная приходит со счета fx валюты To
+func (d *DB) Exchange(ctx context.Context, ex models.Exchange) (models.Exchange, error) {
+	if ex.From == ex.To {
+		return ex, problems.ErrSameCurrency
+	}
+	err := d.inTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		if ex.Rate, err = crossRate(ctx, tx, ex.From, ex.To); err != nil {
+			return err
+		}
+		ex.Received = ex.Amount.MulRate(ex.Rate)
+		if ex.Received <= 0 {
+			return problems.ErrBadCash
+		}
+		wallets, err := lockWallets(ctx, tx, ex.UserID, ex.From, ex.To)
+		if err != nil {
+			return err
+		}
+		if ex.Amount > wallets[ex.From] {
+			return problems.ErrLowBalance
+		}
+		return post(ctx, tx, exchangeEntry(ex))
+	})
+	return ex, err
+}
+
+// Докупает пользователю недостающие до cash деньги в валюте currency за базовую валюту. Так сделка в валюте листинга
+// проходит, даже если у пользователя нет кошелька в ней. Кошельки пользователя должны быть уже заблокированы lockWallets
+func convertShortfall(ctx context.Context, tx pgx.Tx, userID uuid.UUID, currency string, cash money.Amount, wallets map[string]money.Amount) error {
+	if currency == models.BaseCurrency {
+		return problems.ErrLowBalance
+	}
+	r, err := crossRate(ctx, tx, currency, models.BaseCurrency)
+	if err != nil {
+		return err
+	}
+	shortfall := cash - wallets[currency]
+	// Базовой валюты списывается с округлением вверх, чтобы ее точно хватило на недостающую сумму
+	ex := models.Exchange{UserID: userID, From: models.BaseCurrency, To: currency, Received: shortfall, Rate: 1 / r,
+		Amount: money.Amount(math.Ceil(float64(shortfall)*r - 1e-9))}
+	if ex.Amount > wallets[models.BaseCurrency] {
+		return problems.ErrLowBalance
+	}
+	return post(ctx, tx, exchangeEntry(ex))
+}
+
+// Блокирует счета cash пользователя в валютах currencies в порядке их кодов, чтобы операции с несколькими кошельками
+// не ждали друг друга по кругу. Возвращает балансы кошельков
+func lockWallets(ctx context.Context, tx pgx.Tx, userID uuid.UUID, currencies ...string) (map[string]money.Amount, error) {
+	slices.Sort(currencies)
+	wallets := map[string]money.Amount{}
+	for _, currency := range slices.Compact(currencies) {
+		balance, err := lock(ctx, tx, models.CashAccount(userID, currency))
+		if err != nil {
+			return nil, err
+		}
+		wallets[currency] = balance
+	}
+	return wallets, nil
+}
+
+func exchangeEntry(ex models.Exchange) models.Entry {
+	entry := models.Entry{Kind: models.EntryExchange}
+	entry.Move(models.CashAccount(ex.UserID, ex.From), models.SystemAccount(models.AccountFX, ex.From), ex.Amount)
+	entry.Move(models.SystemAccount(models.AccountFX, ex.To), models.CashAccount(ex.UserID, ex.To), ex.Received)
+	return entry
+}
+
+// Сколько пользователь может потратить в валюте currency: кошелек валюты и базовая валюта, обмененная по текущему курсу
+func (d *DB) GetAvailable(ctx context.Context, userID uuid.UUID, currency string) (money.Amount, error) {
+	r, err := crossRate(ctx, d.db, currency, models.BaseCurrency)
+	if err != nil {
+		return 0, err
+	}
+	wallet, err := d.GetUserBalance(userID, currency)
+	if err != nil {
+		return 0, err
+	}
+	if currency == models.BaseCurrency {
+		return wallet, nil
+	}
+	base, err := d.GetUserBalance(userID, models.BaseCurrency)
+	if err != nil {
+		return 0, err
+	}
+	return wallet + money.Amount(math.Floor(float64(base)/r+1e-9)), nil
+}
+
+// Сумма cash в валюте currency, переведенная в базовую по текущему курсу
+func (d *DB) ToBase(ctx context.Context, currency string, cash money.Amount) (money.Amount, error) {
+	r, err := rate(ctx, d.db, currency)
+	if err != nil {
+		return 0, err
+	}
+	return cash.MulRate(r), nil
+}
+
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Сколько единиц валюты to стоит единица валюты from
+func crossRate(ctx context.Context, q querier, from, to string) (float64, error) {
+	fromRate, err := rate(ctx, q, from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := rate(ctx, q, to)
+	if err != nil {
+		return 0, err
+	}
+	return fromRate / toRate, nil
+}
+
+// Курс валюты к базовой. Неизвестная валюта - problems.ErrNoCurrency
+func rate(ctx context.Context, q querier, currency string) (float64, error) {
+	var r float64
+	err := q.QueryRow(ctx, `select rate from public.currencies where code = $1`, currency).Scan(&r)
+	if err == pgx.ErrNoRows {
+		return 0, problems.ErrNoCurrency
+	}
+	if err != nil {
+		log.Println("Cant get currency rate:", err)
+		return 0, err
+	}
+	return r, nil
+}
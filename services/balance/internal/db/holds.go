@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"log"
+
+	problems "balance/internal/pkg/customErrors"
+	"balance/internal/pkg/models"
+	"balance/internal/pkg/money"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Ставит резерв в валюте currency: деньги пользователя переходят со счета cash на hold. Если в кошельке валюты денег
+// не хватает, недостающее докупается за базовую валюту, если не хватает и ее - problems.ErrLowBalance.
+// Резерв market(нулевой userID) только запоминается: деньги market балансом не ограничены
+func (d *DB) Reserve(ctx context.Context, userID uuid.UUID, currency string, cash money.Amount, reference *uuid.UUID) (uuid.UUID, error) {
+	var holdID uuid.UUID
+	err := d.inTx(ctx, func(tx pgx.Tx) error {
+		if _, err := rate(ctx, tx, currency); err != nil {
+			return err
+		}
+		if userID != uuid.Nil {
+			wallets, err := lockWallets(ctx, tx, userID, currency, models.BaseCurrency)
+			if err != nil {
+				return err
+			}
+			if cash > wallets[currency] {
+				if err := convertShortfall(ctx, tx, userID, currency, cash, wallets); err != nil {
+					return err
+				}
+			}
+		}
+		err := tx.QueryRow(ctx, `insert into public.holds(user_id, amount, currency, reference) values($1, $2, $3, $4) returning id`,
+			nullable(userID), cash, currency, reference).Scan(&holdID)
+		if err != nil {
+			log.Println("Cant insert hold:", err)
+			return err
+		}
+		if userID == uuid.Nil {
+			return nil
+		}
+		entry := models.Entry{Kind: models.EntryReserve, Reference: reference}
+		entry.Move(models.CashAccount(userID, currency), models.HoldAccount(userID, currency), cash)
+		return post(ctx, tx, entry)
+	})
+	return holdID, err
+}
+
+// Списывает из резерва выплаты получателям одной записью журнала в валюте резерва. Сумма выплат не может превышать остаток резерва
+func (d *DB) Commit(ctx context.Context, holdID uuid.UUID, payouts []models.Payout, reference *uuid.UUID) error {
+	return d.inTx(ctx, func(tx pgx.Tx) error {
+		h, err := lockHold(ctx, tx, holdID)
+		if err != nil {
+			return err
+		}
+		source := models.SystemAccount(models.AccountMarket, h.currency)
+		if h.owner != uuid.Nil {
+			source = models.HoldAccount(h.owner, h.currency)
+		}
+		entry := models.Entry{Kind: models.EntryTrade, Reference: reference}
+		var total money.Amount
+		for _, payout := range payouts {
+			if payout.Amount <= 0 {
+				return problems.ErrBadCash
+			}
+			target := payout.Target(h.currency)
+			if target.Kind != models.AccountCash && target.Kind != models.AccountMarket && target.Kind != models.AccountFees {
+				return problems.ErrBadPayout
+			}
+			entry.Move(source, target, payout.Amount)
+			total += payout.Amount
+		}
+		if total > h.left {
+			return problems.ErrHoldExceeded
+		}
+		if err := post(ctx, tx, entry); err != nil {
+			return err
+		}
+		return updateHold(ctx, tx, holdID, h.left-total)
+	})
+}
+
+// Возвращает владельцу cash из резерва или весь остаток, если cash нулевой
+func (d *DB) Release(ctx context.Context, holdID uuid.UUID, cash money.Amount) error {
+	return d.inTx(ctx, func(tx pgx.Tx) error {
+		h, err := lockHold(ctx, tx, holdID)
+		if err != nil {
+			return err
+		}
+		if cash == 0 {
+			cash = h.left
+		}
+		if cash > h.left {
+			return problems.ErrHoldExceeded
+		}
+		if h.owner != uuid.Nil && cash > 0 {
+			entry := models.Entry{Kind: models.EntryRelease, Reference: h.reference}
+			entry.Move(models.HoldAccount(h.owner, h.currency), models.CashAccount(h.owner, h.currency), cash)
+			if err := post(ctx, tx, entry); err != nil {
+				return err
+			}
+		}
+		return updateHold(ctx, tx, holdID, h.left-cash)
+	})
+}
+
+// Резерв: владелец(нулевой - market), неизрасходованный остаток в валюте резерва и сделка или заявка, под которую он поставлен
+type hold struct {
+	owner     uuid.UUID
+	left      money.Amount
+	currency  string
+	reference *uuid.UUID
+}
+
+// Блокирует резерв до конца транзакции
+func lockHold(ctx context.Context, tx pgx.Tx, holdID uuid.UUID) (hold, error) {
+	var h hold
+	var owner *uuid.UUID
+	err := tx.QueryRow(ctx, `select user_id, amount, currency, reference from public.holds where id = $1 for update`, holdID).Scan(&owner, &h.left, &h.currency, &h.reference)
+	if err == pgx.ErrNoRows {
+		return h, problems.ErrNoHold
+	}
+	if err != nil {
+		log.Println("Cant lock hold:", err)
+		return h, err
+	}
+	if owner != nil {
+		h.owner = *owner
+	}
+	return h, nil
+}
+
+func updateHold(ctx context.Context, tx pgx.Tx, holdID uuid.UUID, left money.Amount) error {
+	if _, err := tx.Exec(ctx, `update public.holds set amount = $1 where id = $2`, left, holdID); err != nil {
+		log.Println("Cant update hold:", err)
+		return err
+	}
+	return nil
+}
+
+// Нулевой uuid(системный счет, резерв market) в БД хранится как null
+func nullable(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"balance/internal/pkg/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Остаток счета a на конец дня: сумма проводок записей, сделанных раньше end(выражение SQL)
+func endOfDayBalance(end string) string {
+	return `(select coalesce(sum(p.amount), 0) from public.postings p
+		join public.entries e on e.id = p.entry_id where p.account_id = a.id and e.created_at < ` + end + `)`
+}
+
+// Последний день, за который начислены проценты, нулевой - начислений еще не было
+func (d *DB) LastInterestDay(ctx context.Context) (time.Time, error) {
+	var day *time.Time
+	if err := d.db.QueryRow(ctx, `select max(day) from public.interest_days`).Scan(&day); err != nil {
+		log.Println("Cant get last interest day:", err)
+		return time.Time{}, err
+	}
+	if day == nil {
+		return time.Time{}, nil
+	}
+	return *day, nil
+}
+
+// Начисляет проценты за день day(UTC) по дневной ставке rate на положительный остаток каждого счета cash на конец дня.
+// День начисляется один раз: повторный вызов ничего не делает
+func (d *DB) AccrueInterest(ctx context.Context, day time.Time, rate float64) error {
+	return d.inTx(ctx, func(tx pgx.Tx) error {
+		res, err := tx.Exec(ctx, `insert into public.interest_days(day) values($1) on conflict do nothing`, day)
+		if err != nil {
+			log.Println("Cant mark interest day:", err)
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return nil
+		}
+		_, err = tx.Exec(ctx, `insert into public.interest_accruals(account_id, day, balance, amount)
+			select a.id, $1, b.balance, b.balance * $3 from public.accounts a, lateral (select `+endOfDayBalance("$2")+` as balance) b
+			where a.kind = $4 and b.balance > 0`, day, day.AddDate(0, 0, 1), rate, models.AccountCash)
+		if err != nil {
+			log.Println("Cant accrue interest:", err)
+		}
+		return err
+	})
+}
+
+// Выплачивает проценты, начисленные за месяцы до before: по записи журнала со счета interest на каждый счет cash
+// с накопленной суммой от копейки. Выплаченный месяц запоминается, поэтому повторный вызов ничего не делает
+func (d *DB) PayInterest(ctx context.Context, before time.Time) ([]models.InterestPayout, error) {
+	var payouts []models.InterestPayout
+	err := d.inTx(ctx, func(tx pgx.Tx) error {
+		res, err := tx.Query(ctx, `with paid as (insert into public.interest_payouts(account_id, month, amount)
+				select i.account_id, date_trunc('month', i.day)::date, round(sum(i.amount), 2) from public.interest_accruals i
+				where i.day < $1 and not exists (select 1 from public.interest_payouts p
+					where p.account_id = i.account_id and p.month = date_trunc('month', i.day)::date)
+				group by i.account_id, date_trunc('month', i.day)
+				returning account_id, month, amount)
+			select a.user_id, a.currency, paid.month, paid.amount from paid join public.accounts a on a.id = paid.account_id`, before)
+		if err != nil {
+			log.Println("Cant insert interest payouts:", err)
+			return err
+		}
+		payouts, err = pgx.CollectRows(res, func(row pgx.CollectableRow) (models.InterestPayout, error) {
+			var p models.InterestPayout
+			err := row.Scan(&p.UserID, &p.Currency, &p.Month, &p.Amount)
+			return p, err
+		})
+		if err != nil {
+			log.Println("Cant scan interest payouts:", err)
+			return err
+		}
+		for _, payout := range payouts {
+			// Меньше копейки за месяц не выплачивается, но месяц все равно закрыт
+			if payout.Amount <= 0 {
+				continue
+			}
+			entry := models.Entry{Kind: models.EntryInterest, Memo: "interest for " + payout.Month.Format("2006-01")}
+			entry.Move(models.SystemAccount(models.AccountInterest, payout.Currency), models.CashAccount(payout.UserID, payout.Currency), payout.Amount)
+			if err := post(ctx, tx, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return payouts, err
+}
+
+// Пробный прогон: сколько процентов накопилось бы с from до to(не включая) по дневной ставке rate. Ничего не записывает
+func (d *DB) PreviewInterest(ctx context.Context, from, to time.Time, rate float64) ([]models.InterestPayout, error) {
+	res, err := d.db.Query(ctx, `select a.user_id, a.currency, round(sum(b.balance * $3), 2)
+		from public.accounts a, generate_series($1::timestamptz, $2::timestamptz - interval '1 day', interval '1 day') day,
+			lateral (select `+endOfDayBalance("day + interval '1 day'")+` as balance) b
+		where a.kind = $4 and b.balance > 0
+		group by a.user_id, a.currency
+		order by a.user_id, a.currency`, from, to, rate, models.AccountCash)
+	if err != nil {
+		log.Println("Cant preview interest:", err)
+		return nil, err
+	}
+	payouts, err := pgx.CollectRows(res, func(row pgx.CollectableRow) (models.InterestPayout, error) {
+		p := models.InterestPayout{Month: from}
+		err := row.Scan(&p.UserID, &p.Currency, &p.Amount)
+		return p, err
+	})
+	if err != nil {
+		log.Println("Cant scan interest preview:", err)
+	}
+	return payouts, err
+}
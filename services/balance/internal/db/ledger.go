@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"log"
+	"slices"
+
+	problems "balance/internal/pkg/customErrors"
+	"balance/internal/pkg/models"
+	"balance/internal/pkg/money"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Проводит запись журнала в транзакции tx: проверяет, что проводки в каждой валюте дают в сумме ноль, заводит недостающие счета
+// пользователей и меняет кэш балансов счетов вместе с проводками. Других способов изменить баланс нет
+func post(ctx context.Context, tx pgx.Tx, entry models.Entry) error {
+	if err := validate(entry); err != nil {
+		log.Printf("Refusing to post %v entry: %v\n", entry.Kind, err)
+		return err
+	}
+	var entryID uuid.UUID
+	err := tx.QueryRow(ctx, `insert into public.entries(kind, reference, memo) values($1, $2, nullif($3, '')) returning id`,
+		entry.Kind, entry.Reference, entry.Memo).Scan(&entryID)
+	if err != nil {
+		log.Println("Cant insert ledger entry:", err)
+		return err
+	}
+	changes := map[uuid.UUID]money.Amount{}
+	for _, posting := range entry.Postings {
+		accountID, err := account(ctx, tx, posting.Account)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `insert into public.postings(entry_id, account_id, amount) values($1, $2, $3)`, entryID, accountID, posting.Amount)
+		if err != nil {
+			log.Println("Cant insert ledger posting:", err)
+			return err
+		}
+		changes[accountID] += posting.Amount
+	}
+	// Счета блокируются в одном порядке, чтобы параллельные записи не ждали друг друга по кругу
+	ids := make([]uuid.UUID, 0, len(changes))
+	for id := range changes {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b uuid.UUID) int { return slices.Compare(a[:], b[:]) })
+	for _, id := range ids {
+		if _, err := tx.Exec(ctx, `update public.accounts set balance = balance + $1 where id = $2`, changes[id], id); err != nil {
+			log.Println("Cant change account balance:", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func validate(entry models.Entry) error {
+	if len(entry.Postings) < 2 {
+		return problems.ErrUnbalanced
+	}
+	sums := map[string]money.Amount{}
+	for _, posting := range entry.Postings {
+		if posting.Amount == 0 || posting.Account.Currency == "" {
+			return problems.ErrUnbalanced
+		}
+		sums[posting.Account.Currency] += posting.Amount
+	}
+	for _, sum := range sums {
+		if sum != 0 {
+			return problems.ErrUnbalanced
+		}
+	}
+	return nil
+}
+
+// Возвращает id счета. Счет пользователя заводится при первой проводке по нему
+func account(ctx context.Context, tx pgx.Tx, acc models.Account) (uuid.UUID, error) {
+	userID := nullable(acc.UserID)
+	_, err := tx.Exec(ctx, `insert into public.accounts(kind, user_id, currency) values($1, $2, $3) on conflict (kind, user_id, currency) do nothing`,
+		acc.Kind, userID, acc.Currency)
+	if err != nil {
+		log.Println("Cant create account:", err)
+		return uuid.Nil, err
+	}
+	var id uuid.UUID
+	err = tx.QueryRow(ctx, `select id from public.accounts where kind = $1 and user_id is not distinct from $2 and currency = $3`,
+		acc.Kind, userID, acc.Currency).Scan(&id)
+	if err != nil {
+		log.Println("Cant get account:", err)
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+// Блокирует счет пользователя до конца транзакции и возвращает его баланс
+func lock(ctx context.Context, tx pgx.Tx, acc models.Account) (money.Amount, error) {
+	id, err := account(ctx, tx, acc)
+	if err != nil {
+		return 0, err
+	}
+	var balance money.Amount
+	if err := tx.QueryRow(ctx, `select balance from public.accounts where id = $1 for update`, id).Scan(&balance); err != nil {
+		log.Println("Cant lock account:", err)
+		return 0, err
+	}
+	return balance, nil
+}
+
+// Проверяет инварианты журнала: каждая запись в каждой валюте в сумме дает ноль, кэш баланса каждого счета равен сумме его проводок,
+// счета пользователей не уходят в минус. Возвращает найденные расхождения
+func (d *DB) CheckLedger(ctx context.Context) ([]models.Drift, error) {
+	checks := []struct {
+		kind  string
+		query string
+	}{
+		{models.DriftEntry, `select p.entry_id, 0::numeric, sum(p.amount) from public.postings p
+			join public.accounts a on a.id = p.account_id group by p.entry_id, a.currency having sum(p.amount) <> 0`},
+		{models.DriftAccount, `select a.id, coalesce(sum(p.amount), 0), a.balance from public.accounts a
+			left join public.postings p on p.account_id = a.id group by a.id having a.balance <> coalesce(sum(p.amount), 0)`},
+		{models.DriftNegative, `select id, 0::numeric, balance from public.accounts where user_id is not null and balance < 0`},
+	}
+	drifts := []models.Drift{}
+	for _, check := range checks {
+		res, err := d.db.Query(ctx, check.query)
+		if err != nil {
+			log.Println("Cant check ledger:", err)
+			return nil, err
+		}
+		for res.Next() {
+			drift := models.Drift{Kind: check.kind}
+			if err := res.Scan(&drift.ID, &drift.Expected, &drift.Actual); err != nil {
+				res.Close()
+				log.Println("Cant scan ledger drift:", err)
+				return nil, err
+			}
+			drifts = append(drifts, drift)
+		}
+		res.Close()
+		if err := res.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return drifts, nil
+}
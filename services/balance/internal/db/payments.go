@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"log"
+
+	problems "balance/internal/pkg/customErrors"
+	"balance/internal/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const paymentColumns = `id, user_id, amount, currency, provider, status, coalesce(reason, ''), created_at, updated_at`
+
+// Создает платеж в статусе pending. Деньги пока не зачисляются
+func (d *DB) CreatePayment(ctx context.Context, payment models.Payment) (models.Payment, error) {
+	if _, err := rate(ctx, d.db, payment.Currency); err != nil {
+		return payment, err
+	}
+	row := d.db.QueryRow(ctx, `insert into public.payments(user_id, amount, currency, provider) values($1, $2, $3, $4)
+		returning `+paymentColumns, payment.UserID, payment.Amount, payment.Currency, payment.Provider)
+	payment, err := scanPayment(row)
+	if err != nil {
+		log.Println("Cant create payment:", err)
+	}
+	return payment, err
+}
+
+// Завершает платеж по callback провайдера: settled зачисляет деньги записью журнала со ссылкой на платеж, failed только
+// запоминает причину. Уже завершенный платеж не меняется и возвращается как есть, поэтому повтор callback ничего не делает.
+// Если сумма или валюта callback не совпадают с платежом или статус не конечный - problems.ErrBadCallback
+func (d *DB) CompletePayment(ctx context.Context, callback models.PaymentCallback) (models.Payment, error) {
+	var payment models.Payment
+	err := d.inTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		payment, err = scanPayment(tx.QueryRow(ctx, `select `+paymentColumns+` from public.payments where id = $1 for update`, callback.PaymentID))
+		if err == pgx.ErrNoRows {
+			return problems.ErrNoPayment
+		}
+		if err != nil {
+			log.Println("Cant lock payment:", err)
+			return err
+		}
+		if payment.Status != models.PaymentPending {
+			return nil
+		}
+		if callback.Amount != payment.Amount || callback.Currency != payment.Currency ||
+			(callback.Status != models.PaymentSettled && callback.Status != models.PaymentFailed) {
+			return problems.ErrBadCallback
+		}
+		if callback.Status == models.PaymentSettled {
+			entry := models.Entry{Kind: models.EntryDeposit, Reference: &payment.ID}
+			entry.Move(models.SystemAccount(models.AccountExternal, payment.Currency), models.CashAccount(payment.UserID, payment.Currency), payment.Amount)
+			if err := post(ctx, tx, entry); err != nil {
+				return err
+			}
+		}
+		payment, err = scanPayment(tx.QueryRow(ctx, `update public.payments set status = $1, reason = nullif($2, ''), updated_at = now()
+			where id = $3 returning `+paymentColumns, callback.Status, callback.Reason, payment.ID))
+		if err != nil {
+			log.Println("Cant update payment:", err)
+		}
+		return err
+	})
+	return payment, err
+}
+
+// Платежи пользователя от новых к старым
+func (d *DB) GetPayments(ctx context.Context, userID uuid.UUID) ([]models.Payment, error) {
+	res, err := d.db.Query(ctx, `select `+paymentColumns+` from public.payments where user_id = $1 order by created_at desc`, userID)
+	if err != nil {
+		log.Println("Cant get user payments:", err)
+		return nil, err
+	}
+	defer res.Close()
+	payments := []models.Payment{}
+	for res.Next() {
+		payment, err := scanPayment(res)
+		if err != nil {
+			log.Println("Cant scan payment:", err)
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+	return payments, res.Err()
+}
+
+// Платеж пользователя по id. Чужой или несуществующий - problems.ErrNoPayment
+func (d *DB) GetPayment(ctx context.Context, userID, paymentID uuid.UUID) (models.Payment, error) {
+	payment, err := scanPayment(d.db.QueryRow(ctx, `select `+paymentColumns+` from public.payments where id = $1 and user_id = $2`, paymentID, userID))
+	if err == pgx.ErrNoRows {
+		return payment, problems.ErrNoPayment
+	}
+	if err != nil {
+		log.Println("Cant get payment:", err)
+	}
+	return payment, err
+}
+
+func scanPayment(row pgx.Row) (models.Payment, error) {
+	var p models.Payment
+	err := row.Scan(&p.ID, &p.UserID, &p.Amount, &p.Currency, &p.Provider, &p.Status, &p.Reason, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
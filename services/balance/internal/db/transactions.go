@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"balance/internal/pkg/models"
+)
+
+// История операций пользователя собирается из журнала: каждая запись, затронувшая его счета, - одна операция на сумму
+// изменения денег пользователя(cash вместе с hold) в каждой валюте записи, обмен валюты - списание и поступление.
+// Резервы и их возврат переводят деньги между его же счетами и пропускаются. Перевод виден обоим пользователям:
+// отправителю списанием, получателю поступлением. Комиссия сделки выделяется в отдельную операцию: в записи сделки
+// с комиссией участвует только платящий ее пользователь, поэтому сделка показывается без комиссии, а комиссия - следом
+// за ней. Баланс после операции считается по всей истории валюты, до фильтров
+const transactionsQuery = `with history as (
+	select e.id, e.kind, e.reference, e.memo, e.created_at, a.currency, min(p.id) as seq,
+		coalesce(sum(p.amount) filter (where a.user_id = $1), 0) as net,
+		coalesce(sum(p.amount) filter (where a.kind = 'fees'), 0) as fee
+	from public.entries e
+	join public.postings p on p.entry_id = e.id
+	join public.accounts a on a.id = p.account_id
+	where e.kind not in ('reserve', 'release') and e.id in (select p.entry_id from public.postings p
+		join public.accounts a on a.id = p.account_id where a.user_id = $1)
+	group by e.id, a.currency
+	having count(*) filter (where a.user_id = $1) > 0
+), operations as (
+	select id, reference, memo, created_at, currency, seq, 0 as part, net + fee as amount, case
+			when kind = 'trade' then case when net + fee < 0 then 'trade_debit' else 'trade_credit' end
+			when kind = 'transfer' then case when net < 0 then 'transfer_out' else 'transfer_in' end
+			else kind end as type
+	from history
+	union all
+	select id, reference, null, created_at, currency, seq, 1, -fee, 'fee' from history where kind = 'trade' and fee <> 0
+), ledger as (
+	select *, sum(amount) over (partition by currency order by seq, part) as balance from operations
+)
+select id, type, currency, amount, balance, reference, coalesce(memo, ''), created_at, seq, part from ledger where amount <> 0`
+
+// Возвращает операции пользователя от новых к старым
+func (d *DB) GetTransactions(ctx context.Context, filter models.TransactionFilter) ([]models.Transaction, error) {
+	log.Printf("Attempt to get user with uuid %v transactions\n", filter.UserID)
+	query := transactionsQuery
+	args := []any{filter.UserID}
+	if filter.Currency != "" {
+		args = append(args, filter.Currency)
+		query += fmt.Sprintf(` and currency = $%d`, len(args))
+	}
+	if len(filter.Types) > 0 {
+		args = append(args, filter.Types)
+		query += fmt.Sprintf(` and type = any($%d)`, len(args))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		query += fmt.Sprintf(` and created_at >= $%d`, len(args))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		query += fmt.Sprintf(` and created_at < $%d`, len(args))
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.Seq, filter.After.Part)
+		query += fmt.Sprintf(` and (seq, part) < ($%d, $%d)`, len(args)-1, len(args))
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` order by seq desc, part desc limit $%d`, len(args))
+	res, err := d.db.Query(ctx, query, args...)
+	if err != nil {
+		log.Println("Cant get user transactions:", err)
+		return nil, err
+	}
+	defer res.Close()
+	transactions := make([]models.Transaction, 0, filter.Limit)
+	for res.Next() {
+		var t models.Transaction
+		err := res.Scan(&t.EntryID, &t.Type, &t.Currency, &t.Amount, &t.Balance, &t.Reference, &t.Memo, &t.CreatedAt, &t.Seq, &t.Part)
+		if err != nil {
+			log.Println("Cant scan transaction:", err)
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, res.Err()
+}
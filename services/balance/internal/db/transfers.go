@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	problems "balance/internal/pkg/customErrors"
+	"balance/internal/pkg/models"
+	"balance/internal/pkg/money"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Переводит деньги со счета cash отправителя на счет cash получателя в валюте перевода одной записью журнала.
+// Получатель ищется по логину в таблице пользователей auth. Лимиты заданы в базовой валюте(0 - без ограничения):
+// limit - на один перевод, dailyLimit - на сумму переводов отправителя во всех валютах за последние сутки
+func (d *DB) Transfer(ctx context.Context, transfer models.Transfer, limit, dailyLimit money.Amount) (models.Transfer, error) {
+	err := d.inTx(ctx, func(tx pgx.Tx) error {
+		r, err := rate(ctx, tx, transfer.Currency)
+		if err != nil {
+			return err
+		}
+		inBase := transfer.Amount.MulRate(r)
+		if limit > 0 && inBase > limit {
+			return problems.ErrOverLimit
+		}
+		err = tx.QueryRow(ctx, `select id from public.users where login = $1`, transfer.ToLogin).Scan(&transfer.To)
+		if err == pgx.ErrNoRows {
+			return problems.ErrNoRecipient
+		}
+		if err != nil {
+			log.Println("Cant get transfer recipient:", err)
+			return err
+		}
+		if transfer.To == transfer.From {
+			return problems.ErrSelfTransfer
+		}
+		if err := tx.QueryRow(ctx, `select login from public.users where id = $1`, transfer.From).Scan(&transfer.FromLogin); err != nil {
+			log.Println("Cant get transfer sender:", err)
+			return err
+		}
+		// Встречные переводы блокируют оба счета в одном порядке, иначе они ждали бы друг друга
+		users := []uuid.UUID{transfer.From, transfer.To}
+		slices.SortFunc(users, func(a, b uuid.UUID) int { return slices.Compare(a[:], b[:]) })
+		balances := map[uuid.UUID]money.Amount{}
+		for _, user := range users {
+			if balances[user], err = lock(ctx, tx, models.CashAccount(user, transfer.Currency)); err != nil {
+				return err
+			}
+		}
+		if transfer.Amount > balances[transfer.From] {
+			return problems.ErrLowBalance
+		}
+		if dailyLimit > 0 {
+			sent, err := transferredSince(ctx, tx, transfer.From, time.Now().Add(-24*time.Hour))
+			if err != nil {
+				return err
+			}
+			if sent+inBase > dailyLimit {
+				return problems.ErrOverLimit
+			}
+		}
+		transfer.ID = uuid.New()
+		entry := models.Entry{Kind: models.EntryTransfer, Reference: &transfer.ID, Memo: transfer.Memo}
+		entry.Move(models.CashAccount(transfer.From, transfer.Currency), models.CashAccount(transfer.To, transfer.Currency), transfer.Amount)
+		return post(ctx, tx, entry)
+	})
+	return transfer, err
+}
+
+// Сумма переводов пользователя другим пользователям начиная с since в базовой валюте по текущим курсам
+func transferredSince(ctx context.Context, tx pgx.Tx, userID uuid.UUID, since time.Time) (money.Amount, error) {
+	var sent money.Amount
+	err := tx.QueryRow(ctx, `select coalesce(round(-sum(p.amount * c.rate), 2), 0) from public.postings p
+		join public.entries e on e.id = p.entry_id
+		join public.accounts a on a.id = p.account_id
+		join public.currencies c on c.code = a.currency
+		where e.kind = $1 and e.created_at >= $2 and a.kind = $3 and a.user_id = $4 and p.amount < 0`,
+		models.EntryTransfer, since, models.AccountCash, userID).Scan(&sent)
+	if err != nil {
+		log.Println("Cant sum user transfers:", err)
+		return 0, err
+	}
+	return sent, nil
+}
@@ -0,0 +1,95 @@
+package interest
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"balance/internal/pkg/models"
+)
+
+type Config struct {
+	// Годовая ставка на остаток cash, 0.02 - 2% годовых. 0 - проценты не начисляются
+	AnnualRate float64 `yaml:"annual_rate" env:"INTEREST_ANNUAL_RATE"`
+	// Пробный прогон: только пишет в лог, сколько было бы выплачено за текущий месяц
+	DryRun bool `yaml:"dry_run" env:"INTEREST_DRY_RUN"`
+	// Как часто в секундах проверять, не пора ли начислить проценты
+	CheckInterval int `yaml:"check_interval" env-default:"3600"`
+}
+
+type IDB interface {
+	LastInterestDay(ctx context.Context) (time.Time, error)
+	AccrueInterest(ctx context.Context, day time.Time, rate float64) error
+	PayInterest(ctx context.Context, before time.Time) ([]models.InterestPayout, error)
+	PreviewInterest(ctx context.Context, from, to time.Time, rate float64) ([]models.InterestPayout, error)
+}
+
+// Начисляет проценты на свободный остаток: каждый прошедший день(UTC) по остатку на его конец, а в начале месяца
+// выплачивает накопленное за прошлые месяцы записью журнала. Дни и месяцы запоминаются в базе, поэтому после
+// перезапуска пропущенные дни доначисляются, а уже сделанное не повторяется
+type Job struct {
+	cfg *Config
+	db  IDB
+	now func() time.Time
+}
+
+func New(cfg *Config, db IDB) *Job {
+	return &Job{cfg: cfg, db: db, now: time.Now}
+}
+
+func (j *Job) Start() {
+	if j.cfg.AnnualRate == 0 {
+		log.Println("Interest annual rate is 0, interest is off")
+		return
+	}
+	// Первый прогон сразу после старта, чтобы доначислить дни простоя
+	j.run()
+	for range time.Tick(time.Duration(j.cfg.CheckInterval) * time.Second) {
+		j.run()
+	}
+}
+
+func (j *Job) run() {
+	if err := j.Run(context.Background()); err != nil {
+		log.Println("Interest run failed:", err)
+	}
+}
+
+// Один прогон: доначисляет дни до сегодняшнего и выплачивает закрытые месяцы. В DryRun только отчет
+func (j *Job) Run(ctx context.Context) error {
+	today := j.now().UTC().Truncate(24 * time.Hour)
+	month := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	rate := j.cfg.AnnualRate / 365
+	if j.cfg.DryRun {
+		payouts, err := j.db.PreviewInterest(ctx, month, today, rate)
+		if err != nil {
+			return err
+		}
+		for _, payout := range payouts {
+			log.Printf("Dry run: user %v would get %v %v interest for %v\n", payout.UserID, payout.Amount, payout.Currency, payout.Month.Format("2006-01"))
+		}
+		return nil
+	}
+	last, err := j.db.LastInterestDay(ctx)
+	if err != nil {
+		return err
+	}
+	// Без истории начисления начинаются со вчерашнего дня, а не с начала времен
+	day := today.AddDate(0, 0, -1)
+	if !last.IsZero() {
+		day = last.UTC().AddDate(0, 0, 1)
+	}
+	for ; day.Before(today); day = day.AddDate(0, 0, 1) {
+		if err := j.db.AccrueInterest(ctx, day, rate); err != nil {
+			return err
+		}
+	}
+	payouts, err := j.db.PayInterest(ctx, month)
+	if err != nil {
+		return err
+	}
+	for _, payout := range payouts {
+		log.Printf("User %v got %v %v interest for %v\n", payout.UserID, payout.Amount, payout.Currency, payout.Month.Format("2006-01"))
+	}
+	return nil
+}
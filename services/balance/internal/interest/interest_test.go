@@ -0,0 +1,81 @@
+package interest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"balance/internal/pkg/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Запоминает начисленные дни и выплаченные месяцы, как это делает база
+type fakeDB struct {
+	days     []time.Time
+	paid     []time.Time
+	previews int
+}
+
+func (d *fakeDB) LastInterestDay(context.Context) (time.Time, error) {
+	if len(d.days) == 0 {
+		return time.Time{}, nil
+	}
+	return d.days[len(d.days)-1], nil
+}
+
+func (d *fakeDB) AccrueInterest(_ context.Context, day time.Time, _ float64) error {
+	d.days = append(d.days, day)
+	return nil
+}
+
+func (d *fakeDB) PayInterest(_ context.Context, before time.Time) ([]models.InterestPayout, error) {
+	d.paid = append(d.paid, before)
+	return nil, nil
+}
+
+func (d *fakeDB) PreviewInterest(context.Context, time.Time, time.Time, float64) ([]models.InterestPayout, error) {
+	d.previews++
+	return nil, nil
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	db := &fakeDB{}
+	now := time.Date(2026, 3, 30, 15, 0, 0, 0, time.UTC)
+	job := &Job{cfg: &Config{AnnualRate: 0.0365}, db: db, now: func() time.Time { return now }}
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tfirst run accrues only yesterday", testID)
+		require.NoError(t, job.Run(ctx))
+		require.Equal(t, []time.Time{time.Date(2026, 3, 29, 0, 0, 0, 0, time.UTC)}, db.days)
+		require.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), db.paid[0])
+	}
+	{
+		testID := 1
+		t.Logf("\tTest %d:\trun on the same day accrues nothing", testID)
+		require.NoError(t, job.Run(ctx))
+		require.Len(t, db.days, 1)
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\trun after downtime catches up missed days and pays the closed month", testID)
+		now = time.Date(2026, 4, 2, 1, 0, 0, 0, time.UTC)
+		require.NoError(t, job.Run(ctx))
+		require.Equal(t, []time.Time{
+			time.Date(2026, 3, 29, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 30, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		}, db.days)
+		require.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), db.paid[len(db.paid)-1])
+	}
+	{
+		testID := 3
+		t.Logf("\tTest %d:\tdry run writes nothing", testID)
+		job.cfg.DryRun = true
+		now = time.Date(2026, 4, 10, 0, 0, 0, 0, time.UTC)
+		require.NoError(t, job.Run(ctx))
+		require.Len(t, db.days, 4)
+		require.Len(t, db.paid, 3)
+		require.Equal(t, 1, db.previews)
+	}
+}
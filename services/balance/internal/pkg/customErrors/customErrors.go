@@ -1,7 +1,83 @@
 package problems
 
+import (
+	"balance/internal/pkg/money"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	NoPaper      = "requested paper does not exists"
+	LowBalance   = "not enough money on balance"
+	LowPaper     = "not enough papers to sold"
+	BadCash      = "amount of money must be positive"
+	Unbalanced   = "ledger entry postings must sum to zero"
+	NoHold       = "hold does not exists"
+	HoldExceeded = "hold is smaller than requested amount"
+	BadPayout    = "payout must go to a user or to market or fees account"
+	NoRecipient  = "recipient does not exists"
+	SelfTransfer = "cannot transfer money to yourself"
+	LongMemo     = "transfer memo is too long"
+	OverLimit    = "transfer exceeds the limit"
+	NoCurrency   = "currency is not supported"
+	SameCurrency = "cannot exchange currency to itself"
+	NoPayment    = "payment does not exists"
+	BadSignature = "payment callback signature is invalid"
+	BadCallback  = "payment callback does not match the payment"
+)
+
 var (
-	NoPaper    = "requested paper does not exists"
-	LowBalance = "not enough money on balance"
-	LowPaper   = "not enough papers to sold"
+	ErrLowBalance   = errors.New(LowBalance)
+	ErrBadCash      = errors.New(BadCash)
+	ErrUnbalanced   = errors.New(Unbalanced)
+	ErrNoHold       = errors.New(NoHold)
+	ErrHoldExceeded = errors.New(HoldExceeded)
+	ErrBadPayout    = errors.New(BadPayout)
+	ErrNoRecipient  = errors.New(NoRecipient)
+	ErrSelfTransfer = errors.New(SelfTransfer)
+	ErrOverLimit    = errors.New(OverLimit)
+	ErrNoCurrency   = errors.New(NoCurrency)
+	ErrSameCurrency = errors.New(SameCurrency)
+	ErrNoPayment    = errors.New(NoPayment)
+	ErrBadSignature = errors.New(BadSignature)
+	ErrBadCallback  = errors.New(BadCallback)
 )
+
+// Правила лимитов пополнения и вывода
+const (
+	RuleNotPositive = "AMOUNT_NOT_POSITIVE"
+	RuleBelowMin    = "AMOUNT_BELOW_MIN"
+	RuleAboveMax    = "AMOUNT_ABOVE_MAX"
+	RuleDailyCap    = "DAILY_CAP_EXCEEDED"
+	RuleMonthlyCap  = "MONTHLY_CAP_EXCEEDED"
+	RuleCooldown    = "COOLDOWN"
+)
+
+// Нарушение правила лимитов. Limit - граница правила в базовой валюте, Remaining - сколько еще можно по лимиту,
+// RetryAfter - через сколько операция будет разрешена
+type Violation struct {
+	Rule       string
+	Operation  string
+	Limit      money.Amount
+	Remaining  money.Amount
+	RetryAfter time.Duration
+}
+
+func (v *Violation) Error() string {
+	switch v.Rule {
+	case RuleNotPositive:
+		return BadCash
+	case RuleBelowMin:
+		return fmt.Sprintf("%v amount is below the minimum of %v", v.Operation, v.Limit)
+	case RuleAboveMax:
+		return fmt.Sprintf("%v amount is above the maximum of %v", v.Operation, v.Limit)
+	case RuleDailyCap:
+		return fmt.Sprintf("%v exceeds the daily cap of %v, %v left", v.Operation, v.Limit, v.Remaining)
+	case RuleMonthlyCap:
+		return fmt.Sprintf("%v exceeds the monthly cap of %v, %v left", v.Operation, v.Limit, v.Remaining)
+	case RuleCooldown:
+		return fmt.Sprintf("%v is allowed again in %v", v.Operation, v.RetryAfter.Round(time.Second))
+	}
+	return v.Rule
+}
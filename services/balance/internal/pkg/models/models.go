@@ -0,0 +1,228 @@
+package models
+
+import (
+	"balance/internal/pkg/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Базовая валюта: в ней курсы остальных валют, в нее же попали деньги, заведенные до мультивалютных кошельков
+const BaseCurrency = "USD"
+
+// Виды счетов журнала. cash и hold принадлежат пользователю, остальные - системные
+const (
+	AccountCash     = "cash"
+	AccountHold     = "hold"
+	AccountExternal = "external"
+	AccountMarket   = "market"
+	AccountFees     = "fees"
+	AccountFX       = "fx"
+	AccountInterest = "interest"
+)
+
+// Виды записей журнала
+const (
+	EntryOpening    = "opening"
+	EntryDeposit    = "deposit"
+	EntryWithdrawal = "withdrawal"
+	EntryTrade      = "trade"
+	EntryReserve    = "reserve"
+	EntryRelease    = "release"
+	EntryTransfer   = "transfer"
+	EntryExchange   = "exchange"
+	EntryInterest   = "interest"
+)
+
+// Виды нарушений инвариантов журнала
+const (
+	DriftEntry    = "entry"
+	DriftAccount  = "account"
+	DriftNegative = "negative"
+)
+
+// Счет журнала в валюте Currency. У системных счетов нет пользователя
+type Account struct {
+	Kind     string
+	UserID   uuid.UUID
+	Currency string
+}
+
+func CashAccount(userID uuid.UUID, currency string) Account {
+	return Account{Kind: AccountCash, UserID: userID, Currency: currency}
+}
+
+func HoldAccount(userID uuid.UUID, currency string) Account {
+	return Account{Kind: AccountHold, UserID: userID, Currency: currency}
+}
+
+// Системный счет kind в валюте currency
+func SystemAccount(kind, currency string) Account {
+	return Account{Kind: kind, Currency: currency}
+}
+
+// Проводка по счету: положительная сумма - поступление
+type Posting struct {
+	Account Account
+	Amount  money.Amount
+}
+
+// Запись журнала. Проводки записи в сумме дают ноль
+type Entry struct {
+	Kind      string
+	Reference *uuid.UUID
+	Memo      string
+	Postings  []Posting
+}
+
+// Добавляет в запись перевод amount со счета from на счет to. Нулевой перевод не добавляется
+func (e *Entry) Move(from, to Account, amount money.Amount) {
+	if amount == 0 {
+		return
+	}
+	e.Postings = append(e.Postings, Posting{Account: from, Amount: -amount}, Posting{Account: to, Amount: amount})
+}
+
+// Выплата из резерва: на счет cash пользователя или, если пользователя нет, на системный счет Account
+type Payout struct {
+	UserID  uuid.UUID
+	Account string
+	Amount  money.Amount
+}
+
+func (p Payout) Target(currency string) Account {
+	if p.UserID != uuid.Nil {
+		return CashAccount(p.UserID, currency)
+	}
+	return SystemAccount(p.Account, currency)
+}
+
+// Нарушение инварианта, найденное проверкой журнала: запись с ненулевой суммой проводок,
+// кэш баланса счета, не совпадающий с суммой его проводок, или ушедший в минус счет пользователя
+type Drift struct {
+	Kind     string
+	ID       uuid.UUID
+	Expected money.Amount
+	Actual   money.Amount
+}
+
+// Виды операций в истории пользователя. Резервы под заявки и их возврат в историю не попадают:
+// деньги при этом остаются у пользователя
+const (
+	TransactionOpening     = "opening"
+	TransactionDeposit     = "deposit"
+	TransactionWithdrawal  = "withdrawal"
+	TransactionTradeDebit  = "trade_debit"
+	TransactionTradeCredit = "trade_credit"
+	TransactionFee         = "fee"
+	TransactionTransferIn  = "transfer_in"
+	TransactionTransferOut = "transfer_out"
+	TransactionExchange    = "exchange"
+	TransactionInterest    = "interest"
+)
+
+// Операция пользователя в одной валюте. Amount со знаком, Balance - деньги пользователя в этой валюте после операции вместе с резервами.
+// Seq и Part задают порядок операций: запись журнала и номер операции внутри нее(сделка, затем ее комиссия)
+type Transaction struct {
+	EntryID   uuid.UUID
+	Type      string
+	Currency  string
+	Amount    money.Amount
+	Balance   money.Amount
+	Reference *uuid.UUID
+	Memo      string
+	CreatedAt time.Time
+	Seq       int64
+	Part      int
+}
+
+// Фильтр истории операций. Пустые Types, нулевые From/To не ограничивают выборку, After - курсор(последняя операция предыдущей страницы)
+type TransactionFilter struct {
+	UserID   uuid.UUID
+	Currency string
+	Types    []string
+	From     time.Time
+	To       time.Time
+	After    *Transaction
+	Limit    int
+}
+
+// Перевод между пользователями. Получатель ищется по логину, ID перевода - ссылка(reference) его записи журнала
+type Transfer struct {
+	ID        uuid.UUID
+	From      uuid.UUID
+	FromLogin string
+	To        uuid.UUID
+	ToLogin   string
+	Currency  string
+	Amount    money.Amount
+	Memo      string
+}
+
+// Деньги пользователя в одной валюте
+type Wallet struct {
+	Currency string
+	Amount   money.Amount
+}
+
+// Обмен валюты: Amount в валюте From продано, Received в валюте To получено по курсу Rate(единиц To за единицу From)
+type Exchange struct {
+	UserID   uuid.UUID
+	From     string
+	To       string
+	Amount   money.Amount
+	Received money.Amount
+	Rate     float64
+}
+
+// Пополнение или вывод денег, которые проверяются правилами лимитов. Kind - EntryDeposit или EntryWithdrawal
+type Operation struct {
+	UserID   uuid.UUID
+	Kind     string
+	Currency string
+	Amount   money.Amount
+}
+
+// Операции пользователя одного вида: суммы в базовой валюте за последние сутки и 30 дней и время последней операции
+type Activity struct {
+	Day   money.Amount
+	Month money.Amount
+	Last  time.Time
+}
+
+// Статусы платежа провайдера
+const (
+	PaymentPending = "pending"
+	PaymentSettled = "settled"
+	PaymentFailed  = "failed"
+)
+
+// Пополнение через платежного провайдера. Reason - причина отказа провайдера
+type Payment struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Currency  string
+	Amount    money.Amount
+	Provider  string
+	Status    string
+	Reason    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Результат платежа, который провайдер присылает в callback
+type PaymentCallback struct {
+	PaymentID uuid.UUID    `json:"payment_id"`
+	Status    string       `json:"status"`
+	Amount    money.Amount `json:"amount"`
+	Currency  string       `json:"currency"`
+	Reason    string       `json:"reason,omitempty"`
+}
+
+// Проценты на остаток пользователя в одной валюте: начисленные за месяц Month или, в пробном прогоне, за его часть
+type InterestPayout struct {
+	UserID   uuid.UUID
+	Currency string
+	Month    time.Time
+	Amount   money.Amount
+}
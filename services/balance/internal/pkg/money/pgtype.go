@@ -0,0 +1,38 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Суммы хранятся в numeric колонках. pgx переводит их в Amount через эти методы, поэтому в запросах ничего не меняется
+
+func (a *Amount) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		return fmt.Errorf("%w: cannot scan NULL", ErrBadAmount)
+	}
+	// v = Int * 10^Exp, в копейках это Int * 10^(Exp+2)
+	n := new(big.Int).Set(v.Int)
+	if exp := int64(v.Exp) + 2; exp >= 0 {
+		n.Mul(n, new(big.Int).Exp(big.NewInt(10), big.NewInt(exp), nil))
+	} else {
+		divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(-exp), nil)
+		var rem big.Int
+		n.QuoRem(n, divisor, &rem)
+		// Округление половины от нуля
+		if rem.Abs(&rem).Mul(&rem, big.NewInt(2)).Cmp(divisor) >= 0 {
+			n.Add(n, big.NewInt(int64(v.Int.Sign())))
+		}
+	}
+	if !n.IsInt64() {
+		return fmt.Errorf("%w: %v does not fit", ErrBadAmount, n)
+	}
+	*a = Amount(n.Int64())
+	return nil
+}
+
+func (a Amount) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(int64(a)), Exp: -2, Valid: true}, nil
+}
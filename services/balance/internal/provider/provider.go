@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	problems "balance/internal/pkg/customErrors"
+	"balance/internal/pkg/models"
+)
+
+// Заголовок с подписью callback: "sha256=" и HMAC-SHA256 тела в hex
+const SignatureHeader = "X-Signature"
+
+type Config struct {
+	// Адрес webhook gateway, куда провайдер присылает результат платежа
+	WebhookURL string `yaml:"webhook_url" env:"PAYMENTS_WEBHOOK_URL" env-default:"http://gateway:8080/webhooks/payments"`
+	// Общий с balance секрет подписи callback
+	Secret string `yaml:"secret" env:"PAYMENTS_SECRET"`
+	// Через сколько секунд платеж завершается и какая доля платежей отклоняется
+	Delay    float64 `yaml:"delay" env-default:"2"`
+	FailRate float64 `yaml:"fail_rate"`
+	// Сколько раз повторять callback, если webhook не ответил 2xx
+	Retries int `yaml:"retries" env-default:"5"`
+}
+
+// Локальный провайдер для разработки: принимает любой платеж и через Delay секунд сам присылает подписанный callback
+// с результатом, как это делают настоящие провайдеры
+type Fake struct {
+	cfg    *Config
+	client *http.Client
+}
+
+// Без секрета подпись callback может посчитать кто угодно, а webhook публичный: balance не запускается
+func New(cfg *Config) *Fake {
+	if cfg.Secret == "" {
+		log.Fatalln("Payments secret is empty, set PAYMENTS_SECRET: without it anyone can forge payment callbacks")
+	}
+	return &Fake{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) CreatePayment(_ context.Context, payment models.Payment) error {
+	go f.complete(payment)
+	return nil
+}
+
+// Проверяет подпись callback и разбирает его. Неверная подпись - problems.ErrBadSignature
+func (f *Fake) ParseCallback(payload []byte, signature string) (models.PaymentCallback, error) {
+	var callback models.PaymentCallback
+	if !hmac.Equal([]byte(signature), []byte(Sign(f.cfg.Secret, payload))) {
+		return callback, problems.ErrBadSignature
+	}
+	if err := json.Unmarshal(payload, &callback); err != nil {
+		return callback, fmt.Errorf("%w: %v", problems.ErrBadCallback, err)
+	}
+	return callback, nil
+}
+
+// Подпись тела callback секретом
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Решает судьбу платежа и присылает callback, повторяя его с растущей паузой, пока webhook не примет
+func (f *Fake) complete(payment models.Payment) {
+	time.Sleep(time.Duration(f.cfg.Delay * float64(time.Second)))
+	callback := models.PaymentCallback{PaymentID: payment.ID, Status: models.PaymentSettled, Amount: payment.Amount, Currency: payment.Currency}
+	if rand.Float64() < f.cfg.FailRate {
+		callback.Status, callback.Reason = models.PaymentFailed, "card declined"
+	}
+	payload, err := json.Marshal(callback)
+	if err != nil {
+		log.Println("Cant marshal payment callback:", err)
+		return
+	}
+	for attempt := range f.cfg.Retries + 1 {
+		if attempt > 0 {
+			time.Sleep(time.Duration(1<<attempt) * time.Second)
+		}
+		if err := f.send(payload); err != nil {
+			log.Printf("Payment %v callback attempt %v failed: %v\n", payment.ID, attempt+1, err)
+			continue
+		}
+		log.Printf("Payment %v completed as %v\n", payment.ID, callback.Status)
+		return
+	}
+}
+
+func (f *Fake) send(payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, f.cfg.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(f.cfg.Secret, payload))
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook responded %v", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	problems "balance/internal/pkg/customErrors"
+	"balance/internal/pkg/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeProvider(t *testing.T) {
+	t.Log("Testing fake payment provider in process...")
+	callbacks := make(chan models.PaymentCallback, 1)
+	var provider *Fake
+	attempts := 0
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		// Первый callback отклоняется, провайдер должен его повторить
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		payload, _ := io.ReadAll(r.Body)
+		callback, err := provider.ParseCallback(payload, r.Header.Get(SignatureHeader))
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		callbacks <- callback
+	}))
+	defer webhook.Close()
+	provider = New(&Config{WebhookURL: webhook.URL, Secret: "secret", Retries: 1})
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tPayment is completed by a signed callback", testID)
+		payment := models.Payment{ID: uuid.New(), Currency: "USD", Amount: 1000}
+		require.NoError(t, provider.CreatePayment(context.Background(), payment))
+		select {
+		case callback := <-callbacks:
+			require.Equal(t, payment.ID, callback.PaymentID)
+			require.Equal(t, models.PaymentSettled, callback.Status)
+			require.Equal(t, payment.Amount, callback.Amount)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Callback was not retried")
+		}
+	}
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tForged callback is rejected", testID)
+		payload := []byte(`{"payment_id":"` + uuid.NewString() + `","status":"settled","amount":1000,"currency":"USD"}`)
+		_, err := provider.ParseCallback(payload, Sign("other", payload))
+		require.ErrorIs(t, err, problems.ErrBadSignature)
+		_, err = provider.ParseCallback(payload, Sign("secret", payload))
+		require.NoError(t, err)
+	}
+}
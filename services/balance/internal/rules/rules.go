@@ -0,0 +1,110 @@
+package rules
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	problems "balance/internal/pkg/customErrors"
+	"balance/internal/pkg/models"
+	"balance/internal/pkg/money"
+
+	"github.com/google/uuid"
+)
+
+// Лимиты одного вида операций в базовой валюте, 0 - без ограничения
+type Limits struct {
+	// Минимальная и максимальная сумма одной операции
+	Min float64 `yaml:"min"`
+	Max float64 `yaml:"max"`
+	// Сумма операций за последние сутки и за последние 30 дней
+	Daily   float64 `yaml:"daily"`
+	Monthly float64 `yaml:"monthly"`
+	// Сколько секунд ждать после предыдущей операции
+	Cooldown float64 `yaml:"cooldown"`
+}
+
+type Config struct {
+	Deposit  Limits `yaml:"deposit"`
+	Withdraw Limits `yaml:"withdraw"`
+}
+
+type IDB interface {
+	ToBase(ctx context.Context, currency string, cash money.Amount) (money.Amount, error)
+	GetActivity(ctx context.Context, userID uuid.UUID, kind string, day, month time.Time) (models.Activity, error)
+}
+
+// Проверяет пополнения и выводы по лимитам из конфига. Операции одного пользователя проверяются и проводятся по
+// очереди, иначе параллельные операции прошли бы проверку по одной и той же истории
+type Engine struct {
+	cfg   *Config
+	db    IDB
+	now   func() time.Time
+	locks [64]sync.Mutex
+}
+
+func New(cfg *Config, db IDB) *Engine {
+	return &Engine{cfg: cfg, db: db, now: time.Now}
+}
+
+// Проверяет операцию и, если правила не нарушены, проводит ее через apply. Нарушение - *problems.Violation
+func (e *Engine) Apply(ctx context.Context, op models.Operation, apply func() error) error {
+	lock := &e.locks[op.UserID[0]%byte(len(e.locks))]
+	lock.Lock()
+	defer lock.Unlock()
+	if err := e.Check(ctx, op); err != nil {
+		return err
+	}
+	return apply()
+}
+
+// Проверяет операцию по правилам ее вида: сумма, затем перерыв, затем лимиты за сутки и за месяц
+func (e *Engine) Check(ctx context.Context, op models.Operation) error {
+	limits := e.limits(op.Kind)
+	violation := &problems.Violation{Operation: op.Kind}
+	if op.Amount <= 0 {
+		violation.Rule = problems.RuleNotPositive
+		return violation
+	}
+	amount, err := e.db.ToBase(ctx, op.Currency, op.Amount)
+	if err != nil {
+		return err
+	}
+	if limit := money.FromFloat(limits.Min); limit > 0 && amount < limit {
+		violation.Rule, violation.Limit = problems.RuleBelowMin, limit
+		return violation
+	}
+	if limit := money.FromFloat(limits.Max); limit > 0 && amount > limit {
+		violation.Rule, violation.Limit = problems.RuleAboveMax, limit
+		return violation
+	}
+	if limits.Cooldown <= 0 && limits.Daily <= 0 && limits.Monthly <= 0 {
+		return nil
+	}
+	now := e.now()
+	activity, err := e.db.GetActivity(ctx, op.UserID, op.Kind, now.Add(-24*time.Hour), now.Add(-30*24*time.Hour))
+	if err != nil {
+		return err
+	}
+	cooldown := time.Duration(limits.Cooldown * float64(time.Second))
+	if wait := activity.Last.Add(cooldown).Sub(now); cooldown > 0 && !activity.Last.IsZero() && wait > 0 {
+		violation.Rule, violation.RetryAfter = problems.RuleCooldown, wait
+		return violation
+	}
+	if limit := money.FromFloat(limits.Daily); limit > 0 && activity.Day+amount > limit {
+		violation.Rule, violation.Limit, violation.Remaining = problems.RuleDailyCap, limit, max(limit-activity.Day, 0)
+		return violation
+	}
+	if limit := money.FromFloat(limits.Monthly); limit > 0 && activity.Month+amount > limit {
+		violation.Rule, violation.Limit, violation.Remaining = problems.RuleMonthlyCap, limit, max(limit-activity.Month, 0)
+		return violation
+	}
+	return nil
+}
+
+func (e *Engine) limits(kind string) Limits {
+	if kind == models.EntryWithdrawal {
+		return e.cfg.Withdraw
+	}
+	return e.cfg.Deposit
+}
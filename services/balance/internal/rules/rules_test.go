@@ -0,0 +1,109 @@
+package rules
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	problems "balance/internal/pkg/customErrors"
+	"balance/internal/pkg/models"
+	"balance/internal/pkg/money"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// История операций в памяти, EUR в два раза дороже базовой валюты
+type fakeDB struct {
+	operations []models.Operation
+	times      []time.Time
+}
+
+func (d *fakeDB) ToBase(_ context.Context, currency string, cash money.Amount) (money.Amount, error) {
+	if currency == "EUR" {
+		return cash * 2, nil
+	}
+	return cash, nil
+}
+
+func (d *fakeDB) GetActivity(_ context.Context, userID uuid.UUID, kind string, day, month time.Time) (models.Activity, error) {
+	var activity models.Activity
+	for i, op := range d.operations {
+		if op.UserID != userID || op.Kind != kind {
+			continue
+		}
+		if !d.times[i].Before(day) {
+			activity.Day += op.Amount
+		}
+		if !d.times[i].Before(month) {
+			activity.Month += op.Amount
+		}
+		activity.Last = d.times[i]
+	}
+	return activity, nil
+}
+
+func TestRules(t *testing.T) {
+	t.Log("Testing deposit and withdrawal rules in process...")
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	db := &fakeDB{}
+	engine := New(&Config{
+		Deposit:  Limits{Min: 1, Max: 100, Daily: 150, Monthly: 300},
+		Withdraw: Limits{Cooldown: 60},
+	}, db)
+	engine.now = func() time.Time { return now }
+	user := uuid.New()
+	deposit := func(amount money.Amount, currency string) error {
+		op := models.Operation{UserID: user, Kind: models.EntryDeposit, Currency: currency, Amount: amount}
+		return engine.Apply(context.Background(), op, func() error {
+			db.operations = append(db.operations, models.Operation{UserID: user, Kind: op.Kind, Amount: amount})
+			db.times = append(db.times, now)
+			return nil
+		})
+	}
+	rule := func(err error) string {
+		var violation *problems.Violation
+		require.ErrorAs(t, err, &violation)
+		return violation.Rule
+	}
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tAmount must be positive and within per-transaction bounds", testID)
+		require.Equal(t, problems.RuleNotPositive, rule(deposit(0, "USD")))
+		require.Equal(t, problems.RuleNotPositive, rule(deposit(-100, "USD")))
+		require.Equal(t, problems.RuleBelowMin, rule(deposit(99, "USD")))
+		require.Equal(t, problems.RuleAboveMax, rule(deposit(6000, "EUR")), "Limits are in base currency")
+		require.Empty(t, db.operations, "Rejected operations must not be applied")
+	}
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tDaily and monthly caps", testID)
+		require.NoError(t, deposit(10000, "USD"))
+		err := deposit(6000, "USD")
+		require.Equal(t, problems.RuleDailyCap, rule(err))
+		var violation *problems.Violation
+		require.ErrorAs(t, err, &violation)
+		require.Equal(t, money.Amount(5000), violation.Remaining)
+		now = now.Add(25 * time.Hour)
+		require.NoError(t, deposit(10000, "USD"))
+		now = now.Add(25 * time.Hour)
+		require.NoError(t, deposit(10000, "USD"))
+		now = now.Add(25 * time.Hour)
+		require.Equal(t, problems.RuleMonthlyCap, rule(deposit(100, "USD")))
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\tCooldown between withdrawals", testID)
+		withdraw := models.Operation{UserID: user, Kind: models.EntryWithdrawal, Currency: "USD", Amount: 100}
+		db.operations = append(db.operations, withdraw)
+		db.times = append(db.times, now.Add(-20*time.Second))
+		err := engine.Check(context.Background(), withdraw)
+		require.Equal(t, problems.RuleCooldown, rule(err))
+		var violation *problems.Violation
+		require.ErrorAs(t, err, &violation)
+		require.Equal(t, 40*time.Second, violation.RetryAfter)
+		now = now.Add(40 * time.Second)
+		require.NoError(t, engine.Check(context.Background(), withdraw))
+		require.NoError(t, engine.Check(context.Background(), models.Operation{UserID: uuid.New(), Kind: models.EntryWithdrawal, Currency: "USD", Amount: 100}))
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"idempotency"
+	"log"
+
+	problems "balance/internal/pkg/customErrors"
+	pb "balance/internal/pkg/grpc/pb/balanceService"
+	"balance/internal/pkg/models"
+	"balance/internal/pkg/money"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Платежный провайдер: принимает платеж и позже присылает его результат в подписанном callback
+type IProvider interface {
+	Name() string
+	CreatePayment(ctx context.Context, payment models.Payment) error
+	ParseCallback(payload []byte, signature string) (models.PaymentCallback, error)
+}
+
+// Создает платеж пополнения у провайдера. Сумма проверяется правилами лимитов пополнения, деньги зачисляются позже,
+// по callback провайдера. С ключом идемпотентности повтор запроса возвращает уже созданный платеж
+func (s *server) CreatePayment(ctx context.Context, in *pb.PaymentRequest) (*pb.Payment, error) {
+	return idempotency.Do(ctx, s.keys, "balance/CreatePayment", requestUser(in.UserId), in.IdempotencyKey, in, func() (*pb.Payment, error) {
+		return s.createPayment(ctx, in)
+	})
+}
+
+func (s *server) createPayment(ctx context.Context, in *pb.PaymentRequest) (*pb.Payment, error) {
+	userID, err := uuid.FromBytes(in.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	op := models.Operation{UserID: userID, Kind: models.EntryDeposit, Currency: currencyOf(in.Currency), Amount: money.Amount(in.AmountMinor)}
+	var payment models.Payment
+	err = s.rules.Apply(ctx, op, func() error {
+		payment, err = s.db.CreatePayment(ctx, models.Payment{UserID: userID, Currency: op.Currency, Amount: op.Amount, Provider: s.provider.Name()})
+		return err
+	})
+	var violation *problems.Violation
+	switch {
+	case errors.As(err, &violation):
+		return nil, violationError(violation)
+	case errors.Is(err, problems.ErrNoCurrency):
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	case err != nil:
+		return nil, err
+	}
+	if err := s.provider.CreatePayment(ctx, payment); err != nil {
+		log.Printf("Provider rejected payment %v: %v\n", payment.ID, err)
+		failed := models.PaymentCallback{PaymentID: payment.ID, Status: models.PaymentFailed, Amount: payment.Amount, Currency: payment.Currency, Reason: err.Error()}
+		completed, err := s.db.CompletePayment(ctx, failed)
+		if err != nil {
+			// Платеж уже создан: повтор с тем же ключом вернет его, а не создаст второй
+			return paymentToPb(payment), idempotency.Committed(err)
+		}
+		payment = completed
+	}
+	return paymentToPb(payment), nil
+}
+
+// Принимает callback провайдера, пересланный gateway. Деньги зачисляются только по callback с верной подписью
+// и только один раз: повтор возвращает уже завершенный платеж
+func (s *server) ConfirmPayment(ctx context.Context, in *pb.PaymentCallback) (*pb.Payment, error) {
+	callback, err := s.provider.ParseCallback(in.Payload, in.Signature)
+	if err != nil {
+		return nil, paymentError(err)
+	}
+	payment, err := s.db.CompletePayment(ctx, callback)
+	if err != nil {
+		return nil, paymentError(err)
+	}
+	log.Printf("Payment %v of user %v is %v\n", payment.ID, payment.UserID, payment.Status)
+	return paymentToPb(payment), nil
+}
+
+// Платежи пользователя или один его платеж
+func (s *server) GetPayments(ctx context.Context, in *pb.PaymentsRequest) (*pb.Payments, error) {
+	userID, err := uuid.FromBytes(in.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	paymentID, err := optionalID(in.Id)
+	if err != nil {
+		return nil, err
+	}
+	var payments []models.Payment
+	if paymentID != uuid.Nil {
+		payment, err := s.db.GetPayment(ctx, userID, paymentID)
+		if err != nil {
+			return nil, paymentError(err)
+		}
+		payments = append(payments, payment)
+	} else if payments, err = s.db.GetPayments(ctx, userID); err != nil {
+		return nil, err
+	}
+	resp := &pb.Payments{Payments: make([]*pb.Payment, 0, len(payments))}
+	for _, payment := range payments {
+		resp.Payments = append(resp.Payments, paymentToPb(payment))
+	}
+	return resp, nil
+}
+
+// Переводит ошибки платежа в коды grpc: неверная подпись - Unauthenticated, несовпадающий callback - InvalidArgument,
+// неизвестный платеж - NotFound
+func paymentError(err error) error {
+	switch {
+	case errors.Is(err, problems.ErrBadSignature):
+		return status.Errorf(codes.Unauthenticated, "%v", err)
+	case errors.Is(err, problems.ErrBadCallback):
+		return status.Errorf(codes.InvalidArgument, "%v", err)
+	case errors.Is(err, problems.ErrNoPayment):
+		return status.Errorf(codes.NotFound, "%v", err)
+	}
+	log.Println("Payment error:", err)
+	return err
+}
+
+func paymentToPb(p models.Payment) *pb.Payment {
+	return &pb.Payment{
+		Id: p.ID[:], UserId: p.UserID[:], Currency: p.Currency, AmountMinor: int64(p.Amount), Status: p.Status, Reason: p.Reason,
+		CreatedAt: p.CreatedAt.Unix(), UpdatedAt: p.UpdatedAt.Unix(),
+	}
+}
@@ -3,21 +3,65 @@ package server
 import (
 	problems "balance/internal/pkg/customErrors"
 	pb "balance/internal/pkg/grpc/pb/balanceService"
+	"balance/internal/pkg/models"
+	"balance/internal/pkg/money"
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"idempotency"
 	"log"
+	"math"
 	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
+// Размер страницы истории операций по умолчанию и максимальный
+const (
+	DefaultHistoryLimit = 50
+	MaxHistoryLimit     = 200
+)
+
+var TransactionTypes = []string{models.TransactionOpening, models.TransactionDeposit, models.TransactionWithdrawal,
+	models.TransactionTradeDebit, models.TransactionTradeCredit, models.TransactionFee,
+	models.TransactionTransferIn, models.TransactionTransferOut, models.TransactionExchange, models.TransactionInterest}
+
+// Максимальная длина комментария к переводу в символах
+const MaxMemoLength = 140
+
 type Config struct {
 	Port string `yaml:"port"`
+	// Как часто проверять инварианты журнала, в секундах
+	CheckInterval int `yaml:"check_interval" env-default:"60"`
+	// Максимальная сумма одного перевода и сумма переводов пользователя за сутки в базовой валюте, 0 - без ограничения
+	TransferLimit      float64 `yaml:"transfer_limit" env-default:"0"`
+	TransferDailyLimit float64 `yaml:"transfer_daily_limit" env-default:"0"`
+	// Сколько секунд хранится ключ идемпотентности пополнения или вывода
+	IdempotencyTTL int `yaml:"idempotency_ttl" env-default:"86400"`
+	// Раз в сколько секунд удаляются истекшие ключи идемпотентности
+	IdempotencyCleanupInterval int `yaml:"idempotency_cleanup_interval" env-default:"3600"`
 }
 
 type server struct {
 	pb.UnimplementedBalanceManagementServer
-	db IDBManager
+	cfg      *Config
+	db       IDBManager
+	rules    IRules
+	provider IProvider
+	keys     *idempotency.Keys
 }
 
 type Service struct {
@@ -28,51 +72,430 @@ type Service struct {
 }
 
 type IDBManager interface {
-	GetUserBalance(userID uuid.UUID) (float32, error)
-	ChangeBalance(userID uuid.UUID, cash float32) error
+	GetUserBalance(userID uuid.UUID, currency string) (money.Amount, error)
+	GetWallets(userID uuid.UUID) ([]models.Wallet, error)
+	GetAvailable(ctx context.Context, userID uuid.UUID, currency string) (money.Amount, error)
+	Deposit(userID uuid.UUID, currency string, cash money.Amount) error
+	Withdraw(userID uuid.UUID, currency string, cash money.Amount) error
+	CheckLedger(ctx context.Context) ([]models.Drift, error)
+	Reserve(ctx context.Context, userID uuid.UUID, currency string, cash money.Amount, reference *uuid.UUID) (uuid.UUID, error)
+	Commit(ctx context.Context, holdID uuid.UUID, payouts []models.Payout, reference *uuid.UUID) error
+	Release(ctx context.Context, holdID uuid.UUID, cash money.Amount) error
+	GetTransactions(ctx context.Context, filter models.TransactionFilter) ([]models.Transaction, error)
+	Transfer(ctx context.Context, transfer models.Transfer, limit, dailyLimit money.Amount) (models.Transfer, error)
+	Exchange(ctx context.Context, ex models.Exchange) (models.Exchange, error)
+	CreatePayment(ctx context.Context, payment models.Payment) (models.Payment, error)
+	CompletePayment(ctx context.Context, callback models.PaymentCallback) (models.Payment, error)
+	GetPayments(ctx context.Context, userID uuid.UUID) ([]models.Payment, error)
+	GetPayment(ctx context.Context, userID, paymentID uuid.UUID) (models.Payment, error)
+}
+
+// Лимиты пополнений и выводов: операция проводится через apply, только если правила не нарушены
+type IRules interface {
+	Apply(ctx context.Context, op models.Operation, apply func() error) error
 }
 
 // Создание сервера сервиса аутентификации
-func New(cfg *Config, db IDBManager) (*Service, error) {
+func New(cfg *Config, db IDBManager, rules IRules, provider IProvider, keys *idempotency.Keys) (*Service, error) {
 	log.Println(cfg.Port)
 	lis, err := net.Listen("tcp", cfg.Port)
 	if err != nil {
 		return nil, err
 	}
 	s := grpc.NewServer()
-	pb.RegisterBalanceManagementServer(s, &server{db: db})
+	pb.RegisterBalanceManagementServer(s, &server{cfg: cfg, db: db, rules: rules, provider: provider, keys: keys})
 	log.Printf("Auth server listening at %v\n", lis.Addr())
 	return &Service{Server: s, Listener: &lis, cfg: cfg, db: db}, nil
 }
 
-func (s *server) GetBalance(_ context.Context, in *pb.User) (*pb.Balance, error) {
-	balance, err := s.db.GetUserBalance(uuid.UUID(in.Id))
+// Баланс пользователя в запрошенной валюте и все его кошельки
+func (s *server) GetBalance(ctx context.Context, in *pb.User) (*pb.Balance, error) {
+	userID := uuid.UUID(in.Id)
+	currency := currencyOf(in.Currency)
+	available, err := s.db.GetAvailable(ctx, userID, currency)
+	if errors.Is(err, problems.ErrNoCurrency) {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	balance, err := s.db.GetUserBalance(userID, currency)
 	if err != nil {
 		return nil, err
 	}
-	return &pb.Balance{Cash: balance}, nil
+	wallets, err := s.db.GetWallets(userID)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.Balance{Cash: balance.Float32(), CashMinor: int64(balance), AvailableMinor: int64(available), Wallets: make([]*pb.Wallet, 0, len(wallets))}
+	for _, wallet := range wallets {
+		resp.Wallets = append(resp.Wallets, &pb.Wallet{Currency: wallet.Currency, AmountMinor: int64(wallet.Amount)})
+	}
+	return resp, nil
+}
+
+// Пополнение. Нарушение лимитов возвращается ошибкой grpc с подробностями(см. violationError)
+func (s *server) AddBalance(ctx context.Context, in *pb.Money) (*pb.Status, error) {
+	return idempotency.Do(ctx, s.keys, "balance/AddBalance", requestUser(in.Id), in.IdempotencyKey, in, func() (*pb.Status, error) {
+		op := models.Operation{UserID: uuid.UUID(in.Id), Kind: models.EntryDeposit, Currency: currencyOf(in.Currency), Amount: cash(in)}
+		err := s.rules.Apply(ctx, op, func() error { return s.db.Deposit(op.UserID, op.Currency, op.Amount) })
+		var violation *problems.Violation
+		switch {
+		case errors.As(err, &violation):
+			return nil, violationError(violation)
+		case errors.Is(err, problems.ErrNoCurrency):
+			return &pb.Status{Response: problems.NoCurrency}, nil
+		case err != nil:
+			return nil, err
+		}
+		return nil, nil
+	})
+}
+
+// Вывод. Нарушение лимитов возвращается ошибкой grpc с подробностями(см. violationError)
+func (s *server) TakeBalance(ctx context.Context, in *pb.Money) (*pb.Status, error) {
+	if in.ReplayOnly {
+		// Ответ ищется по тому же запросу, с которым он сохранен
+		req := proto.Clone(in).(*pb.Money)
+		req.ReplayOnly = false
+		return idempotency.Replay[*pb.Status](ctx, s.keys, "balance/TakeBalance", requestUser(in.Id), in.IdempotencyKey, req)
+	}
+	return idempotency.Do(ctx, s.keys, "balance/TakeBalance", requestUser(in.Id), in.IdempotencyKey, in, func() (*pb.Status, error) {
+		op := models.Operation{UserID: uuid.UUID(in.Id), Kind: models.EntryWithdrawal, Currency: currencyOf(in.Currency), Amount: cash(in)}
+		err := s.rules.Apply(ctx, op, func() error { return s.db.Withdraw(op.UserID, op.Currency, op.Amount) })
+		var violation *problems.Violation
+		switch {
+		case errors.As(err, &violation):
+			return nil, violationError(violation)
+		case errors.Is(err, problems.ErrLowBalance), errors.Is(err, problems.ErrNoCurrency):
+			return &pb.Status{Response: err.Error()}, nil
+		case err != nil:
+			return nil, err
+		}
+		return nil, nil
+	})
+}
+
+// Пользователь запроса для ключа идемпотентности. Неверный id - нулевой пользователь, такой запрос отклонит сам метод
+func requestUser(id []byte) uuid.UUID {
+	userID, _ := uuid.FromBytes(id)
+	return userID
 }
 
-func (s *server) AddBalance(_ context.Context, in *pb.Money) (*pb.Status, error) {
-	err := s.db.ChangeBalance(uuid.UUID(in.Id), in.Cash)
+// Нарушение лимитов в ошибке grpc: InvalidArgument для неверной суммы, ResourceExhausted для исчерпанных лимитов
+// и перерыва. В подробностях ErrorInfo с правилом в Reason и границей, остатком лимита и временем ожидания(в секундах)
+// в Metadata, для перерыва еще RetryInfo
+func violationError(v *problems.Violation) error {
+	code := codes.InvalidArgument
+	if v.Rule == problems.RuleDailyCap || v.Rule == problems.RuleMonthlyCap || v.Rule == problems.RuleCooldown {
+		code = codes.ResourceExhausted
+	}
+	info := &errdetails.ErrorInfo{Reason: v.Rule, Domain: "balance", Metadata: map[string]string{"operation": v.Operation}}
+	if v.Limit > 0 {
+		info.Metadata["limit"] = v.Limit.String()
+	}
+	if v.Rule == problems.RuleDailyCap || v.Rule == problems.RuleMonthlyCap {
+		info.Metadata["remaining"] = v.Remaining.String()
+	}
+	details := []protoadapt.MessageV1{info}
+	if v.RetryAfter > 0 {
+		info.Metadata["retry_after"] = strconv.Itoa(int(math.Ceil(v.RetryAfter.Seconds())))
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(v.RetryAfter)})
+	}
+	st, err := status.New(code, v.Error()).WithDetails(details...)
 	if err != nil {
+		log.Println("Cant attach violation details:", err)
+		return status.Error(code, v.Error())
+	}
+	return st.Err()
+}
+
+// Обмен денег пользователя между его кошельками по текущему курсу
+func (s *server) Exchange(ctx context.Context, in *pb.ExchangeRequest) (*pb.ExchangeResult, error) {
+	return idempotency.Do(ctx, s.keys, "balance/Exchange", requestUser(in.UserId), in.IdempotencyKey, in, func() (*pb.ExchangeResult, error) {
+		return s.exchange(ctx, in)
+	})
+}
+
+func (s *server) exchange(ctx context.Context, in *pb.ExchangeRequest) (*pb.ExchangeResult, error) {
+	userID, err := uuid.FromBytes(in.UserId)
+	if err != nil {
+		log.Println("Cant get uuid from given bytes:", err)
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	if in.AmountMinor <= 0 {
+		return &pb.ExchangeResult{Response: problems.BadCash}, nil
+	}
+	ex := models.Exchange{UserID: userID, From: currencyOf(in.From), To: currencyOf(in.To), Amount: money.Amount(in.AmountMinor)}
+	ex, err = s.db.Exchange(ctx, ex)
+	switch {
+	case errors.Is(err, problems.ErrLowBalance), errors.Is(err, problems.ErrNoCurrency),
+		errors.Is(err, problems.ErrSameCurrency), errors.Is(err, problems.ErrBadCash):
+		return &pb.ExchangeResult{Response: err.Error()}, nil
+	case err != nil:
+		log.Println("Cant exchange money:", err)
 		return nil, err
 	}
-	return nil, nil
+	log.Printf("User %v exchanged %v %v to %v %v\n", ex.UserID, ex.Amount, ex.From, ex.Received, ex.To)
+	return &pb.ExchangeResult{ReceivedMinor: int64(ex.Received), Rate: ex.Rate}, nil
+}
+
+// Резервирует деньги пользователя(или market) под сделку или заявку papers
+func (s *server) Reserve(ctx context.Context, in *pb.HoldRequest) (*pb.Hold, error) {
+	return idempotency.Do(ctx, s.keys, "balance/Reserve", requestUser(in.UserId), in.IdempotencyKey, in, func() (*pb.Hold, error) {
+		userID, err := optionalID(in.UserId)
+		if err != nil {
+			return nil, err
+		}
+		reference, err := optionalID(in.Reference)
+		if err != nil {
+			return nil, err
+		}
+		if in.AmountMinor <= 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", problems.BadCash)
+		}
+		holdID, err := s.db.Reserve(ctx, userID, currencyOf(in.Currency), money.Amount(in.AmountMinor), nullable(reference))
+		if errors.Is(err, problems.ErrLowBalance) {
+			return &pb.Hold{Response: problems.LowBalance}, nil
+		}
+		if err != nil {
+			return nil, holdError(err)
+		}
+		return &pb.Hold{Id: holdID[:]}, nil
+	})
 }
 
-func (s *server) TakeBalance(_ context.Context, in *pb.Money) (*pb.Status, error) {
-	userBalance, err := s.db.GetUserBalance(uuid.UUID(in.Id))
+// Списывает из резерва выплаты продавцу, market или в комиссию
+func (s *server) Commit(ctx context.Context, in *pb.CommitRequest) (*pb.Status, error) {
+	// Списывает и возвращает резервы только papers, ключ - id его расчета, поэтому ключ не привязан к пользователю
+	return idempotency.Do(ctx, s.keys, "balance/Commit", uuid.Nil, in.IdempotencyKey, in, func() (*pb.Status, error) {
+		holdID, err := uuid.FromBytes(in.HoldId)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+		reference, err := optionalID(in.Reference)
+		if err != nil {
+			return nil, err
+		}
+		payouts := make([]models.Payout, 0, len(in.Payouts))
+		for _, payout := range in.Payouts {
+			userID, err := optionalID(payout.UserId)
+			if err != nil {
+				return nil, err
+			}
+			payouts = append(payouts, models.Payout{UserID: userID, Account: payout.Account, Amount: money.Amount(payout.AmountMinor)})
+		}
+		if err := s.db.Commit(ctx, holdID, payouts, nullable(reference)); err != nil {
+			return nil, holdError(err)
+		}
+		return &pb.Status{}, nil
+	})
+}
+
+// Возвращает владельцу часть резерва или весь остаток
+func (s *server) Release(ctx context.Context, in *pb.ReleaseRequest) (*pb.Status, error) {
+	return idempotency.Do(ctx, s.keys, "balance/Release", uuid.Nil, in.IdempotencyKey, in, func() (*pb.Status, error) {
+		holdID, err := uuid.FromBytes(in.HoldId)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+		if in.AmountMinor < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", problems.BadCash)
+		}
+		if err := s.db.Release(ctx, holdID, money.Amount(in.AmountMinor)); err != nil {
+			return nil, holdError(err)
+		}
+		return &pb.Status{}, nil
+	})
+}
+
+// История операций пользователя от новых к старым с фильтром по видам операций и времени(unix секунды, to не включительно).
+// Для следующей страницы нужно передать nextCursor из ответа
+func (s *server) GetTransactions(ctx context.Context, req *pb.TransactionsRequest) (*pb.Transactions, error) {
+	userID, err := uuid.FromBytes(req.UserId)
 	if err != nil {
-		log.Println("Cant get user balance:", err)
+		log.Println("Cant get uuid from given bytes:", err)
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	for _, kind := range req.Types {
+		if !slices.Contains(TransactionTypes, kind) {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown transaction type %q", kind)
+		}
+	}
+	filter := models.TransactionFilter{UserID: userID, Currency: req.Currency, Types: req.Types, Limit: int(req.Limit)}
+	if req.From > 0 {
+		filter.From = time.Unix(req.From, 0)
+	}
+	if req.To > 0 {
+		filter.To = time.Unix(req.To, 0)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultHistoryLimit
+	}
+	filter.Limit = min(filter.Limit, MaxHistoryLimit)
+	if req.Cursor != "" {
+		filter.After, err = decodeCursor(req.Cursor)
+		if err != nil {
+			log.Println("Cant decode transactions cursor:", err)
+			return nil, status.Errorf(codes.InvalidArgument, "invalid cursor")
+		}
+	}
+	// Запрашиваем на одну операцию больше, чтобы понять, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit++
+	transactions, err := s.db.GetTransactions(ctx, filter)
+	if err != nil {
+		log.Println("Cant get user transactions:", err)
 		return nil, err
 	}
-	if in.Cash > userBalance {
-		return &pb.Status{Response: problems.LowBalance}, nil
+	history := &pb.Transactions{Transactions: make([]*pb.Transaction, 0, min(len(transactions), limit))}
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+		history.NextCursor = encodeCursor(transactions[limit-1])
+	}
+	for _, t := range transactions {
+		transaction := &pb.Transaction{
+			EntryId:      t.EntryID[:],
+			Type:         t.Type,
+			AmountMinor:  int64(t.Amount),
+			BalanceMinor: int64(t.Balance),
+			CreatedAt:    t.CreatedAt.Unix(),
+			Memo:         t.Memo,
+			Currency:     t.Currency,
+		}
+		if t.Reference != nil {
+			transaction.Reference = t.Reference[:]
+		}
+		history.Transactions = append(history.Transactions, transaction)
+	}
+	return history, nil
+}
+
+// Перевод денег другому пользователю по логину. Отказ(нет получателя, перевод себе, не хватает денег, превышен лимит)
+// возвращается в response
+func (s *server) Transfer(ctx context.Context, in *pb.TransferRequest) (*pb.TransferResult, error) {
+	if in.ReplayOnly {
+		req := proto.Clone(in).(*pb.TransferRequest)
+		req.ReplayOnly = false
+		return idempotency.Replay[*pb.TransferResult](ctx, s.keys, "balance/Transfer", requestUser(in.FromId), in.IdempotencyKey, req)
+	}
+	return idempotency.Do(ctx, s.keys, "balance/Transfer", requestUser(in.FromId), in.IdempotencyKey, in, func() (*pb.TransferResult, error) {
+		return s.transfer(ctx, in)
+	})
+}
+
+func (s *server) transfer(ctx context.Context, in *pb.TransferRequest) (*pb.TransferResult, error) {
+	fromID, err := uuid.FromBytes(in.FromId)
+	if err != nil {
+		log.Println("Cant get uuid from given bytes:", err)
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	transfer := models.Transfer{
+		From: fromID, ToLogin: in.ToLogin, Currency: currencyOf(in.Currency), Amount: money.Amount(in.AmountMinor), Memo: strings.TrimSpace(in.Memo),
+	}
+	switch {
+	case transfer.Amount <= 0:
+		return &pb.TransferResult{Response: problems.BadCash}, nil
+	case utf8.RuneCountInString(transfer.Memo) > MaxMemoLength:
+		return &pb.TransferResult{Response: problems.LongMemo}, nil
+	}
+	transfer, err = s.db.Transfer(ctx, transfer, money.FromFloat(s.cfg.TransferLimit), money.FromFloat(s.cfg.TransferDailyLimit))
+	switch {
+	case errors.Is(err, problems.ErrNoRecipient), errors.Is(err, problems.ErrSelfTransfer), errors.Is(err, problems.ErrNoCurrency),
+		errors.Is(err, problems.ErrLowBalance), errors.Is(err, problems.ErrOverLimit):
+		return &pb.TransferResult{Response: err.Error()}, nil
+	case err != nil:
+		log.Println("Cant transfer money:", err)
+		return nil, err
 	}
-	err = s.db.ChangeBalance(uuid.UUID(in.Id), -in.Cash)
+	log.Printf("User %v transferred %v %v to %v\n", transfer.From, transfer.Amount, transfer.Currency, transfer.To)
+	return &pb.TransferResult{Id: transfer.ID[:], ToId: transfer.To[:], FromLogin: transfer.FromLogin}, nil
+}
+
+// Курсор - номер записи журнала и операции внутри нее
+func encodeCursor(t models.Transaction) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", t.Seq, t.Part)))
+}
+
+func decodeCursor(cursor string) (*models.Transaction, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
 		return nil, err
 	}
-	return nil, nil
+	seq, part, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, errors.New("cursor has no separator")
+	}
+	t := &models.Transaction{}
+	if t.Seq, err = strconv.ParseInt(seq, 10, 64); err != nil {
+		return nil, err
+	}
+	if t.Part, err = strconv.Atoi(part); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Переводит ошибки резервов в коды grpc
+func holdError(err error) error {
+	switch {
+	case errors.Is(err, problems.ErrNoHold):
+		return status.Errorf(codes.NotFound, "%v", err)
+	case errors.Is(err, problems.ErrHoldExceeded):
+		return status.Errorf(codes.FailedPrecondition, "%v", err)
+	case errors.Is(err, problems.ErrBadCash), errors.Is(err, problems.ErrBadPayout), errors.Is(err, problems.ErrUnbalanced),
+		errors.Is(err, problems.ErrNoCurrency):
+		return status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	log.Println("Hold error:", err)
+	return err
+}
+
+// Необязательный id из запроса: пустой - нулевой uuid(системный счет, нет ссылки)
+func optionalID(id []byte) (uuid.UUID, error) {
+	if len(id) == 0 {
+		return uuid.Nil, nil
+	}
+	parsed, err := uuid.FromBytes(id)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	return parsed, nil
+}
+
+func nullable(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
+
+// Валюта запроса, пустая - базовая
+func currencyOf(currency string) string {
+	if currency == "" {
+		return models.BaseCurrency
+	}
+	return strings.ToUpper(currency)
+}
+
+// Сумма запроса в копейках. Старые клиенты заполняют только float поле
+func cash(in *pb.Money) money.Amount {
+	if in.CashMinor != 0 {
+		return money.Amount(in.CashMinor)
+	}
+	return money.FromFloat(float64(in.Cash))
+}
+
+// Периодически проверяет инварианты журнала и пишет в лог найденные расхождения
+func (s *Service) WatchLedger() {
+	for range time.Tick(time.Duration(s.cfg.CheckInterval) * time.Second) {
+		drifts, err := s.db.CheckLedger(context.Background())
+		if err != nil {
+			log.Println("Ledger check failed:", err)
+			continue
+		}
+		for _, drift := range drifts {
+			log.Printf("Ledger drift: %v %v expected %v, got %v\n", drift.Kind, drift.ID, drift.Expected, drift.Actual)
+		}
+	}
 }
@@ -2,9 +2,11 @@ package main
 
 import (
 	"gateway/internal/pkg/jwt"
+	"gateway/internal/redis"
 	rtr "gateway/internal/router"
 	aus "gateway/internal/services/authService"
 	balance "gateway/internal/services/balanceService"
+	market "gateway/internal/services/marketService"
 	pps "gateway/internal/services/ppsService"
 	"log"
 
@@ -17,6 +19,8 @@ type Config struct {
 	PPSConfig     *pps.Config     `yaml:"pps"`
 	RTRConfig     *rtr.Config     `yaml:"rtr" env-prefix:"RTR_"`
 	BalanceConfig *balance.Config `yaml:"balance" env-prefix:"BALANCE_"`
+	MarketConfig  *market.Config  `yaml:"market" env-prefix:"MARKET_"`
+	RDSConfig     *redis.Config   `yaml:"rds"`
 }
 
 func readConfig(filename string) (*Config, error) {
@@ -33,15 +37,19 @@ func main() {
 		log.Fatalln(err)
 	}
 	log.Println("Config file read successfully")
-	jwt, err := jwt.New(cfg.JWTConfig)
-	if err != nil {
-		log.Fatalln("Failed to create jwt: " + err.Error())
-	}
 	authService, err := aus.New(cfg.AUSConfig)
 	if err != nil {
 		log.Fatalln(err)
 	}
 	log.Println("Auth service connected successfully")
+	rds, err := redis.New(cfg.RDSConfig)
+	if err != nil {
+		log.Fatalln("Failed to connect to redis:", err.Error())
+	}
+	jwt, err := jwt.New(cfg.JWTConfig, authService, rds)
+	if err != nil {
+		log.Fatalln("Failed to create jwt: " + err.Error())
+	}
 	ppsService, err := pps.New(cfg.PPSConfig)
 	if err != nil {
 		log.Fatalln(err)
@@ -51,7 +59,11 @@ func main() {
 	if err != nil {
 		log.Fatalln("Failed to connect to balance service:", err.Error())
 	}
-	router, err := rtr.New(cfg.RTRConfig, authService, ppsService, balanceService, &jwt)
+	marketService, err := market.New(cfg.MarketConfig)
+	if err != nil {
+		log.Fatalln("Failed to connect to market:", err.Error())
+	}
+	router, err := rtr.New(cfg.RTRConfig, authService, ppsService, balanceService, marketService, jwt, rds)
 	if err != nil {
 		log.Fatalln("Failed to host router:", err.Error())
 	}
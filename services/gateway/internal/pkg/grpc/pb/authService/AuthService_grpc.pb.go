@@ -19,20 +19,43 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Authentification_GetPrivateKey_FullMethodName = "/AuthService.Authentification/GetPrivateKey"
-	Authentification_Register_FullMethodName      = "/AuthService.Authentification/Register"
-	Authentification_Login_FullMethodName         = "/AuthService.Authentification/Login"
-	Authentification_UpdateTokens_FullMethodName  = "/AuthService.Authentification/UpdateTokens"
+	Authentification_GetJWKS_FullMethodName      = "/AuthService.Authentification/GetJWKS"
+	Authentification_Register_FullMethodName     = "/AuthService.Authentification/Register"
+	Authentification_Login_FullMethodName        = "/AuthService.Authentification/Login"
+	Authentification_UpdateTokens_FullMethodName = "/AuthService.Authentification/UpdateTokens"
+	Authentification_GetSessions_FullMethodName  = "/AuthService.Authentification/GetSessions"
+	Authentification_Logout_FullMethodName       = "/AuthService.Authentification/Logout"
+	Authentification_LogoutAll_FullMethodName    = "/AuthService.Authentification/LogoutAll"
+	Authentification_EnrollTOTP_FullMethodName   = "/AuthService.Authentification/EnrollTOTP"
+	Authentification_ActivateTOTP_FullMethodName = "/AuthService.Authentification/ActivateTOTP"
+	Authentification_LoginTOTP_FullMethodName    = "/AuthService.Authentification/LoginTOTP"
+	Authentification_VerifyTOTP_FullMethodName   = "/AuthService.Authentification/VerifyTOTP"
 )
 
 // AuthentificationClient is the client API for Authentification service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type AuthentificationClient interface {
-	GetPrivateKey(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*PrivateKey, error)
+	// Публичные ключи проверки токенов в формате JWKS. Приватные ключи не покидают aus
+	GetJWKS(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*JWKS, error)
 	Register(ctx context.Context, in *User, opts ...grpc.CallOption) (*AuthData, error)
 	Login(ctx context.Context, in *User, opts ...grpc.CallOption) (*AuthData, error)
+	// Обменивает refresh токен на новую пару. Каждый refresh токен используется один раз: повторное предъявление
+	// уже обмененного токена отзывает всю сессию(семью токенов) и возвращает Unauthenticated
 	UpdateTokens(ctx context.Context, in *AuthData, opts ...grpc.CallOption) (*AuthData, error)
+	GetSessions(ctx context.Context, in *SessionsRequest, opts ...grpc.CallOption) (*Sessions, error)
+	// Выход: Logout завершает сессию access токена, LogoutAll - все сессии пользователя. Refresh токены сессий
+	// отзываются, их access токены запрещаются до истечения
+	Logout(ctx context.Context, in *LogoutRequest, opts ...grpc.CallOption) (*LogoutResult, error)
+	LogoutAll(ctx context.Context, in *LogoutRequest, opts ...grpc.CallOption) (*LogoutResult, error)
+	// Двухфакторная аутентификация TOTP(RFC 6238). EnrollTOTP выдает секрет и provisioning URI для приложения,
+	// ActivateTOTP включает 2FA первым кодом из приложения и возвращает коды восстановления(они показываются один раз)
+	EnrollTOTP(ctx context.Context, in *TOTPRequest, opts ...grpc.CallOption) (*TOTPEnrollment, error)
+	ActivateTOTP(ctx context.Context, in *TOTPRequest, opts ...grpc.CallOption) (*RecoveryCodes, error)
+	// Второй шаг входа с 2FA: challenge_token из Login и код из приложения или код восстановления
+	LoginTOTP(ctx context.Context, in *TOTPLogin, opts ...grpc.CallOption) (*AuthData, error)
+	// Проверка кода приложения для подтверждения операции. Без включенной 2FA - FailedPrecondition
+	VerifyTOTP(ctx context.Context, in *TOTPRequest, opts ...grpc.CallOption) (*TOTPResult, error)
 }
 
 type authentificationClient struct {
@@ -43,10 +66,10 @@ func NewAuthentificationClient(cc grpc.ClientConnInterface) AuthentificationClie
 	return &authentificationClient{cc}
 }
 
-func (c *authentificationClient) GetPrivateKey(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*PrivateKey, error) {
+func (c *authentificationClient) GetJWKS(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*JWKS, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(PrivateKey)
-	err := c.cc.Invoke(ctx, Authentification_GetPrivateKey_FullMethodName, in, out, cOpts...)
+	out := new(JWKS)
+	err := c.cc.Invoke(ctx, Authentification_GetJWKS_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -83,14 +106,100 @@ func (c *authentificationClient) UpdateTokens(ctx context.Context, in *AuthData,
 	return out, nil
 }
 
+func (c *authentificationClient) GetSessions(ctx context.Context, in *SessionsRequest, opts ...grpc.CallOption) (*Sessions, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Sessions)
+	err := c.cc.Invoke(ctx, Authentification_GetSessions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authentificationClient) Logout(ctx context.Context, in *LogoutRequest, opts ...grpc.CallOption) (*LogoutResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LogoutResult)
+	err := c.cc.Invoke(ctx, Authentification_Logout_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authentificationClient) LogoutAll(ctx context.Context, in *LogoutRequest, opts ...grpc.CallOption) (*LogoutResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LogoutResult)
+	err := c.cc.Invoke(ctx, Authentification_LogoutAll_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authentificationClient) EnrollTOTP(ctx context.Context, in *TOTPRequest, opts ...grpc.CallOption) (*TOTPEnrollment, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TOTPEnrollment)
+	err := c.cc.Invoke(ctx, Authentification_EnrollTOTP_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authentificationClient) ActivateTOTP(ctx context.Context, in *TOTPRequest, opts ...grpc.CallOption) (*RecoveryCodes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecoveryCodes)
+	err := c.cc.Invoke(ctx, Authentification_ActivateTOTP_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authentificationClient) LoginTOTP(ctx context.Context, in *TOTPLogin, opts ...grpc.CallOption) (*AuthData, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuthData)
+	err := c.cc.Invoke(ctx, Authentification_LoginTOTP_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authentificationClient) VerifyTOTP(ctx context.Context, in *TOTPRequest, opts ...grpc.CallOption) (*TOTPResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TOTPResult)
+	err := c.cc.Invoke(ctx, Authentification_VerifyTOTP_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AuthentificationServer is the server API for Authentification service.
 // All implementations must embed UnimplementedAuthentificationServer
 // for forward compatibility.
 type AuthentificationServer interface {
-	GetPrivateKey(context.Context, *KeyRequest) (*PrivateKey, error)
+	// Публичные ключи проверки токенов в формате JWKS. Приватные ключи не покидают aus
+	GetJWKS(context.Context, *KeyRequest) (*JWKS, error)
 	Register(context.Context, *User) (*AuthData, error)
 	Login(context.Context, *User) (*AuthData, error)
+	// Обменивает refresh токен на новую пару. Каждый refresh токен используется один раз: повторное предъявление
+	// уже обмененного токена отзывает всю сессию(семью токенов) и возвращает Unauthenticated
 	UpdateTokens(context.Context, *AuthData) (*AuthData, error)
+	GetSessions(context.Context, *SessionsRequest) (*Sessions, error)
+	// Выход: Logout завершает сессию access токена, LogoutAll - все сессии пользователя. Refresh токены сессий
+	// отзываются, их access токены запрещаются до истечения
+	Logout(context.Context, *LogoutRequest) (*LogoutResult, error)
+	LogoutAll(context.Context, *LogoutRequest) (*LogoutResult, error)
+	// Двухфакторная аутентификация TOTP(RFC 6238). EnrollTOTP выдает секрет и provisioning URI для приложения,
+	// ActivateTOTP включает 2FA первым кодом из приложения и возвращает коды восстановления(они показываются один раз)
+	EnrollTOTP(context.Context, *TOTPRequest) (*TOTPEnrollment, error)
+	ActivateTOTP(context.Context, *TOTPRequest) (*RecoveryCodes, error)
+	// Второй шаг входа с 2FA: challenge_token из Login и код из приложения или код восстановления
+	LoginTOTP(context.Context, *TOTPLogin) (*AuthData, error)
+	// Проверка кода приложения для подтверждения операции. Без включенной 2FA - FailedPrecondition
+	VerifyTOTP(context.Context, *TOTPRequest) (*TOTPResult, error)
 	mustEmbedUnimplementedAuthentificationServer()
 }
 
@@ -101,8 +210,8 @@ type AuthentificationServer interface {
 // pointer dereference when methods are called.
 type UnimplementedAuthentificationServer struct{}
 
-func (UnimplementedAuthentificationServer) GetPrivateKey(context.Context, *KeyRequest) (*PrivateKey, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetPrivateKey not implemented")
+func (UnimplementedAuthentificationServer) GetJWKS(context.Context, *KeyRequest) (*JWKS, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJWKS not implemented")
 }
 func (UnimplementedAuthentificationServer) Register(context.Context, *User) (*AuthData, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
@@ -113,6 +222,27 @@ func (UnimplementedAuthentificationServer) Login(context.Context, *User) (*AuthD
 func (UnimplementedAuthentificationServer) UpdateTokens(context.Context, *AuthData) (*AuthData, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateTokens not implemented")
 }
+func (UnimplementedAuthentificationServer) GetSessions(context.Context, *SessionsRequest) (*Sessions, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSessions not implemented")
+}
+func (UnimplementedAuthentificationServer) Logout(context.Context, *LogoutRequest) (*LogoutResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Logout not implemented")
+}
+func (UnimplementedAuthentificationServer) LogoutAll(context.Context, *LogoutRequest) (*LogoutResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LogoutAll not implemented")
+}
+func (UnimplementedAuthentificationServer) EnrollTOTP(context.Context, *TOTPRequest) (*TOTPEnrollment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EnrollTOTP not implemented")
+}
+func (UnimplementedAuthentificationServer) ActivateTOTP(context.Context, *TOTPRequest) (*RecoveryCodes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ActivateTOTP not implemented")
+}
+func (UnimplementedAuthentificationServer) LoginTOTP(context.Context, *TOTPLogin) (*AuthData, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LoginTOTP not implemented")
+}
+func (UnimplementedAuthentificationServer) VerifyTOTP(context.Context, *TOTPRequest) (*TOTPResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyTOTP not implemented")
+}
 func (UnimplementedAuthentificationServer) mustEmbedUnimplementedAuthentificationServer() {}
 func (UnimplementedAuthentificationServer) testEmbeddedByValue()                          {}
 
@@ -134,20 +264,20 @@ func RegisterAuthentificationServer(s grpc.ServiceRegistrar, srv Authentificatio
 	s.RegisterService(&Authentification_ServiceDesc, srv)
 }
 
-func _Authentification_GetPrivateKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+func _Authentification_GetJWKS_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(KeyRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AuthentificationServer).GetPrivateKey(ctx, in)
+		return srv.(AuthentificationServer).GetJWKS(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: Authentification_GetPrivateKey_FullMethodName,
+		FullMethod: Authentification_GetJWKS_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AuthentificationServer).GetPrivateKey(ctx, req.(*KeyRequest))
+		return srv.(AuthentificationServer).GetJWKS(ctx, req.(*KeyRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -206,6 +336,132 @@ func _Authentification_UpdateTokens_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Authentification_GetSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthentificationServer).GetSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authentification_GetSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthentificationServer).GetSessions(ctx, req.(*SessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentification_Logout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LogoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthentificationServer).Logout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authentification_Logout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthentificationServer).Logout(ctx, req.(*LogoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentification_LogoutAll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LogoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthentificationServer).LogoutAll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authentification_LogoutAll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthentificationServer).LogoutAll(ctx, req.(*LogoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentification_EnrollTOTP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TOTPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthentificationServer).EnrollTOTP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authentification_EnrollTOTP_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthentificationServer).EnrollTOTP(ctx, req.(*TOTPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentification_ActivateTOTP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TOTPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthentificationServer).ActivateTOTP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authentification_ActivateTOTP_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthentificationServer).ActivateTOTP(ctx, req.(*TOTPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentification_LoginTOTP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TOTPLogin)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthentificationServer).LoginTOTP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authentification_LoginTOTP_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthentificationServer).LoginTOTP(ctx, req.(*TOTPLogin))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentification_VerifyTOTP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TOTPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthentificationServer).VerifyTOTP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Authentification_VerifyTOTP_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthentificationServer).VerifyTOTP(ctx, req.(*TOTPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Authentification_ServiceDesc is the grpc.ServiceDesc for Authentification service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -214,8 +470,8 @@ var Authentification_ServiceDesc = grpc.ServiceDesc{
 	HandlerType: (*AuthentificationServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{
-			MethodName: "GetPrivateKey",
-			Handler:    _Authentification_GetPrivateKey_Handler,
+			MethodName: "GetJWKS",
+			Handler:    _Authentification_GetJWKS_Handler,
 		},
 		{
 			MethodName: "Register",
@@ -229,6 +485,34 @@ var Authentification_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateTokens",
 			Handler:    _Authentification_UpdateTokens_Handler,
 		},
+		{
+			MethodName: "GetSessions",
+			Handler:    _Authentification_GetSessions_Handler,
+		},
+		{
+			MethodName: "Logout",
+			Handler:    _Authentification_Logout_Handler,
+		},
+		{
+			MethodName: "LogoutAll",
+			Handler:    _Authentification_LogoutAll_Handler,
+		},
+		{
+			MethodName: "EnrollTOTP",
+			Handler:    _Authentification_EnrollTOTP_Handler,
+		},
+		{
+			MethodName: "ActivateTOTP",
+			Handler:    _Authentification_ActivateTOTP_Handler,
+		},
+		{
+			MethodName: "LoginTOTP",
+			Handler:    _Authentification_LoginTOTP_Handler,
+		},
+		{
+			MethodName: "VerifyTOTP",
+			Handler:    _Authentification_VerifyTOTP_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "AuthService.proto",
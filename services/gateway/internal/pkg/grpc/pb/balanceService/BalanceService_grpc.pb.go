@@ -19,9 +19,18 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	BalanceManagement_GetBalance_FullMethodName  = "/BalanceService.BalanceManagement/GetBalance"
-	BalanceManagement_AddBalance_FullMethodName  = "/BalanceService.BalanceManagement/AddBalance"
-	BalanceManagement_TakeBalance_FullMethodName = "/BalanceService.BalanceManagement/TakeBalance"
+	BalanceManagement_GetBalance_FullMethodName      = "/BalanceService.BalanceManagement/GetBalance"
+	BalanceManagement_AddBalance_FullMethodName      = "/BalanceService.BalanceManagement/AddBalance"
+	BalanceManagement_TakeBalance_FullMethodName     = "/BalanceService.BalanceManagement/TakeBalance"
+	BalanceManagement_Reserve_FullMethodName         = "/BalanceService.BalanceManagement/Reserve"
+	BalanceManagement_Commit_FullMethodName          = "/BalanceService.BalanceManagement/Commit"
+	BalanceManagement_Release_FullMethodName         = "/BalanceService.BalanceManagement/Release"
+	BalanceManagement_GetTransactions_FullMethodName = "/BalanceService.BalanceManagement/GetTransactions"
+	BalanceManagement_Transfer_FullMethodName        = "/BalanceService.BalanceManagement/Transfer"
+	BalanceManagement_Exchange_FullMethodName        = "/BalanceService.BalanceManagement/Exchange"
+	BalanceManagement_CreatePayment_FullMethodName   = "/BalanceService.BalanceManagement/CreatePayment"
+	BalanceManagement_ConfirmPayment_FullMethodName  = "/BalanceService.BalanceManagement/ConfirmPayment"
+	BalanceManagement_GetPayments_FullMethodName     = "/BalanceService.BalanceManagement/GetPayments"
 )
 
 // BalanceManagementClient is the client API for BalanceManagement service.
@@ -31,6 +40,19 @@ type BalanceManagementClient interface {
 	GetBalance(ctx context.Context, in *User, opts ...grpc.CallOption) (*Balance, error)
 	AddBalance(ctx context.Context, in *Money, opts ...grpc.CallOption) (*Status, error)
 	TakeBalance(ctx context.Context, in *Money, opts ...grpc.CallOption) (*Status, error)
+	// Деньги сделок и заявок papers проходят через резерв: он ставится до сделки, списывается после нее
+	// или возвращается, если сделка не состоялась
+	Reserve(ctx context.Context, in *HoldRequest, opts ...grpc.CallOption) (*Hold, error)
+	Commit(ctx context.Context, in *CommitRequest, opts ...grpc.CallOption) (*Status, error)
+	Release(ctx context.Context, in *ReleaseRequest, opts ...grpc.CallOption) (*Status, error)
+	GetTransactions(ctx context.Context, in *TransactionsRequest, opts ...grpc.CallOption) (*Transactions, error)
+	Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*TransferResult, error)
+	Exchange(ctx context.Context, in *ExchangeRequest, opts ...grpc.CallOption) (*ExchangeResult, error)
+	// Пополнение через платежного провайдера: платеж создается в статусе pending, деньги зачисляются только
+	// по подписанному callback провайдера, который gateway передает в ConfirmPayment
+	CreatePayment(ctx context.Context, in *PaymentRequest, opts ...grpc.CallOption) (*Payment, error)
+	ConfirmPayment(ctx context.Context, in *PaymentCallback, opts ...grpc.CallOption) (*Payment, error)
+	GetPayments(ctx context.Context, in *PaymentsRequest, opts ...grpc.CallOption) (*Payments, error)
 }
 
 type balanceManagementClient struct {
@@ -71,6 +93,96 @@ func (c *balanceManagementClient) TakeBalance(ctx context.Context, in *Money, op
 	return out, nil
 }
 
+func (c *balanceManagementClient) Reserve(ctx context.Context, in *HoldRequest, opts ...grpc.CallOption) (*Hold, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Hold)
+	err := c.cc.Invoke(ctx, BalanceManagement_Reserve_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *balanceManagementClient) Commit(ctx context.Context, in *CommitRequest, opts ...grpc.CallOption) (*Status, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Status)
+	err := c.cc.Invoke(ctx, BalanceManagement_Commit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *balanceManagementClient) Release(ctx context.Context, in *ReleaseRequest, opts ...grpc.CallOption) (*Status, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Status)
+	err := c.cc.Invoke(ctx, BalanceManagement_Release_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *balanceManagementClient) GetTransactions(ctx context.Context, in *TransactionsRequest, opts ...grpc.CallOption) (*Transactions, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Transactions)
+	err := c.cc.Invoke(ctx, BalanceManagement_GetTransactions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *balanceManagementClient) Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*TransferResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TransferResult)
+	err := c.cc.Invoke(ctx, BalanceManagement_Transfer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *balanceManagementClient) Exchange(ctx context.Context, in *ExchangeRequest, opts ...grpc.CallOption) (*ExchangeResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExchangeResult)
+	err := c.cc.Invoke(ctx, BalanceManagement_Exchange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *balanceManagementClient) CreatePayment(ctx context.Context, in *PaymentRequest, opts ...grpc.CallOption) (*Payment, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Payment)
+	err := c.cc.Invoke(ctx, BalanceManagement_CreatePayment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *balanceManagementClient) ConfirmPayment(ctx context.Context, in *PaymentCallback, opts ...grpc.CallOption) (*Payment, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Payment)
+	err := c.cc.Invoke(ctx, BalanceManagement_ConfirmPayment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *balanceManagementClient) GetPayments(ctx context.Context, in *PaymentsRequest, opts ...grpc.CallOption) (*Payments, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Payments)
+	err := c.cc.Invoke(ctx, BalanceManagement_GetPayments_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BalanceManagementServer is the server API for BalanceManagement service.
 // All implementations must embed UnimplementedBalanceManagementServer
 // for forward compatibility.
@@ -78,6 +190,19 @@ type BalanceManagementServer interface {
 	GetBalance(context.Context, *User) (*Balance, error)
 	AddBalance(context.Context, *Money) (*Status, error)
 	TakeBalance(context.Context, *Money) (*Status, error)
+	// Деньги сделок и заявок papers проходят через резерв: он ставится до сделки, списывается после нее
+	// или возвращается, если сделка не состоялась
+	Reserve(context.Context, *HoldRequest) (*Hold, error)
+	Commit(context.Context, *CommitRequest) (*Status, error)
+	Release(context.Context, *ReleaseRequest) (*Status, error)
+	GetTransactions(context.Context, *TransactionsRequest) (*Transactions, error)
+	Transfer(context.Context, *TransferRequest) (*TransferResult, error)
+	Exchange(context.Context, *ExchangeRequest) (*ExchangeResult, error)
+	// Пополнение через платежного провайдера: платеж создается в статусе pending, деньги зачисляются только
+	// по подписанному callback провайдера, который gateway передает в ConfirmPayment
+	CreatePayment(context.Context, *PaymentRequest) (*Payment, error)
+	ConfirmPayment(context.Context, *PaymentCallback) (*Payment, error)
+	GetPayments(context.Context, *PaymentsRequest) (*Payments, error)
 	mustEmbedUnimplementedBalanceManagementServer()
 }
 
@@ -97,6 +222,33 @@ func (UnimplementedBalanceManagementServer) AddBalance(context.Context, *Money)
 func (UnimplementedBalanceManagementServer) TakeBalance(context.Context, *Money) (*Status, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method TakeBalance not implemented")
 }
+func (UnimplementedBalanceManagementServer) Reserve(context.Context, *HoldRequest) (*Hold, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reserve not implemented")
+}
+func (UnimplementedBalanceManagementServer) Commit(context.Context, *CommitRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Commit not implemented")
+}
+func (UnimplementedBalanceManagementServer) Release(context.Context, *ReleaseRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Release not implemented")
+}
+func (UnimplementedBalanceManagementServer) GetTransactions(context.Context, *TransactionsRequest) (*Transactions, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTransactions not implemented")
+}
+func (UnimplementedBalanceManagementServer) Transfer(context.Context, *TransferRequest) (*TransferResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transfer not implemented")
+}
+func (UnimplementedBalanceManagementServer) Exchange(context.Context, *ExchangeRequest) (*ExchangeResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Exchange not implemented")
+}
+func (UnimplementedBalanceManagementServer) CreatePayment(context.Context, *PaymentRequest) (*Payment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePayment not implemented")
+}
+func (UnimplementedBalanceManagementServer) ConfirmPayment(context.Context, *PaymentCallback) (*Payment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmPayment not implemented")
+}
+func (UnimplementedBalanceManagementServer) GetPayments(context.Context, *PaymentsRequest) (*Payments, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPayments not implemented")
+}
 func (UnimplementedBalanceManagementServer) mustEmbedUnimplementedBalanceManagementServer() {}
 func (UnimplementedBalanceManagementServer) testEmbeddedByValue()                           {}
 
@@ -172,6 +324,168 @@ func _BalanceManagement_TakeBalance_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BalanceManagement_Reserve_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HoldRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BalanceManagementServer).Reserve(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BalanceManagement_Reserve_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BalanceManagementServer).Reserve(ctx, req.(*HoldRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BalanceManagement_Commit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BalanceManagementServer).Commit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BalanceManagement_Commit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BalanceManagementServer).Commit(ctx, req.(*CommitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BalanceManagement_Release_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BalanceManagementServer).Release(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BalanceManagement_Release_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BalanceManagementServer).Release(ctx, req.(*ReleaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BalanceManagement_GetTransactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransactionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BalanceManagementServer).GetTransactions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BalanceManagement_GetTransactions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BalanceManagementServer).GetTransactions(ctx, req.(*TransactionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BalanceManagement_Transfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BalanceManagementServer).Transfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BalanceManagement_Transfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BalanceManagementServer).Transfer(ctx, req.(*TransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BalanceManagement_Exchange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExchangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BalanceManagementServer).Exchange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BalanceManagement_Exchange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BalanceManagementServer).Exchange(ctx, req.(*ExchangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BalanceManagement_CreatePayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BalanceManagementServer).CreatePayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BalanceManagement_CreatePayment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BalanceManagementServer).CreatePayment(ctx, req.(*PaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BalanceManagement_ConfirmPayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaymentCallback)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BalanceManagementServer).ConfirmPayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BalanceManagement_ConfirmPayment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BalanceManagementServer).ConfirmPayment(ctx, req.(*PaymentCallback))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BalanceManagement_GetPayments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaymentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BalanceManagementServer).GetPayments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BalanceManagement_GetPayments_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BalanceManagementServer).GetPayments(ctx, req.(*PaymentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // BalanceManagement_ServiceDesc is the grpc.ServiceDesc for BalanceManagement service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -191,6 +505,42 @@ var BalanceManagement_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "TakeBalance",
 			Handler:    _BalanceManagement_TakeBalance_Handler,
 		},
+		{
+			MethodName: "Reserve",
+			Handler:    _BalanceManagement_Reserve_Handler,
+		},
+		{
+			MethodName: "Commit",
+			Handler:    _BalanceManagement_Commit_Handler,
+		},
+		{
+			MethodName: "Release",
+			Handler:    _BalanceManagement_Release_Handler,
+		},
+		{
+			MethodName: "GetTransactions",
+			Handler:    _BalanceManagement_GetTransactions_Handler,
+		},
+		{
+			MethodName: "Transfer",
+			Handler:    _BalanceManagement_Transfer_Handler,
+		},
+		{
+			MethodName: "Exchange",
+			Handler:    _BalanceManagement_Exchange_Handler,
+		},
+		{
+			MethodName: "CreatePayment",
+			Handler:    _BalanceManagement_CreatePayment_Handler,
+		},
+		{
+			MethodName: "ConfirmPayment",
+			Handler:    _BalanceManagement_ConfirmPayment_Handler,
+		},
+		{
+			MethodName: "GetPayments",
+			Handler:    _BalanceManagement_GetPayments_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "BalanceService.proto",
@@ -1,155 +1,207 @@
 package jwt
 
 import (
-	"crypto/rand"
+	"context"
 	"crypto/rsa"
-	"crypto/x509"
+	"encoding/base64"
 	"errors"
 	"log"
-	"regexp"
-	"strconv"
-	"strings"
+	"math/big"
+	"sync"
 	"time"
 
+	"gateway/internal/pkg/models"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+// Проверяет токены, выпущенные aus, по его публичным ключам из JWKS. Ключи кэшируются и перечитываются раз в
+// KeysRefresh или при встрече токена с неизвестным kid(после ротации)
 type JWT struct {
-	PrivateKey             *rsa.PrivateKey
-	PublicKey              *rsa.PublicKey
-	AccessTokenExpiration  time.Duration
-	RefreshTokenExpiration time.Duration
+	cfg      *Config
+	source   IKeySource
+	denylist IDenylist
+
+	mu        sync.RWMutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// Источник публичных ключей - aus
+type IKeySource interface {
+	GetJWKS() ([]models.JWK, error)
+}
+
+// Отозванные при выходе access токены, по jti
+type IDenylist interface {
+	IsDenied(ctx context.Context, jti string) (bool, error)
 }
 
 type Config struct {
-	Key                    string `yaml:"private_key" env-prefix:"PRIVATEKEY" env-default:""`
-	AccessTokenExpiration  int    `yaml:"access_token_expiration" env-prefix:"ACCESSTOKENEXPIRATION" env-default:"3600"`
-	RefreshTokenExpiration int    `yaml:"refresh_token_expiration" env-prefix:"PRIVATEKEY" env-default:"36000"`
+	// Как часто в секундах перечитывать ключи из aus
+	KeysRefresh int `yaml:"keys_refresh" env:"KEYS_REFRESH" env-default:"300"`
 }
 
+// Чаще этого ключи не перечитываются даже при неизвестном kid, чтобы мусорные токены не нагружали aus
+const minKeysRefresh = 10 * time.Second
+
 const (
 	InvalidToken = "invalid token"
 	ExpiredToken = "expired token"
+	RevokedToken = "revoked token"
 )
 
-var (
-	needToProvideAuthTokenURLs = []*regexp.Regexp{
-		regexp.MustCompile("^/buypaper$"),
-		regexp.MustCompile("^/sellpaper$"),
-		regexp.MustCompile("^/mypapers$"),
-		regexp.MustCompile("^/getbalance$"),
-		regexp.MustCompile("^/takebalance$"),
-		regexp.MustCompile("^/addbalance$"),
-	}
-	needToProvideRefreshTokenURLs = []*regexp.Regexp{
-		regexp.MustCompile("^/refresh$"),
-	}
+// Типы токенов в клейме typ: вместо access токена нельзя предъявить refresh или challenge(вход с 2FA) токен
+const (
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
 )
 
-// Создает jwt объект с ключами и сроком действия токенов
-func New(cfg *Config) (JWT, error) {
-	jwt := JWT{}
-	var err error
-	privateKeyString := cfg.Key
-	jwt.AccessTokenExpiration = time.Second * time.Duration(cfg.AccessTokenExpiration)
-	jwt.RefreshTokenExpiration = time.Second * time.Duration(cfg.RefreshTokenExpiration)
-	if privateKeyString == "" {
-		jwt.PrivateKey, err = rsa.GenerateKey(rand.Reader, 2048)
-		if err != nil {
-			return JWT{}, err
-		}
-		jwt.PublicKey = &jwt.PrivateKey.PublicKey
-		return jwt, nil
+// Создает jwt объект и загружает ключи из aus. Если aus пока недоступен, ключи загрузятся при первой проверке токена
+func New(cfg *Config, source IKeySource, denylist IDenylist) (*JWT, error) {
+	j := &JWT{cfg: cfg, source: source, denylist: denylist, keys: map[string]*rsa.PublicKey{}}
+	if err := j.fetch(); err != nil {
+		log.Println("Cant get signing keys from aus, will retry on first token:", err)
 	}
-	keyBytes := convertStringToBytesSlice(privateKeyString)
-	jwt.PrivateKey, err = x509.ParsePKCS1PrivateKey(keyBytes)
-	jwt.PublicKey = &jwt.PrivateKey.PublicKey
+	return j, nil
+}
+
+// Перечитывает публичные ключи из aus
+func (j *JWT) fetch() error {
+	jwks, err := j.source.GetJWKS()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.fetchedAt = time.Now()
 	if err != nil {
-		return JWT{}, err
+		return err
 	}
-	return jwt, nil
+	keys := make(map[string]*rsa.PublicKey, len(jwks))
+	for _, jwk := range jwks {
+		key, err := publicKeyFromJWK(jwk)
+		if err != nil {
+			log.Printf("Cant parse key %v: %v\n", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	j.keys = keys
+	return nil
 }
 
-// Создаёт jwt объект, если ключ уже существует(это нужно, потому что при отсутствии ключа в cfg файле сервисы пытаются создать его сами, и создают разные ключи)
-// Это не очень хорошо, так как gateway зависит от authService, и это стоило бы как-то исправить
-func NewWithKey(cfg *Config, key *rsa.PrivateKey) (JWT, error) {
-	jwt := JWT{PrivateKey: key, PublicKey: &key.PublicKey,
-		AccessTokenExpiration: time.Second * time.Duration(cfg.AccessTokenExpiration), RefreshTokenExpiration: time.Second * time.Duration(cfg.RefreshTokenExpiration)}
-	return jwt, nil
+// Публичный ключ из kid заголовка токена. Ключ ищется в кэше, кэш обновляется, если устарел или kid в нем нет
+func (j *JWT) publicKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New(InvalidToken)
+	}
+	j.mu.RLock()
+	key, ok := j.keys[kid]
+	age := time.Since(j.fetchedAt)
+	j.mu.RUnlock()
+	if age > time.Duration(j.cfg.KeysRefresh)*time.Second || !ok && age > minKeysRefresh {
+		if err := j.fetch(); err != nil {
+			log.Println("Cant refresh signing keys:", err)
+		}
+		j.mu.RLock()
+		key, ok = j.keys[kid]
+		j.mu.RUnlock()
+	}
+	if !ok {
+		return nil, errors.New(InvalidToken)
+	}
+	return key, nil
 }
 
-// Создаёт токены для пользователя по его id. В access лежит id, в refresh ничего
-func (j *JWT) CreateTokens(user_id uuid.UUID) (string, string, error) {
-	subj := user_id.String()
-	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.AccessTokenExpiration)),
-		Subject:   subj,
-	}).SignedString(j.PrivateKey)
+func publicKeyFromJWK(jwk models.JWK) (*rsa.PublicKey, error) {
+	if jwk.Kty != "RSA" {
+		return nil, errors.New("unsupported key type " + jwk.Kty)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
-	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.RefreshTokenExpiration)),
-	}).SignedString(j.PrivateKey)
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
-	return accessToken, refreshToken, nil
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
 }
 
-// Проверяет валидность токенов
+// Проверяет валидность access токена
 func (j *JWT) ValidateToken(c *fiber.Ctx, token string) (bool, error) {
+	claims, err := j.validate(token)
+	if err != nil {
+		return false, err
+	}
+	if claims["typ"] != accessTokenType {
+		return false, errors.New(InvalidToken)
+	}
+	if err := j.checkDenied(claims); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Проверяет валидность refresh токена. Использован ли он уже, проверяет aus при обмене
+func (j *JWT) ValidateRefreshToken(c *fiber.Ctx, token string) (bool, error) {
+	claims, err := j.validate(token)
+	if err != nil {
+		return false, err
+	}
+	if claims["typ"] != refreshTokenType {
+		return false, errors.New(InvalidToken)
+	}
+	return true, nil
+}
+
+func (j *JWT) validate(token string) (jwt.MapClaims, error) {
 	claims := jwt.MapClaims{}
-	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return j.PublicKey, nil
-	})
+	_, err := jwt.ParseWithClaims(token, claims, j.publicKey, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
 	if err != nil {
 		switch {
 		case errors.Is(err, jwt.ErrTokenExpired):
 			log.Println("Should expire at:", time.Unix(0, int64(claims["exp"].(float64))))
-			return false, err
+			return nil, err
 		case errors.Is(err, jwt.ErrSignatureInvalid) || errors.Is(err, jwt.ErrTokenUnverifiable):
-			return false, err
+			return nil, err
 		default:
-			return false, err
+			return nil, err
 		}
 	}
-	return true, nil
+	return claims, nil
 }
 
-// Фильтрует руты, на которых access или refresh токены не понадобятся
-func (j *JWT) AuthFilter(c *fiber.Ctx) bool { //TODO: переделать чтобы была одна функция фильтра вместо двух
-	originalURL := strings.ToLower(c.OriginalURL())
-	for _, pattern := range needToProvideAuthTokenURLs {
-		if pattern.MatchString(originalURL) {
-			return false
-		}
+// Проверяет, не отозван ли access токен при выходе. Если denylist недоступен, токен не пропускается
+func (j *JWT) checkDenied(claims jwt.MapClaims) error {
+	jti, _ := claims["jti"].(string)
+	if j.denylist == nil || jti == "" {
+		return nil
 	}
-	return true
-}
-
-// -||-
-func (j *JWT) RefreshFilter(c *fiber.Ctx) bool {
-	originalURL := strings.ToLower(c.OriginalURL())
-	for _, pattern := range needToProvideRefreshTokenURLs {
-		if pattern.MatchString(originalURL) {
-			return false
-		}
+	denied, err := j.denylist.IsDenied(context.Background(), jti)
+	if err != nil {
+		log.Println("Cant check access token, rejecting it:", err)
+		return err
 	}
-	return true
+	if denied {
+		return errors.New(RevokedToken)
+	}
+	return nil
 }
 
 // Получает id пользователя из access токена
 func (j *JWT) GetIDFromToken(token string) (*uuid.UUID, error) {
-	claims := jwt.MapClaims{}
-	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return j.PublicKey, nil
-	})
+	claims, err := j.validate(token)
 	if err != nil {
 		return nil, err
 	}
+	if claims["typ"] != accessTokenType {
+		return nil, errors.New(InvalidToken)
+	}
+	if err := j.checkDenied(claims); err != nil {
+		return nil, err
+	}
 	id, err := getIdFromClaims(claims)
 	log.Printf("Got id: %v\n", id)
 	if err != nil {
@@ -158,14 +210,23 @@ func (j *JWT) GetIDFromToken(token string) (*uuid.UUID, error) {
 	return id, nil
 }
 
-// Получение ключей из jwt
-func (j *JWT) GetPrivateKey() *rsa.PrivateKey {
-	return j.PrivateKey
-}
-
-// -||-
-func (j *JWT) GetPublicKey() *rsa.PublicKey {
-	return j.PublicKey
+// Права из access токена(клейм perms), по ним Router.Authorize пускает на руты
+func (j *JWT) GetPermissionsFromToken(token string) ([]string, error) {
+	claims, err := j.validate(token)
+	if err != nil {
+		return nil, err
+	}
+	if claims["typ"] != accessTokenType {
+		return nil, errors.New(InvalidToken)
+	}
+	raw, _ := claims["perms"].([]interface{})
+	permissions := make([]string, 0, len(raw))
+	for _, permission := range raw {
+		if p, ok := permission.(string); ok {
+			permissions = append(permissions, p)
+		}
+	}
+	return permissions, nil
 }
 
 // Получение id из клеймов токена
@@ -178,18 +239,3 @@ func getIdFromClaims(claims jwt.MapClaims) (*uuid.UUID, error) {
 	}
 	return &user_id, nil
 }
-
-// Перевод застрингованного слайса байтов в слайс байтов "[1 2 3]" -> []byte{1, 2, 3}
-func convertStringToBytesSlice(line string) []byte {
-	line = strings.Trim(line, "[]")
-	parts := strings.Split(line, " ")
-	var bytes []byte
-	for _, part := range parts {
-		num, err := strconv.Atoi(part)
-		if err != nil {
-			panic(err)
-		}
-		bytes = append(bytes, byte(num))
-	}
-	return bytes
-}
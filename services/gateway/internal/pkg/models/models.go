@@ -1,6 +1,11 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"gateway/internal/pkg/money"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // Данные о пользователе
 type User struct {
@@ -10,18 +15,248 @@ type User struct {
 }
 
 type Paper struct {
-	Name   string  `json:"name"`
-	Price  float32 `json:"price"`
-	Amount int32   `json:"amount"`
+	Name   string       `json:"name"`
+	Price  money.Amount `json:"price"`
+	Amount int32        `json:"amount"`
+	Halted bool         `json:"halted,omitempty"`
+	// Валюта листинга, в ней цена бумаги
+	Currency string `json:"currency,omitempty"`
+	// Ключ идемпотентности из заголовка Idempotency-Key
+	IdempotencyKey string `json:"-"`
 }
 
 // Данные для аутентификации
+// ChallengeToken - вместо токенов после пароля, если у пользователя включена 2FA
 type AuthData struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken    string `json:"access_token"`
+	RefreshToken   string `json:"refresh_token"`
+	ChallengeToken string `json:"challenge_token,omitempty"`
+}
+
+// Второй шаг входа с 2FA: код из приложения или код восстановления
+type TOTPLogin struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// Секрет 2FA для приложения-аутентификатора, URI - для QR кода
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// Устройство, с которого выполнен вход
+type Device struct {
+	UserAgent string `json:"user_agent"`
+	IP        string `json:"ip"`
 }
 
+// Активная сессия входа. Current - сессия, с которой сделан запрос
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	Device     Device    `json:"device"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// Публичный ключ проверки токенов в формате JWK(RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// Пустая валюта - базовая(USD)
 type Money struct {
-	ID   uuid.UUID `json:"id"`
-	Cash float32   `json:"cash"`
+	ID       uuid.UUID    `json:"id"`
+	Cash     money.Amount `json:"cash"`
+	Currency string       `json:"currency,omitempty"`
+	// Ключ идемпотентности из заголовка Idempotency-Key
+	IdempotencyKey string `json:"-"`
+}
+
+// Кошелек пользователя в одной валюте
+type Wallet struct {
+	Currency string       `json:"currency"`
+	Amount   money.Amount `json:"amount"`
+}
+
+// Обмен Amount валюты From на Received валюты To по курсу Rate(единиц To за единицу From).
+// Response содержит причину отказа, если обмен не выполнен
+type Exchange struct {
+	From     string       `json:"from"`
+	To       string       `json:"to"`
+	Amount   money.Amount `json:"amount"`
+	Received money.Amount `json:"received,omitempty"`
+	Rate     float64      `json:"rate,omitempty"`
+	Response string       `json:"response,omitempty"`
+	// Ключ идемпотентности из заголовка Idempotency-Key
+	IdempotencyKey string `json:"-"`
+}
+
+// Запрос клиента в вебсокете: {"action": "subscribe", "papers": ["Dogecoin"]}
+type WSRequest struct {
+	Action string   `json:"action"`
+	Papers []string `json:"papers"`
+}
+
+// Сообщение сервера в вебсокете
+type WSMessage struct {
+	Type  string `json:"type"`
+	Data  any    `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Сделка одного участника исполнения из канала trade_fills papers
+type FillEvent struct {
+	UserID     uuid.UUID    `json:"user_id"`
+	OrderID    uuid.UUID    `json:"order_id"`
+	Side       string       `json:"side"`
+	Paper      string       `json:"paper"`
+	Amount     int32        `json:"amount"`
+	Price      money.Amount `json:"price"`
+	ExecutedAt time.Time    `json:"executed_at"`
+}
+
+// Сделка пользователя(подтверждение в вебсокете или запись истории)
+type Trade struct {
+	ID         *uuid.UUID   `json:"id,omitempty"`
+	OrderID    *uuid.UUID   `json:"order_id,omitempty"`
+	Side       string       `json:"side"`
+	Paper      string       `json:"paper"`
+	Amount     int32        `json:"amount"`
+	Price      money.Amount `json:"price,omitempty"`
+	Fee        money.Amount `json:"fee,omitempty"`
+	Status     string       `json:"status"`
+	ExecutedAt *time.Time   `json:"executed_at,omitempty"`
+}
+
+// Фильтр истории сделок
+type TradeFilter struct {
+	Paper  string
+	From   int64
+	To     int64
+	Cursor string
+	Limit  int32
+}
+
+// Страница истории сделок
+type TradeHistory struct {
+	Trades     []Trade `json:"trades"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// Фильтр истории операций по балансу
+type TransactionFilter struct {
+	Types    []string
+	Currency string
+	From     int64
+	To       int64
+	Cursor   string
+	Limit    int32
+}
+
+// Операция по балансу: Amount со знаком, Balance - деньги пользователя после операции вместе с резервами под заявки
+type Transaction struct {
+	ID        uuid.UUID    `json:"id"`
+	Type      string       `json:"type"`
+	Currency  string       `json:"currency"`
+	Amount    money.Amount `json:"amount"`
+	Balance   money.Amount `json:"balance"`
+	Reference *uuid.UUID   `json:"reference,omitempty"`
+	Memo      string       `json:"memo,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// Страница истории операций
+type Transactions struct {
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
+}
+
+// Перевод другому пользователю: {"to": "login", "amount": 12.5, "memo": "за обед"}. Response содержит причину отказа
+type Transfer struct {
+	ID       *uuid.UUID   `json:"id,omitempty"`
+	From     string       `json:"from,omitempty"`
+	To       string       `json:"to"`
+	Amount   money.Amount `json:"amount"`
+	Currency string       `json:"currency,omitempty"`
+	Memo     string       `json:"memo,omitempty"`
+	Response string       `json:"response,omitempty"`
+	// Ключ идемпотентности из заголовка Idempotency-Key
+	IdempotencyKey string `json:"-"`
+}
+
+// Нарушение лимита пополнения или вывода: правило, сообщение и подробности(граница, остаток лимита, секунды ожидания)
+type Violation struct {
+	Rule    string            `json:"rule,omitempty"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Пополнение через платежного провайдера: pending, пока провайдер не пришлет результат, затем settled или failed
+// с причиной отказа
+type Payment struct {
+	ID        uuid.UUID    `json:"id"`
+	Currency  string       `json:"currency"`
+	Amount    money.Amount `json:"amount"`
+	Status    string       `json:"status"`
+	Reason    string       `json:"reason,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// Изменение баланса пользователя
+type BalanceChange struct {
+	Balance money.Amount `json:"balance"`
+}
+
+// Заявка пользователя в стакане. Response содержит причину отказа, если заявка не принята
+type Order struct {
+	ID        *uuid.UUID   `json:"id,omitempty"`
+	Paper     string       `json:"paper"`
+	Side      string       `json:"side"`
+	Type      string       `json:"type"`
+	Price     money.Amount `json:"price"`
+	Amount    int32        `json:"amount"`
+	Filled    int32        `json:"filled"`
+	Status    string       `json:"status,omitempty"`
+	CreatedAt *time.Time   `json:"created_at,omitempty"`
+	Response  string       `json:"response,omitempty"`
+	// Ключ идемпотентности из заголовка Idempotency-Key
+	IdempotencyKey string `json:"-"`
+}
+
+// Уровень цены в стакане
+type PriceLevel struct {
+	Price  money.Amount `json:"price"`
+	Amount int32        `json:"amount"`
+}
+
+// Агрегированный стакан бумаги
+type OrderBook struct {
+	Paper string       `json:"paper"`
+	Bids  []PriceLevel `json:"bids"`
+	Asks  []PriceLevel `json:"asks"`
+}
+
+// Свеча OHLC за интервал, начинающийся в Time(unix секунды)
+type Candle struct {
+	Time  int64        `json:"time"`
+	Open  money.Amount `json:"open"`
+	High  money.Amount `json:"high"`
+	Low   money.Amount `json:"low"`
+	Close money.Amount `json:"close"`
+}
+
+// Свечи бумаги для графика
+type PriceHistory struct {
+	Paper    string   `json:"paper"`
+	Interval string   `json:"interval"`
+	Candles  []Candle `json:"candles"`
 }
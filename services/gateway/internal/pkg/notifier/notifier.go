@@ -0,0 +1,62 @@
+package notifier
+
+import (
+	"gateway/internal/pkg/models"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Рассылает пользователю события(подтверждения сделок, изменения баланса) во все его открытые вебсокеты
+type Notifier struct {
+	mu         sync.RWMutex
+	listeners  map[uuid.UUID]map[int]chan models.WSMessage
+	nextID     int
+	bufferSize int
+}
+
+func New(bufferSize int) *Notifier {
+	return &Notifier{listeners: map[uuid.UUID]map[int]chan models.WSMessage{}, bufferSize: bufferSize}
+}
+
+// Регистрирует слушателя событий пользователя. Возвращаемая функция снимает регистрацию
+func (n *Notifier) Subscribe(userID uuid.UUID) (<-chan models.WSMessage, func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	id := n.nextID
+	n.nextID++
+	events := make(chan models.WSMessage, n.bufferSize)
+	if n.listeners[userID] == nil {
+		n.listeners[userID] = map[int]chan models.WSMessage{}
+	}
+	n.listeners[userID][id] = events
+	return events, func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		delete(n.listeners[userID], id)
+		if len(n.listeners[userID]) == 0 {
+			delete(n.listeners, userID)
+		}
+	}
+}
+
+// Есть ли у пользователя открытые вебсокеты
+func (n *Notifier) HasListeners(userID uuid.UUID) bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return len(n.listeners[userID]) > 0
+}
+
+// Отправляет событие всем слушателям пользователя. Если буфер слушателя переполнен, событие для него теряется
+func (n *Notifier) Notify(userID uuid.UUID, msg models.WSMessage) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	for id, events := range n.listeners[userID] {
+		select {
+		case events <- msg:
+		default:
+			log.Printf("Dropping %v event for user %v listener %v: buffer is full\n", msg.Type, userID, id)
+		}
+	}
+}
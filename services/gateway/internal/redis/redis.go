@@ -0,0 +1,84 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"gateway/internal/pkg/models"
+	"log"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type Config struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"dbnum"`
+}
+
+// Префикс ключей denylist access токенов, их пишет aus при выходе: denied:<jti>
+const DenylistPrefix = "denied:"
+
+// Канал, в который papers публикует сделки пользователей
+const TradeFillsChannel = "trade_fills"
+
+type Redis struct {
+	client *redis.Client
+}
+
+func New(cfg *Config) (*Redis, error) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     cfg.Host + cfg.Port,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	ret := Redis{rdb}
+	return &ret, nil
+}
+
+// Запрещен ли access токен jti. Ключ сам исчезает, когда токен истекает
+func (r *Redis) IsDenied(ctx context.Context, jti string) (bool, error) {
+	n, err := r.client.Exists(ctx, DenylistPrefix+jti).Result()
+	if err != nil {
+		log.Println("Cant check token denylist:", err)
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Подписывается на сделки пользователей из papers. Канал закрывается при отмене контекста
+func (r *Redis) SubscribeFills(ctx context.Context) (<-chan models.FillEvent, error) {
+	pubsub := r.client.Subscribe(ctx, TradeFillsChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		log.Println("Cant subscribe to trade fills:", err)
+		pubsub.Close()
+		return nil, err
+	}
+	fills := make(chan models.FillEvent)
+	go func() {
+		defer close(fills)
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var fill models.FillEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &fill); err != nil {
+					log.Println("Cant unmarshal trade fill:", err)
+					continue
+				}
+				select {
+				case fills <- fill:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return fills, nil
+}
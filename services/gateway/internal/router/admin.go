@@ -0,0 +1,90 @@
+package router
+
+import (
+	"encoding/json"
+	"gateway/internal/pkg/models"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Все торгуемые бумаги, в т.ч. приостановленные
+func (r *Router) AdminListPapers() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		papers, err := r.market.ListPapers()
+		if err != nil {
+			return adminResult(c, nil, err)
+		}
+		return c.Status(200).JSON(papers)
+	}
+}
+
+// Новая бумага: {"name": "Ichor", "price": 666, "currency": "EUR"}, без currency - в базовой валюте
+func (r *Router) AdminCreatePaper() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var paper models.Paper
+		if err := json.Unmarshal(c.Body(), &paper); err != nil {
+			log.Println("unmarshal error:", err)
+			c.Status(fiber.StatusBadRequest)
+			return nil
+		}
+		created, err := r.market.CreatePaper(paper)
+		return adminResult(c, created, err)
+	}
+}
+
+func (r *Router) AdminDelistPaper() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		paper, err := r.market.DelistPaper(c.Params("name"))
+		return adminResult(c, paper, err)
+	}
+}
+
+func (r *Router) AdminHaltPaper() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		paper, err := r.market.HaltPaper(c.Params("name"))
+		return adminResult(c, paper, err)
+	}
+}
+
+func (r *Router) AdminResumePaper() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		paper, err := r.market.ResumePaper(c.Params("name"))
+		return adminResult(c, paper, err)
+	}
+}
+
+// Ручная цена: {"price": 101}
+func (r *Router) AdminSetPrice() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var paper models.Paper
+		if err := json.Unmarshal(c.Body(), &paper); err != nil {
+			log.Println("unmarshal error:", err)
+			c.Status(fiber.StatusBadRequest)
+			return nil
+		}
+		paper.Name = c.Params("name")
+		updated, err := r.market.SetPrice(paper)
+		return adminResult(c, updated, err)
+	}
+}
+
+// Отвечает бумагой или переводит код ошибки market в http статус
+func adminResult(c *fiber.Ctx, paper *models.Paper, err error) error {
+	if err == nil {
+		return c.Status(200).JSON(paper)
+	}
+	log.Println("market admin error:", err)
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		return c.Status(fiber.StatusBadRequest).SendString(status.Convert(err).Message())
+	case codes.NotFound:
+		return c.Status(fiber.StatusNotFound).SendString(status.Convert(err).Message())
+	case codes.AlreadyExists:
+		return c.Status(fiber.StatusConflict).SendString(status.Convert(err).Message())
+	}
+	c.Status(500)
+	return nil
+}
@@ -0,0 +1,142 @@
+package router
+
+import (
+	"encoding/json"
+	"gateway/internal/pkg/models"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Стакан бумаги: /orderbook/Dogecoin?depth=10
+func (r *Router) GetOrderBook() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		book, err := r.pps.GetOrderBook(c.Params("name"), int32(c.QueryInt("depth")))
+		if err != nil {
+			log.Println("getting order book error:", err)
+			c.Status(500)
+			return nil
+		}
+		return c.Status(200).JSON(book)
+	}
+}
+
+// Последние заявки пользователя
+func (r *Router) GetUserOrders() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := r.userIDFromRequest(c)
+		if err != nil {
+			return nil
+		}
+		orders, err := r.pps.GetUserOrders(userID)
+		if err != nil {
+			log.Println("getting user orders error:", err)
+			c.Status(500)
+			return nil
+		}
+		return c.Status(200).JSON(orders)
+	}
+}
+
+// Новая заявка: {"paper": "Dogecoin", "side": "buy", "type": "limit", "price": 100, "amount": 5}
+func (r *Router) PlaceOrder() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var order models.Order
+		if err := json.Unmarshal(c.Body(), &order); err != nil {
+			log.Println("unmarshal error:", err)
+			c.Status(fiber.StatusBadRequest)
+			return nil
+		}
+		userID, err := r.userIDFromRequest(c)
+		if err != nil {
+			return nil
+		}
+		if order.IdempotencyKey, err = idempotencyKey(c); err != nil {
+			return nil
+		}
+		placed, err := r.pps.PlaceOrder(userID, order)
+		return r.orderResult(c, userID, placed, err)
+	}
+}
+
+// Замена заявки: {"price": 101, "amount": 3}
+func (r *Router) ReplaceOrder() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		orderID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return nil
+		}
+		var order models.Order
+		if err := json.Unmarshal(c.Body(), &order); err != nil {
+			log.Println("unmarshal error:", err)
+			c.Status(fiber.StatusBadRequest)
+			return nil
+		}
+		userID, err := r.userIDFromRequest(c)
+		if err != nil {
+			return nil
+		}
+		if order.IdempotencyKey, err = idempotencyKey(c); err != nil {
+			return nil
+		}
+		replaced, err := r.pps.ReplaceOrder(userID, orderID, order)
+		return r.orderResult(c, userID, replaced, err)
+	}
+}
+
+// Отмена заявки
+func (r *Router) CancelOrder() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		orderID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return nil
+		}
+		userID, err := r.userIDFromRequest(c)
+		if err != nil {
+			return nil
+		}
+		cancelled, err := r.pps.CancelOrder(userID, orderID)
+		return r.orderResult(c, userID, cancelled, err)
+	}
+}
+
+// Отвечает заявкой, отказ сервиса(response) - 400, остановленные торги и запрос с тем же ключом идемпотентности,
+// который еще выполняется, - 409, ключ с другим запросом - 422. Исполнения меняют баланс, поэтому пользователю
+// отправляется новый баланс
+func (r *Router) orderResult(c *fiber.Ctx, userID *uuid.UUID, order *models.Order, err error) error {
+	if err != nil {
+		log.Println("order error:", err)
+		switch status.Code(err) {
+		case codes.InvalidArgument:
+			return c.Status(fiber.StatusBadRequest).JSON(models.Order{Response: status.Convert(err).Message()})
+		case codes.FailedPrecondition, codes.Aborted:
+			return c.Status(fiber.StatusConflict).JSON(models.Order{Response: status.Convert(err).Message()})
+		case codes.AlreadyExists:
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(models.Order{Response: status.Convert(err).Message()})
+		}
+		c.Status(500)
+		return nil
+	}
+	if order.Response != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(order)
+	}
+	r.notifyBalance(*userID)
+	return c.Status(200).JSON(order)
+}
+
+// Id пользователя из access токена. При ошибке ответ уже записан
+func (r *Router) userIDFromRequest(c *fiber.Ctx) (*uuid.UUID, error) {
+	access := c.GetReqHeaders()["X-Access-Token"][0]
+	userID, err := r.jwt.GetIDFromToken(access)
+	if err != nil {
+		log.Println("getting id from token error:", err)
+		c.Status(500)
+		return nil, err
+	}
+	return userID, nil
+}
@@ -0,0 +1,109 @@
+package router
+
+import (
+	"encoding/json"
+	"gateway/internal/pkg/models"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Заголовок с подписью callback платежного провайдера
+const paymentSignatureHeader = "X-Signature"
+
+// Пополнение: {"cash": 100, "currency": "EUR"}. Платеж создается в статусе pending, деньги зачисляются,
+// когда провайдер подтвердит его через webhook. Нарушение лимитов - 400 или 429. Повтор с тем же Idempotency-Key
+// возвращает уже созданный платеж
+func (r *Router) Deposit() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var deposit models.Money
+		if err := json.Unmarshal(c.Body(), &deposit); err != nil {
+			log.Println("unmarshal error:", err)
+			c.Status(fiber.StatusBadRequest)
+			return nil
+		}
+		userID, err := r.userIDFromRequest(c)
+		if err != nil {
+			return nil
+		}
+		if deposit.IdempotencyKey, err = idempotencyKey(c); err != nil {
+			return nil
+		}
+		payment, err := r.balance.CreatePayment(userID, deposit)
+		if err != nil {
+			log.Println("creating payment error:", err)
+			return balanceError(c, err)
+		}
+		r.notifyPayment(*userID, *payment)
+		return c.Status(fiber.StatusAccepted).JSON(payment)
+	}
+}
+
+// Платежи пользователя от новых к старым
+func (r *Router) GetPayments() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := r.userIDFromRequest(c)
+		if err != nil {
+			return nil
+		}
+		payments, err := r.balance.GetPayments(userID, nil)
+		if err != nil {
+			log.Println("getting payments error:", err)
+			c.Status(500)
+			return nil
+		}
+		return c.Status(200).JSON(payments)
+	}
+}
+
+// Один платеж пользователя
+func (r *Router) GetPayment() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		paymentID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return nil
+		}
+		userID, err := r.userIDFromRequest(c)
+		if err != nil {
+			return nil
+		}
+		payments, err := r.balance.GetPayments(userID, &paymentID)
+		if err != nil {
+			log.Println("getting payment error:", err)
+			if status.Code(err) == codes.NotFound {
+				return c.Status(fiber.StatusNotFound).SendString(status.Convert(err).Message())
+			}
+			c.Status(500)
+			return nil
+		}
+		return c.Status(200).JSON(payments[0])
+	}
+}
+
+// Callback платежного провайдера. Тело и подпись передаются в balance без изменений: подпись проверяет balance.
+// Неверная подпись - 401, неизвестный платеж - 404, callback не совпадает с платежом - 400
+func (r *Router) PaymentWebhook() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		payment, userID, err := r.balance.ConfirmPayment(c.Body(), c.Get(paymentSignatureHeader))
+		if err != nil {
+			log.Println("payment webhook error:", err)
+			switch status.Code(err) {
+			case codes.Unauthenticated:
+				return c.Status(fiber.StatusUnauthorized).SendString(status.Convert(err).Message())
+			case codes.NotFound:
+				return c.Status(fiber.StatusNotFound).SendString(status.Convert(err).Message())
+			case codes.InvalidArgument:
+				return c.Status(fiber.StatusBadRequest).SendString(status.Convert(err).Message())
+			}
+			c.Status(500)
+			return nil
+		}
+		r.notifyPayment(*userID, *payment)
+		c.Status(200)
+		return nil
+	}
+}
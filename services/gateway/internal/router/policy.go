@@ -0,0 +1,118 @@
+package router
+
+import (
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Какой токен рут требует в заголовке
+type tokenKind int
+
+const (
+	accessToken tokenKind = iota + 1
+	refreshToken
+	// Рут без токена
+	public
+)
+
+// Права в access токене. aus выдает их по ролям пользователя: admin - market:read и market:write, auditor - market:read
+const (
+	permMarketRead  = "market:read"
+	permMarketWrite = "market:write"
+)
+
+// Требование рута: метод(пустой - любой), путь без query, нужный токен и права, которые должны быть в access токене
+type routePolicy struct {
+	method      string
+	path        *regexp.Regexp
+	token       tokenKind
+	permissions []string
+}
+
+// Политики рутов, срабатывает первая подходящая. Рут, которого здесь нет, требует access токен: публичные руты
+// перечислены явно. /ws проверяет токен сам(WSAuth): при апгрейде токен может прийти в query
+var policies = []routePolicy{
+	{method: fiber.MethodGet, path: regexp.MustCompile(`^/(ping|metrics)$`), token: public},
+	{method: fiber.MethodPost, path: regexp.MustCompile(`^/(login|login/totp|register)$`), token: public},
+	{method: fiber.MethodGet, path: regexp.MustCompile(`^/papers(/[^/]+/candles)?$`), token: public},
+	{method: fiber.MethodGet, path: regexp.MustCompile(`^/orderbook/[^/]+$`), token: public},
+	{method: fiber.MethodPost, path: regexp.MustCompile(`^/webhooks/payments$`), token: public},
+	{method: fiber.MethodGet, path: regexp.MustCompile(`^/ws(/.*)?$`), token: public},
+
+	{path: regexp.MustCompile(`^/refresh$`), token: refreshToken},
+	{path: regexp.MustCompile(`^/sessions$`), token: accessToken},
+	{path: regexp.MustCompile(`^/logout(/all)?$`), token: accessToken},
+	{path: regexp.MustCompile(`^/totp/(enroll|activate)$`), token: accessToken},
+
+	{path: regexp.MustCompile(`^/(buypaper|sellpaper|mypapers|trades)$`), token: accessToken},
+	{path: regexp.MustCompile(`^/orders(/[^/]+)?$`), token: accessToken},
+
+	{path: regexp.MustCompile(`^/balance(/transactions)?$`), token: accessToken},
+	{path: regexp.MustCompile(`^/(transfer|wallets|exchange|addbalance|takebalance)$`), token: accessToken},
+	{path: regexp.MustCompile(`^/deposits(/[^/]+)?$`), token: accessToken},
+
+	{method: fiber.MethodGet, path: regexp.MustCompile(`^/admin/papers$`), token: accessToken, permissions: []string{permMarketRead}},
+	{path: regexp.MustCompile(`^/admin(/.*)?$`), token: accessToken, permissions: []string{permMarketWrite}},
+}
+
+// Политика рута, которого нет в таблице: только access токен
+var defaultPolicy = routePolicy{token: accessToken}
+
+// Первая политика, подходящая под метод и путь, или defaultPolicy
+func findPolicy(method, path string) *routePolicy {
+	path = strings.ToLower(path)
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	for i := range policies {
+		policy := &policies[i]
+		if policy.method != "" && policy.method != method {
+			continue
+		}
+		if policy.path.MatchString(path) {
+			return policy
+		}
+	}
+	return &defaultPolicy
+}
+
+// Проверяет токен и права запроса по таблице политик: без валидного токена 401, без нужных прав 403
+func (r *Router) Authorize() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		policy := findPolicy(c.Method(), c.Path())
+		switch policy.token {
+		case public:
+			return c.Next()
+		case refreshToken:
+			if ok, err := r.jwt.ValidateRefreshToken(c, c.Get("X-Refresh-Token")); !ok {
+				log.Println("Wrong refresh token:", err)
+				return c.SendStatus(fiber.StatusUnauthorized)
+			}
+			return c.Next()
+		}
+		token := c.Get("X-Access-Token")
+		if ok, err := r.jwt.ValidateToken(c, token); !ok {
+			log.Println("Wrong access token:", err)
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+		if len(policy.permissions) == 0 {
+			return c.Next()
+		}
+		granted, err := r.jwt.GetPermissionsFromToken(token)
+		if err != nil {
+			log.Println("getting permissions from token error:", err)
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+		for _, permission := range policy.permissions {
+			if !slices.Contains(granted, permission) {
+				log.Printf("%v %v needs %v permission\n", c.Method(), c.Path(), permission)
+				return c.SendStatus(fiber.StatusForbidden)
+			}
+		}
+		return c.Next()
+	}
+}
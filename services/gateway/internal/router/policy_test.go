@@ -0,0 +1,124 @@
+package router
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// Токены: access с правами market:read, access с market:read и market:write, refresh
+const (
+	readToken    = "access-read"
+	writeToken   = "access-write"
+	refreshValue = "refresh"
+)
+
+type fakeJWT struct{}
+
+func (fakeJWT) GetIDFromToken(_ string) (*uuid.UUID, error) {
+	id := uuid.New()
+	return &id, nil
+}
+
+func (fakeJWT) ValidateToken(_ *fiber.Ctx, key string) (bool, error) {
+	return key == readToken || key == writeToken, nil
+}
+
+func (fakeJWT) ValidateRefreshToken(_ *fiber.Ctx, key string) (bool, error) {
+	return key == refreshValue, nil
+}
+
+func (fakeJWT) GetPermissionsFromToken(token string) ([]string, error) {
+	if token == writeToken {
+		return []string{permMarketRead, permMarketWrite}, nil
+	}
+	return []string{permMarketRead}, nil
+}
+
+func TestAuthorize(t *testing.T) {
+	r := &Router{jwt: fakeJWT{}}
+	app := fiber.New()
+	app.Use(r.Authorize())
+	app.Use(func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	type request struct {
+		method, path, access, refresh string
+		status                        int
+	}
+	check := func(t *testing.T, requests []request) {
+		for _, req := range requests {
+			httpReq := httptest.NewRequest(req.method, req.path, nil)
+			if req.access != "" {
+				httpReq.Header.Set("X-Access-Token", req.access)
+			}
+			if req.refresh != "" {
+				httpReq.Header.Set("X-Refresh-Token", req.refresh)
+			}
+			resp, err := app.Test(httpReq)
+			require.NoError(t, err)
+			require.Equal(t, req.status, resp.StatusCode, "%v %v", req.method, req.path)
+		}
+	}
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tPublic routes need no token", testID)
+		check(t, []request{
+			{method: fiber.MethodGet, path: "/ping", status: fiber.StatusOK},
+			{method: fiber.MethodGet, path: "/metrics", status: fiber.StatusOK},
+			{method: fiber.MethodPost, path: "/login", status: fiber.StatusOK},
+			{method: fiber.MethodPost, path: "/login/totp", status: fiber.StatusOK},
+			{method: fiber.MethodPost, path: "/register", status: fiber.StatusOK},
+			{method: fiber.MethodGet, path: "/papers", status: fiber.StatusOK},
+			{method: fiber.MethodGet, path: "/papers/Dogecoin/candles", status: fiber.StatusOK},
+			{method: fiber.MethodGet, path: "/orderbook/Dogecoin", status: fiber.StatusOK},
+			{method: fiber.MethodPost, path: "/webhooks/payments", status: fiber.StatusOK},
+			{method: fiber.MethodGet, path: "/ws/prices", status: fiber.StatusOK},
+			{method: fiber.MethodPost, path: "/papers", status: fiber.StatusUnauthorized},
+		})
+	}
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tUnlisted routes fall to the default policy and need an access token", testID)
+		require.Same(t, &defaultPolicy, findPolicy(fiber.MethodGet, "/not/listed"))
+		check(t, []request{
+			{method: fiber.MethodGet, path: "/not/listed", status: fiber.StatusUnauthorized},
+			{method: fiber.MethodGet, path: "/not/listed", access: "forged", status: fiber.StatusUnauthorized},
+			{method: fiber.MethodGet, path: "/not/listed", access: readToken, status: fiber.StatusOK},
+			{method: fiber.MethodPost, path: "/orders", refresh: refreshValue, status: fiber.StatusUnauthorized},
+		})
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\tAdmin routes check market permissions", testID)
+		check(t, []request{
+			{method: fiber.MethodGet, path: "/admin/papers", status: fiber.StatusUnauthorized},
+			{method: fiber.MethodGet, path: "/admin/papers", access: readToken, status: fiber.StatusOK},
+			{method: fiber.MethodPost, path: "/admin/papers", access: readToken, status: fiber.StatusForbidden},
+			{method: fiber.MethodPost, path: "/admin/papers/Dogecoin/halt", access: readToken, status: fiber.StatusForbidden},
+			{method: fiber.MethodDelete, path: "/admin/papers/Dogecoin", access: readToken, status: fiber.StatusForbidden},
+			{method: fiber.MethodPost, path: "/admin/papers", access: writeToken, status: fiber.StatusOK},
+		})
+	}
+	{
+		testID := 3
+		t.Logf("\tTest %d:\t/refresh needs a refresh token, not an access token", testID)
+		check(t, []request{
+			{method: fiber.MethodGet, path: "/refresh", access: readToken, status: fiber.StatusUnauthorized},
+			{method: fiber.MethodGet, path: "/refresh", refresh: "forged", status: fiber.StatusUnauthorized},
+			{method: fiber.MethodGet, path: "/refresh", refresh: refreshValue, status: fiber.StatusOK},
+		})
+	}
+	{
+		testID := 4
+		t.Logf("\tTest %d:\tPath case and trailing slash do not change the policy", testID)
+		require.Equal(t, findPolicy(fiber.MethodGet, "/admin/papers"), findPolicy(fiber.MethodGet, "/Admin/Papers/"))
+		require.Equal(t, findPolicy(fiber.MethodGet, "/ping"), findPolicy(fiber.MethodGet, "/PING/"))
+		check(t, []request{
+			{method: fiber.MethodGet, path: "/PING/", status: fiber.StatusOK},
+			{method: fiber.MethodPost, path: "/ADMIN/papers/", access: readToken, status: fiber.StatusForbidden},
+			{method: fiber.MethodGet, path: "/Refresh/", access: readToken, status: fiber.StatusUnauthorized},
+		})
+	}
+}
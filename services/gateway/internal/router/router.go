@@ -1,46 +1,82 @@
 package router
 
 import (
-	"crypto/rsa"
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"gateway/internal/pkg/models"
+	"gateway/internal/pkg/money"
+	"gateway/internal/pkg/notifier"
 	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/keyauth"
 	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Router struct {
-	App     *fiber.App
-	Config  *Config
-	balance IBalanceService
-	pps     IPapersService
-	asvc    IAuthService
-	jwt     IJWTManager
+	App      *fiber.App
+	Config   *Config
+	balance  IBalanceService
+	pps      IPapersService
+	market   IMarketAdmin
+	asvc     IAuthService
+	jwt      IJWTManager
+	notifier *notifier.Notifier
+}
+
+// Сделки пользователей, которые публикует papers. Сделка приходит во все gateway, вебсокет пользователя может быть
+// открыт в любом из них
+type IFills interface {
+	SubscribeFills(ctx context.Context) (<-chan models.FillEvent, error)
 }
 
 type Config struct {
-	Host string `yaml:"router_host" env-prefix:"ROUTERHOST"`
-	Port string `yaml:"router_port" env-prefix:"ROUTERPORT"`
+	Host        string `yaml:"router_host" env-prefix:"ROUTERHOST"`
+	Port        string `yaml:"router_port" env-prefix:"ROUTERPORT"`
+	WSHeartbeat int    `yaml:"ws_heartbeat" env-default:"15"`
+	// Выводы и переводы другим пользователям больше порога в своей валюте требуют кода 2FA в заголовке X-TOTP-Code.
+	// Для валют не из списка код нужен всегда, пустой список - 2FA не требуется
+	WithdrawalTOTPThresholds map[string]float64 `yaml:"withdrawal_totp_thresholds"`
 }
 
 type IAuthService interface {
-	Register(user models.User) (*models.AuthData, error)
-	Login(user models.User) (*models.AuthData, error)
-	UpdateTokens(tokens models.AuthData) (*models.AuthData, error)
+	Register(user models.User, device models.Device) (*models.AuthData, error)
+	Login(user models.User, device models.Device) (*models.AuthData, error)
+	UpdateTokens(tokens models.AuthData, device models.Device) (*models.AuthData, error)
+	GetSessions(accessToken string) ([]models.Session, error)
+	Logout(accessToken string) (int, error)
+	LogoutAll(accessToken string) (int, error)
+	LoginTOTP(login models.TOTPLogin, device models.Device) (*models.AuthData, error)
+	EnrollTOTP(accessToken string) (*models.TOTPEnrollment, error)
+	ActivateTOTP(accessToken, code string) ([]string, error)
+	VerifyTOTP(accessToken, code string) error
 }
 
 type IBalanceService interface {
-	GetBalance(*uuid.UUID) (float32, error)
-	AddBalance(*models.Money) (string, error)
+	GetBalance(userID *uuid.UUID, currency string) (money.Amount, error)
+	GetWallets(userID *uuid.UUID) ([]models.Wallet, error)
+	Exchange(userID *uuid.UUID, exchange models.Exchange) (*models.Exchange, error)
+	CreatePayment(userID *uuid.UUID, deposit models.Money) (*models.Payment, error)
+	ConfirmPayment(payload []byte, signature string) (*models.Payment, *uuid.UUID, error)
+	GetPayments(userID *uuid.UUID, paymentID *uuid.UUID) ([]models.Payment, error)
 	TakeBalance(*models.Money) (string, error)
+	ReplayTakeBalance(*models.Money) (string, bool, error)
+	GetTransactions(userID *uuid.UUID, filter models.TransactionFilter) (*models.Transactions, error)
+	Transfer(userID *uuid.UUID, transfer models.Transfer) (*models.Transfer, *uuid.UUID, error)
+	ReplayTransfer(userID *uuid.UUID, transfer models.Transfer) (*models.Transfer, *uuid.UUID, bool, error)
 }
 
 type IPapersService interface {
@@ -48,14 +84,30 @@ type IPapersService interface {
 	GetUserPapers(userID *uuid.UUID) ([]byte, error)
 	SellPaper(userID *uuid.UUID, paper models.Paper) (string, error)
 	BuyPaper(userID *uuid.UUID, paper models.Paper) (string, error)
+	Subscribe(ctx context.Context, papers []string) (<-chan models.Paper, error)
+	GetTradeHistory(userID *uuid.UUID, filter models.TradeFilter) (*models.TradeHistory, error)
+	PlaceOrder(userID *uuid.UUID, order models.Order) (*models.Order, error)
+	CancelOrder(userID *uuid.UUID, orderID uuid.UUID) (*models.Order, error)
+	ReplaceOrder(userID *uuid.UUID, orderID uuid.UUID, order models.Order) (*models.Order, error)
+	GetUserOrders(userID *uuid.UUID) ([]models.Order, error)
+	GetOrderBook(paper string, depth int32) (*models.OrderBook, error)
+	GetPriceHistory(paper, interval string, from, to int64) (*models.PriceHistory, error)
+}
+
+type IMarketAdmin interface {
+	ListPapers() ([]models.Paper, error)
+	CreatePaper(paper models.Paper) (*models.Paper, error)
+	DelistPaper(name string) (*models.Paper, error)
+	HaltPaper(name string) (*models.Paper, error)
+	ResumePaper(name string) (*models.Paper, error)
+	SetPrice(paper models.Paper) (*models.Paper, error)
 }
 
 type IJWTManager interface {
-	GetPublicKey() *rsa.PublicKey
 	GetIDFromToken(token string) (*uuid.UUID, error)
 	ValidateToken(c *fiber.Ctx, key string) (bool, error)
-	AuthFilter(c *fiber.Ctx) bool
-	RefreshFilter(c *fiber.Ctx) bool
+	ValidateRefreshToken(c *fiber.Ctx, key string) (bool, error)
+	GetPermissionsFromToken(token string) ([]string, error)
 }
 
 var (
@@ -70,53 +122,78 @@ var (
 	})
 )
 
+// Заголовок с ключом идемпотентности изменяющих запросов и его максимальная длина
+const (
+	idempotencyHeader = "Idempotency-Key"
+	maxIdempotencyKey = 255
+)
+
+var errLongIdempotencyKey = errors.New("idempotency key is too long")
+
 // Создание рутов для запросов с применением middleware для проверки валидности токенов и началом получения сообщений из брокера
-func New(cfg *Config, auservice IAuthService, pps IPapersService, balance IBalanceService, jwt IJWTManager) (*Router, error) {
+func New(cfg *Config, auservice IAuthService, pps IPapersService, balance IBalanceService, market IMarketAdmin, jwt IJWTManager, fills IFills) (*Router, error) {
 	app := fiber.New()
-	router := Router{App: app, Config: cfg, jwt: jwt, asvc: auservice, pps: pps, balance: balance}
-	router.App.Use("/ws", func(c *fiber.Ctx) error {
-		if websocket.IsWebSocketUpgrade(c) {
-			err := c.Next()
-			if err != nil {
-				log.Println(err.Error())
-				return err
-			}
-		}
-		return nil
-	})
+	router := Router{App: app, Config: cfg, jwt: jwt, asvc: auservice, pps: pps, balance: balance, market: market, notifier: notifier.New(wsBufferSize)}
+	router.App.Use("/ws", router.WSAuth())
 	router.App.Use(cors.New(cors.Config{
-		AllowHeaders: "X-Access-Token, X-Refresh-Token",
-	}))
-	router.App.Use(keyauth.New(keyauth.Config{
-		Next:         router.jwt.AuthFilter,
-		KeyLookup:    "header:X-Access-Token",
-		Validator:    router.jwt.ValidateToken,
-		ErrorHandler: router.ErrorHandler(),
-	}))
-	router.App.Use(keyauth.New(keyauth.Config{
-		Next:         router.jwt.RefreshFilter,
-		KeyLookup:    "header:X-Refresh-Token",
-		Validator:    router.jwt.ValidateToken,
-		ErrorHandler: router.ErrorHandler(),
+		AllowHeaders: "X-Access-Token, X-Refresh-Token, Idempotency-Key, X-TOTP-Code",
 	}))
+	router.App.Use(router.Authorize())
 	registerMetrics()
+	trades, err := fills.SubscribeFills(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	go router.deliverFills(trades)
 
 	router.App.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
 
 	router.App.Get("/ping", Ping)
 
 	router.App.Post("/login", router.Login())
+	router.App.Post("/login/totp", router.LoginTOTP())
+	router.App.Post("/totp/enroll", router.EnrollTOTP())
+	router.App.Post("/totp/activate", router.ActivateTOTP())
 	router.App.Post("/register", router.Register())
 	router.App.Get("/refresh", router.UpdateTokens())
+	router.App.Get("/sessions", router.GetSessions())
+	router.App.Post("/logout", router.Logout(false))
+	router.App.Post("/logout/all", router.Logout(true))
 
 	router.App.Get("/papers", router.GetPapers())
+	router.App.Get("/papers/:name/candles", router.GetCandles())
 	router.App.Post("/buypaper", router.BuyPaper())
 	router.App.Post("/sellpaper", router.SellPaper())
 	router.App.Get("/mypapers", router.GetUserPapers())
+	router.App.Get("/trades", router.GetTradeHistory())
+
+	router.App.Get("/orderbook/:name", router.GetOrderBook())
+	router.App.Get("/orders", router.GetUserOrders())
+	router.App.Post("/orders", router.PlaceOrder())
+	router.App.Put("/orders/:id", router.ReplaceOrder())
+	router.App.Delete("/orders/:id", router.CancelOrder())
 
 	router.App.Get("/balance", router.GetBalance())
-	router.App.Post("/addbalance", router.AddBalance())
+	router.App.Get("/balance/transactions", router.GetTransactions())
+	router.App.Post("/transfer", router.Transfer())
+	router.App.Get("/wallets", router.GetWallets())
+	router.App.Post("/exchange", router.Exchange())
+	// Пополнение идет через платежного провайдера, /addbalance оставлен для старых клиентов
+	router.App.Post("/addbalance", router.Deposit())
+	router.App.Post("/deposits", router.Deposit())
+	router.App.Get("/deposits", router.GetPayments())
+	router.App.Get("/deposits/:id", router.GetPayment())
+	router.App.Post("/webhooks/payments", router.PaymentWebhook())
 	router.App.Post("/takebalance", router.TakeBalance())
+
+	router.App.Get("/ws/prices", router.PricesFeed())
+
+	router.App.Get("/admin/papers", router.AdminListPapers())
+	router.App.Post("/admin/papers", router.AdminCreatePaper())
+	router.App.Delete("/admin/papers/:name", router.AdminDelistPaper())
+	router.App.Post("/admin/papers/:name/halt", router.AdminHaltPaper())
+	router.App.Post("/admin/papers/:name/resume", router.AdminResumePaper())
+	router.App.Put("/admin/papers/:name/price", router.AdminSetPrice())
 	return &router, nil
 }
 
@@ -158,9 +235,18 @@ func (r *Router) SellPaper() fiber.Handler {
 			c.Status(500)
 			return nil
 		}
+		if paper.IdempotencyKey, err = idempotencyKey(c); err != nil {
+			return nil
+		}
 		bod, err := r.pps.SellPaper(userID, paper)
 		if err != nil {
 			log.Println("selling paper error:", err)
+			switch status.Code(err) {
+			case codes.FailedPrecondition, codes.Aborted:
+				return c.Status(fiber.StatusConflict).SendString(status.Convert(err).Message())
+			case codes.AlreadyExists:
+				return c.Status(fiber.StatusUnprocessableEntity).SendString(status.Convert(err).Message())
+			}
 			c.Status(500)
 			return nil
 		}
@@ -186,9 +272,18 @@ func (r *Router) BuyPaper() fiber.Handler {
 			c.Status(500)
 			return nil
 		}
+		if paper.IdempotencyKey, err = idempotencyKey(c); err != nil {
+			return nil
+		}
 		bod, err := r.pps.BuyPaper(userID, paper)
 		if err != nil {
 			log.Println("buying paper error:", err)
+			switch status.Code(err) {
+			case codes.FailedPrecondition, codes.Aborted:
+				return c.Status(fiber.StatusConflict).SendString(status.Convert(err).Message())
+			case codes.AlreadyExists:
+				return c.Status(fiber.StatusUnprocessableEntity).SendString(status.Convert(err).Message())
+			}
 			c.Status(500)
 			return nil
 		}
@@ -219,6 +314,54 @@ func (r *Router) GetUserPapers() fiber.Handler {
 	}
 }
 
+// История сделок пользователя: /trades?paper=Dogecoin&from=1700000000&to=1800000000&limit=50&cursor=...
+func (r *Router) GetTradeHistory() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		access := c.GetReqHeaders()["X-Access-Token"][0]
+		userID, err := r.jwt.GetIDFromToken(access)
+		if err != nil {
+			log.Println("getting id from token error:", err)
+			c.Status(500)
+			return nil
+		}
+		filter := models.TradeFilter{
+			Paper:  c.Query("paper"),
+			From:   int64(c.QueryInt("from")),
+			To:     int64(c.QueryInt("to")),
+			Cursor: c.Query("cursor"),
+			Limit:  int32(c.QueryInt("limit")),
+		}
+		history, err := r.pps.GetTradeHistory(userID, filter)
+		if err != nil {
+			log.Println("getting trade history error:", err)
+			if status.Code(err) == codes.InvalidArgument {
+				c.Status(fiber.StatusBadRequest)
+				return nil
+			}
+			c.Status(500)
+			return nil
+		}
+		return c.Status(200).JSON(history)
+	}
+}
+
+// Свечи бумаги для графика: /papers/Dogecoin/candles?interval=1m&from=1700000000&to=1800000000
+func (r *Router) GetCandles() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		history, err := r.pps.GetPriceHistory(c.Params("name"), c.Query("interval", "1m"), int64(c.QueryInt("from")), int64(c.QueryInt("to")))
+		if err != nil {
+			log.Println("getting price history error:", err)
+			if status.Code(err) == codes.InvalidArgument {
+				return c.Status(fiber.StatusBadRequest).SendString(status.Convert(err).Message())
+			}
+			c.Status(500)
+			return nil
+		}
+		return c.Status(200).JSON(history)
+	}
+}
+
+// Баланс в валюте: /balance?currency=EUR, без currency - в базовой
 func (r *Router) GetBalance() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		access := c.GetReqHeaders()["X-Access-Token"][0]
@@ -228,47 +371,179 @@ func (r *Router) GetBalance() fiber.Handler {
 			c.Status(500)
 			return nil
 		}
-		balance, err := r.balance.GetBalance(userID)
+		currency := strings.ToUpper(c.Query("currency"))
+		balance, err := r.balance.GetBalance(userID, currency)
 		if err != nil {
 			log.Println("getting user balance error:", err)
+			if status.Code(err) == codes.InvalidArgument {
+				return c.Status(fiber.StatusBadRequest).SendString(status.Convert(err).Message())
+			}
 			c.Status(500)
 			return nil
 		}
 		c.Status(200)
-		c.WriteString(fmt.Sprintf("Your balance is %.2f", balance))
+		if currency == "" {
+			c.WriteString(fmt.Sprintf("Your balance is %v", balance))
+		} else {
+			c.WriteString(fmt.Sprintf("Your balance is %v %v", balance, currency))
+		}
 		return nil
 	}
 }
 
-func (r *Router) AddBalance() fiber.Handler {
+// Кошельки пользователя во всех валютах
+func (r *Router) GetWallets() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		access := c.GetReqHeaders()["X-Access-Token"][0]
-		money := models.Money{}
-		err := json.Unmarshal(c.Body(), &money)
+		userID, err := r.userIDFromRequest(c)
 		if err != nil {
-			log.Println("unmarshal error:", err)
+			return nil
+		}
+		wallets, err := r.balance.GetWallets(userID)
+		if err != nil {
+			log.Println("getting user wallets error:", err)
 			c.Status(500)
 			return nil
 		}
+		return c.Status(200).JSON(wallets)
+	}
+}
+
+// Обмен валюты по текущему курсу: {"from": "USD", "to": "EUR", "amount": 1000}. Отказ balance - 400
+func (r *Router) Exchange() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var exchange models.Exchange
+		if err := json.Unmarshal(c.Body(), &exchange); err != nil {
+			log.Println("unmarshal error:", err)
+			c.Status(fiber.StatusBadRequest)
+			return nil
+		}
+		userID, err := r.userIDFromRequest(c)
+		if err != nil {
+			return nil
+		}
+		if exchange.IdempotencyKey, err = idempotencyKey(c); err != nil {
+			return nil
+		}
+		exchange.From, exchange.To = strings.ToUpper(exchange.From), strings.ToUpper(exchange.To)
+		done, err := r.balance.Exchange(userID, exchange)
+		if err != nil {
+			log.Println("exchange error:", err)
+			return balanceError(c, err)
+		}
+		if done.Response != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(done)
+		}
+		r.notifyBalance(*userID)
+		return c.Status(200).JSON(done)
+	}
+}
+
+// История операций по балансу: /balance/transactions?type=deposit,fee&currency=EUR&from=1700000000&to=1800000000&limit=50&cursor=...
+// С format=csv или Accept: text/csv страница отдается в CSV, курсор следующей страницы - в заголовке X-Next-Cursor
+func (r *Router) GetTransactions() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		access := c.GetReqHeaders()["X-Access-Token"][0]
 		userID, err := r.jwt.GetIDFromToken(access)
 		if err != nil {
 			log.Println("getting id from token error:", err)
 			c.Status(500)
 			return nil
 		}
-		money.ID = *userID
-		bod, err := r.balance.AddBalance(&money)
+		filter := models.TransactionFilter{
+			Currency: strings.ToUpper(c.Query("currency")),
+			From:     int64(c.QueryInt("from")),
+			To:       int64(c.QueryInt("to")),
+			Cursor:   c.Query("cursor"),
+			Limit:    int32(c.QueryInt("limit")),
+		}
+		if types := c.Query("type"); types != "" {
+			filter.Types = strings.Split(types, ",")
+		}
+		history, err := r.balance.GetTransactions(userID, filter)
 		if err != nil {
-			log.Println("adding balance error:", err)
+			log.Println("getting transactions error:", err)
+			if status.Code(err) == codes.InvalidArgument {
+				return c.Status(fiber.StatusBadRequest).SendString(status.Convert(err).Message())
+			}
 			c.Status(500)
 			return nil
 		}
-		c.Status(200)
-		c.WriteString(bod)
-		return nil
+		if c.Query("format") != "csv" && c.Accepts(fiber.MIMEApplicationJSON, "text/csv") != "text/csv" {
+			return c.Status(200).JSON(history)
+		}
+		if history.NextCursor != "" {
+			c.Set("X-Next-Cursor", history.NextCursor)
+		}
+		c.Set(fiber.HeaderContentType, "text/csv")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="transactions.csv"`)
+		w := csv.NewWriter(c.Status(200).Response().BodyWriter())
+		w.Write([]string{"id", "type", "currency", "amount", "balance", "reference", "memo", "created_at"})
+		for _, t := range history.Transactions {
+			reference := ""
+			if t.Reference != nil {
+				reference = t.Reference.String()
+			}
+			w.Write([]string{t.ID.String(), t.Type, t.Currency, t.Amount.String(), t.Balance.String(), reference, t.Memo, t.CreatedAt.Format(time.RFC3339)})
+		}
+		w.Flush()
+		return w.Error()
 	}
 }
 
+// Перевод денег другому пользователю по логину. Отказ balance - 400, получатель узнает о переводе в вебсокете.
+// Перевод больше порога требует кода 2FA, как вывод
+func (r *Router) Transfer() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var transfer models.Transfer
+		if err := json.Unmarshal(c.Body(), &transfer); err != nil {
+			log.Println("unmarshal error:", err)
+			c.Status(fiber.StatusBadRequest)
+			return nil
+		}
+		userID, err := r.userIDFromRequest(c)
+		if err != nil {
+			return nil
+		}
+		if transfer.IdempotencyKey, err = idempotencyKey(c); err != nil {
+			return nil
+		}
+		if r.withdrawalNeedsTOTP(transfer.Currency, transfer.Amount) {
+			if transfer.IdempotencyKey != "" {
+				// Повтор проведенного перевода с тем же ключом отдается без нового кода, получатель о нем уже знает
+				done, _, replayed, err := r.balance.ReplayTransfer(userID, transfer)
+				if err != nil {
+					log.Println("replaying transfer error:", err)
+					return balanceError(c, err)
+				}
+				if replayed {
+					return transferResult(c, done)
+				}
+			}
+			if !r.checkWithdrawalTOTP(c) {
+				return nil
+			}
+		}
+		done, recipient, err := r.balance.Transfer(userID, transfer)
+		if err != nil {
+			log.Println("transfer error:", err)
+			return balanceError(c, err)
+		}
+		if done.Response == "" {
+			r.notifyBalance(*userID)
+			r.notifyTransfer(*recipient, *done)
+		}
+		return transferResult(c, done)
+	}
+}
+
+// Отказ balance - 400
+func transferResult(c *fiber.Ctx, done *models.Transfer) error {
+	if done.Response != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(done)
+	}
+	return c.Status(200).JSON(done)
+}
+
 func (r *Router) TakeBalance() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		access := c.GetReqHeaders()["X-Access-Token"][0]
@@ -286,11 +561,33 @@ func (r *Router) TakeBalance() fiber.Handler {
 			return nil
 		}
 		money.ID = *userID
+		if money.IdempotencyKey, err = idempotencyKey(c); err != nil {
+			return nil
+		}
+		needsTOTP := r.withdrawalNeedsTOTP(money.Currency, money.Cash)
+		if needsTOTP && money.IdempotencyKey != "" {
+			// Код из приложения принимается один раз: повтор проведенного вывода с тем же ключом отдается без нового кода
+			bod, replayed, err := r.balance.ReplayTakeBalance(&money)
+			if err != nil {
+				log.Println("replaying withdrawal error:", err)
+				return balanceError(c, err)
+			}
+			if replayed {
+				c.Status(200)
+				c.WriteString(bod)
+				return nil
+			}
+		}
+		if needsTOTP && !r.checkWithdrawalTOTP(c) {
+			return nil
+		}
 		bod, err := r.balance.TakeBalance(&money)
 		if err != nil {
 			log.Println("taking from balance error:", err)
-			c.Status(500)
-			return nil
+			return balanceError(c, err)
+		}
+		if bod == "" {
+			r.notifyBalance(*userID)
 		}
 		c.Status(200)
 		c.WriteString(bod)
@@ -298,6 +595,47 @@ func (r *Router) TakeBalance() fiber.Handler {
 	}
 }
 
+// Переводит нарушение лимитов balance в http: неверная сумма - 400, исчерпанный лимит или перерыв - 429
+// с Retry-After. Ключ идемпотентности с другим запросом - 422, запрос с тем же ключом еще выполняется - 409.
+// Остальные ошибки - 500
+func balanceError(c *fiber.Ctx, err error) error {
+	st := status.Convert(err)
+	code := fiber.StatusBadRequest
+	switch st.Code() {
+	case codes.InvalidArgument:
+	case codes.ResourceExhausted:
+		code = fiber.StatusTooManyRequests
+	case codes.AlreadyExists:
+		return c.Status(fiber.StatusUnprocessableEntity).SendString(st.Message())
+	case codes.Aborted:
+		return c.Status(fiber.StatusConflict).SendString(st.Message())
+	default:
+		c.Status(500)
+		return nil
+	}
+	violation := models.Violation{Message: st.Message()}
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			violation.Rule, violation.Details = d.Reason, d.Metadata
+		case *errdetails.RetryInfo:
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(d.RetryDelay.AsDuration().Seconds()))))
+		}
+	}
+	return c.Status(code).JSON(violation)
+}
+
+// Ключ идемпотентности из заголовка Idempotency-Key: повтор запроса с тем же ключом не проводит операцию второй раз,
+// а возвращает первый ответ. Пустой ключ - без защиты от повтора, слишком длинный - 400
+func idempotencyKey(c *fiber.Ctx) (string, error) {
+	key := c.Get(idempotencyHeader)
+	if len(key) > maxIdempotencyKey {
+		c.Status(fiber.StatusBadRequest).SendString(errLongIdempotencyKey.Error())
+		return "", errLongIdempotencyKey
+	}
+	return key, nil
+}
+
 // Логиним пользователя
 func (r *Router) Login() fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -307,7 +645,7 @@ func (r *Router) Login() fiber.Handler {
 			return err
 		}
 		log.Printf("User to login: %s\n", user.Login)
-		authData, err := r.asvc.Login(user)
+		authData, err := r.asvc.Login(user, device(c))
 		if err != nil {
 			switch err.Error() {
 			case "rpc error: code = AlreadyExists desc = login occupied":
@@ -316,6 +654,10 @@ func (r *Router) Login() fiber.Handler {
 			}
 			return err
 		}
+		if authData.ChallengeToken != "" {
+			// Включена 2FA: токены выдаст /login/totp по коду
+			return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"challenge_token": authData.ChallengeToken})
+		}
 		log.Printf("Tokens to return:\nAccess token: %s\nRefresh token: %s", authData.AccessToken[:20], authData.RefreshToken[:20])
 		c.Context().Response.Header.Set("X-Access-Token", authData.AccessToken)
 		c.Context().Response.Header.Set("X-Refresh-Token", authData.RefreshToken)
@@ -332,7 +674,7 @@ func (r *Router) Register() fiber.Handler {
 			return err
 		}
 		log.Printf("User to register: %s\n", user.Login)
-		authData, err := r.asvc.Register(user)
+		authData, err := r.asvc.Register(user, device(c))
 		if err != nil {
 			return err
 		}
@@ -343,13 +685,16 @@ func (r *Router) Register() fiber.Handler {
 	}
 }
 
-// Создание новой пары токенов
+// Обмен refresh токена на новую пару. Refresh токен одноразовый: повтор уже обмененного токена отзывает сессию, 401
 func (r *Router) UpdateTokens() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		authData := models.AuthData{AccessToken: c.GetReqHeaders()["X-Access-Token"][0], RefreshToken: c.GetReqHeaders()["X-Refresh-Token"][0]}
-		log.Printf("Got tokens:\nAccess token: %s\nRefresh token: %s", authData.AccessToken[:20], authData.RefreshToken[:20])
-		authDataResp, err := r.asvc.UpdateTokens(authData)
+		authData := models.AuthData{AccessToken: c.Get("X-Access-Token"), RefreshToken: c.Get("X-Refresh-Token")}
+		log.Printf("Got refresh token: %s", authData.RefreshToken[:20])
+		authDataResp, err := r.asvc.UpdateTokens(authData, device(c))
 		if err != nil {
+			if status.Code(err) == codes.Unauthenticated {
+				return c.Status(fiber.StatusUnauthorized).SendString(status.Convert(err).Message())
+			}
 			return err
 		}
 
@@ -360,16 +705,45 @@ func (r *Router) UpdateTokens() fiber.Handler {
 	}
 }
 
-// Хэндл ошибок для мидлвейра
-func (r *Router) ErrorHandler() func(c *fiber.Ctx, err error) error {
-	return func(c *fiber.Ctx, err error) error {
-		log.Println("Bad access token: ", c.GetReqHeaders()["X-Access-Token"])
-		log.Println("Bad refresh token: ", c.GetReqHeaders()["X-Refresh-Token"])
-		log.Println("Wrong jwts: " + err.Error())
-		return err
+// Активные сессии пользователя, сессия запроса отмечена current
+func (r *Router) GetSessions() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sessions, err := r.asvc.GetSessions(c.Get("X-Access-Token"))
+		if err != nil {
+			log.Println("getting sessions error:", err)
+			c.Status(500)
+			return nil
+		}
+		return c.Status(200).JSON(sessions)
 	}
 }
 
+// Выход из сессии запроса или, если all, из всех сессий пользователя. Access токен перестает приниматься сразу,
+// refresh токены сессий больше не обмениваются
+func (r *Router) Logout(all bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		logout := r.asvc.Logout
+		if all {
+			logout = r.asvc.LogoutAll
+		}
+		sessions, err := logout(c.Get("X-Access-Token"))
+		if err != nil {
+			log.Println("logout error:", err)
+			if status.Code(err) == codes.Unauthenticated {
+				return c.Status(fiber.StatusUnauthorized).SendString(status.Convert(err).Message())
+			}
+			c.Status(500)
+			return nil
+		}
+		return c.Status(200).JSON(fiber.Map{"sessions": sessions})
+	}
+}
+
+// Устройство запроса для сессии входа
+func device(c *fiber.Ctx) models.Device {
+	return models.Device{UserAgent: c.Get(fiber.HeaderUserAgent), IP: c.IP()}
+}
+
 // Пингуем сервер
 func Ping(c *fiber.Ctx) error {
 	log.Println("Ping")
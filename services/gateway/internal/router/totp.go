@@ -0,0 +1,113 @@
+package router
+
+import (
+	"encoding/json"
+	"gateway/internal/pkg/models"
+	"gateway/internal/pkg/money"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Заголовок с кодом 2FA для подтверждения крупного вывода или перевода
+const totpHeader = "X-TOTP-Code"
+
+// Второй шаг входа с 2FA: {"challenge_token": "...", "code": "123456"}, code - из приложения или код восстановления
+func (r *Router) LoginTOTP() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var login models.TOTPLogin
+		if err := json.Unmarshal(c.Body(), &login); err != nil {
+			log.Println("unmarshal error:", err)
+			c.Status(fiber.StatusBadRequest)
+			return nil
+		}
+		authData, err := r.asvc.LoginTOTP(login, device(c))
+		if err != nil {
+			log.Println("totp login error:", err)
+			return totpError(c, err)
+		}
+		c.Context().Response.Header.Set("X-Access-Token", authData.AccessToken)
+		c.Context().Response.Header.Set("X-Refresh-Token", authData.RefreshToken)
+		return nil
+	}
+}
+
+// Подключение 2FA: секрет и otpauth URI для QR кода. Включается после /totp/activate
+func (r *Router) EnrollTOTP() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		enrollment, err := r.asvc.EnrollTOTP(c.Get("X-Access-Token"))
+		if err != nil {
+			return totpError(c, err)
+		}
+		return c.Status(200).JSON(enrollment)
+	}
+}
+
+// Включение 2FA первым кодом: {"code": "123456"}. Коды восстановления в ответе показываются один раз
+func (r *Router) ActivateTOTP() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body struct {
+			Code string `json:"code"`
+		}
+		if err := json.Unmarshal(c.Body(), &body); err != nil {
+			log.Println("unmarshal error:", err)
+			c.Status(fiber.StatusBadRequest)
+			return nil
+		}
+		recovery, err := r.asvc.ActivateTOTP(c.Get("X-Access-Token"), body.Code)
+		if err != nil {
+			return totpError(c, err)
+		}
+		return c.Status(200).JSON(fiber.Map{"recovery_codes": recovery})
+	}
+}
+
+// Нужен ли код 2FA для вывода или перевода: сумма больше порога своей валюты или валюты нет среди порогов
+func (r *Router) withdrawalNeedsTOTP(currency string, amount money.Amount) bool {
+	if len(r.Config.WithdrawalTOTPThresholds) == 0 {
+		return false
+	}
+	if currency == "" {
+		currency = "USD"
+	}
+	threshold, ok := r.Config.WithdrawalTOTPThresholds[currency]
+	return !ok || amount.Float() > threshold
+}
+
+// Проверяет код 2FA крупного вывода или перевода из заголовка X-TOTP-Code. Без включенной 2FA такие операции
+// запрещены так же, как с неверным кодом. При ошибке ответ уже записан
+func (r *Router) checkWithdrawalTOTP(c *fiber.Ctx) bool {
+	code := c.Get(totpHeader)
+	if code == "" {
+		c.Status(fiber.StatusForbidden).SendString("two-factor code is required for this withdrawal")
+		return false
+	}
+	err := r.asvc.VerifyTOTP(c.Get("X-Access-Token"), code)
+	switch {
+	case status.Code(err) == codes.FailedPrecondition:
+		c.Status(fiber.StatusForbidden).SendString("two-factor authentication is required for this withdrawal")
+		return false
+	case err != nil:
+		totpError(c, err)
+		return false
+	}
+	return true
+}
+
+func totpError(c *fiber.Ctx, err error) error {
+	st := status.Convert(err)
+	switch st.Code() {
+	case codes.Unauthenticated:
+		return c.Status(fiber.StatusUnauthorized).SendString(st.Message())
+	case codes.PermissionDenied:
+		return c.Status(fiber.StatusForbidden).SendString(st.Message())
+	case codes.ResourceExhausted:
+		return c.Status(fiber.StatusTooManyRequests).SendString(st.Message())
+	case codes.FailedPrecondition:
+		return c.Status(fiber.StatusConflict).SendString(st.Message())
+	}
+	c.Status(500)
+	return nil
+}
@@ -0,0 +1,263 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gateway/internal/pkg/models"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Типы сообщений вебсокета
+const (
+	WSSubscribe    = "subscribe"
+	WSUnsubscribe  = "unsubscribe"
+	WSSubscribed   = "subscribed"
+	WSUnsubscribed = "unsubscribed"
+	WSPrice        = "price"
+	WSTrade        = "trade"
+	WSBalance      = "balance"
+	WSTransfer     = "transfer"
+	WSPayment      = "payment"
+	WSHeartbeat    = "heartbeat"
+	WSError        = "error"
+)
+
+const wsBufferSize = 64
+
+// Открытые подписки на цены одного вебсокета
+type wsSession struct {
+	mu      sync.Mutex
+	streams map[string]context.CancelFunc
+}
+
+// Проверяет access токен при апгрейде до вебсокета. Браузер не может передать заголовок при апгрейде, поэтому токен можно передать в query параметре token
+func (r *Router) WSAuth() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !websocket.IsWebSocketUpgrade(c) {
+			return fiber.ErrUpgradeRequired
+		}
+		token := c.Get("X-Access-Token")
+		if token == "" {
+			token = c.Query("token")
+		}
+		if token == "" {
+			log.Println("Websocket upgrade without access token")
+			return fiber.ErrUnauthorized
+		}
+		if ok, err := r.jwt.ValidateToken(c, token); !ok {
+			log.Println("Bad websocket access token:", err)
+			return fiber.ErrUnauthorized
+		}
+		userID, err := r.jwt.GetIDFromToken(token)
+		if err != nil {
+			log.Println("getting id from token error:", err)
+			return fiber.ErrUnauthorized
+		}
+		c.Locals("userID", *userID)
+		c.Locals("accessToken", token)
+		return c.Next()
+	}
+}
+
+// Живая лента цен: клиент присылает {"action": "subscribe"/"unsubscribe", "papers": [...]},
+// сервер присылает цены подписанных бумаг, сделки и изменения баланса пользователя и heartbeat
+func (r *Router) PricesFeed() fiber.Handler {
+	return websocket.New(func(conn *websocket.Conn) {
+		userID := conn.Locals("userID").(uuid.UUID)
+		token := conn.Locals("accessToken").(string)
+		log.Printf("User %v opened prices feed\n", userID)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		out := make(chan models.WSMessage, wsBufferSize)
+		events, stopEvents := r.notifier.Subscribe(userID)
+		defer stopEvents()
+		session := &wsSession{streams: map[string]context.CancelFunc{}}
+		defer session.closeAll()
+
+		heartbeat := time.Second * time.Duration(r.Config.WSHeartbeat)
+		conn.SetReadDeadline(time.Now().Add(3 * heartbeat))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(3 * heartbeat))
+		})
+		go r.wsWriter(ctx, cancel, conn, token, heartbeat, out, events)
+
+		for {
+			_, raw, err := conn.ReadMessage()
+			if err != nil {
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					log.Println("Websocket read error:", err)
+				}
+				break
+			}
+			conn.SetReadDeadline(time.Now().Add(3 * heartbeat))
+			var req models.WSRequest
+			if err := json.Unmarshal(raw, &req); err != nil {
+				wsSend(ctx, out, models.WSMessage{Type: WSError, Error: "invalid request: " + err.Error()})
+				continue
+			}
+			switch req.Action {
+			case WSSubscribe:
+				for _, name := range req.Papers {
+					if err := r.wsSubscribe(ctx, session, name, out); err != nil {
+						wsSend(ctx, out, models.WSMessage{Type: WSError, Data: name, Error: err.Error()})
+						continue
+					}
+					wsSend(ctx, out, models.WSMessage{Type: WSSubscribed, Data: name})
+				}
+			case WSUnsubscribe:
+				for _, name := range req.Papers {
+					session.stop(name)
+					wsSend(ctx, out, models.WSMessage{Type: WSUnsubscribed, Data: name})
+				}
+			default:
+				wsSend(ctx, out, models.WSMessage{Type: WSError, Error: "unknown action: " + req.Action})
+			}
+		}
+		log.Printf("User %v closed prices feed\n", userID)
+	})
+}
+
+// Открывает стрим цен бумаги в papers и пересылает цены в вебсокет
+func (r *Router) wsSubscribe(ctx context.Context, session *wsSession, name string, out chan<- models.WSMessage) error {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if _, ok := session.streams[name]; ok {
+		return nil
+	}
+	streamCtx, stop := context.WithCancel(ctx)
+	updates, err := r.pps.Subscribe(streamCtx, []string{name})
+	if err != nil {
+		stop()
+		return errors.New("cant subscribe to paper")
+	}
+	session.streams[name] = stop
+	go func() {
+		for paper := range updates {
+			wsSend(streamCtx, out, models.WSMessage{Type: WSPrice, Data: paper})
+		}
+		// Стрим закрылся не по отписке(например, papers отключил медленного подписчика)
+		if streamCtx.Err() == nil {
+			session.stop(name)
+			wsSend(ctx, out, models.WSMessage{Type: WSUnsubscribed, Data: name, Error: "price stream closed"})
+		}
+	}()
+	return nil
+}
+
+// Пишет в вебсокет сообщения, события пользователя и heartbeat. Вебсокет не поддерживает конкурентную запись, поэтому писатель один.
+// Токен проверяется только при апгрейде, поэтому на каждом heartbeat он проверяется заново: истекший или отозванный(logout)
+// токен закрывает вебсокет
+func (r *Router) wsWriter(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, token string, heartbeat time.Duration, out <-chan models.WSMessage, events <-chan models.WSMessage) {
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	defer cancel()
+	var err error
+	for err == nil {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-out:
+			err = conn.WriteJSON(msg)
+		case msg := <-events:
+			err = conn.WriteJSON(msg)
+		case now := <-ticker.C:
+			if ok, tokenErr := r.jwt.ValidateToken(nil, token); !ok {
+				log.Println("Closing websocket with invalid access token:", tokenErr)
+				conn.WriteJSON(models.WSMessage{Type: WSError, Error: "access token expired or revoked"})
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "access token expired or revoked"), now.Add(heartbeat))
+				conn.Close()
+				return
+			}
+			if err = conn.WriteControl(websocket.PingMessage, nil, now.Add(heartbeat)); err == nil {
+				err = conn.WriteJSON(models.WSMessage{Type: WSHeartbeat, Data: now.Unix()})
+			}
+		}
+	}
+	log.Println("Websocket write error:", err)
+	// Разблокирует ReadMessage в обработчике
+	conn.Close()
+}
+
+func (s *wsSession) stop(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stop, ok := s.streams[name]; ok {
+		stop()
+		delete(s.streams, name)
+	}
+}
+
+func (s *wsSession) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, stop := range s.streams {
+		stop()
+		delete(s.streams, name)
+	}
+}
+
+func wsSend(ctx context.Context, out chan<- models.WSMessage, msg models.WSMessage) {
+	select {
+	case out <- msg:
+	case <-ctx.Done():
+	}
+}
+
+// Отправляет сделки из papers их участникам(maker и taker), пока канал не закроется
+func (r *Router) deliverFills(fills <-chan models.FillEvent) {
+	for fill := range fills {
+		r.notifyTrade(fill)
+	}
+}
+
+// Отправляет пользователю подтверждение сделки и его новый баланс
+func (r *Router) notifyTrade(fill models.FillEvent) {
+	if !r.notifier.HasListeners(fill.UserID) {
+		return
+	}
+	trade := models.Trade{
+		OrderID: &fill.OrderID, Side: fill.Side, Paper: fill.Paper, Amount: fill.Amount, Price: fill.Price, Status: "executed", ExecutedAt: &fill.ExecutedAt,
+	}
+	r.notifier.Notify(fill.UserID, models.WSMessage{Type: WSTrade, Data: trade})
+	r.notifyBalance(fill.UserID)
+}
+
+// Отправляет получателю поступивший перевод и его новый баланс
+func (r *Router) notifyTransfer(recipient uuid.UUID, transfer models.Transfer) {
+	if !r.notifier.HasListeners(recipient) {
+		return
+	}
+	r.notifier.Notify(recipient, models.WSMessage{Type: WSTransfer, Data: transfer})
+	r.notifyBalance(recipient)
+}
+
+// Отправляет пользователю состояние его платежа, после зачисления - и новый баланс
+func (r *Router) notifyPayment(userID uuid.UUID, payment models.Payment) {
+	if !r.notifier.HasListeners(userID) {
+		return
+	}
+	r.notifier.Notify(userID, models.WSMessage{Type: WSPayment, Data: payment})
+	if payment.Status == "settled" {
+		r.notifyBalance(userID)
+	}
+}
+
+// Отправляет пользователю его текущий баланс
+func (r *Router) notifyBalance(userID uuid.UUID) {
+	if !r.notifier.HasListeners(userID) {
+		return
+	}
+	balance, err := r.balance.GetBalance(&userID, "")
+	if err != nil {
+		log.Println("getting user balance error:", err)
+		return
+	}
+	r.notifier.Notify(userID, models.WSMessage{Type: WSBalance, Data: models.BalanceChange{Balance: balance}})
+}
@@ -2,14 +2,14 @@ package service
 
 import (
 	"context"
-	"crypto/rsa"
-	"crypto/x509"
 	"flag"
 	"log"
+	"time"
 
 	authService "gateway/internal/pkg/grpc/pb/authService"
 	"gateway/internal/pkg/models"
 
+	"github.com/google/uuid"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -37,8 +37,8 @@ func New(cfg *Config) (*Client, error) {
 }
 
 // Вызов функции регистрации
-func (c *Client) Register(user models.User) (*models.AuthData, error) {
-	authDataGed, err := c.client.Register(context.Background(), &authService.User{Login: user.Login, Password: user.Password})
+func (c *Client) Register(user models.User, device models.Device) (*models.AuthData, error) {
+	authDataGed, err := c.client.Register(context.Background(), &authService.User{Login: user.Login, Password: user.Password, Device: deviceToPb(device)})
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -47,32 +47,121 @@ func (c *Client) Register(user models.User) (*models.AuthData, error) {
 }
 
 // Вызов функции аутентификации пользователя
-func (c *Client) Login(user models.User) (*models.AuthData, error) {
-	authDataGed, err := c.client.Login(context.Background(), &authService.User{Login: user.Login, Password: user.Password})
+func (c *Client) Login(user models.User, device models.Device) (*models.AuthData, error) {
+	authDataGed, err := c.client.Login(context.Background(), &authService.User{Login: user.Login, Password: user.Password, Device: deviceToPb(device)})
+	if err != nil {
+		return nil, err
+	}
+	return &models.AuthData{AccessToken: authDataGed.AccessToken, RefreshToken: authDataGed.RefreshToken, ChallengeToken: authDataGed.ChallengeToken}, nil
+}
+
+// Второй шаг входа с 2FA
+func (c *Client) LoginTOTP(login models.TOTPLogin, device models.Device) (*models.AuthData, error) {
+	authDataGed, err := c.client.LoginTOTP(context.Background(), &authService.TOTPLogin{
+		ChallengeToken: login.ChallengeToken, Code: login.Code, Device: deviceToPb(device),
+	})
 	if err != nil {
 		return nil, err
 	}
 	return &models.AuthData{AccessToken: authDataGed.AccessToken, RefreshToken: authDataGed.RefreshToken}, nil
 }
 
+// Новый секрет 2FA пользователя access токена
+func (c *Client) EnrollTOTP(accessToken string) (*models.TOTPEnrollment, error) {
+	resp, err := c.client.EnrollTOTP(context.Background(), &authService.TOTPRequest{AccessToken: accessToken})
+	if err != nil {
+		log.Println("Failed to enroll totp:", err)
+		return nil, err
+	}
+	return &models.TOTPEnrollment{Secret: resp.Secret, URI: resp.Uri}, nil
+}
+
+// Включение 2FA первым кодом, возвращает коды восстановления
+func (c *Client) ActivateTOTP(accessToken, code string) ([]string, error) {
+	resp, err := c.client.ActivateTOTP(context.Background(), &authService.TOTPRequest{AccessToken: accessToken, Code: code})
+	if err != nil {
+		log.Println("Failed to activate totp:", err)
+		return nil, err
+	}
+	return resp.Codes, nil
+}
+
+// Проверка кода 2FA для подтверждения операции
+func (c *Client) VerifyTOTP(accessToken, code string) error {
+	_, err := c.client.VerifyTOTP(context.Background(), &authService.TOTPRequest{AccessToken: accessToken, Code: code})
+	if err != nil {
+		log.Println("Failed to verify totp:", err)
+	}
+	return err
+}
+
 // Вызов функции апдейта токенов
-func (c *Client) UpdateTokens(tokens models.AuthData) (*models.AuthData, error) {
-	authDataGed, err := c.client.UpdateTokens(context.Background(), &authService.AuthData{AccessToken: tokens.AccessToken, RefreshToken: tokens.RefreshToken})
+func (c *Client) UpdateTokens(tokens models.AuthData, device models.Device) (*models.AuthData, error) {
+	authDataGed, err := c.client.UpdateTokens(context.Background(), &authService.AuthData{
+		AccessToken: tokens.AccessToken, RefreshToken: tokens.RefreshToken, Device: deviceToPb(device),
+	})
 	if err != nil {
 		return nil, err
 	}
 	return &models.AuthData{AccessToken: authDataGed.AccessToken, RefreshToken: authDataGed.RefreshToken}, nil
 }
 
-// Получени приватного ключа для jwt(см jwt.NewWithKey())
-func (c *Client) GetPrivateKey() (*rsa.PrivateKey, error) {
-	data, err := c.client.GetPrivateKey(context.Background(), &authService.KeyRequest{})
+// Активные сессии пользователя access токена
+func (c *Client) GetSessions(accessToken string) ([]models.Session, error) {
+	resp, err := c.client.GetSessions(context.Background(), &authService.SessionsRequest{AccessToken: accessToken})
 	if err != nil {
+		log.Println("Failed to get sessions:", err)
 		return nil, err
 	}
-	key, err := x509.ParsePKCS1PrivateKey(data.Key)
+	sessions := make([]models.Session, 0, len(resp.Sessions))
+	for _, session := range resp.Sessions {
+		id, err := uuid.FromBytes(session.Id)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, models.Session{
+			ID: id, Device: models.Device{UserAgent: session.Device.GetUserAgent(), IP: session.Device.GetIp()},
+			CreatedAt: time.Unix(session.CreatedAt, 0), LastUsedAt: time.Unix(session.LastUsedAt, 0), ExpiresAt: time.Unix(session.ExpiresAt, 0),
+			Current: session.Current,
+		})
+	}
+	return sessions, nil
+}
+
+// Завершает сессию access токена. Возвращает число отозванных сессий
+func (c *Client) Logout(accessToken string) (int, error) {
+	resp, err := c.client.Logout(context.Background(), &authService.LogoutRequest{AccessToken: accessToken})
+	if err != nil {
+		log.Println("Failed to logout:", err)
+		return 0, err
+	}
+	return int(resp.Sessions), nil
+}
+
+// Завершает все сессии пользователя access токена
+func (c *Client) LogoutAll(accessToken string) (int, error) {
+	resp, err := c.client.LogoutAll(context.Background(), &authService.LogoutRequest{AccessToken: accessToken})
+	if err != nil {
+		log.Println("Failed to logout from all sessions:", err)
+		return 0, err
+	}
+	return int(resp.Sessions), nil
+}
+
+func deviceToPb(device models.Device) *authService.Device {
+	return &authService.Device{UserAgent: device.UserAgent, Ip: device.IP}
+}
+
+// Публичные ключи проверки токенов aus
+func (c *Client) GetJWKS() ([]models.JWK, error) {
+	resp, err := c.client.GetJWKS(context.Background(), &authService.KeyRequest{})
 	if err != nil {
+		log.Println("Failed to get jwks:", err)
 		return nil, err
 	}
-	return key, nil
+	keys := make([]models.JWK, 0, len(resp.Keys))
+	for _, key := range resp.Keys {
+		keys = append(keys, models.JWK{Kty: key.Kty, Kid: key.Kid, Use: key.Use, Alg: key.Alg, N: key.N, E: key.E})
+	}
+	return keys, nil
 }
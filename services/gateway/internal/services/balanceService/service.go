@@ -4,13 +4,17 @@ import (
 	"context"
 	"flag"
 	"log"
+	"time"
 
 	balanceService "gateway/internal/pkg/grpc/pb/balanceService"
 	"gateway/internal/pkg/models"
+	"gateway/internal/pkg/money"
 
 	"github.com/google/uuid"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 type Config struct {
@@ -34,32 +38,56 @@ func New(cfg *Config) (*Client, error) {
 	return &Client{client: c, conn: conn}, nil
 }
 
-func (c *Client) GetBalance(userID *uuid.UUID) (float32, error) {
+// Деньги пользователя в валюте currency, пустая - базовая
+func (c *Client) GetBalance(userID *uuid.UUID, currency string) (money.Amount, error) {
 	marshaledId, err := userID.MarshalBinary()
 	if err != nil {
 		log.Println("Failed to marshal user uuid")
 		return -1, err
 	}
-	balance, err := c.client.GetBalance(context.Background(), &balanceService.User{Id: marshaledId})
+	balance, err := c.client.GetBalance(context.Background(), &balanceService.User{Id: marshaledId, Currency: currency})
 	if err != nil {
 		log.Println(err)
 		return -1, err
 	}
-	return balance.Cash, nil
+	return money.Amount(balance.CashMinor), nil
 }
 
-func (c *Client) AddBalance(req *models.Money) (string, error) {
-	marshaledId, err := req.ID.MarshalBinary()
+// Кошельки пользователя во всех валютах
+func (c *Client) GetWallets(userID *uuid.UUID) ([]models.Wallet, error) {
+	marshaledId, err := userID.MarshalBinary()
 	if err != nil {
 		log.Println("Failed to marshal user uuid")
-		return "", err
+		return nil, err
 	}
-	resp, err := c.client.AddBalance(context.Background(), &balanceService.Money{Id: marshaledId, Cash: req.Cash})
+	balance, err := c.client.GetBalance(context.Background(), &balanceService.User{Id: marshaledId})
 	if err != nil {
 		log.Println(err)
-		return "", err
+		return nil, err
 	}
-	return resp.Response, nil
+	wallets := make([]models.Wallet, 0, len(balance.Wallets))
+	for _, wallet := range balance.Wallets {
+		wallets = append(wallets, models.Wallet{Currency: wallet.Currency, Amount: money.Amount(wallet.AmountMinor)})
+	}
+	return wallets, nil
+}
+
+// Меняет деньги пользователя между его кошельками. Отказ balance возвращается в Response
+func (c *Client) Exchange(userID *uuid.UUID, exchange models.Exchange) (*models.Exchange, error) {
+	marshaledId, err := userID.MarshalBinary()
+	if err != nil {
+		log.Println("Failed to marshal user uuid")
+		return nil, err
+	}
+	resp, err := c.client.Exchange(context.Background(), &balanceService.ExchangeRequest{
+		UserId: marshaledId, From: exchange.From, To: exchange.To, AmountMinor: int64(exchange.Amount), IdempotencyKey: exchange.IdempotencyKey,
+	})
+	if err != nil {
+		log.Println("Failed to exchange money:", err)
+		return nil, err
+	}
+	exchange.Received, exchange.Rate, exchange.Response = money.Amount(resp.ReceivedMinor), resp.Rate, resp.Response
+	return &exchange, nil
 }
 
 func (c *Client) TakeBalance(req *models.Money) (string, error) {
@@ -68,10 +96,188 @@ func (c *Client) TakeBalance(req *models.Money) (string, error) {
 		log.Println("Failed to marshal user uuid")
 		return "", err
 	}
-	resp, err := c.client.TakeBalance(context.Background(), &balanceService.Money{Id: marshaledId, Cash: req.Cash})
+	resp, err := c.client.TakeBalance(context.Background(), &balanceService.Money{Id: marshaledId, CashMinor: int64(req.Cash), Currency: req.Currency, IdempotencyKey: req.IdempotencyKey})
 	if err != nil {
 		log.Println(err)
 		return "", err
 	}
 	return resp.Response, nil
 }
+
+// Ответ уже проведенного вывода с тем же ключом идемпотентности: replayed = false, если такого вывода не было
+func (c *Client) ReplayTakeBalance(req *models.Money) (response string, replayed bool, err error) {
+	marshaledId, err := req.ID.MarshalBinary()
+	if err != nil {
+		log.Println("Failed to marshal user uuid")
+		return "", false, err
+	}
+	resp, err := c.client.TakeBalance(context.Background(), &balanceService.Money{
+		Id: marshaledId, CashMinor: int64(req.Cash), Currency: req.Currency, IdempotencyKey: req.IdempotencyKey, ReplayOnly: true,
+	})
+	if status.Code(err) == codes.NotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		log.Println(err)
+		return "", false, err
+	}
+	return resp.Response, true, nil
+}
+
+func (c *Client) GetTransactions(userID *uuid.UUID, filter models.TransactionFilter) (*models.Transactions, error) {
+	marshaledId, err := userID.MarshalBinary()
+	if err != nil {
+		log.Println("Failed to marshal user uuid")
+		return nil, err
+	}
+	resp, err := c.client.GetTransactions(context.Background(), &balanceService.TransactionsRequest{
+		UserId: marshaledId, Types: filter.Types, Currency: filter.Currency, From: filter.From, To: filter.To, Cursor: filter.Cursor, Limit: filter.Limit,
+	})
+	if err != nil {
+		log.Println("Failed to get transactions:", err)
+		return nil, err
+	}
+	history := &models.Transactions{Transactions: make([]models.Transaction, 0, len(resp.Transactions)), NextCursor: resp.NextCursor}
+	for _, t := range resp.Transactions {
+		id, err := uuid.FromBytes(t.EntryId)
+		if err != nil {
+			log.Println("Failed to parse transaction uuid:", err)
+			return nil, err
+		}
+		transaction := models.Transaction{
+			ID: id, Type: t.Type, Currency: t.Currency, Amount: money.Amount(t.AmountMinor), Balance: money.Amount(t.BalanceMinor), Memo: t.Memo,
+			CreatedAt: time.Unix(t.CreatedAt, 0).UTC(),
+		}
+		if len(t.Reference) > 0 {
+			reference, err := uuid.FromBytes(t.Reference)
+			if err != nil {
+				log.Println("Failed to parse transaction reference:", err)
+				return nil, err
+			}
+			transaction.Reference = &reference
+		}
+		history.Transactions = append(history.Transactions, transaction)
+	}
+	return history, nil
+}
+
+// Переводит деньги пользователю transfer.To. Возвращает перевод с id и логином отправителя и id получателя
+// для уведомления. Отказ balance возвращается в Response
+func (c *Client) Transfer(userID *uuid.UUID, transfer models.Transfer) (*models.Transfer, *uuid.UUID, error) {
+	return c.transfer(userID, transfer, false)
+}
+
+// Уже проведенный перевод с тем же ключом идемпотентности: replayed = false, если такого перевода не было
+func (c *Client) ReplayTransfer(userID *uuid.UUID, transfer models.Transfer) (done *models.Transfer, recipient *uuid.UUID, replayed bool, err error) {
+	done, recipient, err = c.transfer(userID, transfer, true)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil, false, nil
+	}
+	return done, recipient, err == nil, err
+}
+
+func (c *Client) transfer(userID *uuid.UUID, transfer models.Transfer, replayOnly bool) (*models.Transfer, *uuid.UUID, error) {
+	marshaledId, err := userID.MarshalBinary()
+	if err != nil {
+		log.Println("Failed to marshal user uuid")
+		return nil, nil, err
+	}
+	resp, err := c.client.Transfer(context.Background(), &balanceService.TransferRequest{
+		FromId: marshaledId, ToLogin: transfer.To, AmountMinor: int64(transfer.Amount), Currency: transfer.Currency, Memo: transfer.Memo,
+		IdempotencyKey: transfer.IdempotencyKey, ReplayOnly: replayOnly,
+	})
+	if err != nil {
+		if status.Code(err) != codes.NotFound {
+			log.Println("Failed to transfer money:", err)
+		}
+		return nil, nil, err
+	}
+	if resp.Response != "" {
+		transfer.Response = resp.Response
+		return &transfer, nil, nil
+	}
+	id, err := uuid.FromBytes(resp.Id)
+	if err != nil {
+		log.Println("Failed to parse transfer uuid:", err)
+		return nil, nil, err
+	}
+	recipient, err := uuid.FromBytes(resp.ToId)
+	if err != nil {
+		log.Println("Failed to parse recipient uuid:", err)
+		return nil, nil, err
+	}
+	transfer.ID, transfer.From = &id, resp.FromLogin
+	return &transfer, &recipient, nil
+}
+
+// Создает платеж пополнения у провайдера. Деньги зачисляются, когда провайдер подтвердит платеж
+func (c *Client) CreatePayment(userID *uuid.UUID, deposit models.Money) (*models.Payment, error) {
+	marshaledId, err := userID.MarshalBinary()
+	if err != nil {
+		log.Println("Failed to marshal user uuid")
+		return nil, err
+	}
+	resp, err := c.client.CreatePayment(context.Background(), &balanceService.PaymentRequest{
+		UserId: marshaledId, Currency: deposit.Currency, AmountMinor: int64(deposit.Cash), IdempotencyKey: deposit.IdempotencyKey,
+	})
+	if err != nil {
+		log.Println("Failed to create payment:", err)
+		return nil, err
+	}
+	payment, _, err := paymentFromPb(resp)
+	return payment, err
+}
+
+// Передает в balance callback провайдера с подписью. Возвращает платеж и его владельца
+func (c *Client) ConfirmPayment(payload []byte, signature string) (*models.Payment, *uuid.UUID, error) {
+	resp, err := c.client.ConfirmPayment(context.Background(), &balanceService.PaymentCallback{Payload: payload, Signature: signature})
+	if err != nil {
+		log.Println("Failed to confirm payment:", err)
+		return nil, nil, err
+	}
+	return paymentFromPb(resp)
+}
+
+// Платежи пользователя, с paymentID - только этот платеж
+func (c *Client) GetPayments(userID *uuid.UUID, paymentID *uuid.UUID) ([]models.Payment, error) {
+	marshaledId, err := userID.MarshalBinary()
+	if err != nil {
+		log.Println("Failed to marshal user uuid")
+		return nil, err
+	}
+	req := &balanceService.PaymentsRequest{UserId: marshaledId}
+	if paymentID != nil {
+		req.Id = paymentID[:]
+	}
+	resp, err := c.client.GetPayments(context.Background(), req)
+	if err != nil {
+		log.Println("Failed to get payments:", err)
+		return nil, err
+	}
+	payments := make([]models.Payment, 0, len(resp.Payments))
+	for _, p := range resp.Payments {
+		payment, _, err := paymentFromPb(p)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, *payment)
+	}
+	return payments, nil
+}
+
+func paymentFromPb(p *balanceService.Payment) (*models.Payment, *uuid.UUID, error) {
+	id, err := uuid.FromBytes(p.Id)
+	if err != nil {
+		log.Println("Failed to parse payment uuid:", err)
+		return nil, nil, err
+	}
+	userID, err := uuid.FromBytes(p.UserId)
+	if err != nil {
+		log.Println("Failed to parse payment user uuid:", err)
+		return nil, nil, err
+	}
+	return &models.Payment{
+		ID: id, Currency: p.Currency, Amount: money.Amount(p.AmountMinor), Status: p.Status, Reason: p.Reason,
+		CreatedAt: time.Unix(p.CreatedAt, 0).UTC(), UpdatedAt: time.Unix(p.UpdatedAt, 0).UTC(),
+	}, &userID, nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	marketService "gateway/internal/pkg/grpc/pb/marketService"
+	"gateway/internal/pkg/models"
+	"gateway/internal/pkg/money"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type Config struct {
+	Host string `yaml:"host"`
+	Port string `yaml:"port"`
+}
+
+type Client struct {
+	client marketService.MarketAdminClient
+	conn   *grpc.ClientConn
+}
+
+// Создание клиента для админки market
+func New(cfg *Config) (*Client, error) {
+	conn, err := grpc.NewClient(cfg.Host+cfg.Port, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	c := marketService.NewMarketAdminClient(conn)
+	log.Println("Connecting to market on " + cfg.Host + cfg.Port)
+	return &Client{client: c, conn: conn}, nil
+}
+
+func (c *Client) ListPapers() ([]models.Paper, error) {
+	resp, err := c.client.ListPapers(context.Background(), &marketService.Request{})
+	if err != nil {
+		log.Println("Failed to list market papers:", err)
+		return nil, err
+	}
+	papers := make([]models.Paper, 0, len(resp.Papers))
+	for _, paper := range resp.Papers {
+		papers = append(papers, paperFromPb(paper))
+	}
+	return papers, nil
+}
+
+func (c *Client) CreatePaper(paper models.Paper) (*models.Paper, error) {
+	return paperResult(c.client.CreatePaper(context.Background(), &marketService.Paper{Name: paper.Name, PriceMinor: int64(paper.Price), Currency: paper.Currency}))
+}
+
+func (c *Client) DelistPaper(name string) (*models.Paper, error) {
+	return paperResult(c.client.DelistPaper(context.Background(), &marketService.PaperName{Name: name}))
+}
+
+func (c *Client) HaltPaper(name string) (*models.Paper, error) {
+	return paperResult(c.client.HaltPaper(context.Background(), &marketService.PaperName{Name: name}))
+}
+
+func (c *Client) ResumePaper(name string) (*models.Paper, error) {
+	return paperResult(c.client.ResumePaper(context.Background(), &marketService.PaperName{Name: name}))
+}
+
+func (c *Client) SetPrice(paper models.Paper) (*models.Paper, error) {
+	return paperResult(c.client.SetPrice(context.Background(), &marketService.Paper{Name: paper.Name, PriceMinor: int64(paper.Price)}))
+}
+
+func paperResult(resp *marketService.Paper, err error) (*models.Paper, error) {
+	if err != nil {
+		log.Println("Market admin request failed:", err)
+		return nil, err
+	}
+	paper := paperFromPb(resp)
+	return &paper, nil
+}
+
+func paperFromPb(paper *marketService.Paper) models.Paper {
+	return models.Paper{Name: paper.Name, Price: money.Amount(paper.PriceMinor), Halted: paper.Halted, Currency: paper.Currency}
+}
@@ -3,14 +3,19 @@ package service
 import (
 	"context"
 	"flag"
+	"io"
 	"log"
+	"time"
 
 	papersService "gateway/internal/pkg/grpc/pb/papersService"
 	"gateway/internal/pkg/models"
+	"gateway/internal/pkg/money"
 
 	"github.com/google/uuid"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 type Config struct {
@@ -50,7 +55,7 @@ func (c *Client) BuyPaper(userID *uuid.UUID, paper models.Paper) (string, error)
 		log.Println("Failed to marshal user uuid")
 		return "", err
 	}
-	resp, err := c.client.BuyPaper(context.Background(), &papersService.PaperRequest{UserId: marshaledId, PaperName: paper.Name, PaperAmount: paper.Amount})
+	resp, err := c.client.BuyPaper(context.Background(), &papersService.PaperRequest{UserId: marshaledId, PaperName: paper.Name, PaperAmount: paper.Amount, IdempotencyKey: paper.IdempotencyKey})
 	if err != nil {
 		log.Println("Failed to buy paper:", err)
 		return "", err
@@ -64,7 +69,7 @@ func (c *Client) SellPaper(userID *uuid.UUID, paper models.Paper) (string, error
 		log.Println("Failed to marshal user uuid")
 		return "", err
 	}
-	resp, err := c.client.SellPaper(context.Background(), &papersService.PaperRequest{UserId: marshaledId, PaperName: paper.Name, PaperAmount: paper.Amount})
+	resp, err := c.client.SellPaper(context.Background(), &papersService.PaperRequest{UserId: marshaledId, PaperName: paper.Name, PaperAmount: paper.Amount, IdempotencyKey: paper.IdempotencyKey})
 	if err != nil {
 		log.Println("Failed to sell paper:", err)
 		return "", err
@@ -85,3 +90,186 @@ func (c *Client) GetUserPapers(userID *uuid.UUID) ([]byte, error) {
 	}
 	return resp.Papers, nil
 }
+
+// Подписка на изменения цен бумаг. Канал закрывается, когда стрим завершается(в т.ч. при отмене контекста)
+func (c *Client) Subscribe(ctx context.Context, papers []string) (<-chan models.Paper, error) {
+	stream, err := c.client.Subscribe(ctx, &papersService.PaperRequest{PaperNames: papers})
+	if err != nil {
+		log.Println("Failed to subscribe to papers:", err)
+		return nil, err
+	}
+	// Сервис сначала присылает текущие цены, поэтому ошибка подписки(например, несуществующая бумага) придет на первом Recv
+	first, err := stream.Recv()
+	if err != nil {
+		log.Println("Failed to subscribe to papers:", err)
+		return nil, err
+	}
+	updates := make(chan models.Paper)
+	go func() {
+		defer close(updates)
+		paper := first
+		for {
+			select {
+			case updates <- models.Paper{Name: paper.Name, Price: money.Amount(paper.PriceMinor)}:
+			case <-ctx.Done():
+				return
+			}
+			paper, err = stream.Recv()
+			if err != nil {
+				if err != io.EOF && status.Code(err) != codes.Canceled {
+					log.Println("Papers subscription closed:", err)
+				}
+				return
+			}
+		}
+	}()
+	return updates, nil
+}
+
+func (c *Client) GetTradeHistory(userID *uuid.UUID, filter models.TradeFilter) (*models.TradeHistory, error) {
+	marshaledId, err := userID.MarshalBinary()
+	if err != nil {
+		log.Println("Failed to marshal user uuid")
+		return nil, err
+	}
+	resp, err := c.client.GetTradeHistory(context.Background(), &papersService.TradeHistoryRequest{
+		UserId: marshaledId, PaperName: filter.Paper, From: filter.From, To: filter.To, Cursor: filter.Cursor, Limit: filter.Limit,
+	})
+	if err != nil {
+		log.Println("Failed to get trade history:", err)
+		return nil, err
+	}
+	history := &models.TradeHistory{Trades: make([]models.Trade, 0, len(resp.Trades)), NextCursor: resp.NextCursor}
+	for _, trade := range resp.Trades {
+		id, err := uuid.FromBytes(trade.Id)
+		if err != nil {
+			log.Println("Failed to parse trade uuid:", err)
+			return nil, err
+		}
+		executedAt := time.Unix(trade.ExecutedAt, 0).UTC()
+		history.Trades = append(history.Trades, models.Trade{
+			ID: &id, Side: trade.Side, Paper: trade.PaperName, Amount: trade.Amount,
+			Price: money.Amount(trade.PriceMinor), Fee: money.Amount(trade.FeeMinor), Status: trade.Status, ExecutedAt: &executedAt,
+		})
+	}
+	return history, nil
+}
+
+func (c *Client) PlaceOrder(userID *uuid.UUID, order models.Order) (*models.Order, error) {
+	marshaledId, err := userID.MarshalBinary()
+	if err != nil {
+		log.Println("Failed to marshal user uuid")
+		return nil, err
+	}
+	resp, err := c.client.PlaceOrder(context.Background(), &papersService.OrderRequest{
+		UserId: marshaledId, PaperName: order.Paper, Side: order.Side, Type: order.Type, PriceMinor: int64(order.Price), Amount: order.Amount,
+		IdempotencyKey: order.IdempotencyKey,
+	})
+	if err != nil {
+		log.Println("Failed to place order:", err)
+		return nil, err
+	}
+	return orderFromPb(resp)
+}
+
+func (c *Client) CancelOrder(userID *uuid.UUID, orderID uuid.UUID) (*models.Order, error) {
+	marshaledId, err := userID.MarshalBinary()
+	if err != nil {
+		log.Println("Failed to marshal user uuid")
+		return nil, err
+	}
+	resp, err := c.client.CancelOrder(context.Background(), &papersService.OrderRequest{UserId: marshaledId, OrderId: orderID[:]})
+	if err != nil {
+		log.Println("Failed to cancel order:", err)
+		return nil, err
+	}
+	return orderFromPb(resp)
+}
+
+func (c *Client) ReplaceOrder(userID *uuid.UUID, orderID uuid.UUID, order models.Order) (*models.Order, error) {
+	marshaledId, err := userID.MarshalBinary()
+	if err != nil {
+		log.Println("Failed to marshal user uuid")
+		return nil, err
+	}
+	resp, err := c.client.ReplaceOrder(context.Background(), &papersService.OrderRequest{
+		UserId: marshaledId, OrderId: orderID[:], PriceMinor: int64(order.Price), Amount: order.Amount, IdempotencyKey: order.IdempotencyKey,
+	})
+	if err != nil {
+		log.Println("Failed to replace order:", err)
+		return nil, err
+	}
+	return orderFromPb(resp)
+}
+
+func (c *Client) GetUserOrders(userID *uuid.UUID) ([]models.Order, error) {
+	marshaledId, err := userID.MarshalBinary()
+	if err != nil {
+		log.Println("Failed to marshal user uuid")
+		return nil, err
+	}
+	resp, err := c.client.GetUserOrders(context.Background(), &papersService.User{Id: marshaledId})
+	if err != nil {
+		log.Println("Failed to get user orders:", err)
+		return nil, err
+	}
+	orders := make([]models.Order, 0, len(resp.Orders))
+	for _, pbOrder := range resp.Orders {
+		order, err := orderFromPb(pbOrder)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+	return orders, nil
+}
+
+func (c *Client) GetOrderBook(paper string, depth int32) (*models.OrderBook, error) {
+	resp, err := c.client.GetOrderBook(context.Background(), &papersService.OrderBookRequest{PaperName: paper, Depth: depth})
+	if err != nil {
+		log.Println("Failed to get order book:", err)
+		return nil, err
+	}
+	book := &models.OrderBook{Paper: resp.PaperName, Bids: make([]models.PriceLevel, 0, len(resp.Bids)), Asks: make([]models.PriceLevel, 0, len(resp.Asks))}
+	for _, level := range resp.Bids {
+		book.Bids = append(book.Bids, models.PriceLevel{Price: money.Amount(level.PriceMinor), Amount: level.Amount})
+	}
+	for _, level := range resp.Asks {
+		book.Asks = append(book.Asks, models.PriceLevel{Price: money.Amount(level.PriceMinor), Amount: level.Amount})
+	}
+	return book, nil
+}
+
+func (c *Client) GetPriceHistory(paper, interval string, from, to int64) (*models.PriceHistory, error) {
+	resp, err := c.client.GetPriceHistory(context.Background(), &papersService.PriceHistoryRequest{PaperName: paper, Interval: interval, From: from, To: to})
+	if err != nil {
+		log.Println("Failed to get price history:", err)
+		return nil, err
+	}
+	history := &models.PriceHistory{Paper: resp.PaperName, Interval: resp.Interval, Candles: make([]models.Candle, 0, len(resp.Candles))}
+	for _, candle := range resp.Candles {
+		history.Candles = append(history.Candles, models.Candle{
+			Time: candle.Time, Open: money.Amount(candle.OpenMinor), High: money.Amount(candle.HighMinor),
+			Low: money.Amount(candle.LowMinor), Close: money.Amount(candle.CloseMinor),
+		})
+	}
+	return history, nil
+}
+
+func orderFromPb(resp *papersService.Order) (*models.Order, error) {
+	order := &models.Order{
+		Paper: resp.PaperName, Side: resp.Side, Type: resp.Type, Price: money.Amount(resp.PriceMinor),
+		Amount: resp.Amount, Filled: resp.Filled, Status: resp.Status, Response: resp.Response,
+	}
+	if len(resp.Id) == 0 {
+		return order, nil
+	}
+	id, err := uuid.FromBytes(resp.Id)
+	if err != nil {
+		log.Println("Failed to parse order uuid:", err)
+		return nil, err
+	}
+	createdAt := time.Unix(resp.CreatedAt, 0).UTC()
+	order.ID, order.CreatedAt = &id, &createdAt
+	return order, nil
+}
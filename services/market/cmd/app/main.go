@@ -1,17 +1,25 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"market/internal/admin"
+	"market/internal/clock"
 	pg "market/internal/db"
 	"market/internal/redis"
+	"market/internal/replay"
 	"market/internal/service"
+	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	DBConfig  *pg.Config    `yaml:"db" env-prefix:"DB_"`
-	RDSConfig *redis.Config `yaml:"rds"`
+	DBConfig  *pg.Config      `yaml:"db" env-prefix:"DB_"`
+	RDSConfig *redis.Config   `yaml:"rds"`
+	SVCConfig *service.Config `yaml:"market"`
+	ADMConfig *admin.Config   `yaml:"admin"`
 }
 
 func readConfig(filename string) (*Config, error) {
@@ -39,10 +47,63 @@ func main() {
 		log.Fatalln(err)
 	}
 	log.Println("Redis connected successfully")
-	svc, err := service.New(db, rds)
+	clk, err := newClock(cfg.SVCConfig)
 	if err != nil {
 		log.Fatalln(err)
 	}
+	svc, err := service.New(cfg.SVCConfig, db, rds, clk)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	adm, err := admin.New(cfg.ADMConfig, svc)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	go func() {
+		if err := adm.AdminServer.Serve(*adm.Listener); err != nil {
+			log.Fatalln(err)
+		}
+	}()
+	if cfg.SVCConfig.Replay != "" {
+		ticks, err := replay.Open(cfg.SVCConfig.Replay)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer ticks.Close()
+		log.Println("Market replay started:", cfg.SVCConfig.Replay)
+		if err := svc.Replay(ticks); err != nil {
+			log.Fatalln(err)
+		}
+		log.Println("Market replay finished")
+		return
+	}
+	if cfg.SVCConfig.Record != "" {
+		f, err := os.OpenFile(cfg.SVCConfig.Record, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer f.Close()
+		svc.Recorder = replay.NewWriter(f)
+	}
 	log.Println("Market started successfully")
 	svc.StartFun()
 }
+
+// Часы market: реальное время или модельное с заданного момента и с заданной скоростью
+func newClock(cfg *service.Config) (clock.Clock, error) {
+	start := time.Now()
+	if cfg.StartTime != "" {
+		var err error
+		start, err = time.Parse(time.RFC3339, cfg.StartTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Fast {
+		return clock.NewFake(start), nil
+	}
+	if cfg.Speed <= 0 {
+		return nil, fmt.Errorf("market speed must be positive, got %v", cfg.Speed)
+	}
+	return clock.NewScaled(start, cfg.Speed), nil
+}
@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"log"
+	problems "market/internal/pkg/customErrors"
+	pb "market/internal/pkg/grpc/pb/marketService"
+	"market/internal/pkg/models"
+	"market/internal/pkg/money"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Config struct {
+	Port string `yaml:"port" env-default:":50054"`
+}
+
+type IMarket interface {
+	Papers() []models.Paper
+	CreatePaper(name, currency string, price money.Amount) (models.Paper, error)
+	DelistPaper(name string) (models.Paper, error)
+	SetHalted(name string, halted bool) (models.Paper, error)
+	SetPrice(name string, price money.Amount) (models.Paper, error)
+}
+
+type server struct {
+	pb.UnimplementedMarketAdminServer
+	market IMarket
+}
+
+type Service struct {
+	AdminServer *grpc.Server
+	Listener    *net.Listener
+}
+
+// Админка market: список бумаг, выставление, снятие с торгов, приостановка и ручная цена
+func New(cfg *Config, market IMarket) (*Service, error) {
+	lis, err := net.Listen("tcp", cfg.Port)
+	if err != nil {
+		return nil, err
+	}
+	s := grpc.NewServer()
+	pb.RegisterMarketAdminServer(s, &server{market: market})
+	log.Printf("Market admin server listening at %v\n", lis.Addr())
+	return &Service{AdminServer: s, Listener: &lis}, nil
+}
+
+func (s *server) ListPapers(_ context.Context, _ *pb.Request) (*pb.Papers, error) {
+	papers := s.market.Papers()
+	resp := &pb.Papers{Papers: make([]*pb.Paper, 0, len(papers))}
+	for _, paper := range papers {
+		resp.Papers = append(resp.Papers, paperToPb(paper))
+	}
+	return resp, nil
+}
+
+func (s *server) CreatePaper(_ context.Context, req *pb.Paper) (*pb.Paper, error) {
+	if req.Name == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "request missing paper name")
+	}
+	return paperResponse(s.market.CreatePaper(req.Name, strings.ToUpper(req.Currency), requestPrice(req)))
+}
+
+func (s *server) DelistPaper(_ context.Context, req *pb.PaperName) (*pb.Paper, error) {
+	return paperResponse(s.market.DelistPaper(req.Name))
+}
+
+func (s *server) HaltPaper(_ context.Context, req *pb.PaperName) (*pb.Paper, error) {
+	return paperResponse(s.market.SetHalted(req.Name, true))
+}
+
+func (s *server) ResumePaper(_ context.Context, req *pb.PaperName) (*pb.Paper, error) {
+	return paperResponse(s.market.SetHalted(req.Name, false))
+}
+
+func (s *server) SetPrice(_ context.Context, req *pb.Paper) (*pb.Paper, error) {
+	return paperResponse(s.market.SetPrice(req.Name, requestPrice(req)))
+}
+
+// Переводит ошибки market в коды grpc
+func paperResponse(paper models.Paper, err error) (*pb.Paper, error) {
+	switch {
+	case errors.Is(err, problems.ErrNoPaper):
+		return nil, status.Errorf(codes.NotFound, "%v", err)
+	case errors.Is(err, problems.ErrPaperExists):
+		return nil, status.Errorf(codes.AlreadyExists, "%v", err)
+	case errors.Is(err, problems.ErrBadPrice), errors.Is(err, problems.ErrNoCurrency):
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	case err != nil:
+		log.Println("Market admin error:", err)
+		return nil, err
+	}
+	return paperToPb(paper), nil
+}
+
+func paperToPb(paper models.Paper) *pb.Paper {
+	return &pb.Paper{Name: paper.Name, Price: paper.Price.Float32(), PriceMinor: int64(paper.Price), Halted: paper.Halted, Currency: paper.Currency}
+}
+
+// Цена из запроса в копейках. Старые клиенты заполняют только float поле
+func requestPrice(req *pb.Paper) money.Amount {
+	if req.PriceMinor != 0 {
+		return money.Amount(req.PriceMinor)
+	}
+	return money.FromFloat(float64(req.Price))
+}
@@ -0,0 +1,55 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Источник модельного времени market
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// Модельное время, идущее в speed раз быстрее реального и начинающееся со start
+type Scaled struct {
+	start time.Time
+	real  time.Time
+	speed float64
+}
+
+func NewScaled(start time.Time, speed float64) *Scaled {
+	return &Scaled{start: start, real: time.Now(), speed: speed}
+}
+
+func (c *Scaled) Now() time.Time {
+	return c.start.Add(time.Duration(float64(time.Since(c.real)) * c.speed))
+}
+
+func (c *Scaled) Sleep(d time.Duration) {
+	time.Sleep(time.Duration(float64(d) / c.speed))
+}
+
+// Модельное время без ожидания: Sleep сразу сдвигает часы
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+func (c *Fake) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Fake) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d > 0 {
+		c.now = c.now.Add(d)
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"log"
+	problems "market/internal/pkg/customErrors"
 	"market/internal/pkg/models"
+	"market/internal/pkg/money"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -37,7 +40,7 @@ func New(cfg *Config) (*DB, error) {
 }
 
 func (d *DB) GetListOfPapers() ([]models.Paper, error) {
-	res, err := d.db.Query(context.Background(), `select name, price from public.papers`)
+	res, err := d.db.Query(context.Background(), `select name, price, currency, halted, halted_until from public.papers where listed order by name`)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return []models.Paper{}, nil
@@ -48,22 +51,111 @@ func (d *DB) GetListOfPapers() ([]models.Paper, error) {
 	papers := []models.Paper{}
 	for res.Next() {
 		var name pgtype.Text
-		var value pgtype.Float4
-		err := res.Scan(&name, &value)
+		var value money.Amount
+		var currency string
+		var halted bool
+		var until pgtype.Timestamptz
+		err := res.Scan(&name, &value, &currency, &halted, &until)
 		if err != nil {
 			log.Println("Failed to scan result:", err)
 			return nil, err
 		}
-		papers = append(papers, models.Paper{Name: name.String, Price: value.Float32})
+		papers = append(papers, models.Paper{Name: name.String, Price: value, Currency: currency, Halted: halted, HaltedUntil: until.Time})
 	}
 	return papers, nil
 }
 
-func (d *DB) PutValue(name string, value float32) error {
-	_, err := d.db.Exec(context.Background(), `update public.papers set price = $1 where name = $2`, value, name)
+// Обновляет текущую цену бумаги и записывает тик в историю цен одной транзакцией
+func (d *DB) PutValue(name string, value money.Amount, at time.Time) error {
+	ctx := context.Background()
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		log.Println("Failed to begin transaction:", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, `update public.papers set price = $1 where name = $2`, value, name)
 	if err != nil {
 		log.Println("Failed to put new value:", err)
 		return err
 	}
+	_, err = tx.Exec(ctx, `insert into public.price_ticks(paper_name, price, created_at) values($1, $2, $3)`, name, value, at)
+	if err != nil {
+		log.Println("Failed to put price tick:", err)
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Добавляет бумагу или снова выставляет снятую с торгов. Если бумага уже торгуется - ErrPaperExists
+func (d *DB) CreatePaper(name, currency string, price money.Amount) error {
+	res, err := d.db.Exec(context.Background(), `insert into public.papers(name, price, currency) values($1, $2, $3)
+		on conflict (name) do update set price = excluded.price, currency = excluded.currency, listed = true, halted = false, halted_until = null
+		where not papers.listed`, name, price, currency)
+	if err != nil {
+		log.Println("Failed to create paper:", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return problems.ErrPaperExists
+	}
+	return nil
+}
+
+// Снимает бумагу с торгов. Строка остается, на нее ссылаются портфели и история
+func (d *DB) DelistPaper(name string) error {
+	res, err := d.db.Exec(context.Background(), `update public.papers set listed = false where name = $1 and listed`, name)
+	if err != nil {
+		log.Println("Failed to delist paper:", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return problems.ErrNoPaper
+	}
+	return nil
+}
+
+// Приостанавливает или возобновляет торги бумагой. until - время автоматического возобновления, нулевое - без него
+func (d *DB) SetHalted(name string, halted bool, until time.Time) error {
+	haltedUntil := pgtype.Timestamptz{Time: until, Valid: !until.IsZero()}
+	res, err := d.db.Exec(context.Background(), `update public.papers set halted = $1, halted_until = $2 where name = $3 and listed`, halted, haltedUntil, name)
+	if err != nil {
+		log.Println("Failed to set paper halt:", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return problems.ErrNoPaper
+	}
+	return nil
+}
+
+// Курсы всех валют к базовой
+func (d *DB) GetRates() (map[string]float64, error) {
+	res, err := d.db.Query(context.Background(), `select code, rate from public.currencies`)
+	if err != nil {
+		log.Println("Failed to get currency rates from db:", err)
+		return nil, err
+	}
+	defer res.Close()
+	rates := map[string]float64{}
+	for res.Next() {
+		var code string
+		var rate float64
+		if err := res.Scan(&code, &rate); err != nil {
+			log.Println("Failed to scan result:", err)
+			return nil, err
+		}
+		rates[code] = rate
+	}
+	return rates, res.Err()
+}
+
+// Обновляет курс валюты к базовой. По нему balance меняет деньги пользователей
+func (d *DB) PutRate(currency string, rate float64, at time.Time) error {
+	_, err := d.db.Exec(context.Background(), `update public.currencies set rate = $1, updated_at = $2 where code = $3`, rate, at, currency)
+	if err != nil {
+		log.Println("Failed to put new rate:", err)
+		return err
+	}
 	return nil
 }
@@ -1,7 +1,19 @@
 package problems
 
+import "errors"
+
+var (
+	NoPaper     = "requested paper does not exists"
+	LowBalance  = "not enough money on balance"
+	LowPaper    = "not enough papers to sold"
+	PaperExists = "paper already exists"
+	BadPrice    = "price must be positive"
+	NoCurrency  = "currency does not exists"
+)
+
 var (
-	NoPaper    = "requested paper does not exists"
-	LowBalance = "not enough money on balance"
-	LowPaper   = "not enough papers to sold"
+	ErrNoPaper     = errors.New(NoPaper)
+	ErrPaperExists = errors.New(PaperExists)
+	ErrBadPrice    = errors.New(BadPrice)
+	ErrNoCurrency  = errors.New(NoCurrency)
 )
@@ -0,0 +1,338 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.35.1
+// 	protoc        v5.28.3
+// source: MarketService.proto
+
+package __
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Request struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *Request) Reset() {
+	*x = Request{}
+	mi := &file_MarketService_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Request) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Request) ProtoMessage() {}
+
+func (x *Request) ProtoReflect() protoreflect.Message {
+	mi := &file_MarketService_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Request.ProtoReflect.Descriptor instead.
+func (*Request) Descriptor() ([]byte, []int) {
+	return file_MarketService_proto_rawDescGZIP(), []int{0}
+}
+
+type PaperName struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (x *PaperName) Reset() {
+	*x = PaperName{}
+	mi := &file_MarketService_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PaperName) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaperName) ProtoMessage() {}
+
+func (x *PaperName) ProtoReflect() protoreflect.Message {
+	mi := &file_MarketService_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaperName.ProtoReflect.Descriptor instead.
+func (*PaperName) Descriptor() ([]byte, []int) {
+	return file_MarketService_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PaperName) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// Цена в минорных единицах(копейках) валюты листинга currency, float price оставлен для старых клиентов
+type Paper struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name       string  `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Price      float32 `protobuf:"fixed32,2,opt,name=price,proto3" json:"price,omitempty"`
+	Halted     bool    `protobuf:"varint,3,opt,name=halted,proto3" json:"halted,omitempty"`
+	PriceMinor int64   `protobuf:"varint,4,opt,name=priceMinor,proto3" json:"priceMinor,omitempty"`
+	Currency   string  `protobuf:"bytes,5,opt,name=currency,proto3" json:"currency,omitempty"`
+}
+
+func (x *Paper) Reset() {
+	*x = Paper{}
+	mi := &file_MarketService_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Paper) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Paper) ProtoMessage() {}
+
+func (x *Paper) ProtoReflect() protoreflect.Message {
+	mi := &file_MarketService_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Paper.ProtoReflect.Descriptor instead.
+func (*Paper) Descriptor() ([]byte, []int) {
+	return file_MarketService_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Paper) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Paper) GetPrice() float32 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Paper) GetHalted() bool {
+	if x != nil {
+		return x.Halted
+	}
+	return false
+}
+
+func (x *Paper) GetPriceMinor() int64 {
+	if x != nil {
+		return x.PriceMinor
+	}
+	return 0
+}
+
+func (x *Paper) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+type Papers struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Papers []*Paper `protobuf:"bytes,1,rep,name=papers,proto3" json:"papers,omitempty"`
+}
+
+func (x *Papers) Reset() {
+	*x = Papers{}
+	mi := &file_MarketService_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Papers) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Papers) ProtoMessage() {}
+
+func (x *Papers) ProtoReflect() protoreflect.Message {
+	mi := &file_MarketService_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Papers.ProtoReflect.Descriptor instead.
+func (*Papers) Descriptor() ([]byte, []int) {
+	return file_MarketService_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Papers) GetPapers() []*Paper {
+	if x != nil {
+		return x.Papers
+	}
+	return nil
+}
+
+var File_MarketService_proto protoreflect.FileDescriptor
+
+var file_MarketService_proto_rawDesc = []byte{
+	0x0a, 0x13, 0x4d, 0x61, 0x72, 0x6b, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0d, 0x4d, 0x61, 0x72, 0x6b, 0x65, 0x74, 0x53, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x22, 0x09, 0x0a, 0x07, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22,
+	0x1f, 0x0a, 0x09, 0x50, 0x61, 0x70, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65,
+	0x22, 0x85, 0x01, 0x0a, 0x05, 0x50, 0x61, 0x70, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x14,
+	0x0a, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x02, 0x52, 0x05, 0x70,
+	0x72, 0x69, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x61, 0x6c, 0x74, 0x65, 0x64, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x68, 0x61, 0x6c, 0x74, 0x65, 0x64, 0x12, 0x1e, 0x0a, 0x0a,
+	0x70, 0x72, 0x69, 0x63, 0x65, 0x4d, 0x69, 0x6e, 0x6f, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0a, 0x70, 0x72, 0x69, 0x63, 0x65, 0x4d, 0x69, 0x6e, 0x6f, 0x72, 0x12, 0x1a, 0x0a, 0x08,
+	0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08,
+	0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x22, 0x36, 0x0a, 0x06, 0x50, 0x61, 0x70, 0x65,
+	0x72, 0x73, 0x12, 0x2c, 0x0a, 0x06, 0x70, 0x61, 0x70, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x14, 0x2e, 0x4d, 0x61, 0x72, 0x6b, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x69,
+	0x63, 0x65, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x52, 0x06, 0x70, 0x61, 0x70, 0x65, 0x72, 0x73,
+	0x32, 0x84, 0x03, 0x0a, 0x0b, 0x4d, 0x61, 0x72, 0x6b, 0x65, 0x74, 0x41, 0x64, 0x6d, 0x69, 0x6e,
+	0x12, 0x3d, 0x0a, 0x0a, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x12, 0x16,
+	0x2e, 0x4d, 0x61, 0x72, 0x6b, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x4d, 0x61, 0x72, 0x6b, 0x65, 0x74, 0x53,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x73, 0x22, 0x00, 0x12,
+	0x3b, 0x0a, 0x0b, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x50, 0x61, 0x70, 0x65, 0x72, 0x12, 0x14,
+	0x2e, 0x4d, 0x61, 0x72, 0x6b, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50,
+	0x61, 0x70, 0x65, 0x72, 0x1a, 0x14, 0x2e, 0x4d, 0x61, 0x72, 0x6b, 0x65, 0x74, 0x53, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x22, 0x00, 0x12, 0x3f, 0x0a, 0x0b,
+	0x44, 0x65, 0x6c, 0x69, 0x73, 0x74, 0x50, 0x61, 0x70, 0x65, 0x72, 0x12, 0x18, 0x2e, 0x4d, 0x61,
+	0x72, 0x6b, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x61, 0x70, 0x65,
+	0x72, 0x4e, 0x61, 0x6d, 0x65, 0x1a, 0x14, 0x2e, 0x4d, 0x61, 0x72, 0x6b, 0x65, 0x74, 0x53, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x22, 0x00, 0x12, 0x3d, 0x0a,
+	0x09, 0x48, 0x61, 0x6c, 0x74, 0x50, 0x61, 0x70, 0x65, 0x72, 0x12, 0x18, 0x2e, 0x4d, 0x61, 0x72,
+	0x6b, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72,
+	0x4e, 0x61, 0x6d, 0x65, 0x1a, 0x14, 0x2e, 0x4d, 0x61, 0x72, 0x6b, 0x65, 0x74, 0x53, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x22, 0x00, 0x12, 0x3f, 0x0a, 0x0b,
+	0x52, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x50, 0x61, 0x70, 0x65, 0x72, 0x12, 0x18, 0x2e, 0x4d, 0x61,
+	0x72, 0x6b, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x61, 0x70, 0x65,
+	0x72, 0x4e, 0x61, 0x6d, 0x65, 0x1a, 0x14, 0x2e, 0x4d, 0x61, 0x72, 0x6b, 0x65, 0x74, 0x53, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x22, 0x00, 0x12, 0x38, 0x0a,
+	0x08, 0x53, 0x65, 0x74, 0x50, 0x72, 0x69, 0x63, 0x65, 0x12, 0x14, 0x2e, 0x4d, 0x61, 0x72, 0x6b,
+	0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x50, 0x61, 0x70, 0x65, 0x72, 0x1a,
+	0x14, 0x2e, 0x4d, 0x61, 0x72, 0x6b, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e,
+	0x50, 0x61, 0x70, 0x65, 0x72, 0x22, 0x00, 0x42, 0x04, 0x5a, 0x02, 0x2e, 0x2f, 0x62, 0x06, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_MarketService_proto_rawDescOnce sync.Once
+	file_MarketService_proto_rawDescData = file_MarketService_proto_rawDesc
+)
+
+func file_MarketService_proto_rawDescGZIP() []byte {
+	file_MarketService_proto_rawDescOnce.Do(func() {
+		file_MarketService_proto_rawDescData = protoimpl.X.CompressGZIP(file_MarketService_proto_rawDescData)
+	})
+	return file_MarketService_proto_rawDescData
+}
+
+var file_MarketService_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_MarketService_proto_goTypes = []any{
+	(*Request)(nil),   // 0: MarketService.Request
+	(*PaperName)(nil), // 1: MarketService.PaperName
+	(*Paper)(nil),     // 2: MarketService.Paper
+	(*Papers)(nil),    // 3: MarketService.Papers
+}
+var file_MarketService_proto_depIdxs = []int32{
+	2, // 0: MarketService.Papers.papers:type_name -> MarketService.Paper
+	0, // 1: MarketService.MarketAdmin.ListPapers:input_type -> MarketService.Request
+	2, // 2: MarketService.MarketAdmin.CreatePaper:input_type -> MarketService.Paper
+	1, // 3: MarketService.MarketAdmin.DelistPaper:input_type -> MarketService.PaperName
+	1, // 4: MarketService.MarketAdmin.HaltPaper:input_type -> MarketService.PaperName
+	1, // 5: MarketService.MarketAdmin.ResumePaper:input_type -> MarketService.PaperName
+	2, // 6: MarketService.MarketAdmin.SetPrice:input_type -> MarketService.Paper
+	3, // 7: MarketService.MarketAdmin.ListPapers:output_type -> MarketService.Papers
+	2, // 8: MarketService.MarketAdmin.CreatePaper:output_type -> MarketService.Paper
+	2, // 9: MarketService.MarketAdmin.DelistPaper:output_type -> MarketService.Paper
+	2, // 10: MarketService.MarketAdmin.HaltPaper:output_type -> MarketService.Paper
+	2, // 11: MarketService.MarketAdmin.ResumePaper:output_type -> MarketService.Paper
+	2, // 12: MarketService.MarketAdmin.SetPrice:output_type -> MarketService.Paper
+	7, // [7:13] is the sub-list for method output_type
+	1, // [1:7] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_MarketService_proto_init() }
+func file_MarketService_proto_init() {
+	if File_MarketService_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_MarketService_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_MarketService_proto_goTypes,
+		DependencyIndexes: file_MarketService_proto_depIdxs,
+		MessageInfos:      file_MarketService_proto_msgTypes,
+	}.Build()
+	File_MarketService_proto = out.File
+	file_MarketService_proto_rawDesc = nil
+	file_MarketService_proto_goTypes = nil
+	file_MarketService_proto_depIdxs = nil
+}
@@ -1,6 +1,11 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"market/internal/pkg/money"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // Данные о пользователе
 type User struct {
@@ -9,10 +14,18 @@ type User struct {
 	Password string    `json:"password"`
 }
 
+// Валюта, к которой считаются курсы остальных
+const BaseCurrency = "USD"
+
 type Paper struct {
-	Name   string  `json:"name"`
-	Price  float32 `json:"price"`
-	Amount int32   `json:"amount"`
+	Name   string       `json:"name"`
+	Price  money.Amount `json:"price"`
+	Amount int32        `json:"amount"`
+	Halted bool         `json:"halted,omitempty"`
+	// Валюта листинга, в ней цена бумаги
+	Currency string `json:"currency,omitempty"`
+	// Когда торги возобновятся сами(после срабатывания circuit breaker), нулевое - только вручную
+	HaltedUntil time.Time `json:"-"`
 }
 
 // Данные для аутентификации
@@ -22,6 +35,13 @@ type AuthData struct {
 }
 
 type Money struct {
-	ID   uuid.UUID `json:"id"`
-	Cash float32   `json:"cash"`
+	ID   uuid.UUID    `json:"id"`
+	Cash money.Amount `json:"cash"`
+}
+
+// Изменение цены бумаги в момент Time
+type Tick struct {
+	Time  time.Time    `json:"time"`
+	Name  string       `json:"name"`
+	Price money.Amount `json:"price"`
 }
@@ -0,0 +1,127 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Денежная сумма или цена в минорных единицах(копейках). Вся арифметика целочисленная,
+// float появляется только на границах: поля старых клиентов и модели цены market
+type Amount int64
+
+// Минорных единиц в одной основной
+const Scale = 100
+
+var ErrBadAmount = errors.New("invalid money amount")
+
+// Сумма из float старых клиентов, округляется до копейки
+func FromFloat(value float64) Amount {
+	return Amount(math.Round(value * Scale))
+}
+
+func (a Amount) Float() float64 {
+	return float64(a) / Scale
+}
+
+// Для float полей proto, оставленных ради старых клиентов
+func (a Amount) Float32() float32 {
+	return float32(a.Float())
+}
+
+// Стоимость amount бумаг по цене a
+func (a Amount) Mul(amount int32) Amount {
+	return a * Amount(amount)
+}
+
+// Доля суммы(например комиссия), округляется до копейки
+func (a Amount) MulRate(rate float64) Amount {
+	return Amount(math.Round(float64(a) * rate))
+}
+
+// Десятичная запись с двумя знаками: 12.50, -0.05
+func (a Amount) String() string {
+	sign := ""
+	value := int64(a)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, value/Scale, value%Scale)
+}
+
+// Разбирает десятичную запись без потери точности. Знаки после второго округляются
+func Parse(value string) (Amount, error) {
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimLeft(value, "+-")
+	whole, frac, _ := strings.Cut(value, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("%w: %q", ErrBadAmount, value)
+	}
+	units, err := parseDigits(whole)
+	if err != nil {
+		return 0, err
+	}
+	cents, err := parseDigits((frac + "00")[:2])
+	if err != nil {
+		return 0, err
+	}
+	if len(frac) > 2 {
+		if _, err := parseDigits(frac[2:]); err != nil {
+			return 0, err
+		}
+		if frac[2] >= '5' {
+			cents++
+		}
+	}
+	if units > (math.MaxInt64-cents)/Scale {
+		return 0, fmt.Errorf("%w: %q is too big", ErrBadAmount, value)
+	}
+	result := Amount(units*Scale + cents)
+	if negative {
+		result = -result
+	}
+	return result, nil
+}
+
+func parseDigits(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("%w: %q", ErrBadAmount, value)
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrBadAmount, value)
+	}
+	return n, nil
+}
+
+// В JSON сумма - число с двумя знаками после точки, как и раньше для float, но без погрешности
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// Принимает число или строку. Экспоненциальная запись старых клиентов разбирается как float
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "null" {
+		return nil
+	}
+	parsed, err := Parse(value)
+	if err != nil {
+		float, ferr := strconv.ParseFloat(value, 64)
+		if ferr != nil {
+			return err
+		}
+		parsed = FromFloat(float)
+	}
+	*a = parsed
+	return nil
+}
@@ -0,0 +1,120 @@
+package pricemodel
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+)
+
+// Типы моделей цены
+const (
+	Legacy            = "legacy"
+	GBM               = "gbm"
+	OrnsteinUhlenbeck = "ou"
+	JumpDiffusion     = "jump"
+)
+
+// Модель изменения цены бумаги. dt - прошедшее модельное время в годах
+type PriceModel interface {
+	Next(price float64, dt float64, rng *rand.Rand) float64
+}
+
+// Параметры модели цены бумаги. Drift, Volatility и JumpIntensity - годовые
+type Config struct {
+	Type         string  `yaml:"type" env-default:"legacy"`
+	TickInterval float64 `yaml:"tick_interval"`
+	Drift        float64 `yaml:"drift"`
+	Volatility   float64 `yaml:"volatility"`
+	// Ornstein–Uhlenbeck: скорость возврата к среднему и само среднее(0 - начальная цена)
+	Reversion float64 `yaml:"reversion"`
+	Mean      float64 `yaml:"mean"`
+	// Jump-diffusion: среднее число скачков в год, средний размер и разброс логарифма скачка
+	JumpIntensity float64 `yaml:"jump_intensity"`
+	JumpMean      float64 `yaml:"jump_mean"`
+	JumpStd       float64 `yaml:"jump_std"`
+}
+
+// Создает модель по конфигу. initial - начальная цена бумаги(среднее OU по умолчанию)
+func New(cfg Config, initial float64) (PriceModel, error) {
+	switch cfg.Type {
+	case "", Legacy:
+		return LegacyModel{}, nil
+	case GBM:
+		return GBMModel{Drift: cfg.Drift, Volatility: cfg.Volatility}, nil
+	case OrnsteinUhlenbeck:
+		mean := cfg.Mean
+		if mean == 0 {
+			mean = initial
+		}
+		return OUModel{Reversion: cfg.Reversion, Mean: mean, Volatility: cfg.Volatility}, nil
+	case JumpDiffusion:
+		return JumpModel{
+			GBMModel:  GBMModel{Drift: cfg.Drift, Volatility: cfg.Volatility},
+			Intensity: cfg.JumpIntensity,
+			JumpMean:  cfg.JumpMean,
+			JumpStd:   cfg.JumpStd,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown price model %q", cfg.Type)
+}
+
+// Старое правило: цена умножается на случайный коэффициент 0.96–1.05 на каждом тике
+type LegacyModel struct{}
+
+func (LegacyModel) Next(price float64, _ float64, rng *rand.Rand) float64 {
+	return price * (0.95 + float64(rng.IntN(10)+1)/100)
+}
+
+// Геометрическое броуновское движение: dS = μS dt + σS dW
+type GBMModel struct {
+	Drift      float64
+	Volatility float64
+}
+
+func (m GBMModel) Next(price float64, dt float64, rng *rand.Rand) float64 {
+	return price * math.Exp((m.Drift-m.Volatility*m.Volatility/2)*dt+m.Volatility*math.Sqrt(dt)*rng.NormFloat64())
+}
+
+// Процесс Орнштейна–Уленбека: dS = θ(μ - S) dt + σ dW. Цена не опускается ниже цента
+type OUModel struct {
+	Reversion  float64
+	Mean       float64
+	Volatility float64
+}
+
+func (m OUModel) Next(price float64, dt float64, rng *rand.Rand) float64 {
+	// Точное решение на шаге dt
+	decay := math.Exp(-m.Reversion * dt)
+	std := m.Volatility * math.Sqrt(dt)
+	if m.Reversion > 0 {
+		std = m.Volatility * math.Sqrt((1-decay*decay)/(2*m.Reversion))
+	}
+	return math.Max(0.01, m.Mean+(price-m.Mean)*decay+std*rng.NormFloat64())
+}
+
+// Модель Мертона: геометрическое броуновское движение со скачками, число которых распределено по Пуассону
+type JumpModel struct {
+	GBMModel
+	Intensity float64
+	JumpMean  float64
+	JumpStd   float64
+}
+
+func (m JumpModel) Next(price float64, dt float64, rng *rand.Rand) float64 {
+	price = m.GBMModel.Next(price, dt, rng)
+	for range poisson(m.Intensity*dt, rng) {
+		price *= math.Exp(m.JumpMean + m.JumpStd*rng.NormFloat64())
+	}
+	return price
+}
+
+// Алгоритм Кнута, для малых lambda(скачки редкие)
+func poisson(lambda float64, rng *rand.Rand) int {
+	limit := math.Exp(-lambda)
+	k, p := 0, rng.Float64()
+	for p > limit {
+		k++
+		p *= rng.Float64()
+	}
+	return k
+}
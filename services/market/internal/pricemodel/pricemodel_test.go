@@ -0,0 +1,51 @@
+package pricemodel
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModels(t *testing.T) {
+	t.Log("Testing price models in process...")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tSame seed gives the same path", testID)
+		model, err := New(Config{Type: JumpDiffusion, Drift: 0.1, Volatility: 0.5, JumpIntensity: 50, JumpStd: 0.1}, 100)
+		require.NoError(t, err)
+		first, second := rand.New(rand.NewPCG(1, 2)), rand.New(rand.NewPCG(1, 2))
+		a, b := 100.0, 100.0
+		for range 1000 {
+			a, b = model.Next(a, 0.01, first), model.Next(b, 0.01, second)
+		}
+		require.Equal(t, a, b)
+	}
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tGBM without volatility follows the drift", testID)
+		model, err := New(Config{Type: GBM, Drift: 0.1}, 100)
+		require.NoError(t, err)
+		price := model.Next(100, 1, rand.New(rand.NewPCG(1, 2)))
+		require.InDelta(t, 100*math.Exp(0.1), price, 1e-9)
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\tOU reverts to the initial price", testID)
+		model, err := New(Config{Type: OrnsteinUhlenbeck, Reversion: 5, Volatility: 1}, 50)
+		require.NoError(t, err)
+		rng := rand.New(rand.NewPCG(1, 2))
+		price := 100.0
+		for range 1000 {
+			price = model.Next(price, 0.01, rng)
+		}
+		require.InDelta(t, 50, price, 5)
+	}
+	{
+		testID := 3
+		t.Logf("\tTest %d:\tUnknown model is rejected", testID)
+		_, err := New(Config{Type: "coinflip"}, 1)
+		require.Error(t, err)
+	}
+}
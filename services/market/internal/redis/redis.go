@@ -2,7 +2,10 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
 	"log"
+	"market/internal/pkg/models"
+	"market/internal/pkg/money"
 
 	"github.com/go-redis/redis/v8"
 )
@@ -14,6 +17,9 @@ type Config struct {
 	DB       int    `yaml:"dbnum"`
 }
 
+// Канал, в который публикуется каждое изменение цены(на него подписывается papers)
+const StockUpdatesChannel = "stock_updates"
+
 type Redis struct {
 	client *redis.Client
 }
@@ -28,14 +34,53 @@ func New(cfg *Config) (*Redis, error) {
 	return &ret, nil
 }
 
-func (r *Redis) UpdateStock(name string, value float32) error {
-	name = "stock:" + name
-	value = float32(int(value*100)) / 100
-	err := r.client.Set(context.Background(), name, value, 0)
+func (r *Redis) UpdateStock(name string, value money.Amount) error {
+	key := "stock:" + name
+	err := r.client.Set(context.Background(), key, value.String(), 0)
 	if err.Err() != nil {
-		log.Printf("Failed to update stock named %v with value %v: %v\n", name, value, err.Err())
+		log.Printf("Failed to update stock named %v with value %v: %v\n", key, value, err.Err())
 		return err.Err()
 	}
+	return r.publishStock(models.Paper{Name: name, Price: value})
+}
+
+// Убирает цену снятой с торгов бумаги, после этого papers ее не показывает и не торгует
+func (r *Redis) DeleteStock(name string) error {
+	key := "stock:" + name
+	if err := r.client.Del(context.Background(), key).Err(); err != nil {
+		log.Printf("Failed to delete stock named %v: %v\n", key, err)
+		return err
+	}
+	return nil
+}
+
+// Флаг остановки торгов рядом с ценой бумаги: halt:<name>. Пока он есть, papers не принимает сделки и заявки
+func (r *Redis) SetHalt(name string, halted bool) error {
+	key := "halt:" + name
+	var err error
+	if halted {
+		err = r.client.Set(context.Background(), key, 1, 0).Err()
+	} else {
+		err = r.client.Del(context.Background(), key).Err()
+	}
+	if err != nil {
+		log.Printf("Failed to set halt flag %v to %v: %v\n", key, halted, err)
+		return err
+	}
+	return nil
+}
+
+// Оповещает подписчиков(papers) об изменении цены
+func (r *Redis) publishStock(paper models.Paper) error {
+	update, err := json.Marshal(paper)
+	if err != nil {
+		log.Println("Failed to marshal stock update:", err)
+		return err
+	}
+	if err := r.client.Publish(context.Background(), StockUpdatesChannel, update).Err(); err != nil {
+		log.Printf("Failed to publish stock update for %v: %v\n", paper.Name, err)
+		return err
+	}
 	return nil
 }
 
@@ -0,0 +1,117 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"market/internal/pkg/models"
+	"market/internal/pkg/money"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Читает тики из CSV(time,paper,price; время в RFC3339 или unix секундах, заголовок необязателен)
+// или из лога тиков, записанного Writer(по JSON тику на строку). Тики должны идти по времени
+type Reader struct {
+	closer io.Closer
+	csv    *csv.Reader
+	lines  *bufio.Scanner
+	header bool
+}
+
+// Открывает файл для воспроизведения, формат определяется по расширению(.csv или лог тиков)
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := NewReader(f, strings.EqualFold(filepath.Ext(path), ".csv"))
+	r.closer = f
+	return r, nil
+}
+
+func NewReader(r io.Reader, isCSV bool) *Reader {
+	if isCSV {
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = 3
+		reader.TrimLeadingSpace = true
+		return &Reader{csv: reader, header: true}
+	}
+	return &Reader{lines: bufio.NewScanner(r)}
+}
+
+// Следующий тик, io.EOF в конце файла
+func (r *Reader) Next() (models.Tick, error) {
+	if r.csv != nil {
+		return r.nextCSV()
+	}
+	for r.lines.Scan() {
+		line := strings.TrimSpace(r.lines.Text())
+		if line == "" {
+			continue
+		}
+		var tick models.Tick
+		if err := json.Unmarshal([]byte(line), &tick); err != nil {
+			return tick, fmt.Errorf("bad tick %q: %w", line, err)
+		}
+		return tick, nil
+	}
+	if err := r.lines.Err(); err != nil {
+		return models.Tick{}, err
+	}
+	return models.Tick{}, io.EOF
+}
+
+func (r *Reader) nextCSV() (models.Tick, error) {
+	record, err := r.csv.Read()
+	if err != nil {
+		return models.Tick{}, err
+	}
+	if r.header {
+		r.header = false
+		if _, err := money.Parse(record[2]); err != nil {
+			return r.nextCSV()
+		}
+	}
+	at, err := parseTime(record[0])
+	if err != nil {
+		return models.Tick{}, fmt.Errorf("bad tick time %q: %w", record[0], err)
+	}
+	price, err := money.Parse(record[2])
+	if err != nil {
+		return models.Tick{}, fmt.Errorf("bad tick price %q: %w", record[2], err)
+	}
+	return models.Tick{Time: at, Name: record[1], Price: price}, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// Записывает лог тиков, который потом можно воспроизвести через Reader
+type Writer struct {
+	enc *json.Encoder
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{enc: json.NewEncoder(w)}
+}
+
+func (w *Writer) Write(tick models.Tick) error {
+	return w.enc.Encode(tick)
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"log"
+	problems "market/internal/pkg/customErrors"
+	"market/internal/pkg/models"
+	"market/internal/pkg/money"
+	"time"
+)
+
+// Торгуемые бумаги
+func (s *Server) Papers() []models.Paper {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	papers := make([]models.Paper, 0, len(s.papers))
+	for _, paper := range s.papers {
+		papers = append(papers, paper.Paper)
+	}
+	return papers
+}
+
+// Выставляет новую бумагу на торги: в БД, в расписание тиков и в redis. Цена - в валюте листинга currency,
+// пустая - базовая валюта
+func (s *Server) CreatePaper(name, currency string, price money.Amount) (models.Paper, error) {
+	if price <= 0 {
+		return models.Paper{}, problems.ErrBadPrice
+	}
+	if currency == "" {
+		currency = models.BaseCurrency
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.knownCurrency(currency) {
+		return models.Paper{}, problems.ErrNoCurrency
+	}
+	if s.find(name) != nil {
+		return models.Paper{}, problems.ErrPaperExists
+	}
+	paper, err := s.newListing(models.Paper{Name: name, Price: price, Currency: currency})
+	if err != nil {
+		return models.Paper{}, err
+	}
+	if err := s.db.CreatePaper(name, currency, price); err != nil {
+		return models.Paper{}, err
+	}
+	s.papers = append(s.papers, paper)
+	s.publish(models.Tick{Time: s.clock.Now(), Name: name, Price: price})
+	log.Printf("Paper %v listed with price %v %v\n", name, price, currency)
+	return paper.Paper, nil
+}
+
+// Снимает бумагу с торгов: тики прекращаются, цена пропадает из redis
+func (s *Server) DelistPaper(name string) (models.Paper, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, paper := range s.papers {
+		if paper.Name != name {
+			continue
+		}
+		if err := s.db.DelistPaper(name); err != nil {
+			return models.Paper{}, err
+		}
+		s.papers = append(s.papers[:i], s.papers[i+1:]...)
+		if err := s.rds.DeleteStock(name); err != nil {
+			return paper.Paper, err
+		}
+		if err := s.rds.SetHalt(name, false); err != nil {
+			return paper.Paper, err
+		}
+		log.Printf("Paper %v delisted\n", name)
+		return paper.Paper, nil
+	}
+	return models.Paper{}, problems.ErrNoPaper
+}
+
+// Приостанавливает или возобновляет торги и изменение цены бумаги. После возобновления отсчет интервала начинается заново.
+// Ручная остановка не снимается сама, даже если до этого сработал breaker
+func (s *Server) SetHalted(name string, halted bool) (models.Paper, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	paper := s.find(name)
+	if paper == nil {
+		return models.Paper{}, problems.ErrNoPaper
+	}
+	if err := s.halt(paper, halted, time.Time{}); err != nil {
+		return paper.Paper, err
+	}
+	log.Printf("Paper %v halted: %v\n", name, halted)
+	return paper.Paper, nil
+}
+
+// Ставит цену бумаги вручную, как обычный тик
+func (s *Server) SetPrice(name string, price money.Amount) (models.Paper, error) {
+	if price <= 0 {
+		return models.Paper{}, problems.ErrBadPrice
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	paper := s.find(name)
+	if paper == nil {
+		return models.Paper{}, problems.ErrNoPaper
+	}
+	paper.Price = price
+	s.publish(models.Tick{Time: s.clock.Now(), Name: name, Price: price})
+	log.Printf("Paper %v price set to %v\n", name, price)
+	return paper.Paper, nil
+}
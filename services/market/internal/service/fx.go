@@ -0,0 +1,78 @@
+package service
+
+import (
+	"log"
+	"maps"
+	"market/internal/pkg/models"
+	"market/internal/pricemodel"
+	"math"
+	"slices"
+	"time"
+)
+
+// Курс валюты к базовой и его расписание. У валюты без модели в конфиге курс не меняется
+type fxRate struct {
+	code     string
+	rate     float64
+	model    pricemodel.PriceModel
+	interval time.Duration
+	next     time.Time
+}
+
+// Загружает курсы валют из БД и создает модели для валют из конфига. Курс базовой валюты всегда 1
+func (s *Server) loadRates() error {
+	rates, err := s.db.GetRates()
+	if err != nil {
+		log.Println("Cant get currency rates:", err)
+		return err
+	}
+	// Курсы меняются в порядке кодов, чтобы при одном seed последовательность была одна и та же
+	for _, code := range slices.Sorted(maps.Keys(rates)) {
+		r := &fxRate{code: code, rate: rates[code]}
+		if cfg, ok := s.cfg.Currencies[code]; ok && code != models.BaseCurrency {
+			if r.model, err = pricemodel.New(cfg, r.rate); err != nil {
+				log.Printf("Cant create rate model for %v: %v\n", code, err)
+				return err
+			}
+			interval := cfg.TickInterval
+			if interval <= 0 {
+				interval = s.cfg.TickInterval
+			}
+			r.interval = seconds(interval)
+			r.next = s.clock.Now().Add(r.interval)
+		}
+		s.rates = append(s.rates, r)
+	}
+	return nil
+}
+
+// Меняет курсы, время которых пришло, и записывает их в БД, откуда их берет balance
+func (s *Server) advanceRates() {
+	now := s.clock.Now()
+	for _, r := range s.rates {
+		for r.model != nil && !r.next.After(now) {
+			dt := r.interval.Seconds() * s.cfg.TimeScale / secondsPerYear
+			// Курс хранится с точностью до 8 знаков и не опускается до нуля
+			r.rate = max(math.Round(r.model.Next(r.rate, dt, s.rng)*1e8)/1e8, 1e-8)
+			if err := s.db.PutRate(r.code, r.rate, r.next); err != nil {
+				log.Printf("Failed to update %v rate with value %v: %v\n", r.code, r.rate, err)
+			}
+			r.next = r.next.Add(r.interval)
+		}
+	}
+}
+
+// Ближайшее изменение курса, нулевое - курсы не меняются
+func (s *Server) nextRate() time.Time {
+	var next time.Time
+	for _, r := range s.rates {
+		if r.model != nil && (next.IsZero() || r.next.Before(next)) {
+			next = r.next
+		}
+	}
+	return next
+}
+
+func (s *Server) knownCurrency(code string) bool {
+	return slices.ContainsFunc(s.rates, func(r *fxRate) bool { return r.code == code })
+}
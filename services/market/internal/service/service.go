@@ -1,59 +1,328 @@
 package service
 
 import (
+	"io"
 	"log"
+	"market/internal/clock"
 	"market/internal/pkg/models"
+	"market/internal/pkg/money"
+	"market/internal/pricemodel"
 	"math/rand/v2"
+	"sync"
 	"time"
 )
 
+// Секунд в модельном году, в них считаются годовые параметры моделей
+const secondsPerYear = 365 * 24 * 60 * 60
+
+type Config struct {
+	// Интервал между тиками бумаги в секундах, если он не задан в модели бумаги
+	TickInterval float64 `yaml:"tick_interval" env-default:"5"`
+	// Сколько модельных секунд проходит за одну реальную(86400 - день за секунду)
+	TimeScale float64 `yaml:"time_scale" env-default:"86400"`
+	// Модель для бумаг, которых нет в papers
+	Default pricemodel.Config            `yaml:"default_model"`
+	Papers  map[string]pricemodel.Config `yaml:"papers"`
+	// Модели курсов валют к базовой, курсы валют без модели не меняются
+	Currencies map[string]pricemodel.Config `yaml:"currencies"`
+	// Seed генератора цен, 0 - случайный(выбранный seed пишется в лог, чтобы прогон можно было повторить)
+	Seed uint64 `yaml:"seed" env:"MARKET_SEED"`
+	// Часы: начало модельного времени(RFC3339, пусто - сейчас), во сколько раз оно быстрее реального,
+	// fast - не ждать между тиками вовсе
+	StartTime string  `yaml:"start_time" env:"MARKET_START_TIME"`
+	Speed     float64 `yaml:"speed" env:"MARKET_SPEED" env-default:"1"`
+	Fast      bool    `yaml:"fast" env:"MARKET_FAST"`
+	// Файл CSV или лог тиков для воспроизведения вместо генерации цен
+	Replay string `yaml:"replay" env:"MARKET_REPLAY"`
+	// Файл, в который дописывается лог сгенерированных тиков
+	Record string `yaml:"record" env:"MARKET_RECORD"`
+	// Circuit breaker для бумаг, которых нет в breakers
+	Breaker  Breaker            `yaml:"breaker"`
+	Breakers map[string]Breaker `yaml:"breakers"`
+}
+
+// Останавливает торги бумагой, если ее цена за window секунд модельного времени изменилась на move процентов и больше.
+// Торги возобновляются через halt секунд, 0 - только вручную. move 0 - breaker выключен
+type Breaker struct {
+	Move   float64 `yaml:"move"`
+	Window float64 `yaml:"window" env-default:"60"`
+	Halt   float64 `yaml:"halt" env-default:"300"`
+}
+
 type Server struct {
-	Papers []models.Paper
-	db     IDB
-	rds    IRDS
+	// Куда записывать сгенерированные тики, nil - никуда
+	Recorder IRecorder
+	cfg      *Config
+	db       IDB
+	rds      IRDS
+	clock    clock.Clock
+	rng      *rand.Rand
+	// Торгуемые бумаги с их расписанием, меняются тиками и админкой
+	mu     sync.Mutex
+	papers []*listing
+	// Курсы валют, меняются тиками так же, как цены бумаг
+	rates []*fxRate
+}
+
+// Бумага и ее расписание: когда и по какой модели менять цену
+type listing struct {
+	models.Paper
+	model    pricemodel.PriceModel
+	interval time.Duration
+	next     time.Time
+	breaker  Breaker
+	// Тики за последнее окно breaker
+	window []models.Tick
 }
 
 type IDB interface {
 	GetListOfPapers() ([]models.Paper, error)
-	PutValue(name string, value float32) error
+	PutValue(name string, value money.Amount, at time.Time) error
+	CreatePaper(name, currency string, price money.Amount) error
+	DelistPaper(name string) error
+	SetHalted(name string, halted bool, until time.Time) error
+	GetRates() (map[string]float64, error)
+	PutRate(currency string, rate float64, at time.Time) error
 }
 
 type IRDS interface {
-	UpdateStock(name string, value float32) error
+	UpdateStock(name string, value money.Amount) error
+	DeleteStock(name string) error
+	SetHalt(name string, halted bool) error
 }
 
-var BasePapers = []models.Paper{{Name: "BasePaper", Price: 1}}
+type IRecorder interface {
+	Write(tick models.Tick) error
+}
+
+type ITickReader interface {
+	Next() (models.Tick, error)
+}
 
-func New(db IDB, rds IRDS) (*Server, error) {
-	server := &Server{db: db, rds: rds}
-	var err error
-	server.Papers, err = server.db.GetListOfPapers()
+var BasePapers = []models.Paper{{Name: "BasePaper", Price: money.Scale, Currency: models.BaseCurrency}}
+
+func New(cfg *Config, db IDB, rds IRDS, clk clock.Clock) (*Server, error) {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	log.Printf("Market seed: %v\n", seed)
+	server := &Server{cfg: cfg, db: db, rds: rds, clock: clk, rng: rand.New(rand.NewPCG(seed, 0))}
+	if err := server.loadRates(); err != nil {
+		return nil, err
+	}
+	papers, err := server.db.GetListOfPapers()
 	if err != nil {
 		log.Println("Cant get papers:", err)
 		return nil, err
 	}
-	if len(server.Papers) == 0 {
-		copy(server.Papers, BasePapers)
+	if len(papers) == 0 {
+		papers = append(papers, BasePapers...)
 	}
-	for _, value := range server.Papers {
+	for _, value := range papers {
+		paper, err := server.newListing(value)
+		if err != nil {
+			return nil, err
+		}
+		server.papers = append(server.papers, paper)
 		server.rds.UpdateStock(value.Name, value.Price)
+		server.rds.SetHalt(value.Name, value.Halted)
 	}
 	return server, nil
 }
 
+// Создает расписание бумаги по ее модели из конфига
+func (s *Server) newListing(paper models.Paper) (*listing, error) {
+	paperCfg, ok := s.cfg.Papers[paper.Name]
+	if !ok {
+		paperCfg = s.cfg.Default
+	}
+	model, err := pricemodel.New(paperCfg, paper.Price.Float())
+	if err != nil {
+		log.Printf("Cant create price model for %v: %v\n", paper.Name, err)
+		return nil, err
+	}
+	interval := paperCfg.TickInterval
+	if interval <= 0 {
+		interval = s.cfg.TickInterval
+	}
+	breaker, ok := s.cfg.Breakers[paper.Name]
+	if !ok {
+		breaker = s.cfg.Breaker
+	}
+	return &listing{Paper: paper, model: model, interval: seconds(interval), next: s.clock.Now().Add(seconds(interval)), breaker: breaker}, nil
+}
+
+// Меняет цены бумаг по их моделям, каждую со своим интервалом
 func (s *Server) StartFun() {
 	for {
-		time.Sleep(5 * time.Second)
-		choice := rand.IntN(len(s.Papers)) // the choice is yooooooours
-		s.Papers[choice].Price *= (0.95 + float32(rand.IntN(10)+1)/100)
-		log.Printf("Trying to update %v with value %v\n", s.Papers[choice].Name, s.Papers[choice].Price)
-		err := s.db.PutValue(s.Papers[choice].Name, s.Papers[choice].Price)
-		if err != nil {
-			log.Printf("Failed to update %v in db with value %v: %v\n", s.Papers[choice].Name, s.Papers[choice].Price, err)
+		s.Tick()
+	}
+}
+
+// Ждет ближайший тик и меняет цену его бумаги, попутно меняя курсы валют, время которых пришло.
+// Тики идут по очереди, поэтому при одном seed последовательность цен и курсов одна и та же
+func (s *Server) Tick() models.Tick {
+	for {
+		s.mu.Lock()
+		wake := s.wakeTime()
+		s.mu.Unlock()
+		s.clock.Sleep(wake.Sub(s.clock.Now()))
+		s.mu.Lock()
+		s.resumeExpired()
+		s.advanceRates()
+		// Пока ждали, админка могла поменять бумаги
+		next := s.nextTick()
+		if next == nil || next.next.After(s.clock.Now()) {
+			s.mu.Unlock()
+			continue
+		}
+		dt := next.interval.Seconds() * s.cfg.TimeScale / secondsPerYear
+		// Модели считают во float, цена округляется до копейки и не опускается ниже нее
+		next.Price = max(money.FromFloat(next.model.Next(next.Price.Float(), dt, s.rng)), 1)
+		generated := models.Tick{Time: next.next, Name: next.Name, Price: next.Price}
+		next.next = next.next.Add(next.interval)
+		s.publish(generated)
+		if s.Recorder != nil {
+			if err := s.Recorder.Write(generated); err != nil {
+				log.Println("Failed to record tick:", err)
+			}
+		}
+		s.checkBreaker(next, generated)
+		s.mu.Unlock()
+		return generated
+	}
+}
+
+// Воспроизводит записанные тики с их исходным временем, выдерживая между ними паузы по часам market
+func (s *Server) Replay(r ITickReader) error {
+	var prev time.Time
+	for {
+		replayed, err := r.Next()
+		if err == io.EOF {
+			return nil
 		}
-		err = s.rds.UpdateStock(s.Papers[choice].Name, s.Papers[choice].Price)
 		if err != nil {
-			log.Printf("Failed to update %v in redis with value %v: %v\n", s.Papers[choice].Name, s.Papers[choice].Price, err)
+			log.Println("Failed to read tick:", err)
+			return err
+		}
+		if !prev.IsZero() {
+			s.clock.Sleep(replayed.Time.Sub(prev))
+		}
+		prev = replayed.Time
+		s.mu.Lock()
+		if paper := s.find(replayed.Name); paper != nil {
+			paper.Price = replayed.Price
+		}
+		s.publish(replayed)
+		s.mu.Unlock()
+	}
+}
+
+// Записывает новую цену в БД(с историей) и в redis, откуда она расходится подписчикам
+func (s *Server) publish(t models.Tick) {
+	log.Printf("Trying to update %v with value %v\n", t.Name, t.Price)
+	err := s.db.PutValue(t.Name, t.Price, t.Time)
+	if err != nil {
+		log.Printf("Failed to update %v in db with value %v: %v\n", t.Name, t.Price, err)
+	}
+	err = s.rds.UpdateStock(t.Name, t.Price)
+	if err != nil {
+		log.Printf("Failed to update %v in redis with value %v: %v\n", t.Name, t.Price, err)
+	}
+}
+
+// Проверяет движение цены бумаги за окно breaker и при слишком большом останавливает торги
+func (s *Server) checkBreaker(paper *listing, t models.Tick) {
+	if paper.breaker.Move <= 0 {
+		return
+	}
+	window := seconds(paper.breaker.Window)
+	old := 0
+	for old < len(paper.window) && t.Time.Sub(paper.window[old].Time) > window {
+		old++
+	}
+	paper.window = append(paper.window[old:], t)
+	low, high := t.Price, t.Price
+	for _, past := range paper.window {
+		low, high = min(low, past.Price), max(high, past.Price)
+	}
+	move := 100 * max(t.Price.Float()/low.Float()-1, 1-t.Price.Float()/high.Float())
+	if move < paper.breaker.Move {
+		return
+	}
+	var until time.Time
+	if paper.breaker.Halt > 0 {
+		until = t.Time.Add(seconds(paper.breaker.Halt))
+	}
+	log.Printf("Circuit breaker: %v moved %.2f%% within %v, trading halted until %v\n", t.Name, move, window, until)
+	if err := s.halt(paper, true, until); err != nil {
+		log.Printf("Failed to halt %v: %v\n", t.Name, err)
+	}
+}
+
+// Останавливает или возобновляет торги бумагой в БД, redis и в расписании тиков
+func (s *Server) halt(paper *listing, halted bool, until time.Time) error {
+	if err := s.db.SetHalted(paper.Name, halted, until); err != nil {
+		return err
+	}
+	paper.Halted = halted
+	paper.HaltedUntil = until
+	paper.window = nil
+	paper.next = s.clock.Now().Add(paper.interval)
+	return s.rds.SetHalt(paper.Name, halted)
+}
+
+// Возобновляет торги бумагами, у которых истекла остановка breaker
+func (s *Server) resumeExpired() {
+	now := s.clock.Now()
+	for _, paper := range s.papers {
+		if paper.Halted && !paper.HaltedUntil.IsZero() && !paper.HaltedUntil.After(now) {
+			log.Printf("Trading in %v resumed after circuit breaker\n", paper.Name)
+			if err := s.halt(paper, false, time.Time{}); err != nil {
+				log.Printf("Failed to resume %v: %v\n", paper.Name, err)
+			}
+		}
+	}
+}
+
+// Когда проснуться: ближайший тик, изменение курса или возобновление торгов, если ничего нет - через tick_interval
+func (s *Server) wakeTime() time.Time {
+	wake := s.clock.Now().Add(seconds(s.cfg.TickInterval))
+	if next := s.nextTick(); next != nil {
+		wake = next.next
+	}
+	if next := s.nextRate(); !next.IsZero() && next.Before(wake) {
+		wake = next
+	}
+	for _, paper := range s.papers {
+		if paper.Halted && !paper.HaltedUntil.IsZero() && paper.HaltedUntil.Before(wake) {
+			wake = paper.HaltedUntil
+		}
+	}
+	return wake
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
+
+// Неприостановленная бумага с ближайшим тиком(при равном времени - первая по списку), nil - таких нет
+func (s *Server) nextTick() *listing {
+	var next *listing
+	for _, paper := range s.papers {
+		if !paper.Halted && (next == nil || paper.next.Before(next.next)) {
+			next = paper
+		}
+	}
+	return next
+}
+
+func (s *Server) find(name string) *listing {
+	for _, paper := range s.papers {
+		if paper.Name == name {
+			return paper
 		}
 	}
+	return nil
 }
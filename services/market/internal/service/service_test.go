@@ -0,0 +1,181 @@
+package service
+
+import (
+	"market/internal/clock"
+	problems "market/internal/pkg/customErrors"
+	"market/internal/pkg/models"
+	"market/internal/pkg/money"
+	"market/internal/pricemodel"
+	"market/internal/replay"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDB struct {
+	ticks []models.Tick
+	rates []rateUpdate
+}
+
+type rateUpdate struct {
+	currency string
+	rate     float64
+	at       time.Time
+}
+
+func (d *fakeDB) GetListOfPapers() ([]models.Paper, error) {
+	return []models.Paper{{Name: "Amogus", Price: 1250}, {Name: "Dogecoin", Price: 10000}}, nil
+}
+
+func (d *fakeDB) PutValue(name string, value money.Amount, at time.Time) error {
+	d.ticks = append(d.ticks, models.Tick{Time: at, Name: name, Price: value})
+	return nil
+}
+
+func (d *fakeDB) CreatePaper(string, string, money.Amount) error { return nil }
+func (d *fakeDB) DelistPaper(string) error                       { return nil }
+func (d *fakeDB) SetHalted(string, bool, time.Time) error        { return nil }
+
+func (d *fakeDB) GetRates() (map[string]float64, error) {
+	return map[string]float64{"USD": 1, "EUR": 1.08, "RUB": 0.011}, nil
+}
+
+func (d *fakeDB) PutRate(currency string, rate float64, at time.Time) error {
+	d.rates = append(d.rates, rateUpdate{currency: currency, rate: rate, at: at})
+	return nil
+}
+
+type fakeRDS struct{}
+
+func (fakeRDS) UpdateStock(string, money.Amount) error { return nil }
+func (fakeRDS) DeleteStock(string) error               { return nil }
+func (fakeRDS) SetHalt(string, bool) error             { return nil }
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestServer(t *testing.T, seed uint64) (*Server, *fakeDB) {
+	cfg := &Config{
+		TickInterval: 5,
+		TimeScale:    86400,
+		Seed:         seed,
+		Papers:       map[string]pricemodel.Config{"Dogecoin": {Type: pricemodel.GBM, Drift: 0.1, Volatility: 0.5, TickInterval: 2}},
+	}
+	db := &fakeDB{}
+	server, err := New(cfg, db, fakeRDS{}, clock.NewFake(start))
+	require.NoError(t, err)
+	return server, db
+}
+
+func TestMarket(t *testing.T) {
+	t.Log("Testing market in process...")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tSame seed gives the same ticks", testID)
+		first, firstDB := newTestServer(t, 42)
+		second, secondDB := newTestServer(t, 42)
+		for range 100 {
+			first.Tick()
+			second.Tick()
+		}
+		require.Equal(t, firstDB.ticks, secondDB.ticks)
+		require.Equal(t, start.Add(2*time.Second), firstDB.ticks[0].Time, "Dogecoin ticks every 2 seconds")
+		require.Equal(t, "Dogecoin", firstDB.ticks[0].Name)
+		require.Equal(t, "Amogus", firstDB.ticks[2].Name, "Amogus ticks at 5 seconds after Dogecoin at 4")
+	}
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tRecorded tick log replays into the same ticks", testID)
+		server, db := newTestServer(t, 7)
+		var log strings.Builder
+		server.Recorder = replay.NewWriter(&log)
+		for range 10 {
+			server.Tick()
+		}
+		replayed, replayedDB := newTestServer(t, 1)
+		require.NoError(t, replayed.Replay(replay.NewReader(strings.NewReader(log.String()), false)))
+		require.Equal(t, db.ticks, replayedDB.ticks)
+		require.Equal(t, server.Papers(), replayed.Papers())
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\tCSV replay keeps historical time", testID)
+		server, db := newTestServer(t, 1)
+		csv := "time,paper,price\n2020-03-01T10:00:00Z,Dogecoin,90.5\n1583056860,Amogus,13\n"
+		require.NoError(t, server.Replay(replay.NewReader(strings.NewReader(csv), true)))
+		require.Equal(t, []models.Tick{
+			{Time: time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC), Name: "Dogecoin", Price: 9050},
+			{Time: time.Date(2020, 3, 1, 10, 1, 0, 0, time.UTC), Name: "Amogus", Price: 1300},
+		}, db.ticks)
+	}
+	{
+		testID := 3
+		t.Logf("\tTest %d:\tAdmin changes the set of ticking papers", testID)
+		server, _ := newTestServer(t, 1)
+		_, err := server.CreatePaper("Ichor", "", 66600)
+		require.NoError(t, err)
+		_, err = server.CreatePaper("Ichor", "EUR", 100)
+		require.ErrorIs(t, err, problems.ErrPaperExists)
+		_, err = server.SetHalted("Dogecoin", true)
+		require.NoError(t, err)
+		_, err = server.DelistPaper("Amogus")
+		require.NoError(t, err)
+		for range 5 {
+			require.Equal(t, "Ichor", server.Tick().Name, "Only listed and not halted papers tick")
+		}
+		_, err = server.SetPrice("Amogus", 100)
+		require.ErrorIs(t, err, problems.ErrNoPaper)
+		paper, err := server.SetPrice("Ichor", 70000)
+		require.NoError(t, err)
+		require.Equal(t, money.Amount(70000), paper.Price)
+		require.Len(t, server.Papers(), 2)
+	}
+	{
+		testID := 4
+		t.Logf("\tTest %d:\tCircuit breaker halts a paper and resumes it later", testID)
+		server, _ := newTestServer(t, 3)
+		server.cfg.Breakers = map[string]Breaker{"Dogecoin": {Move: 5, Window: 10, Halt: 30}}
+		server.papers[1].breaker = server.cfg.Breakers["Dogecoin"]
+		server.papers[1].model = pricemodel.GBMModel{Volatility: 50}
+		var haltedUntil time.Time
+		for range 100 {
+			server.Tick()
+			if paper := server.Papers()[1]; paper.Halted {
+				haltedUntil = paper.HaltedUntil
+				break
+			}
+		}
+		require.False(t, haltedUntil.IsZero(), "Breaker must trigger on a volatile paper")
+		for {
+			tick := server.Tick()
+			if tick.Name == "Dogecoin" {
+				require.False(t, tick.Time.Before(haltedUntil), "Halted paper must not tick")
+				break
+			}
+		}
+		require.False(t, server.Papers()[1].Halted)
+	}
+	{
+		testID := 5
+		t.Logf("\tTest %d:\tCurrency rates tick by their models alongside papers", testID)
+		server, db := newTestServer(t, 5)
+		server.cfg.Currencies = map[string]pricemodel.Config{"EUR": {Type: pricemodel.GBM, Volatility: 0.2, TickInterval: 3}}
+		server.rates = nil
+		require.NoError(t, server.loadRates())
+		for range 10 {
+			server.Tick()
+		}
+		require.NotEmpty(t, db.rates)
+		for i, update := range db.rates {
+			require.Equal(t, "EUR", update.currency, "Only currencies with a model change, base never does")
+			require.Equal(t, start.Add(time.Duration(3*(i+1))*time.Second), update.at)
+			require.Greater(t, update.rate, 0.0)
+		}
+		_, err := server.CreatePaper("Ichor", "JPY", 100)
+		require.ErrorIs(t, err, problems.ErrNoCurrency)
+		paper, err := server.CreatePaper("Ichor", "RUB", 100)
+		require.NoError(t, err)
+		require.Equal(t, "RUB", paper.Currency)
+	}
+}
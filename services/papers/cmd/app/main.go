@@ -1,18 +1,27 @@
 package main
 
 import (
+	"context"
+	"idempotency"
 	"log"
+	"papers/internal/balance"
 	"papers/internal/db"
+	"papers/internal/orderbook"
 	"papers/internal/redis"
 	"papers/internal/server"
+	"papers/internal/settlement"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	DBConfig  *db.Config     `yaml:"db"`
-	RDSConfig *redis.Config  `yaml:"rds"`
-	PPSConfig *server.Config `yaml:"pps"`
+	DBConfig  *db.Config         `yaml:"db"`
+	RDSConfig *redis.Config      `yaml:"rds"`
+	PPSConfig *server.Config     `yaml:"pps"`
+	OBConfig  *orderbook.Config  `yaml:"orderbook"`
+	BLConfig  *balance.Config    `yaml:"balance"`
+	STConfig  *settlement.Config `yaml:"settlement"`
 }
 
 func readConfig(filename string) (*Config, error) {
@@ -39,7 +48,21 @@ func main() {
 		log.Fatalln(err)
 	}
 	log.Println("Redis client created successfully")
-	server, err := server.New(cfg.PPSConfig, db, rds)
+	bl, err := balance.New(cfg.BLConfig)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	log.Println("Balance client created successfully")
+	settler := settlement.New(cfg.STConfig, db, bl)
+	go settler.Run()
+	engine := orderbook.New(cfg.OBConfig, db, bl, rds, settler, rds)
+	if err := engine.Load(context.Background()); err != nil {
+		log.Fatalln(err)
+	}
+	log.Println("Order books loaded successfully")
+	keys := idempotency.New(db.Pool(), time.Duration(cfg.PPSConfig.IdempotencyTTL)*time.Second)
+	go keys.Cleanup(time.Duration(cfg.PPSConfig.IdempotencyCleanupInterval) * time.Second)
+	server, err := server.New(cfg.PPSConfig, db, rds, engine, bl, settler, keys)
 	if err != nil {
 		log.Fatalln(err)
 	}
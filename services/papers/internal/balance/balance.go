@@ -0,0 +1,97 @@
+package balance
+
+import (
+	"context"
+	"log"
+	pb "papers/internal/pkg/balanceService"
+	problems "papers/internal/pkg/customErrors"
+	"papers/internal/pkg/models"
+	"papers/internal/pkg/money"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type Config struct {
+	Host string `yaml:"host"`
+	Port string `yaml:"port"`
+}
+
+// Клиент balance - единственного сервиса, который меняет деньги пользователей. papers ставит резерв до сделки,
+// списывает его после успешной записи сделки в БД и возвращает, если сделка не состоялась(см. settlement)
+type Client struct {
+	client pb.BalanceManagementClient
+	conn   *grpc.ClientConn
+}
+
+func New(cfg *Config) (*Client, error) {
+	conn, err := grpc.NewClient(cfg.Host+cfg.Port, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	log.Println("Connecting to balance service on " + cfg.Host + cfg.Port)
+	return &Client{client: pb.NewBalanceManagementClient(conn), conn: conn}, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Сколько пользователь может потратить в валюте currency вместе с базовой валютой, которую balance обменяет при резерве
+func (c *Client) GetBalance(ctx context.Context, userID uuid.UUID, currency string) (money.Amount, error) {
+	balance, err := c.client.GetBalance(ctx, &pb.User{Id: userID[:], Currency: currency})
+	if err != nil {
+		log.Println("Cant get user balance:", err)
+		return 0, err
+	}
+	return money.Amount(balance.AvailableMinor), nil
+}
+
+// Резервирует деньги пользователя или, при нулевом userID, market в валюте currency. Если денег не хватает - problems.ErrLowBalance.
+// Непустой key в Reserve, Commit и Release - ключ идемпотентности: повтор с ним не проводит операцию второй раз
+func (c *Client) Reserve(ctx context.Context, userID uuid.UUID, currency string, cash money.Amount, reference uuid.UUID, key string) (uuid.UUID, error) {
+	resp, err := c.client.Reserve(ctx, &pb.HoldRequest{UserId: optional(userID), Currency: currency, AmountMinor: int64(cash), Reference: optional(reference), IdempotencyKey: key})
+	if err != nil {
+		log.Println("Cant reserve cash:", err)
+		return uuid.Nil, err
+	}
+	if resp.Response == problems.LowBalance {
+		return uuid.Nil, problems.ErrLowBalance
+	}
+	return uuid.FromBytes(resp.Id)
+}
+
+// Списывает из резерва выплаты получателям
+func (c *Client) Commit(ctx context.Context, holdID uuid.UUID, payouts []models.Payout, reference uuid.UUID, key string) error {
+	req := &pb.CommitRequest{HoldId: holdID[:], Reference: optional(reference), Payouts: make([]*pb.Payout, 0, len(payouts)), IdempotencyKey: key}
+	for _, payout := range payouts {
+		// Нулевые выплаты(например, комиссия при нулевой ставке) balance не принимает
+		if payout.Amount == 0 {
+			continue
+		}
+		req.Payouts = append(req.Payouts, &pb.Payout{UserId: optional(payout.UserID), Account: payout.Account, AmountMinor: int64(payout.Amount)})
+	}
+	if _, err := c.client.Commit(ctx, req); err != nil {
+		log.Printf("Cant commit hold %v: %v\n", holdID, err)
+		return err
+	}
+	return nil
+}
+
+// Возвращает владельцу cash из резерва или весь остаток, если cash нулевой
+func (c *Client) Release(ctx context.Context, holdID uuid.UUID, cash money.Amount, key string) error {
+	if _, err := c.client.Release(ctx, &pb.ReleaseRequest{HoldId: holdID[:], AmountMinor: int64(cash), IdempotencyKey: key}); err != nil {
+		log.Printf("Cant release hold %v: %v\n", holdID, err)
+		return err
+	}
+	return nil
+}
+
+// Нулевой uuid(market, нет ссылки) передается пустым полем
+func optional(id uuid.UUID) []byte {
+	if id == uuid.Nil {
+		return nil
+	}
+	return id[:]
+}
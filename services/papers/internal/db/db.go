@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	problems "papers/internal/pkg/customErrors"
 	"papers/internal/pkg/models"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Config struct {
@@ -21,25 +23,35 @@ type Config struct {
 
 type DB struct {
 	config *Config
-	db     *pgx.Conn
+	db     *pgxpool.Pool
 }
 
-// Создает соединение с существующей БД
+// Создает пул соединений с существующей БД(одно соединение pgx нельзя использовать из параллельных запросов)
 func New(cfg *Config) (*DB, error) {
 	d := &DB{config: cfg}
 	connection := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DBName)
-	db, err := pgx.Connect(context.Background(), connection)
+	db, err := pgxpool.New(context.Background(), connection)
 	log.Println("Connecting to: " + connection)
 	if err != nil {
 		return nil, err
 	}
+	if err := db.Ping(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
 	d.db = db
 	return d, nil
 }
 
-// Закрывает соединение с БД
+// Пул соединений для пакетов со своими таблицами в той же БД(idempotency)
+func (d *DB) Pool() *pgxpool.Pool {
+	return d.db
+}
+
+// Закрывает соединения с БД
 func (d *DB) Close() error {
-	return d.db.Close(context.Background())
+	d.db.Close()
+	return nil
 }
 
 func (d *DB) GetUserPapers(userId uuid.UUID) ([]models.Paper, error) {
@@ -49,6 +61,7 @@ func (d *DB) GetUserPapers(userId uuid.UUID) ([]models.Paper, error) {
 		log.Println("Cant get user papers:", err)
 		return nil, err
 	}
+	defer res.Close()
 	papers := make([]models.Paper, 0, 3)
 	for res.Next() {
 		var paper models.Paper
@@ -62,57 +75,365 @@ func (d *DB) GetUserPapers(userId uuid.UUID) ([]models.Paper, error) {
 	return papers, nil
 }
 
-func (d *DB) GetUserBalance(userId uuid.UUID) (float32, error) {
-	log.Printf("Attempt to get user with uuid %v balance\n", userId)
-	var balance pgtype.Float4
-	err := d.db.QueryRow(context.Background(), `select balance from public.users where id = $1`, userId).Scan(&balance)
+// Атомарно проводит бумажную часть сделки: блокирует бумаги пользователя(select ... for update), при продаже проверяет их количество,
+// меняет его и записывает сделку в public.trades и ее денежный расчет settlement в одной транзакции. Деньги под сделку к этому
+// моменту уже зарезервированы в balance. Отклоненная сделка тоже записывается, при этом возвращается problems.ErrLowPaper,
+// а settlement заменяется возвратом всего резерва
+func (d *DB) ExecuteTrade(ctx context.Context, trade models.Trade, settlement *models.Settlement) (models.Trade, error) {
+	log.Printf("Attempt to %v %v papers %v for user with uuid %v at %v\n", trade.Side, trade.Amount, trade.Paper, trade.UserID, trade.Price)
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		log.Println("Cant begin transaction:", err)
+		return trade, err
+	}
+	defer tx.Rollback(ctx)
+	var amount int32
+	err = tx.QueryRow(ctx, `select amount from public.storage where id = $1 and paper_name = $2 for update`, trade.UserID, trade.Paper).Scan(&amount)
+	if err != nil && err != pgx.ErrNoRows {
+		log.Println("Cant lock user paper amount:", err)
+		return trade, err
+	}
+	var rejection error
+	switch trade.Side {
+	case models.Buy:
+		_, err = tx.Exec(ctx, `insert into public.storage(id, paper_name, amount) values($1, $2, $3)
+			on conflict (id, paper_name) do update set amount = public.storage.amount + excluded.amount`, trade.UserID, trade.Paper, trade.Amount)
+		if err != nil {
+			log.Println("Cant change user paper amount:", err)
+			return trade, err
+		}
+	case models.Sell:
+		if trade.Amount > amount {
+			rejection = problems.ErrLowPaper
+			break
+		}
+		if _, err := tx.Exec(ctx, `update public.storage set amount = amount - $1 where id = $2 and paper_name = $3`, trade.Amount, trade.UserID, trade.Paper); err != nil {
+			log.Println("Cant change user paper amount:", err)
+			return trade, err
+		}
+	default:
+		return trade, fmt.Errorf("unknown trade side %q", trade.Side)
+	}
+	trade.Status = models.Executed
+	if rejection != nil {
+		trade.Status = models.Rejected
+		*settlement = models.Settlement{HoldID: settlement.HoldID, Reference: settlement.Reference}
+	}
+	if trade, err = insertTrade(ctx, tx, trade); err != nil {
+		return trade, err
+	}
+	if err := insertSettlements(ctx, tx, []models.Settlement{*settlement}); err != nil {
+		return trade, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Println("Cant commit trade:", err)
+		return trade, err
+	}
+	return trade, rejection
+}
+
+// Записывает отклоненную сделку(balance не зарезервировал под нее деньги)
+func (d *DB) RejectTrade(ctx context.Context, trade models.Trade) (models.Trade, error) {
+	trade.Status = models.Rejected
+	return insertTrade(ctx, d.db, trade)
+}
+
+// Валюта листинга бумаги: в ней считаются цены и резервируются деньги сделок. Неизвестная или снятая с листинга
+// бумага - problems.ErrNoPaper
+func (d *DB) GetPaperCurrency(ctx context.Context, paper string) (string, error) {
+	var currency string
+	err := d.db.QueryRow(ctx, `select currency from public.papers where name = $1 and listed`, paper).Scan(&currency)
+	if err == pgx.ErrNoRows {
+		return "", problems.ErrNoPaper
+	}
+	if err != nil {
+		log.Println("Cant get paper currency:", err)
+		return "", err
+	}
+	return currency, nil
+}
+
+// Бумаги, снятые market с листинга
+func (d *DB) GetDelistedPapers(ctx context.Context) ([]string, error) {
+	res, err := d.db.Query(ctx, `select name from public.papers where not listed`)
+	if err != nil {
+		log.Println("Cant get delisted papers:", err)
+		return nil, err
+	}
+	defer res.Close()
+	papers := []string{}
+	for res.Next() {
+		var name string
+		if err := res.Scan(&name); err != nil {
+			log.Println("Cant scan paper name:", err)
+			return nil, err
+		}
+		papers = append(papers, name)
+	}
+	return papers, res.Err()
+}
+
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func insertTrade(ctx context.Context, q querier, trade models.Trade) (models.Trade, error) {
+	if trade.ID == uuid.Nil {
+		trade.ID = uuid.New()
+	}
+	err := q.QueryRow(ctx, `insert into public.trades(id, user_id, paper_name, side, amount, price, fee, status) values($1, $2, $3, $4, $5, $6, $7, $8) returning created_at`,
+		trade.ID, trade.UserID, trade.Paper, trade.Side, trade.Amount, trade.Price, trade.Fee, trade.Status).Scan(&trade.CreatedAt)
 	if err != nil {
-		log.Println("Cant get user balance:", err)
-		return 0, err
+		log.Println("Cant record trade:", err)
+		return trade, err
 	}
-	return balance.Float32, nil
+	return trade, nil
 }
 
-func (d *DB) ChangeBalance(userId uuid.UUID, change float32) error {
-	log.Printf("Attempt to change user with uuid %v balance, adding %v\n", userId, change)
-	_, err := d.db.Exec(context.Background(), `update public.users set balance = balance + $1 where id = $2`, change, userId)
+// Возвращает сделки пользователя от новых к старым
+func (d *DB) GetTradeHistory(ctx context.Context, filter models.TradeFilter) ([]models.Trade, error) {
+	log.Printf("Attempt to get user with uuid %v trades\n", filter.UserID)
+	query := `select id, paper_name, side, amount, price, fee, status, created_at from public.trades where user_id = $1`
+	args := []any{filter.UserID}
+	if filter.Paper != "" {
+		args = append(args, filter.Paper)
+		query += fmt.Sprintf(` and paper_name = $%d`, len(args))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		query += fmt.Sprintf(` and created_at >= $%d`, len(args))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		query += fmt.Sprintf(` and created_at < $%d`, len(args))
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+		query += fmt.Sprintf(` and (created_at, id) < ($%d, $%d)`, len(args)-1, len(args))
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` order by created_at desc, id desc limit $%d`, len(args))
+	res, err := d.db.Query(ctx, query, args...)
 	if err != nil {
-		log.Println("Cant change user balance:", err)
+		log.Println("Cant get user trades:", err)
+		return nil, err
+	}
+	defer res.Close()
+	trades := make([]models.Trade, 0, filter.Limit)
+	for res.Next() {
+		trade := models.Trade{UserID: filter.UserID}
+		err := res.Scan(&trade.ID, &trade.Paper, &trade.Side, &trade.Amount, &trade.Price, &trade.Fee, &trade.Status, &trade.CreatedAt)
+		if err != nil {
+			log.Println("Cant scan trade:", err)
+			return nil, err
+		}
+		trades = append(trades, trade)
+	}
+	return trades, res.Err()
+}
+
+// Свечи бумаги по тикам из истории цен за [from, to). Интервалы отсчитываются от начала эпохи,
+// интервалы без тиков пропускаются
+func (d *DB) GetPriceHistory(ctx context.Context, paper string, interval time.Duration, from, to time.Time) ([]models.Candle, error) {
+	log.Printf("Attempt to get %v price history with interval %v\n", paper, interval)
+	res, err := d.db.Query(ctx, `select date_bin(make_interval(secs => $2), created_at, 'epoch'::timestamptz) as bucket,
+		(array_agg(price order by created_at))[1], max(price), min(price), (array_agg(price order by created_at desc))[1]
+		from public.price_ticks where paper_name = $1 and created_at >= $3 and created_at < $4
+		group by bucket order by bucket`, paper, interval.Seconds(), from, to)
+	if err != nil {
+		log.Println("Cant get price history:", err)
+		return nil, err
+	}
+	defer res.Close()
+	candles := []models.Candle{}
+	for res.Next() {
+		var candle models.Candle
+		if err := res.Scan(&candle.Time, &candle.Open, &candle.High, &candle.Low, &candle.Close); err != nil {
+			log.Println("Cant scan candle:", err)
+			return nil, err
+		}
+		candles = append(candles, candle)
+	}
+	return candles, res.Err()
+}
+
+// Проводит бумажную часть заявки и ее исполнений одной транзакцией: резервирует бумаги под продажу, записывает заявку
+// вместе с резервом денег покупки из balance, переводит бумаги по каждому исполнению, обновляет исполненные заявки из стакана
+// и записывает сделки обеих сторон вместе с денежными расчетами исполнений settlements, которые затем проводит balance.
+// Заявки поставщика ликвидности(нулевой id) не проводятся
+func (d *DB) SettleOrder(ctx context.Context, taker models.Order, fills []models.Fill, settlements []models.Settlement) error {
+	log.Printf("Attempt to settle order %v with %v fills\n", taker.ID, len(fills))
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		log.Println("Cant begin transaction:", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if taker.UserID != uuid.Nil {
+		if err := reserve(ctx, tx, taker, fills); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `insert into public.orders(id, user_id, paper_name, side, type, price, amount, filled, status, hold_id, created_at)
+			values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+			taker.ID, taker.UserID, taker.Paper, taker.Side, taker.Type, taker.Price, taker.Amount, taker.Filled, taker.Status, nullable(taker.HoldID), taker.CreatedAt)
+		if err != nil {
+			log.Println("Cant insert order:", err)
+			return err
+		}
+	}
+	for _, fill := range fills {
+		if taker.UserID != uuid.Nil {
+			if err := settleFill(ctx, tx, taker, fill); err != nil {
+				return err
+			}
+		}
+		if fill.Maker.UserID != uuid.Nil {
+			if err := settleFill(ctx, tx, fill.Maker, fill); err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx, `update public.orders set filled = filled + $1,
+				status = case when filled + $1 >= amount then 'filled' else 'partial' end where id = $2`, fill.Amount, fill.Maker.ID)
+			if err != nil {
+				log.Println("Cant update maker order:", err)
+				return err
+			}
+		}
+	}
+	if err := insertSettlements(ctx, tx, settlements); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Println("Cant commit order:", err)
 		return err
 	}
 	return nil
 }
 
-func (d *DB) AddPaper(userId uuid.UUID, name string, amount int) error {
-	log.Printf("Attempt to add user with uuid %v papers %v, adding %v\n", userId, name, amount)
-	_, err := d.db.Exec(context.Background(), `insert into public.storage(id, paper_name, amount) values($1, $2, $3)`, userId, name, amount)
+// Снимает заявку и возвращает зарезервированные под неисполненный остаток бумаги. Резерв денег покупки возвращает balance
+// по расчетам settlements, записанным вместе с отменой
+func (d *DB) CancelOrder(ctx context.Context, order models.Order, settlements []models.Settlement) error {
+	log.Printf("Attempt to cancel order %v\n", order.ID)
+	tx, err := d.db.Begin(ctx)
 	if err != nil {
-		log.Println("Cant change user paper amount:", err)
+		log.Println("Cant begin transaction:", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+	var remaining int32
+	err = tx.QueryRow(ctx, `update public.orders set status = 'cancelled' where id = $1 and user_id = $2 and status in ('open', 'partial')
+		returning amount - filled`, order.ID, order.UserID).Scan(&remaining)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return problems.ErrNoOrder
+		}
+		log.Println("Cant cancel order:", err)
+		return err
+	}
+	if order.Side == models.Sell {
+		_, err = tx.Exec(ctx, `update public.storage set amount = amount + $1 where id = $2 and paper_name = $3`, remaining, order.UserID, order.Paper)
+	}
+	if err != nil {
+		log.Println("Cant release order reserve:", err)
+		return err
+	}
+	if err := insertSettlements(ctx, tx, settlements); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Println("Cant commit order cancel:", err)
 		return err
 	}
 	return nil
 }
 
-func (d *DB) ChangePaperAmount(userId uuid.UUID, name string, amount int) error {
-	log.Printf("Attempt to change user with uuid %v amount of papers %v, adding %v\n", userId, name, amount)
-	_, err := d.db.Exec(context.Background(), `update public.storage set amount = amount + $1 where id = $2 and paper_name = $3`, amount, userId, name)
+// Открытые заявки всех пользователей от старых к новым(для восстановления стаканов)
+func (d *DB) GetOpenOrders(ctx context.Context) ([]models.Order, error) {
+	return d.queryOrders(ctx, `select id, user_id, paper_name, side, type, price, amount, filled, status, hold_id, created_at
+		from public.orders where status in ('open', 'partial') order by created_at, id`)
+}
+
+// Последние заявки пользователя от новых к старым
+func (d *DB) GetUserOrders(ctx context.Context, userId uuid.UUID, limit int) ([]models.Order, error) {
+	log.Printf("Attempt to get user with uuid %v orders\n", userId)
+	return d.queryOrders(ctx, `select id, user_id, paper_name, side, type, price, amount, filled, status, hold_id, created_at
+		from public.orders where user_id = $1 order by created_at desc, id desc limit $2`, userId, limit)
+}
+
+func (d *DB) queryOrders(ctx context.Context, query string, args ...any) ([]models.Order, error) {
+	res, err := d.db.Query(ctx, query, args...)
 	if err != nil {
-		log.Println("Cant change user paper amount:", err)
+		log.Println("Cant get orders:", err)
+		return nil, err
+	}
+	defer res.Close()
+	orders := []models.Order{}
+	for res.Next() {
+		var order models.Order
+		var holdID *uuid.UUID
+		err := res.Scan(&order.ID, &order.UserID, &order.Paper, &order.Side, &order.Type, &order.Price, &order.Amount, &order.Filled, &order.Status, &holdID, &order.CreatedAt)
+		if err != nil {
+			log.Println("Cant scan order:", err)
+			return nil, err
+		}
+		if holdID != nil {
+			order.HoldID = *holdID
+		}
+		orders = append(orders, order)
+	}
+	return orders, res.Err()
+}
+
+// Резервирует бумаги под входящую заявку на продажу. Деньги покупки резервирует balance до проводки заявки
+func reserve(ctx context.Context, tx pgx.Tx, taker models.Order, fills []models.Fill) error {
+	if taker.Side == models.Buy {
+		return nil
+	}
+	papers := taker.Amount
+	if taker.Type == models.Market {
+		papers = 0
+		for _, fill := range fills {
+			papers += fill.Amount
+		}
+	}
+	var amount int32
+	err := tx.QueryRow(ctx, `select amount from public.storage where id = $1 and paper_name = $2 for update`, taker.UserID, taker.Paper).Scan(&amount)
+	if err != nil && err != pgx.ErrNoRows {
+		log.Println("Cant lock user paper amount:", err)
+		return err
+	}
+	if papers > amount {
+		return problems.ErrLowPaper
+	}
+	if _, err := tx.Exec(ctx, `update public.storage set amount = amount - $1 where id = $2 and paper_name = $3`, papers, taker.UserID, taker.Paper); err != nil {
+		log.Println("Cant reserve user papers:", err)
 		return err
 	}
 	return nil
 }
 
-func (d *DB) GetPaperAmount(userId uuid.UUID, name string) (int, error) {
-	log.Printf("Attempt to get user with uuid %v amount of paper %v\n", userId, name)
-	var amount pgtype.Int4
-	err := d.db.QueryRow(context.Background(), `select amount from public.storage where id = $1 and paper_name = $2`, userId, name).Scan(&amount)
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			return -1, nil
+// Переводит покупателю бумаги и записывает сделку стороны исполнения. Резерв уже списан при постановке заявки
+func settleFill(ctx context.Context, tx pgx.Tx, order models.Order, fill models.Fill) error {
+	if order.Side == models.Buy {
+		_, err := tx.Exec(ctx, `insert into public.storage(id, paper_name, amount) values($1, $2, $3)
+			on conflict (id, paper_name) do update set amount = public.storage.amount + excluded.amount`, order.UserID, order.Paper, fill.Amount)
+		if err != nil {
+			log.Println("Cant settle fill:", err)
+			return err
 		}
-		log.Println("Cant get user paper amount:", err)
-		return 0, err
 	}
-	return int(amount.Int32), nil
+	_, err := tx.Exec(ctx, `insert into public.trades(user_id, paper_name, side, amount, price, fee, status, order_id) values($1, $2, $3, $4, $5, 0, $6, $7)`,
+		order.UserID, order.Paper, order.Side, fill.Amount, fill.Price, models.Executed, order.ID)
+	if err != nil {
+		log.Println("Cant record trade:", err)
+		return err
+	}
+	return nil
+}
+
+// Нулевой uuid(нет резерва) в БД хранится как null
+func nullable(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
 }
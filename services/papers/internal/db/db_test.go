@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	problems "papers/internal/pkg/customErrors"
+	"papers/internal/pkg/models"
+	"papers/internal/pkg/money"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallelSellsNeverOversell(t *testing.T) {
+	cfg := Config{Host: "db", Port: "5432", User: "user", Password: "123", DBName: "papersdb"}
+	db, err := New(&cfg)
+	if err != nil {
+		t.Skipf("Database is not available: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	const (
+		paper   = "Dogecoin"
+		price   = money.Amount(1000)
+		owned   = 5
+		sellers = 20
+	)
+	var userID uuid.UUID
+	err = db.db.QueryRow(ctx, `insert into public.users(login, password) values($1, 'fakehash') returning id`, "paralleltest").Scan(&userID)
+	require.NoError(t, err, "Cannot add test user")
+	defer dropUser(ctx, db, userID)
+	_, err = db.ExecuteTrade(ctx, models.Trade{UserID: userID, Paper: paper, Side: models.Buy, Amount: owned, Price: price}, &models.Settlement{Reference: userID})
+	require.NoError(t, err, "Cannot buy test papers")
+
+	t.Log("Testing parallel sells in process...")
+	var wg sync.WaitGroup
+	results := make(chan error, sellers)
+	for range sellers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.ExecuteTrade(ctx, models.Trade{UserID: userID, Paper: paper, Side: models.Sell, Amount: 1, Price: price}, &models.Settlement{Reference: userID})
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded, rejected := 0, 0
+	for err := range results {
+		switch err {
+		case nil:
+			succeeded++
+		case problems.ErrLowPaper:
+			rejected++
+		default:
+			require.NoError(t, err, "Unexpected trade error")
+		}
+	}
+	require.Equal(t, owned, succeeded, "Wrong amount of executed sells")
+	require.Equal(t, sellers-owned, rejected, "Wrong amount of rejected sells")
+
+	var amount int32
+	err = db.db.QueryRow(ctx, `select amount from public.storage where id = $1 and paper_name = $2`, userID, paper).Scan(&amount)
+	require.NoError(t, err, "Cannot get test user papers")
+	require.Zero(t, amount, "Papers were oversold")
+
+	var executed, recorded int
+	err = db.db.QueryRow(ctx, `select count(*) filter (where status = $2), count(*) from public.trades where user_id = $1`, userID, models.Executed).Scan(&executed, &recorded)
+	require.NoError(t, err, "Cannot count test user trades")
+	require.Equal(t, owned+1, executed, "Trades ledger does not match executed trades")
+	require.Equal(t, sellers+1, recorded, "Rejected sells must be recorded too")
+}
+
+func TestPriceHistoryCandles(t *testing.T) {
+	cfg := Config{Host: "db", Port: "5432", User: "user", Password: "123", DBName: "papersdb"}
+	db, err := New(&cfg)
+	if err != nil {
+		t.Skipf("Database is not available: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	// Тики в далеком прошлом, чтобы не пересекаться с тиками market
+	paper := "Dogecoin"
+	start := time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)
+	ticks := []struct {
+		offset time.Duration
+		price  money.Amount
+	}{{0, 10}, {20 * time.Second, 12}, {40 * time.Second, 9}, {50 * time.Second, 11}, {2 * time.Minute, 20}}
+	for _, tick := range ticks {
+		_, err := db.db.Exec(ctx, `insert into public.price_ticks(paper_name, price, created_at) values($1, $2, $3)`, paper, tick.price, start.Add(tick.offset))
+		require.NoError(t, err, "Cannot add test tick")
+	}
+	defer db.db.Exec(ctx, `delete from public.price_ticks where paper_name = $1 and created_at < $2`, paper, start.Add(time.Hour))
+
+	t.Log("Testing candles aggregation in process...")
+	candles, err := db.GetPriceHistory(ctx, paper, time.Minute, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, candles, 2, "Minute without ticks must be skipped")
+	require.True(t, candles[0].Time.Equal(start))
+	require.Equal(t, models.Candle{Time: candles[0].Time, Open: 10, High: 12, Low: 9, Close: 11}, candles[0])
+	require.True(t, candles[1].Time.Equal(start.Add(2*time.Minute)))
+	require.Equal(t, money.Amount(20), candles[1].Open)
+	require.Equal(t, money.Amount(20), candles[1].Close)
+}
+
+// Расчеты тестовых сделок ссылаются на id пользователя
+func dropUser(ctx context.Context, db *DB, userID uuid.UUID) {
+	db.db.Exec(ctx, `delete from public.settlements where reference = $1`, userID)
+	db.db.Exec(ctx, `delete from public.trades where user_id = $1`, userID)
+	db.db.Exec(ctx, `delete from public.storage where id = $1`, userID)
+	db.db.Exec(ctx, `delete from public.users where id = $1`, userID)
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"papers/internal/pkg/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Записывает расчеты в транзакции сделки, заявки или отмены и проставляет им id
+func insertSettlements(ctx context.Context, tx pgx.Tx, settlements []models.Settlement) error {
+	for i := range settlements {
+		if settlements[i].ID == uuid.Nil {
+			settlements[i].ID = uuid.New()
+		}
+		st := settlements[i]
+		payouts, err := json.Marshal(st.Payouts)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `insert into public.settlements(id, hold_id, currency, cash, payouts, reference) values($1, $2, nullif($3, ''), $4, $5, $6)`,
+			st.ID, nullable(st.HoldID), st.Currency, st.Cash, payouts, nullable(st.Reference))
+		if err != nil {
+			log.Println("Cant record settlement:", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Непроведенные расчеты от старых к новым
+func (d *DB) GetPendingSettlements(ctx context.Context, limit int) ([]models.Settlement, error) {
+	res, err := d.db.Query(ctx, `select id, hold_id, coalesce(currency, ''), cash, payouts, reference, attempts from public.settlements
+		order by created_at, id limit $1`, limit)
+	if err != nil {
+		log.Println("Cant get pending settlements:", err)
+		return nil, err
+	}
+	defer res.Close()
+	settlements := []models.Settlement{}
+	for res.Next() {
+		var st models.Settlement
+		var holdID, reference *uuid.UUID
+		var payouts []byte
+		if err := res.Scan(&st.ID, &holdID, &st.Currency, &st.Cash, &payouts, &reference, &st.Attempts); err != nil {
+			log.Println("Cant scan settlement:", err)
+			return nil, err
+		}
+		if err := json.Unmarshal(payouts, &st.Payouts); err != nil {
+			log.Printf("Cant unmarshal settlement %v payouts: %v\n", st.ID, err)
+			return nil, err
+		}
+		if holdID != nil {
+			st.HoldID = *holdID
+		}
+		if reference != nil {
+			st.Reference = *reference
+		}
+		settlements = append(settlements, st)
+	}
+	return settlements, res.Err()
+}
+
+// Удаляет проведенный в balance расчет
+func (d *DB) FinishSettlement(ctx context.Context, id uuid.UUID) error {
+	if _, err := d.db.Exec(ctx, `delete from public.settlements where id = $1`, id); err != nil {
+		log.Println("Cant finish settlement:", err)
+		return err
+	}
+	return nil
+}
+
+// Отмечает неудачную попытку провести расчет
+func (d *DB) FailSettlement(ctx context.Context, id uuid.UUID, reason string) error {
+	_, err := d.db.Exec(ctx, `update public.settlements set attempts = attempts + 1, last_error = $2 where id = $1`, id, reason)
+	if err != nil {
+		log.Println("Cant record settlement failure:", err)
+	}
+	return err
+}
+
+// Брошенные резервы пользователей старше age: с остатком, без открытой заявки и без непроведенного расчета. Такие
+// остаются, если сделка или заявка не записалась в БД, а вернуть резерв сразу не удалось
+func (d *DB) GetOrphanHolds(ctx context.Context, age time.Duration) ([]uuid.UUID, error) {
+	res, err := d.db.Query(ctx, `select h.id from public.holds h where h.user_id is not null and h.amount > 0 and h.created_at < $1
+		and not exists (select 1 from public.orders o where o.hold_id = h.id and o.status in ('open', 'partial'))
+		and not exists (select 1 from public.settlements s where s.hold_id = h.id)`, time.Now().Add(-age))
+	if err != nil {
+		log.Println("Cant get orphan holds:", err)
+		return nil, err
+	}
+	defer res.Close()
+	holds := []uuid.UUID{}
+	for res.Next() {
+		var id uuid.UUID
+		if err := res.Scan(&id); err != nil {
+			log.Println("Cant scan hold id:", err)
+			return nil, err
+		}
+		holds = append(holds, id)
+	}
+	return holds, res.Err()
+}
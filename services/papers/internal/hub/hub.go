@@ -0,0 +1,179 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"log"
+	"papers/internal/pkg/models"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSlowConsumer = errors.New("subscriber is too slow, subscription evicted")
+	ErrUnsubscribed = errors.New("subscription closed by unsubscribe")
+)
+
+// Рассылает обновления цен бумаг подписчикам
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[uuid.UUID]*Subscriber
+	bufferSize  int
+}
+
+// Подписчик на обновления цен одной или нескольких бумаг
+type Subscriber struct {
+	ID      uuid.UUID
+	mu      sync.RWMutex
+	papers  map[string]struct{}
+	updates chan models.Paper
+	done    chan struct{}
+	once    sync.Once
+	err     error
+}
+
+func New(bufferSize int) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	return &Hub{subscribers: map[uuid.UUID]*Subscriber{}, bufferSize: bufferSize}
+}
+
+// Читает обновления цен из канала и рассылает их, пока канал не закроется
+func (h *Hub) Run(updates <-chan models.Paper) {
+	for paper := range updates {
+		h.Publish(paper)
+	}
+	log.Println("Stock updates channel closed, closing all subscriptions")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for id, sub := range h.subscribers {
+		sub.close(nil)
+		delete(h.subscribers, id)
+	}
+}
+
+// Создает подписку на переданные бумаги. Подписка закрывается сама, когда отменяется ctx(клиент отключился)
+func (h *Hub) Subscribe(ctx context.Context, papers []string) *Subscriber {
+	sub := &Subscriber{
+		ID:      uuid.New(),
+		papers:  make(map[string]struct{}, len(papers)),
+		updates: make(chan models.Paper, h.bufferSize),
+		done:    make(chan struct{}),
+	}
+	for _, name := range papers {
+		sub.papers[name] = struct{}{}
+	}
+	h.mu.Lock()
+	h.subscribers[sub.ID] = sub
+	h.mu.Unlock()
+	log.Printf("Subscriber %v subscribed to %v\n", sub.ID, papers)
+	go func() {
+		select {
+		case <-ctx.Done():
+			h.Remove(sub.ID)
+		case <-sub.done:
+		}
+	}()
+	return sub
+}
+
+// Отписывает подписчика от переданных бумаг, при пустом списке или отписке от всех бумаг закрывает подписку.
+// Возвращает false, если подписки не существует
+func (h *Hub) Unsubscribe(id uuid.UUID, papers []string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sub, ok := h.subscribers[id]
+	if !ok {
+		return false
+	}
+	sub.mu.Lock()
+	for _, name := range papers {
+		delete(sub.papers, name)
+	}
+	left := len(sub.papers)
+	sub.mu.Unlock()
+	if len(papers) == 0 || left == 0 {
+		sub.close(ErrUnsubscribed)
+		delete(h.subscribers, id)
+	}
+	log.Printf("Subscriber %v unsubscribed from %v\n", id, papers)
+	return true
+}
+
+// Удаляет подписку(вызывается при завершении стрима)
+func (h *Hub) Remove(id uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if sub, ok := h.subscribers[id]; ok {
+		sub.close(nil)
+		delete(h.subscribers, id)
+	}
+}
+
+// Отправляет обновление цены всем подписчикам бумаги. Подписчики с переполненным буфером отключаются
+func (h *Hub) Publish(paper models.Paper) {
+	var slow []uuid.UUID
+	h.mu.RLock()
+	for id, sub := range h.subscribers {
+		if !sub.wants(paper.Name) {
+			continue
+		}
+		select {
+		case sub.updates <- paper:
+		default:
+			slow = append(slow, id)
+		}
+	}
+	h.mu.RUnlock()
+	if len(slow) == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, id := range slow {
+		if sub, ok := h.subscribers[id]; ok {
+			log.Printf("Evicting slow subscriber %v\n", id)
+			sub.close(ErrSlowConsumer)
+			delete(h.subscribers, id)
+		}
+	}
+}
+
+// Количество активных подписок
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers)
+}
+
+// Канал с обновлениями цен
+func (s *Subscriber) Updates() <-chan models.Paper {
+	return s.updates
+}
+
+// Канал закрывается при завершении подписки
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Причина завершения подписки(nil если подписка завершена штатно)
+func (s *Subscriber) Err() error {
+	<-s.done
+	return s.err
+}
+
+func (s *Subscriber) wants(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.papers[name]
+	return ok
+}
+
+func (s *Subscriber) close(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+}
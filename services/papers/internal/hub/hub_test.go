@@ -0,0 +1,85 @@
+package hub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"papers/internal/pkg/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ждет закрытия подписки, чтобы тест не зависал при ошибке
+func waitDone(t *testing.T, sub *Subscriber) {
+	t.Helper()
+	select {
+	case <-sub.Done():
+	case <-time.After(time.Second):
+		t.Fatal("subscription was not closed")
+	}
+}
+
+func TestHub(t *testing.T) {
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tsubscriber gets only its papers", testID)
+		h := New(2)
+		sub := h.Subscribe(context.Background(), []string{"Ichor"})
+		h.Publish(models.Paper{Name: "Gold"})
+		h.Publish(models.Paper{Name: "Ichor"})
+		require.Equal(t, "Ichor", (<-sub.Updates()).Name)
+		require.Empty(t, sub.Updates())
+	}
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tsubscriber with a full buffer is evicted, others keep receiving", testID)
+		h := New(2)
+		slow := h.Subscribe(context.Background(), []string{"Ichor"})
+		fast := h.Subscribe(context.Background(), []string{"Ichor"})
+		for range 3 {
+			h.Publish(models.Paper{Name: "Ichor"})
+			<-fast.Updates()
+		}
+		waitDone(t, slow)
+		require.ErrorIs(t, slow.Err(), ErrSlowConsumer)
+		require.Equal(t, 1, h.Len())
+		h.Publish(models.Paper{Name: "Ichor"})
+		require.Len(t, fast.Updates(), 1)
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\tunsubscribe from some papers keeps the subscription, from all closes it", testID)
+		h := New(2)
+		sub := h.Subscribe(context.Background(), []string{"Ichor", "Gold"})
+		require.True(t, h.Unsubscribe(sub.ID, []string{"Gold"}))
+		h.Publish(models.Paper{Name: "Gold"})
+		require.Empty(t, sub.Updates())
+		require.True(t, h.Unsubscribe(sub.ID, []string{"Ichor"}))
+		waitDone(t, sub)
+		require.ErrorIs(t, sub.Err(), ErrUnsubscribed)
+		require.False(t, h.Unsubscribe(sub.ID, nil), "Closed subscription must be forgotten")
+	}
+	{
+		testID := 3
+		t.Logf("\tTest %d:\tcancelled context tears the subscription down", testID)
+		h := New(2)
+		ctx, cancel := context.WithCancel(context.Background())
+		sub := h.Subscribe(ctx, []string{"Ichor"})
+		cancel()
+		waitDone(t, sub)
+		require.NoError(t, sub.Err())
+		require.Eventually(t, func() bool { return h.Len() == 0 }, time.Second, 10*time.Millisecond)
+	}
+	{
+		testID := 4
+		t.Logf("\tTest %d:\tclosed updates channel closes every subscription", testID)
+		h := New(2)
+		sub := h.Subscribe(context.Background(), []string{"Ichor"})
+		updates := make(chan models.Paper)
+		close(updates)
+		h.Run(updates)
+		waitDone(t, sub)
+		require.Zero(t, h.Len())
+	}
+}
@@ -0,0 +1,425 @@
+package orderbook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	problems "papers/internal/pkg/customErrors"
+	"papers/internal/pkg/models"
+	"papers/internal/pkg/money"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Config struct {
+	// Поставщик ликвидности выставляет заявки вокруг цены market на расстоянии spread(доля цены) объемом quote_size, 0 - выключен
+	Spread    float64 `yaml:"spread" env-default:"0.01"`
+	QuoteSize int32   `yaml:"quote_size" env-default:"100"`
+}
+
+type IDB interface {
+	SettleOrder(ctx context.Context, taker models.Order, fills []models.Fill, settlements []models.Settlement) error
+	CancelOrder(ctx context.Context, order models.Order, settlements []models.Settlement) error
+	GetOpenOrders(ctx context.Context) ([]models.Order, error)
+	GetPaperCurrency(ctx context.Context, paper string) (string, error)
+	GetDelistedPapers(ctx context.Context) ([]string, error)
+}
+
+// Флаг остановки торгов бумагой(circuit breaker или админ market)
+type IHalts interface {
+	IsHalted(name string) (bool, error)
+}
+
+// Деньги заявок: покупка резервирует их до проводки. Если заявку не удалось записать, резерв сразу возвращается
+type IBalance interface {
+	GetBalance(ctx context.Context, userID uuid.UUID, currency string) (money.Amount, error)
+	Reserve(ctx context.Context, userID uuid.UUID, currency string, cash money.Amount, reference uuid.UUID, key string) (uuid.UUID, error)
+	Release(ctx context.Context, holdID uuid.UUID, cash money.Amount, key string) error
+}
+
+// Проводит в balance расчеты, записанные вместе с заявкой: исполнения списывают резерв покупателя продавцу,
+// отмена возвращает остаток резерва
+type ISettler interface {
+	Settle(ctx context.Context, settlements []models.Settlement)
+}
+
+// Сообщает участникам о проведенных сделках
+type IEvents interface {
+	PublishFills(ctx context.Context, events []models.FillEvent)
+}
+
+// Стаканы всех бумаг. Заявки по одной бумаге сопоставляются и проводятся в БД строго по очереди
+type Engine struct {
+	cfg     *Config
+	db      IDB
+	balance IBalance
+	halts   IHalts
+	settler ISettler
+	events  IEvents
+	mu      sync.Mutex
+	books   map[string]*lockedBook
+}
+
+type lockedBook struct {
+	sync.Mutex
+	*Book
+}
+
+func New(cfg *Config, db IDB, balance IBalance, halts IHalts, settler ISettler, events IEvents) *Engine {
+	return &Engine{cfg: cfg, db: db, balance: balance, halts: halts, settler: settler, events: events, books: map[string]*lockedBook{}}
+}
+
+// Восстанавливает стаканы из открытых заявок в БД
+func (e *Engine) Load(ctx context.Context) error {
+	orders, err := e.db.GetOpenOrders(ctx)
+	if err != nil {
+		log.Println("Cant get open orders:", err)
+		return err
+	}
+	for i := range orders {
+		book := e.book(orders[i].Paper)
+		book.Lock()
+		book.Add(&orders[i])
+		book.Unlock()
+	}
+	log.Printf("Loaded %v open orders\n", len(orders))
+	return nil
+}
+
+// Принимает заявку: сопоставляет ее со стаканом, проводит исполнения в БД и ставит остаток лимитной заявки в стакан
+func (e *Engine) Place(ctx context.Context, order models.Order) (models.Order, error) {
+	if err := validate(order); err != nil {
+		return order, err
+	}
+	order.ID = uuid.New()
+	order.Filled = 0
+	order.Status = models.Open
+	order.CreatedAt = time.Now()
+	if order.Type == models.Market {
+		order.Price = 0
+	}
+	if err := e.checkHalted(order.Paper); err != nil {
+		return order, err
+	}
+	// Деньги заявки резервируются в валюте листинга бумаги
+	currency, err := e.db.GetPaperCurrency(ctx, order.Paper)
+	if err != nil {
+		return order, err
+	}
+	book := e.book(order.Paper)
+	book.Lock()
+	defer book.Unlock()
+	var budget money.Amount
+	if order.Side == models.Buy && order.Type == models.Market {
+		balance, err := e.balance.GetBalance(ctx, order.UserID, currency)
+		if err != nil {
+			return order, err
+		}
+		if balance <= 0 {
+			return order, problems.ErrLowBalance
+		}
+		budget = balance
+	}
+	fills := book.Match(&order, budget)
+	if order.Type == models.Market && len(fills) == 0 {
+		return order, problems.ErrNoLiquidity
+	}
+	if order.Side == models.Buy {
+		hold, err := e.balance.Reserve(ctx, order.UserID, currency, reservation(order, fills), order.ID, "")
+		if err != nil {
+			return order, err
+		}
+		order.HoldID = hold
+	}
+	if err := e.settle(ctx, currency, order, fills); err != nil {
+		// Заявка не записана, резерв возвращается сразу. Если и это не удалось, его вернет settlement как брошенный
+		e.release(ctx, order.HoldID)
+		return order, err
+	}
+	book.Apply(&order, fills)
+	log.Printf("Order %v placed with status %v, %v fills\n", order.ID, order.Status, len(fills))
+	return order, nil
+}
+
+// Снимает заявку пользователя из стакана и возвращает зарезервированные под нее деньги или бумаги
+func (e *Engine) Cancel(ctx context.Context, userID, orderID uuid.UUID) (models.Order, error) {
+	book, order := e.find(orderID)
+	if order == nil || order.UserID != userID {
+		if book != nil {
+			book.Unlock()
+		}
+		return models.Order{}, problems.ErrNoOrder
+	}
+	defer book.Unlock()
+	return e.cancel(ctx, book, order)
+}
+
+// Снимает заявки бумаг, снятых с листинга, и возвращает их резервы. Новые заявки по таким бумагам
+// не принимаются(GetPaperCurrency возвращает problems.ErrNoPaper), поэтому стакан после снятия остается пустым
+func (e *Engine) CancelDelisted(ctx context.Context) error {
+	papers, err := e.db.GetDelistedPapers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, paper := range papers {
+		e.mu.Lock()
+		book, ok := e.books[paper]
+		e.mu.Unlock()
+		if !ok {
+			continue
+		}
+		book.Lock()
+		var cancelled int
+		for _, order := range book.Orders() {
+			if order.UserID == uuid.Nil {
+				book.Remove(order.ID)
+				continue
+			}
+			if _, err := e.cancel(ctx, book, order); err != nil {
+				log.Printf("Cant cancel order %v of delisted paper %v: %v\n", order.ID, paper, err)
+				continue
+			}
+			cancelled++
+		}
+		book.Unlock()
+		if cancelled > 0 {
+			log.Printf("Cancelled %v orders of delisted paper %v\n", cancelled, paper)
+		}
+	}
+	return nil
+}
+
+// Снимает заявку из заблокированного стакана
+func (e *Engine) cancel(ctx context.Context, book *lockedBook, order *models.Order) (models.Order, error) {
+	cancelled := *order
+	cancelled.Status = models.Cancelled
+	// Возвращается ровно резерв неисполненного остатка: расчеты исполнений этой заявки могут еще ждать проведения
+	var settlements []models.Settlement
+	if cancelled.HoldID != uuid.Nil {
+		settlements = append(settlements, models.Settlement{HoldID: cancelled.HoldID, Cash: cancelled.Price.Mul(cancelled.Remaining()), Reference: cancelled.ID})
+	}
+	if err := e.db.CancelOrder(ctx, cancelled, settlements); err != nil {
+		return *order, err
+	}
+	e.settler.Settle(ctx, settlements)
+	book.Remove(order.ID)
+	log.Printf("Order %v cancelled\n", order.ID)
+	return cancelled, nil
+}
+
+// Отменяет заявку и ставит новую с другой ценой и объемом(новая заявка теряет приоритет по времени).
+// Если новую заявку поставить не удалось, старая остается отмененной
+func (e *Engine) Replace(ctx context.Context, userID, orderID uuid.UUID, price money.Amount, amount int32) (models.Order, error) {
+	if price <= 0 || amount <= 0 {
+		return models.Order{}, fmt.Errorf("%w: limit price and amount must be positive", problems.ErrBadOrder)
+	}
+	old, err := e.Cancel(ctx, userID, orderID)
+	if err != nil {
+		return old, err
+	}
+	return e.Place(ctx, models.Order{UserID: userID, Paper: old.Paper, Side: old.Side, Type: models.Limit, Price: price, Amount: amount})
+}
+
+// Переставляет заявки поставщика ликвидности вокруг новой цены market. Новые заявки сопоставляются со стаканом как обычные.
+// Пока торги бумагой остановлены, заявки поставщика только снимаются: после возобновления их выставит следующая цена
+func (e *Engine) Quote(ctx context.Context, paper string, price money.Amount) {
+	if e.cfg.QuoteSize <= 0 || price <= 0 {
+		return
+	}
+	halted := e.checkHalted(paper)
+	if halted != nil && !errors.Is(halted, problems.ErrHalted) {
+		return
+	}
+	book := e.book(paper)
+	book.Lock()
+	defer book.Unlock()
+	for _, order := range book.UserOrders(uuid.Nil) {
+		book.Remove(order.ID)
+	}
+	if halted != nil {
+		return
+	}
+	currency, err := e.db.GetPaperCurrency(ctx, paper)
+	if err != nil {
+		log.Printf("Cant get currency of %v: %v\n", paper, err)
+		return
+	}
+	quotes := []models.Order{
+		{Side: models.Buy, Price: price.MulRate(1 - e.cfg.Spread)},
+		{Side: models.Sell, Price: price.MulRate(1 + e.cfg.Spread)},
+	}
+	for _, quote := range quotes {
+		quote.ID = uuid.New()
+		quote.Paper = paper
+		quote.Type = models.Limit
+		quote.Amount = e.cfg.QuoteSize
+		quote.CreatedAt = time.Now()
+		fills := book.Match(&quote, 0)
+		if len(fills) > 0 {
+			if err := e.settle(ctx, currency, quote, fills); err != nil {
+				log.Printf("Cant settle liquidity quote for %v: %v\n", paper, err)
+				continue
+			}
+		}
+		book.Apply(&quote, fills)
+	}
+}
+
+// Записывает заявку с исполнениями в БД, проводит их расчеты в balance и публикует сделки maker и taker
+func (e *Engine) settle(ctx context.Context, currency string, taker models.Order, fills []models.Fill) error {
+	settlements := payments(currency, taker, fills)
+	if err := e.db.SettleOrder(ctx, settled(taker, fills), fills, settlements); err != nil {
+		return err
+	}
+	e.settler.Settle(ctx, settlements)
+	e.events.PublishFills(ctx, fillEvents(taker, fills))
+	return nil
+}
+
+// Сделки пользователей по исполнениям, поставщику ликвидности они не нужны
+func fillEvents(taker models.Order, fills []models.Fill) []models.FillEvent {
+	var events []models.FillEvent
+	now := time.Now()
+	for _, fill := range fills {
+		for _, order := range []models.Order{taker, fill.Maker} {
+			if order.UserID == uuid.Nil {
+				continue
+			}
+			events = append(events, models.FillEvent{
+				UserID: order.UserID, OrderID: order.ID, Side: order.Side, Paper: order.Paper, Amount: fill.Amount, Price: fill.Price, ExecutedAt: now,
+			})
+		}
+	}
+	return events
+}
+
+// Денежные расчеты исполнений заявки: из резерва покупателя продавцу. За поставщика ликвидности платит и получает
+// системный счет market. Лимитной покупке по цене лучше заявленной разница возвращается из резерва
+func payments(currency string, taker models.Order, fills []models.Fill) []models.Settlement {
+	var settlements []models.Settlement
+	for _, fill := range fills {
+		buyer, seller := taker, fill.Maker
+		if taker.Side == models.Sell {
+			buyer, seller = fill.Maker, taker
+		}
+		if buyer.UserID == uuid.Nil && seller.UserID == uuid.Nil {
+			continue
+		}
+		cost := fill.Price.Mul(fill.Amount)
+		payout := models.Payout{UserID: seller.UserID, Account: models.AccountCash, Amount: cost}
+		if seller.UserID == uuid.Nil {
+			payout = models.Payout{Account: models.AccountMarket, Amount: cost}
+		}
+		st := models.Settlement{HoldID: buyer.HoldID, Payouts: []models.Payout{payout}, Reference: taker.ID}
+		if buyer.UserID == uuid.Nil {
+			st.Currency, st.Cash = currency, cost
+		}
+		settlements = append(settlements, st)
+		refund := (taker.Price - fill.Price).Mul(fill.Amount)
+		if taker.Side == models.Buy && taker.Type == models.Limit && taker.UserID != uuid.Nil && refund > 0 {
+			settlements = append(settlements, models.Settlement{HoldID: taker.HoldID, Cash: refund, Reference: taker.ID})
+		}
+	}
+	return settlements
+}
+
+// Остановленная бумага - problems.ErrHalted
+func (e *Engine) checkHalted(paper string) error {
+	halted, err := e.halts.IsHalted(paper)
+	if err != nil {
+		log.Println("Cant check paper halt:", err)
+		return err
+	}
+	if halted {
+		return fmt.Errorf("%w: %v", problems.ErrHalted, paper)
+	}
+	return nil
+}
+
+// Возвращает владельцу весь резерв незаписанной заявки. Нулевой резерв - заявка без денег(продажа)
+func (e *Engine) release(ctx context.Context, hold uuid.UUID) {
+	if hold == uuid.Nil {
+		return
+	}
+	if err := e.balance.Release(ctx, hold, 0, ""); err != nil {
+		log.Printf("Hold %v left unreleased: %v\n", hold, err)
+	}
+}
+
+// Копия заявки из стакана
+func (e *Engine) Get(orderID uuid.UUID) (models.Order, bool) {
+	book, order := e.find(orderID)
+	if order == nil {
+		return models.Order{}, false
+	}
+	defer book.Unlock()
+	return *order, true
+}
+
+// Агрегированный стакан бумаги
+func (e *Engine) Depth(paper string, levels int) (bids, asks []models.PriceLevel) {
+	book := e.book(paper)
+	book.Lock()
+	defer book.Unlock()
+	return book.Depth(levels)
+}
+
+func (e *Engine) book(paper string) *lockedBook {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	book, ok := e.books[paper]
+	if !ok {
+		book = &lockedBook{Book: NewBook(paper)}
+		e.books[paper] = book
+	}
+	return book
+}
+
+// Ищет заявку во всех стаканах. Если заявка найдена, стакан возвращается заблокированным
+func (e *Engine) find(orderID uuid.UUID) (*lockedBook, *models.Order) {
+	e.mu.Lock()
+	books := make([]*lockedBook, 0, len(e.books))
+	for _, book := range e.books {
+		books = append(books, book)
+	}
+	e.mu.Unlock()
+	for _, book := range books {
+		book.Lock()
+		if order := book.Get(orderID); order != nil {
+			return book, order
+		}
+		book.Unlock()
+	}
+	return nil, nil
+}
+
+func validate(order models.Order) error {
+	switch {
+	case order.Side != models.Buy && order.Side != models.Sell:
+		return fmt.Errorf("%w: side must be buy or sell", problems.ErrBadOrder)
+	case order.Type != models.Limit && order.Type != models.Market:
+		return fmt.Errorf("%w: type must be limit or market", problems.ErrBadOrder)
+	case order.Amount <= 0:
+		return fmt.Errorf("%w: %v", problems.ErrBadOrder, problems.BadAmount)
+	case order.Type == models.Limit && order.Price <= 0:
+		return fmt.Errorf("%w: limit price must be positive", problems.ErrBadOrder)
+	case order.Paper == "":
+		return fmt.Errorf("%w: paper name is empty", problems.ErrBadOrder)
+	}
+	return nil
+}
+
+// Сколько денег резервирует покупка: лимитная - по своей цене на весь объем, рыночная - стоимость исполнений
+func reservation(order models.Order, fills []models.Fill) money.Amount {
+	if order.Type == models.Limit {
+		return order.Price.Mul(order.Amount)
+	}
+	var cash money.Amount
+	for _, fill := range fills {
+		cash += fill.Price.Mul(fill.Amount)
+	}
+	return cash
+}
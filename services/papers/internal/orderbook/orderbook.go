@@ -0,0 +1,185 @@
+package orderbook
+
+import (
+	"papers/internal/pkg/models"
+	"papers/internal/pkg/money"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// Стакан одной бумаги с приоритетом цена-время. Не потокобезопасен, синхронизация на стороне Engine
+type Book struct {
+	Paper string
+	// Покупки от дорогих к дешевым, продажи от дешевых к дорогим, при равной цене - от старых к новым
+	bids []*models.Order
+	asks []*models.Order
+}
+
+func NewBook(paper string) *Book {
+	return &Book{Paper: paper}
+}
+
+// Считает исполнения входящей заявки, не меняя стакан. Для рыночной покупки budget ограничивает
+// суммарную стоимость исполнений(0 - без ограничения). Заявки того же пользователя пропускаются
+func (b *Book) Match(taker *models.Order, budget money.Amount) []models.Fill {
+	var fills []models.Fill
+	remaining := taker.Remaining()
+	var spent money.Amount
+	for _, maker := range b.opposite(taker.Side) {
+		if remaining == 0 || !crosses(taker, maker.Price) {
+			break
+		}
+		if maker.UserID == taker.UserID {
+			continue
+		}
+		amount := min(remaining, maker.Remaining())
+		if budget > 0 && taker.Side == models.Buy && taker.Type == models.Market {
+			affordable := int32((budget - spent) / maker.Price)
+			amount = min(amount, affordable)
+			if amount <= 0 {
+				break
+			}
+		}
+		fills = append(fills, models.Fill{Maker: *maker, Taker: *taker, Price: maker.Price, Amount: amount})
+		remaining -= amount
+		spent += maker.Price.Mul(amount)
+	}
+	return fills
+}
+
+// Применяет посчитанные Match исполнения: уменьшает заявки в стакане, убирает исполненные
+// и ставит остаток лимитной заявки в стакан. Остаток рыночной заявки отменяется
+func (b *Book) Apply(taker *models.Order, fills []models.Fill) {
+	for _, fill := range fills {
+		if maker := b.Get(fill.Maker.ID); maker != nil {
+			maker.Filled += fill.Amount
+			maker.Status = models.Partial
+			if maker.Remaining() == 0 {
+				maker.Status = models.Filled
+				b.Remove(maker.ID)
+			}
+		}
+	}
+	*taker = settled(*taker, fills)
+	if taker.Status == models.Open || taker.Status == models.Partial {
+		b.Add(taker)
+	}
+}
+
+// Состояние входящей заявки после исполнений(то же, что получится после Book.Apply)
+func settled(taker models.Order, fills []models.Fill) models.Order {
+	for _, fill := range fills {
+		taker.Filled += fill.Amount
+	}
+	switch {
+	case taker.Remaining() == 0:
+		taker.Status = models.Filled
+	case taker.Type == models.Market:
+		taker.Status = models.Cancelled
+	case taker.Filled > 0:
+		taker.Status = models.Partial
+	default:
+		taker.Status = models.Open
+	}
+	return taker
+}
+
+// Ставит заявку в стакан без сопоставления(используется при восстановлении стакана из БД)
+func (b *Book) Add(order *models.Order) {
+	if order.Side == models.Buy {
+		i := sort.Search(len(b.bids), func(i int) bool { return b.bids[i].Price < order.Price })
+		b.bids = insert(b.bids, i, order)
+		return
+	}
+	i := sort.Search(len(b.asks), func(i int) bool { return b.asks[i].Price > order.Price })
+	b.asks = insert(b.asks, i, order)
+}
+
+// Возвращает заявку из стакана или nil
+func (b *Book) Get(id uuid.UUID) *models.Order {
+	for _, orders := range [][]*models.Order{b.bids, b.asks} {
+		for _, order := range orders {
+			if order.ID == id {
+				return order
+			}
+		}
+	}
+	return nil
+}
+
+// Убирает заявку из стакана, возвращает ее или nil, если ее там не было
+func (b *Book) Remove(id uuid.UUID) *models.Order {
+	for _, side := range []*[]*models.Order{&b.bids, &b.asks} {
+		for i, order := range *side {
+			if order.ID == id {
+				*side = append((*side)[:i], (*side)[i+1:]...)
+				return order
+			}
+		}
+	}
+	return nil
+}
+
+// Заявки пользователя(в т.ч. поставщика ликвидности с нулевым id) в стакане
+func (b *Book) UserOrders(userID uuid.UUID) []*models.Order {
+	var orders []*models.Order
+	for _, side := range [][]*models.Order{b.bids, b.asks} {
+		for _, order := range side {
+			if order.UserID == userID {
+				orders = append(orders, order)
+			}
+		}
+	}
+	return orders
+}
+
+// Все заявки стакана
+func (b *Book) Orders() []*models.Order {
+	return append(append([]*models.Order{}, b.bids...), b.asks...)
+}
+
+// Агрегированные по цене уровни стакана, не больше levels с каждой стороны(0 - все)
+func (b *Book) Depth(levels int) (bids, asks []models.PriceLevel) {
+	return aggregate(b.bids, levels), aggregate(b.asks, levels)
+}
+
+func (b *Book) opposite(side string) []*models.Order {
+	if side == models.Buy {
+		return b.asks
+	}
+	return b.bids
+}
+
+// Проходит ли цена заявки из стакана по ограничению входящей заявки
+func crosses(taker *models.Order, price money.Amount) bool {
+	if taker.Type == models.Market {
+		return true
+	}
+	if taker.Side == models.Buy {
+		return price <= taker.Price
+	}
+	return price >= taker.Price
+}
+
+func insert(orders []*models.Order, i int, order *models.Order) []*models.Order {
+	orders = append(orders, nil)
+	copy(orders[i+1:], orders[i:])
+	orders[i] = order
+	return orders
+}
+
+func aggregate(orders []*models.Order, levels int) []models.PriceLevel {
+	result := []models.PriceLevel{}
+	for _, order := range orders {
+		if n := len(result); n > 0 && result[n-1].Price == order.Price {
+			result[n-1].Amount += order.Remaining()
+			continue
+		}
+		if levels > 0 && len(result) == levels {
+			break
+		}
+		result = append(result, models.PriceLevel{Price: order.Price, Amount: order.Remaining()})
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package orderbook
+
+import (
+	"papers/internal/pkg/models"
+	"papers/internal/pkg/money"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func newOrder(side, kind string, price money.Amount, amount int32) *models.Order {
+	return &models.Order{ID: uuid.New(), UserID: uuid.New(), Paper: "Dogecoin", Side: side, Type: kind, Price: price, Amount: amount}
+}
+
+func TestBook(t *testing.T) {
+	t.Log("Testing order book in process...")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tPrice-time priority", testID)
+		book := NewBook("Dogecoin")
+		first := newOrder(models.Sell, models.Limit, 101, 5)
+		second := newOrder(models.Sell, models.Limit, 101, 5)
+		cheapest := newOrder(models.Sell, models.Limit, 100, 5)
+		book.Add(first)
+		book.Add(second)
+		book.Add(cheapest)
+		taker := newOrder(models.Buy, models.Limit, 101, 8)
+		fills := book.Match(taker, 0)
+		require.Len(t, fills, 2)
+		require.Equal(t, cheapest.ID, fills[0].Maker.ID, "Best price must be filled first")
+		require.Equal(t, money.Amount(100), fills[0].Price, "Fill must happen at maker price")
+		require.Equal(t, first.ID, fills[1].Maker.ID, "Older order must be filled first at equal price")
+		require.EqualValues(t, 3, fills[1].Amount)
+	}
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tPartial fill leaves remainder in the book", testID)
+		book := NewBook("Dogecoin")
+		maker := newOrder(models.Sell, models.Limit, 100, 3)
+		book.Add(maker)
+		taker := newOrder(models.Buy, models.Limit, 100, 5)
+		book.Apply(taker, book.Match(taker, 0))
+		require.Equal(t, models.Partial, taker.Status)
+		require.EqualValues(t, 3, taker.Filled)
+		require.Nil(t, book.Get(maker.ID), "Filled maker must leave the book")
+		bids, asks := book.Depth(0)
+		require.Equal(t, []models.PriceLevel{{Price: 100, Amount: 2}}, bids)
+		require.Empty(t, asks)
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\tMarket order remainder is cancelled and limited by budget", testID)
+		book := NewBook("Dogecoin")
+		book.Add(newOrder(models.Sell, models.Limit, 10, 5))
+		book.Add(newOrder(models.Sell, models.Limit, 20, 5))
+		taker := newOrder(models.Buy, models.Market, 0, 10)
+		fills := book.Match(taker, 75)
+		book.Apply(taker, fills)
+		require.EqualValues(t, 6, taker.Filled, "5 papers for 50 and 1 for 20 fit into the budget of 75")
+		require.Equal(t, models.Cancelled, taker.Status)
+		_, asks := book.Depth(0)
+		require.Equal(t, []models.PriceLevel{{Price: 20, Amount: 4}}, asks)
+	}
+	{
+		testID := 3
+		t.Logf("\tTest %d:\tOrders of the same user do not match", testID)
+		book := NewBook("Dogecoin")
+		maker := newOrder(models.Sell, models.Limit, 100, 5)
+		book.Add(maker)
+		taker := newOrder(models.Buy, models.Limit, 100, 5)
+		taker.UserID = maker.UserID
+		require.Empty(t, book.Match(taker, 0))
+	}
+	{
+		testID := 4
+		t.Logf("\tTest %d:\tFills are published to the maker and the taker, but not to the liquidity provider", testID)
+		book := NewBook("Dogecoin")
+		maker := newOrder(models.Sell, models.Limit, 100, 2)
+		quote := newOrder(models.Sell, models.Limit, 101, 5)
+		quote.UserID = uuid.Nil
+		book.Add(maker)
+		book.Add(quote)
+		taker := newOrder(models.Buy, models.Market, 0, 4)
+		events := fillEvents(*taker, book.Match(taker, 0))
+		require.Len(t, events, 3)
+		require.Equal(t, taker.UserID, events[0].UserID)
+		require.Equal(t, maker.UserID, events[1].UserID)
+		require.Equal(t, models.Sell, events[1].Side)
+		require.EqualValues(t, 2, events[1].Amount)
+		require.Equal(t, taker.ID, events[2].OrderID, "Fill against the liquidity provider goes to the taker only")
+		require.Equal(t, money.Amount(101), events[2].Price)
+	}
+}
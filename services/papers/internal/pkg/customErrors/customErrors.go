@@ -1,7 +1,27 @@
 package problems
 
+import "errors"
+
 var (
 	NoPaper    = "requested paper does not exists"
 	LowBalance = "not enough money on balance"
 	LowPaper   = "not enough papers to sold"
+	BadAmount  = "amount of papers must be positive"
+
+	NoSubscription = "subscription does not exists"
+	NoOrder        = "order does not exists"
+	NoLiquidity    = "no matching orders for market order"
+	BadOrder       = "invalid order"
+	Halted         = "trading in the paper is halted"
+	PartialFill    = "market order is filled partially, the rest is cancelled"
+)
+
+var (
+	ErrNoPaper     = errors.New(NoPaper)
+	ErrLowBalance  = errors.New(LowBalance)
+	ErrLowPaper    = errors.New(LowPaper)
+	ErrNoOrder     = errors.New(NoOrder)
+	ErrNoLiquidity = errors.New(NoLiquidity)
+	ErrBadOrder    = errors.New(BadOrder)
+	ErrHalted      = errors.New(Halted)
 )
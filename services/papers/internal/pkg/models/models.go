@@ -1,6 +1,11 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"papers/internal/pkg/money"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // Данные о пользователе
 type User struct {
@@ -10,9 +15,9 @@ type User struct {
 }
 
 type Paper struct {
-	Name   string  `json:"name"`
-	Price  float32 `json:"price"`
-	Amount int32   `json:"amount"`
+	Name   string       `json:"name"`
+	Price  money.Amount `json:"price"`
+	Amount int32        `json:"amount"`
 }
 
 // Данные для аутентификации
@@ -22,6 +27,137 @@ type AuthData struct {
 }
 
 type Money struct {
-	ID   uuid.UUID `json:"id"`
-	Cash float32   `json:"cash"`
+	ID   uuid.UUID    `json:"id"`
+	Cash money.Amount `json:"cash"`
+}
+
+// Сторона сделки
+const (
+	Buy  = "buy"
+	Sell = "sell"
+)
+
+// Статус сделки
+const (
+	Executed = "executed"
+	Rejected = "rejected"
+)
+
+// Сделка пользователя по текущей цене бумаги
+type Trade struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"user_id"`
+	Paper     string       `json:"paper"`
+	Side      string       `json:"side"`
+	Amount    int32        `json:"amount"`
+	Price     money.Amount `json:"price"`
+	Fee       money.Amount `json:"fee"`
+	Status    string       `json:"status"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// Фильтр истории сделок. Нулевые From/To не ограничивают выборку, After - курсор(последняя сделка предыдущей страницы)
+type TradeFilter struct {
+	UserID uuid.UUID
+	Paper  string
+	From   time.Time
+	To     time.Time
+	After  *Trade
+	Limit  int
+}
+
+// Тип заявки
+const (
+	Limit  = "limit"
+	Market = "market"
+)
+
+// Статус заявки
+const (
+	Open      = "open"
+	Partial   = "partial"
+	Filled    = "filled"
+	Cancelled = "cancelled"
+)
+
+// Заявка в стакане. Заявки поставщика ликвидности имеют нулевой UserID и не хранятся в БД
+type Order struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"user_id"`
+	Paper     string       `json:"paper"`
+	Side      string       `json:"side"`
+	Type      string       `json:"type"`
+	Price     money.Amount `json:"price"`
+	Amount    int32        `json:"amount"`
+	Filled    int32        `json:"filled"`
+	Status    string       `json:"status"`
+	CreatedAt time.Time    `json:"created_at"`
+	// Резерв денег лимитной покупки в balance
+	HoldID uuid.UUID `json:"-"`
+}
+
+// Неисполненный остаток заявки
+func (o *Order) Remaining() int32 {
+	return o.Amount - o.Filled
+}
+
+// Исполнение: сделка между заявкой из стакана(maker) и входящей заявкой(taker) по цене maker
+type Fill struct {
+	Maker  Order        `json:"maker"`
+	Taker  Order        `json:"taker"`
+	Price  money.Amount `json:"price"`
+	Amount int32        `json:"amount"`
+}
+
+// Сделка одного участника исполнения: papers публикует ее в redis, gateway отправляет пользователю в вебсокет
+type FillEvent struct {
+	UserID     uuid.UUID    `json:"user_id"`
+	OrderID    uuid.UUID    `json:"order_id"`
+	Side       string       `json:"side"`
+	Paper      string       `json:"paper"`
+	Amount     int32        `json:"amount"`
+	Price      money.Amount `json:"price"`
+	ExecutedAt time.Time    `json:"executed_at"`
+}
+
+// Уровень цены в стакане
+type PriceLevel struct {
+	Price  money.Amount `json:"price"`
+	Amount int32        `json:"amount"`
+}
+
+// Свеча OHLC: цены первого, максимального, минимального и последнего тика за интервал, начинающийся в Time
+type Candle struct {
+	Time  time.Time    `json:"time"`
+	Open  money.Amount `json:"open"`
+	High  money.Amount `json:"high"`
+	Low   money.Amount `json:"low"`
+	Close money.Amount `json:"close"`
+}
+
+// Счета balance, на которые papers направляет выплаты из резервов
+const (
+	AccountCash   = "cash"
+	AccountMarket = "market"
+	AccountFees   = "fees"
+)
+
+// Выплата из резерва balance: на счет cash пользователя или, если пользователя нет, на системный счет Account
+type Payout struct {
+	UserID  uuid.UUID    `json:"user_id"`
+	Account string       `json:"account"`
+	Amount  money.Amount `json:"amount"`
+}
+
+// Денежный расчет по уже записанной в БД сделке, заявке или отмене. Записывается в той же транзакции и проводится
+// в balance, пока не пройдет. С выплатами - списание резерва HoldID(нулевой - сначала резерв market на Cash в Currency),
+// без выплат - возврат владельцу Cash из резерва(0 - всего остатка). ID - ключ идемпотентности расчета в balance
+type Settlement struct {
+	ID        uuid.UUID
+	HoldID    uuid.UUID
+	Currency  string
+	Cash      money.Amount
+	Payouts   []Payout
+	Reference uuid.UUID
+	Attempts  int
 }
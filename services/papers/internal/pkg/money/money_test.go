@@ -0,0 +1,69 @@
+package money
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmount(t *testing.T) {
+	t.Log("Testing money amounts in process...")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tParsing and formatting are exact", testID)
+		for text, want := range map[string]Amount{"12.5": 1250, "0.1": 10, "-0.05": -5, "100": 10000, "99.995": 10000, ".3": 30} {
+			got, err := Parse(text)
+			require.NoError(t, err, text)
+			require.Equal(t, want, got, text)
+		}
+		require.Equal(t, "-0.05", Amount(-5).String())
+		require.Equal(t, "666666.00", Amount(66666600).String())
+		for _, bad := range []string{"", "1.2.3", "abc", "1e5", "99999999999999999999"} {
+			_, err := Parse(bad)
+			require.ErrorIs(t, err, ErrBadAmount, bad)
+		}
+	}
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tSums of tenths do not drift", testID)
+		var sum Amount
+		for range 1000 {
+			sum += FromFloat(0.1)
+		}
+		require.Equal(t, Amount(10000), sum)
+		require.Equal(t, Amount(3), Amount(1000).MulRate(0.0025), "Fee is rounded to a minor unit")
+	}
+	{
+		testID := 2
+		t.Logf("\tTest %d:\tJSON keeps numbers and accepts old clients", testID)
+		data, err := json.Marshal(struct{ Cash Amount }{1250})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"Cash": 12.50}`, string(data))
+		var got struct{ Cash Amount }
+		for text, want := range map[string]Amount{`{"Cash": 12.5}`: 1250, `{"Cash": "0.1"}`: 10, `{"Cash": 1e2}`: 10000} {
+			require.NoError(t, json.Unmarshal([]byte(text), &got), text)
+			require.Equal(t, want, got.Cash, text)
+		}
+	}
+	{
+		testID := 3
+		t.Logf("\tTest %d:\tNumeric columns round trip through pgx", testID)
+		m := pgtype.NewMap()
+		for _, want := range []Amount{0, 1, -1250, 66666600} {
+			for _, format := range []int16{pgtype.BinaryFormatCode, pgtype.TextFormatCode} {
+				buf, err := m.Encode(pgtype.NumericOID, format, want, nil)
+				require.NoError(t, err)
+				var got Amount
+				require.NoError(t, m.Scan(pgtype.NumericOID, format, buf, &got))
+				require.Equal(t, want, got)
+			}
+		}
+		var got Amount
+		require.NoError(t, got.ScanNumeric(pgtype.Numeric{Int: big.NewInt(-12345), Exp: -3, Valid: true}))
+		require.Equal(t, Amount(-1235), got, "Extra digits are rounded half away from zero")
+		require.Error(t, got.ScanNumeric(pgtype.Numeric{}), "NULL is not an amount")
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"encoding/json"
 	"log"
 	"papers/internal/pkg/models"
-	"strconv"
+	"papers/internal/pkg/money"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
@@ -18,6 +18,12 @@ type Config struct {
 	DB       int    `yaml:"dbnum"`
 }
 
+// Канал, в который market публикует каждое изменение цены
+const StockUpdatesChannel = "stock_updates"
+
+// Канал, в который papers публикует сделки пользователей(models.FillEvent), по одной на сообщение
+const TradeFillsChannel = "trade_fills"
+
 type Redis struct {
 	client *redis.Client
 }
@@ -48,30 +54,41 @@ func (r *Redis) GetAvailablePapers() ([]models.Paper, error) {
 			log.Println("Cant read stock:", err)
 			return nil, err
 		}
-		priceFloat, err := strconv.ParseFloat(price, 32)
+		parsed, err := money.Parse(price)
 		if err != nil {
 			log.Println("Cant parse price:", err)
 			return nil, err
 		}
-		papers = append(papers, models.Paper{Name: key, Price: float32(priceFloat)})
+		papers = append(papers, models.Paper{Name: key, Price: parsed})
 	}
 	return papers, nil
 }
 
-func (r *Redis) GetPaperPrice(name string) (float32, error) {
+func (r *Redis) GetPaperPrice(name string) (money.Amount, error) {
 	log.Printf("Attempt to get paper %v price\n", name)
 	stringPrice, err := r.client.Get(context.Background(), "stock:"+name).Result()
 	if err != nil {
 		log.Println("Cant get paper price:", err)
 		return 0, err
 	}
-	price, err := strconv.ParseFloat(stringPrice, 32)
+	price, err := money.Parse(stringPrice)
 	if err != nil {
 		log.Println("Cant parse price:", err)
 		return 0, err
 	}
-	return float32(price), nil
+	return price, nil
 }
+
+// Остановлены ли торги бумагой(флаг halt:<name> ставит market)
+func (r *Redis) IsHalted(name string) (bool, error) {
+	n, err := r.client.Exists(context.Background(), "halt:"+name).Result()
+	if err != nil {
+		log.Println("Cant get paper halt flag:", err)
+		return false, err
+	}
+	return n > 0, nil
+}
+
 func (r *Redis) GetUserPapers(userId uuid.UUID) ([]models.Paper, error) {
 	log.Printf("Attempt to get user with uuid %v papers from redis\n", userId)
 	stringJson, err := r.client.Get(context.Background(), "user_papers:"+userId.String()).Result()
@@ -101,3 +118,55 @@ func (r *Redis) UpdateUserPapers(userId uuid.UUID, papers []models.Paper) error
 	}
 	return nil
 }
+
+// Публикует сделки пользователей для gateway. Сделки уже проведены, поэтому ошибка публикации только логируется
+func (r *Redis) PublishFills(ctx context.Context, events []models.FillEvent) {
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			log.Println("Cant marshal fill event:", err)
+			continue
+		}
+		if err := r.client.Publish(ctx, TradeFillsChannel, payload).Err(); err != nil {
+			log.Printf("Cant publish fill of order %v: %v\n", event.OrderID, err)
+		}
+	}
+}
+
+// Подписывается на изменения цен бумаг, которые публикует market. Канал закрывается при отмене контекста
+func (r *Redis) SubscribeStocks(ctx context.Context) (<-chan models.Paper, error) {
+	log.Printf("Attempt to subscribe to %v\n", StockUpdatesChannel)
+	pubsub := r.client.Subscribe(ctx, StockUpdatesChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		log.Println("Cant subscribe to stock updates:", err)
+		pubsub.Close()
+		return nil, err
+	}
+	updates := make(chan models.Paper)
+	go func() {
+		defer close(updates)
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var paper models.Paper
+				if err := json.Unmarshal([]byte(msg.Payload), &paper); err != nil {
+					log.Println("Cant unmarshal stock update:", err)
+					continue
+				}
+				select {
+				case updates <- paper:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return updates, nil
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"papers/internal/pkg/models"
+	"papers/internal/pkg/money"
+)
+
+// Переставляет заявки поставщика ликвидности по ценам market отдельно от рассылки цен: Quote ходит в БД и balance,
+// и медленная переставка не должна задерживать ленту. Пока переставка идет, цены бумаги копятся, и переставляется
+// только последняя
+type quoter struct {
+	engine IEngine
+	mu     sync.Mutex
+	prices map[string]money.Amount
+	wake   chan struct{}
+}
+
+func newQuoter(engine IEngine) *quoter {
+	q := &quoter{engine: engine, prices: map[string]money.Amount{}, wake: make(chan struct{}, 1)}
+	go q.run()
+	return q
+}
+
+// Запоминает новую цену бумаги, не дожидаясь переставки
+func (q *quoter) push(paper models.Paper) {
+	q.mu.Lock()
+	q.prices[paper.Name] = paper.Price
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *quoter) run() {
+	for range q.wake {
+		q.mu.Lock()
+		prices := q.prices
+		q.prices = map[string]money.Amount{}
+		q.mu.Unlock()
+		for name, price := range prices {
+			q.engine.Quote(context.Background(), name, price)
+		}
+	}
+}
@@ -2,25 +2,72 @@ package server
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"idempotency"
 	"log"
 	"net"
+	"papers/internal/hub"
 	problems "papers/internal/pkg/customErrors"
 	"papers/internal/pkg/models"
+	"papers/internal/pkg/money"
 	pb "papers/internal/pkg/papersService"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
+// Заголовок, в котором Subscribe возвращает id подписки, а Unsubscribe ожидает его
+const SubscriptionHeader = "x-subscription-id"
+
+// Размер страницы истории сделок по умолчанию и максимальный
+const (
+	DefaultHistoryLimit = 50
+	MaxHistoryLimit     = 200
+)
+
+// Интервалы свечей и максимальное число свечей в одном ответе
+var CandleIntervals = map[string]time.Duration{
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+const MaxCandles = 1000
+
 type Config struct {
-	Port string `yaml:"port"`
+	Port             string  `yaml:"port"`
+	SubscriberBuffer int     `yaml:"subscriber_buffer" env-default:"32"`
+	FeeRate          float64 `yaml:"fee_rate" env-default:"0"`
+	// Сколько секунд хранится ключ идемпотентности сделки
+	IdempotencyTTL int `yaml:"idempotency_ttl" env-default:"86400"`
+	// Раз в сколько секунд удаляются истекшие ключи идемпотентности
+	IdempotencyCleanupInterval int `yaml:"idempotency_cleanup_interval" env-default:"3600"`
+	// Раз в сколько секунд снимаются заявки бумаг, снятых market с листинга
+	DelistSweepInterval int `yaml:"delist_sweep_interval" env-default:"30"`
+	// BuyPaper и SellPaper проводят сделку по цене market напрямую, мимо стакана(как до появления заявок)
+	LegacyTrade bool `yaml:"legacy_trade" env-default:"false"`
 }
 
 type server struct {
 	pb.UnimplementedPapersManagementServer
-	redis IRDS
-	db    IDB
+	cfg     *Config
+	redis   IRDS
+	db      IDB
+	engine  IEngine
+	balance IBalance
+	settler ISettler
+	keys    *idempotency.Keys
+	hub     *hub.Hub
 }
 
 type Service struct {
@@ -33,32 +80,87 @@ type Service struct {
 
 type IRDS interface {
 	GetAvailablePapers() ([]models.Paper, error)
-	GetPaperPrice(name string) (float32, error)
+	GetPaperPrice(name string) (money.Amount, error)
+	IsHalted(name string) (bool, error)
 	GetUserPapers(userId uuid.UUID) ([]models.Paper, error)
 	UpdateUserPapers(userId uuid.UUID, papers []models.Paper) error
+	SubscribeStocks(ctx context.Context) (<-chan models.Paper, error)
+	PublishFills(ctx context.Context, events []models.FillEvent)
 }
 
 type IDB interface {
 	GetUserPapers(userId uuid.UUID) ([]models.Paper, error)
-	GetUserBalance(userId uuid.UUID) (float32, error)
-	ChangeBalance(userId uuid.UUID, change float32) error
-	ChangePaperAmount(userId uuid.UUID, name string, amount int) error
-	GetPaperAmount(userId uuid.UUID, name string) (int, error)
-	AddPaper(userId uuid.UUID, name string, amount int) error
+	ExecuteTrade(ctx context.Context, trade models.Trade, settlement *models.Settlement) (models.Trade, error)
+	RejectTrade(ctx context.Context, trade models.Trade) (models.Trade, error)
+	GetTradeHistory(ctx context.Context, filter models.TradeFilter) ([]models.Trade, error)
+	GetUserOrders(ctx context.Context, userId uuid.UUID, limit int) ([]models.Order, error)
+	GetPriceHistory(ctx context.Context, paper string, interval time.Duration, from, to time.Time) ([]models.Candle, error)
+	GetPaperCurrency(ctx context.Context, paper string) (string, error)
+}
+
+// Деньги сделок по цене market: резерв ставится до сделки и возвращается сразу, если сделку не удалось записать
+type IBalance interface {
+	Reserve(ctx context.Context, userID uuid.UUID, currency string, cash money.Amount, reference uuid.UUID, key string) (uuid.UUID, error)
+	Release(ctx context.Context, holdID uuid.UUID, cash money.Amount, key string) error
+}
+
+// Проводит в balance расчет, записанный вместе со сделкой: списание резерва или его возврат
+type ISettler interface {
+	Settle(ctx context.Context, settlements []models.Settlement)
+}
+
+type IEngine interface {
+	Place(ctx context.Context, order models.Order) (models.Order, error)
+	Cancel(ctx context.Context, userID, orderID uuid.UUID) (models.Order, error)
+	Replace(ctx context.Context, userID, orderID uuid.UUID, price money.Amount, amount int32) (models.Order, error)
+	Get(orderID uuid.UUID) (models.Order, bool)
+	Quote(ctx context.Context, paper string, price money.Amount)
+	Depth(paper string, levels int) (bids, asks []models.PriceLevel)
+	CancelDelisted(ctx context.Context) error
 }
 
-func New(cfg *Config, db IDB, redis IRDS) (*Service, error) {
+func New(cfg *Config, db IDB, redis IRDS, engine IEngine, balance IBalance, settler ISettler, keys *idempotency.Keys) (*Service, error) {
 	log.Println(cfg.Port)
 	lis, err := net.Listen("tcp", cfg.Port)
 	if err != nil {
 		return nil, err
 	}
+	updates, err := redis.SubscribeStocks(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	h := hub.New(cfg.SubscriberBuffer)
+	ticks := make(chan models.Paper)
+	go h.Run(ticks)
+	// Каждое изменение цены market сразу рассылается подписчикам, а заявки поставщика ликвидности переставляются
+	// в фоне(см. quoter)
+	quotes := newQuoter(engine)
+	go func() {
+		defer close(ticks)
+		for paper := range updates {
+			ticks <- paper
+			quotes.push(paper)
+		}
+	}()
+	go sweepDelisted(engine, time.Duration(cfg.DelistSweepInterval)*time.Second)
 	s := grpc.NewServer()
-	pb.RegisterPapersManagementServer(s, &server{redis: redis, db: db})
+	pb.RegisterPapersManagementServer(s, &server{cfg: cfg, redis: redis, db: db, engine: engine, balance: balance, settler: settler, keys: keys, hub: h})
 	log.Printf("Auth server listening at %v\n", lis.Addr())
 	return &Service{PpsServer: s, Listener: &lis, cfg: cfg, db: db, redis: redis}, nil
 }
 
+// Снимает заявки бумаг, снятых с листинга: сразу при старте(бумагу могли снять, пока papers не работал), затем раз в interval
+func sweepDelisted(engine IEngine, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := engine.CancelDelisted(context.Background()); err != nil {
+			log.Println("Cant cancel orders of delisted papers:", err)
+		}
+		<-ticker.C
+	}
+}
+
 func (s *server) GetAvailablePapers(_ context.Context, _ *pb.Request) (*pb.AvailablePapers, error) {
 	papers, err := s.redis.GetAvailablePapers()
 	if err != nil {
@@ -72,15 +174,64 @@ func (s *server) GetAvailablePapers(_ context.Context, _ *pb.Request) (*pb.Avail
 	return &pb.AvailablePapers{Papers: jsoned}, nil
 }
 
-func (s *server) BuyPaper(_ context.Context, paperreq *pb.PaperRequest) (*pb.Status, error) {
+func (s *server) BuyPaper(ctx context.Context, paperreq *pb.PaperRequest) (*pb.Status, error) {
 	userId, err := uuid.FromBytes(paperreq.UserId)
 	if err != nil {
 		log.Println("Cant get uuid from given bytes:", err)
 		return nil, err
 	}
-	userBalance, err := s.db.GetUserBalance(userId)
+	return idempotency.Do(ctx, s.keys, "papers/BuyPaper", userId, paperreq.IdempotencyKey, paperreq, func() (*pb.Status, error) {
+		return s.marketOrder(ctx, models.Buy, userId, paperreq)
+	})
+}
+
+func (s *server) SellPaper(ctx context.Context, paperreq *pb.PaperRequest) (*pb.Status, error) {
+	userId, err := uuid.FromBytes(paperreq.UserId)
 	if err != nil {
-		log.Println("Cant get user balance:", err)
+		log.Println("Cant get uuid from given bytes:", err)
+		return nil, err
+	}
+	return idempotency.Do(ctx, s.keys, "papers/SellPaper", userId, paperreq.IdempotencyKey, paperreq, func() (*pb.Status, error) {
+		return s.marketOrder(ctx, models.Sell, userId, paperreq)
+	})
+}
+
+// Покупка или продажа бумаги рыночной заявкой в стакан: она исполняется по лучшим заявкам пользователей и поставщика
+// ликвидности в порядке цены и времени. Неисполненный остаток отменяется, об этом говорит response. С LegacyTrade
+// сделка проводится по цене market мимо стакана(см. trade)
+func (s *server) marketOrder(ctx context.Context, side string, userId uuid.UUID, paperreq *pb.PaperRequest) (*pb.Status, error) {
+	if s.cfg.LegacyTrade {
+		return s.trade(ctx, side, userId, paperreq)
+	}
+	if paperreq.PaperAmount <= 0 {
+		return &pb.Status{Response: problems.BadAmount}, nil
+	}
+	order, err := s.engine.Place(ctx, models.Order{UserID: userId, Paper: paperreq.PaperName, Side: side, Type: models.Market, Amount: paperreq.PaperAmount})
+	switch {
+	case errors.Is(err, problems.ErrHalted):
+		return nil, status.Errorf(codes.FailedPrecondition, "%v", err)
+	case errors.Is(err, problems.ErrLowBalance), errors.Is(err, problems.ErrLowPaper), errors.Is(err, problems.ErrNoLiquidity),
+		errors.Is(err, problems.ErrNoPaper), errors.Is(err, problems.ErrBadOrder):
+		return &pb.Status{Response: err.Error()}, nil
+	case err != nil:
+		log.Printf("Cant %v paper: %v\n", side, err)
+		return nil, err
+	}
+	s.refreshUserPapers(userId)
+	if order.Filled < order.Amount {
+		return &pb.Status{Response: fmt.Sprintf("%v: filled %v of %v", problems.PartialFill, order.Filled, order.Amount)}, nil
+	}
+	return nil, nil
+}
+
+// Проводит сделку по текущей цене бумаги и обновляет кэш бумаг пользователя. Деньги плательщика(покупателя или market)
+// резервируются в balance до записи сделки в БД. Вместе со сделкой записывается расчет: списание резерва другой стороне
+// и в комиссию или, если сделка отклонена, его возврат. Расчет проводится сразу, а при ошибке balance - повторно settlement
+func (s *server) trade(ctx context.Context, side string, userId uuid.UUID, paperreq *pb.PaperRequest) (*pb.Status, error) {
+	if paperreq.PaperAmount <= 0 {
+		return &pb.Status{Response: problems.BadAmount}, nil
+	}
+	if err := s.checkHalted(paperreq.PaperName); err != nil {
 		return nil, err
 	}
 	paperPrice, err := s.redis.GetPaperPrice(paperreq.PaperName)
@@ -92,117 +243,444 @@ func (s *server) BuyPaper(_ context.Context, paperreq *pb.PaperRequest) (*pb.Sta
 		log.Printf("Paper %v does not exists\n", paperreq.PaperName)
 		return &pb.Status{Response: problems.NoPaper}, nil
 	}
-	cost := paperPrice * float32(paperreq.PaperAmount)
-	if cost > userBalance {
-		return &pb.Status{Response: problems.LowBalance}, nil
+	cost := paperPrice.Mul(paperreq.PaperAmount)
+	fee := cost.MulRate(s.cfg.FeeRate)
+	trade := models.Trade{ID: uuid.New(), UserID: userId, Paper: paperreq.PaperName, Side: side, Amount: paperreq.PaperAmount, Price: paperPrice, Fee: fee}
+	payer, held := userId, cost+fee
+	payouts := []models.Payout{{Account: models.AccountMarket, Amount: cost}, {Account: models.AccountFees, Amount: fee}}
+	if side == models.Sell {
+		payer, held = uuid.Nil, cost
+		payouts = []models.Payout{{UserID: userId, Account: models.AccountCash, Amount: cost - fee}, {Account: models.AccountFees, Amount: fee}}
 	}
-	err = s.db.ChangeBalance(userId, -cost)
-	if err != nil {
-		log.Println("Cant change user balance:", err)
-		return nil, err
+	currency, err := s.db.GetPaperCurrency(ctx, trade.Paper)
+	if errors.Is(err, problems.ErrNoPaper) {
+		return &pb.Status{Response: problems.NoPaper}, nil
 	}
-	am, err := s.db.GetPaperAmount(userId, paperreq.PaperName)
 	if err != nil {
-		log.Println("Cant get user amount of papers:", err)
-		s.db.ChangeBalance(userId, cost)
 		return nil, err
 	}
-	if am == -1 {
-		err = s.db.AddPaper(userId, paperreq.PaperName, int(paperreq.PaperAmount))
-		if err != nil {
-			log.Println("Cant add user papers:", err)
-			s.db.ChangeBalance(userId, cost)
+	hold, err := s.balance.Reserve(ctx, payer, currency, held, trade.ID, "")
+	if errors.Is(err, problems.ErrLowBalance) {
+		if _, err := s.db.RejectTrade(ctx, trade); err != nil {
 			return nil, err
 		}
-	} else {
-		err = s.db.ChangePaperAmount(userId, paperreq.PaperName, int(paperreq.PaperAmount))
+		return &pb.Status{Response: problems.LowBalance}, nil
 	}
 	if err != nil {
-		log.Println("Cant change user amount of papers:", err)
-		s.db.ChangeBalance(userId, cost)
 		return nil, err
 	}
+	settlement := models.Settlement{HoldID: hold, Payouts: payouts, Reference: trade.ID}
+	_, err = s.db.ExecuteTrade(ctx, trade, &settlement)
+	switch {
+	case errors.Is(err, problems.ErrLowPaper):
+		s.settler.Settle(ctx, []models.Settlement{settlement})
+		return &pb.Status{Response: problems.LowPaper}, nil
+	case err != nil:
+		// Сделка не записана, резерв возвращается сразу. Если и это не удалось, его вернет settlement как брошенный
+		if err := s.balance.Release(ctx, hold, 0, ""); err != nil {
+			log.Printf("Hold %v left unreleased: %v\n", hold, err)
+		}
+		log.Printf("Cant %v paper: %v\n", side, err)
+		return nil, err
+	}
+	s.settler.Settle(ctx, []models.Settlement{settlement})
+	s.redis.PublishFills(ctx, []models.FillEvent{{
+		UserID: userId, OrderID: trade.ID, Side: side, Paper: trade.Paper, Amount: trade.Amount, Price: trade.Price, ExecutedAt: time.Now(),
+	}})
+	s.refreshUserPapers(userId)
+	return nil, nil
+}
+
+// Обновляет кэш бумаг пользователя после проведенной сделки. Ошибка только логируется: сделка уже записана,
+// и ошибка кэша не должна дать клиенту повторить ее
+func (s *server) refreshUserPapers(userId uuid.UUID) {
 	papers, err := s.db.GetUserPapers(userId)
 	if err != nil {
 		log.Println("Cant get user papers:", err)
-		return nil, err
+		return
 	}
-	err = s.redis.UpdateUserPapers(userId, papers)
-	if err != nil {
+	if err := s.redis.UpdateUserPapers(userId, papers); err != nil {
 		log.Println("Cant update user papers:", err)
-		return nil, err
 	}
-	return nil, nil
 }
 
-func (s *server) SellPaper(_ context.Context, paperreq *pb.PaperRequest) (*pb.Status, error) {
-	userId, err := uuid.FromBytes(paperreq.UserId)
+func (s *server) GetUserPapers(_ context.Context, user *pb.User) (*pb.AvailablePapers, error) {
+	userId, err := uuid.FromBytes(user.Id)
 	if err != nil {
 		log.Println("Cant get uuid from given bytes:", err)
 		return nil, err
 	}
-	availableAmount, err := s.db.GetPaperAmount(userId, paperreq.PaperName)
+	papers, err := s.redis.GetUserPapers(userId)
 	if err != nil {
-		log.Println("Cant get amount of papers:", err)
+		log.Println("Cant get user papers:", err)
 		return nil, err
 	}
-	if paperreq.PaperAmount > int32(availableAmount) {
-		return &pb.Status{Response: problems.LowPaper}, nil
+	for i := range papers {
+		papers[i].Price, err = s.redis.GetPaperPrice(papers[i].Name)
+		if err != nil {
+			log.Println("Cant get paper price:", err)
+			return nil, err
+		}
 	}
-	paperPrice, err := s.redis.GetPaperPrice(paperreq.PaperName)
+	jsonedPapers, err := json.Marshal(papers)
 	if err != nil {
-		log.Println("Cant get paper price:", err)
+		log.Println("Cant marshal user papers:", err)
 		return nil, err
 	}
-	if paperPrice == 0 {
-		return &pb.Status{Response: problems.NoPaper}, nil
+	return &pb.AvailablePapers{Papers: jsonedPapers}, nil
+}
+
+// Подписка на изменения цен бумаг. Сначала отправляются текущие цены, затем каждое изменение цены, пока клиент не отменит стрим
+func (s *server) Subscribe(paperreq *pb.PaperRequest, stream pb.PapersManagement_SubscribeServer) error {
+	names := requestedPapers(paperreq)
+	if len(names) == 0 {
+		return status.Errorf(codes.InvalidArgument, "request missing paper names")
+	}
+	current := make([]*pb.Paper, 0, len(names))
+	for _, name := range names {
+		price, err := s.redis.GetPaperPrice(name)
+		if err != nil || price == 0 {
+			log.Printf("Paper %v does not exists: %v\n", name, err)
+			return status.Errorf(codes.NotFound, "%v: %v", problems.NoPaper, name)
+		}
+		current = append(current, &pb.Paper{Name: name, Price: price.Float32(), PriceMinor: int64(price)})
+	}
+	sub := s.hub.Subscribe(stream.Context(), names)
+	defer s.hub.Remove(sub.ID)
+	if err := stream.SendHeader(metadata.Pairs(SubscriptionHeader, sub.ID.String())); err != nil {
+		log.Println("Cant send subscription header:", err)
+		return err
+	}
+	for _, paper := range current {
+		if err := stream.Send(paper); err != nil {
+			log.Println("Cant send paper price:", err)
+			return err
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			log.Printf("Subscriber %v disconnected\n", sub.ID)
+			return nil
+		case <-sub.Done():
+			switch err := sub.Err(); {
+			case errors.Is(err, hub.ErrSlowConsumer):
+				return status.Errorf(codes.ResourceExhausted, "%v", err)
+			default:
+				return nil
+			}
+		case paper := <-sub.Updates():
+			if err := stream.Send(&pb.Paper{Name: paper.Name, Price: paper.Price.Float32(), PriceMinor: int64(paper.Price)}); err != nil {
+				log.Println("Cant send paper price:", err)
+				return err
+			}
+		}
+	}
+}
+
+// Отписка от бумаги. Id подписки передается в метаданных(см SubscriptionHeader), при пустом имени бумаги подписка закрывается целиком
+func (s *server) Unsubscribe(ctx context.Context, paper *pb.Paper) (*pb.Status, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(SubscriptionHeader)
+	if len(values) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "request missing %v metadata", SubscriptionHeader)
+	}
+	id, err := uuid.Parse(values[0])
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid subscription id: %v", err)
+	}
+	var names []string
+	if paper.Name != "" {
+		names = []string{paper.Name}
+	}
+	if !s.hub.Unsubscribe(id, names) {
+		return &pb.Status{Response: problems.NoSubscription}, nil
+	}
+	return &pb.Status{}, nil
+}
+
+// Список бумаг из запроса(paperNames и/или paperName)
+func requestedPapers(paperreq *pb.PaperRequest) []string {
+	names := make([]string, 0, len(paperreq.PaperNames)+1)
+	seen := make(map[string]struct{}, cap(names))
+	for _, name := range append([]string{paperreq.PaperName}, paperreq.PaperNames...) {
+		if _, ok := seen[name]; ok || name == "" {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
 	}
-	err = s.db.ChangePaperAmount(userId, paperreq.PaperName, int(-paperreq.PaperAmount))
+	return names
+}
+
+// История сделок пользователя от новых к старым с фильтром по бумаге и времени(unix секунды, to не включительно).
+// Для следующей страницы нужно передать nextCursor из ответа
+func (s *server) GetTradeHistory(ctx context.Context, req *pb.TradeHistoryRequest) (*pb.TradeHistory, error) {
+	userId, err := uuid.FromBytes(req.UserId)
 	if err != nil {
-		log.Println("Cant change user amount of paper:", err)
+		log.Println("Cant get uuid from given bytes:", err)
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	filter := models.TradeFilter{UserID: userId, Paper: req.PaperName, Limit: int(req.Limit)}
+	if req.From > 0 {
+		filter.From = time.Unix(req.From, 0)
+	}
+	if req.To > 0 {
+		filter.To = time.Unix(req.To, 0)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultHistoryLimit
+	}
+	filter.Limit = min(filter.Limit, MaxHistoryLimit)
+	if req.Cursor != "" {
+		filter.After, err = decodeCursor(req.Cursor)
+		if err != nil {
+			log.Println("Cant decode trades cursor:", err)
+			return nil, status.Errorf(codes.InvalidArgument, "invalid cursor")
+		}
+	}
+	// Запрашиваем на одну сделку больше, чтобы понять, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit++
+	trades, err := s.db.GetTradeHistory(ctx, filter)
+	if err != nil {
+		log.Println("Cant get user trades:", err)
 		return nil, err
 	}
-	err = s.db.ChangeBalance(userId, float32(paperreq.PaperAmount)*paperPrice)
+	history := &pb.TradeHistory{Trades: make([]*pb.Trade, 0, min(len(trades), limit))}
+	if len(trades) > limit {
+		trades = trades[:limit]
+		history.NextCursor = encodeCursor(trades[limit-1])
+	}
+	for _, trade := range trades {
+		history.Trades = append(history.Trades, &pb.Trade{
+			Id:         trade.ID[:],
+			PaperName:  trade.Paper,
+			Side:       trade.Side,
+			Amount:     trade.Amount,
+			Price:      trade.Price.Float32(),
+			Fee:        trade.Fee.Float32(),
+			PriceMinor: int64(trade.Price),
+			FeeMinor:   int64(trade.Fee),
+			Status:     trade.Status,
+			ExecutedAt: trade.CreatedAt.Unix(),
+		})
+	}
+	return history, nil
+}
+
+// Курсор - время и id последней сделки страницы
+func encodeCursor(trade models.Trade) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", trade.CreatedAt.UnixNano(), trade.ID)))
+}
+
+func decodeCursor(cursor string) (*models.Trade, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
-		log.Println("Cant change user balance:", err)
-		s.db.ChangePaperAmount(userId, paperreq.PaperName, int(paperreq.PaperAmount))
 		return nil, err
 	}
-	papers, err := s.db.GetUserPapers(userId)
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, errors.New("cursor has no separator")
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
 	if err != nil {
-		log.Println("Cant get user papers:", err)
 		return nil, err
 	}
-	err = s.redis.UpdateUserPapers(userId, papers)
+	tradeId, err := uuid.Parse(id)
 	if err != nil {
-		log.Println("Cant update user papers:", err)
 		return nil, err
 	}
-	return nil, nil
+	return &models.Trade{ID: tradeId, CreatedAt: time.Unix(0, unixNano)}, nil
 }
 
-func (s *server) GetUserPapers(_ context.Context, user *pb.User) (*pb.AvailablePapers, error) {
-	userId, err := uuid.FromBytes(user.Id)
+// Выставляет лимитную или рыночную заявку в стакан
+func (s *server) PlaceOrder(ctx context.Context, req *pb.OrderRequest) (*pb.Order, error) {
+	userId, err := uuid.FromBytes(req.UserId)
 	if err != nil {
 		log.Println("Cant get uuid from given bytes:", err)
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	return idempotency.Do(ctx, s.keys, "papers/PlaceOrder", userId, req.IdempotencyKey, req, func() (*pb.Order, error) {
+		order, err := s.engine.Place(ctx, models.Order{UserID: userId, Paper: req.PaperName, Side: req.Side, Type: req.Type, Price: requestPrice(req.PriceMinor, req.Price), Amount: req.Amount})
+		return orderResponse(order, err)
+	})
+}
+
+// Снимает заявку пользователя
+func (s *server) CancelOrder(ctx context.Context, req *pb.OrderRequest) (*pb.Order, error) {
+	userId, orderId, err := orderIds(req)
+	if err != nil {
 		return nil, err
 	}
-	papers, err := s.redis.GetUserPapers(userId)
+	order, err := s.engine.Cancel(ctx, userId, orderId)
+	return orderResponse(order, err)
+}
+
+// Заменяет заявку пользователя новой с ценой и объемом из запроса
+func (s *server) ReplaceOrder(ctx context.Context, req *pb.OrderRequest) (*pb.Order, error) {
+	userId, orderId, err := orderIds(req)
 	if err != nil {
-		log.Println("Cant get user papers:", err)
 		return nil, err
 	}
-	for i := range papers {
-		papers[i].Price, err = s.redis.GetPaperPrice(papers[i].Name)
-		if err != nil {
-			log.Println("Cant get paper price:", err)
+	// Замена снимает старую заявку, поэтому остановку проверяем до нее
+	if old, ok := s.engine.Get(orderId); ok {
+		if err := s.checkHalted(old.Paper); err != nil {
 			return nil, err
 		}
 	}
-	jsonedPapers, err := json.Marshal(papers)
+	return idempotency.Do(ctx, s.keys, "papers/ReplaceOrder", userId, req.IdempotencyKey, req, func() (*pb.Order, error) {
+		order, err := s.engine.Replace(ctx, userId, orderId, requestPrice(req.PriceMinor, req.Price), req.Amount)
+		return orderResponse(order, err)
+	})
+}
+
+// Последние заявки пользователя
+func (s *server) GetUserOrders(ctx context.Context, user *pb.User) (*pb.Orders, error) {
+	userId, err := uuid.FromBytes(user.Id)
 	if err != nil {
-		log.Println("Cant marshal user papers:", err)
+		log.Println("Cant get uuid from given bytes:", err)
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	orders, err := s.db.GetUserOrders(ctx, userId, MaxHistoryLimit)
+	if err != nil {
+		log.Println("Cant get user orders:", err)
 		return nil, err
 	}
-	return &pb.AvailablePapers{Papers: jsonedPapers}, nil
+	resp := &pb.Orders{Orders: make([]*pb.Order, 0, len(orders))}
+	for _, order := range orders {
+		resp.Orders = append(resp.Orders, orderToPb(order))
+	}
+	return resp, nil
+}
+
+// Агрегированный стакан бумаги
+func (s *server) GetOrderBook(_ context.Context, req *pb.OrderBookRequest) (*pb.OrderBook, error) {
+	bids, asks := s.engine.Depth(req.PaperName, int(req.Depth))
+	book := &pb.OrderBook{PaperName: req.PaperName, Bids: make([]*pb.PriceLevel, 0, len(bids)), Asks: make([]*pb.PriceLevel, 0, len(asks))}
+	for _, level := range bids {
+		book.Bids = append(book.Bids, levelToPb(level))
+	}
+	for _, level := range asks {
+		book.Asks = append(book.Asks, levelToPb(level))
+	}
+	return book, nil
+}
+
+// Свечи бумаги за [from, to)(unix секунды). По умолчанию to - сейчас, from - MaxCandles интервалов назад
+func (s *server) GetPriceHistory(ctx context.Context, req *pb.PriceHistoryRequest) (*pb.PriceHistory, error) {
+	interval, ok := CandleIntervals[req.Interval]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown interval %q", req.Interval)
+	}
+	if req.PaperName == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "request missing paper name")
+	}
+	to := time.Now()
+	if req.To > 0 {
+		to = time.Unix(req.To, 0)
+	}
+	from := to.Add(-MaxCandles * interval)
+	if req.From > 0 {
+		from = time.Unix(req.From, 0)
+	}
+	if !from.Before(to) {
+		return nil, status.Errorf(codes.InvalidArgument, "from must be before to")
+	}
+	if to.Sub(from) > MaxCandles*interval {
+		return nil, status.Errorf(codes.InvalidArgument, "range is too wide, at most %v candles per request", MaxCandles)
+	}
+	candles, err := s.db.GetPriceHistory(ctx, req.PaperName, interval, from, to)
+	if err != nil {
+		log.Println("Cant get price history:", err)
+		return nil, err
+	}
+	history := &pb.PriceHistory{PaperName: req.PaperName, Interval: req.Interval, Candles: make([]*pb.Candle, 0, len(candles))}
+	for _, candle := range candles {
+		history.Candles = append(history.Candles, &pb.Candle{
+			Time:       candle.Time.Unix(),
+			Open:       candle.Open.Float32(),
+			High:       candle.High.Float32(),
+			Low:        candle.Low.Float32(),
+			Close:      candle.Close.Float32(),
+			OpenMinor:  int64(candle.Open),
+			HighMinor:  int64(candle.High),
+			LowMinor:   int64(candle.Low),
+			CloseMinor: int64(candle.Close),
+		})
+	}
+	return history, nil
+}
+
+// Торги остановленной бумагой(circuit breaker или админ) отклоняются отдельным кодом FailedPrecondition,
+// а не строкой в ответе, чтобы клиент мог отличить остановку от обычного отказа
+func (s *server) checkHalted(paper string) error {
+	halted, err := s.redis.IsHalted(paper)
+	if err != nil {
+		log.Println("Cant check paper halt:", err)
+		return err
+	}
+	if halted {
+		return status.Errorf(codes.FailedPrecondition, "%v: %v", problems.ErrHalted, paper)
+	}
+	return nil
+}
+
+func orderIds(req *pb.OrderRequest) (uuid.UUID, uuid.UUID, error) {
+	userId, err := uuid.FromBytes(req.UserId)
+	if err != nil {
+		log.Println("Cant get uuid from given bytes:", err)
+		return uuid.Nil, uuid.Nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	orderId, err := uuid.FromBytes(req.OrderId)
+	if err != nil {
+		log.Println("Cant get order uuid from given bytes:", err)
+		return uuid.Nil, uuid.Nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	return userId, orderId, nil
+}
+
+// Ошибки бизнес-логики возвращаются в поле response, как в Status
+func orderResponse(order models.Order, err error) (*pb.Order, error) {
+	switch {
+	case err == nil:
+		return orderToPb(order), nil
+	case errors.Is(err, problems.ErrBadOrder):
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	case errors.Is(err, problems.ErrHalted):
+		return nil, status.Errorf(codes.FailedPrecondition, "%v", err)
+	case errors.Is(err, problems.ErrLowBalance), errors.Is(err, problems.ErrLowPaper),
+		errors.Is(err, problems.ErrNoOrder), errors.Is(err, problems.ErrNoLiquidity), errors.Is(err, problems.ErrNoPaper):
+		return &pb.Order{Response: err.Error()}, nil
+	default:
+		log.Println("Cant process order:", err)
+		return nil, err
+	}
+}
+
+func orderToPb(order models.Order) *pb.Order {
+	return &pb.Order{
+		Id:         order.ID[:],
+		PaperName:  order.Paper,
+		Side:       order.Side,
+		Type:       order.Type,
+		Price:      order.Price.Float32(),
+		PriceMinor: int64(order.Price),
+		Amount:     order.Amount,
+		Filled:     order.Filled,
+		Status:     order.Status,
+		CreatedAt:  order.CreatedAt.Unix(),
+	}
+}
+
+func levelToPb(level models.PriceLevel) *pb.PriceLevel {
+	return &pb.PriceLevel{Price: level.Price.Float32(), PriceMinor: int64(level.Price), Amount: level.Amount}
+}
+
+// Цена из запроса в копейках. Старые клиенты заполняют только float поле
+func requestPrice(minor int64, legacy float32) money.Amount {
+	if minor != 0 {
+		return money.Amount(minor)
+	}
+	return money.FromFloat(float64(legacy))
 }
@@ -0,0 +1,108 @@
+package settlement
+
+import (
+	"context"
+	"log"
+	"papers/internal/pkg/models"
+	"papers/internal/pkg/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Config struct {
+	// Раз в сколько секунд повторяются непроведенные расчеты и возвращаются брошенные резервы
+	Interval int `yaml:"interval" env-default:"10"`
+	// Сколько расчетов проводится за один проход
+	Batch int `yaml:"batch" env-default:"100"`
+	// Через сколько секунд резерв без открытой заявки и расчета считается брошенным
+	OrphanAge int `yaml:"orphan_age" env-default:"300"`
+}
+
+type IDB interface {
+	GetPendingSettlements(ctx context.Context, limit int) ([]models.Settlement, error)
+	FinishSettlement(ctx context.Context, id uuid.UUID) error
+	FailSettlement(ctx context.Context, id uuid.UUID, reason string) error
+	GetOrphanHolds(ctx context.Context, age time.Duration) ([]uuid.UUID, error)
+}
+
+type IBalance interface {
+	Reserve(ctx context.Context, userID uuid.UUID, currency string, cash money.Amount, reference uuid.UUID, key string) (uuid.UUID, error)
+	Commit(ctx context.Context, holdID uuid.UUID, payouts []models.Payout, reference uuid.UUID, key string) error
+	Release(ctx context.Context, holdID uuid.UUID, cash money.Amount, key string) error
+}
+
+// Проводит в balance денежные расчеты, записанные в БД вместе со сделками, заявками и отменами. Расчет сначала
+// проводится сразу, а если balance недоступен или ответил ошибкой - повторяется в Run, пока не пройдет. Повтор идет
+// с тем же ключом идемпотентности(id расчета), поэтому расчет, ответ на который потерялся, не проводится дважды
+type Settler struct {
+	cfg     *Config
+	db      IDB
+	balance IBalance
+}
+
+func New(cfg *Config, db IDB, balance IBalance) *Settler {
+	return &Settler{cfg: cfg, db: db, balance: balance}
+}
+
+// Проводит только что записанные расчеты. Непроведенные остаются в БД для Run
+func (s *Settler) Settle(ctx context.Context, settlements []models.Settlement) {
+	for _, st := range settlements {
+		s.settle(ctx, st)
+	}
+}
+
+// Раз в Interval повторяет непроведенные расчеты и возвращает владельцам брошенные резервы
+func (s *Settler) Run() {
+	ticker := time.NewTicker(time.Duration(s.cfg.Interval) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx := context.Background()
+		settlements, err := s.db.GetPendingSettlements(ctx, s.cfg.Batch)
+		if err != nil {
+			continue
+		}
+		for _, st := range settlements {
+			s.settle(ctx, st)
+		}
+		s.releaseOrphans(ctx)
+	}
+}
+
+func (s *Settler) settle(ctx context.Context, st models.Settlement) {
+	if err := s.apply(ctx, st); err != nil {
+		log.Printf("Settlement %v failed(attempt %v), will retry: %v\n", st.ID, st.Attempts+1, err)
+		s.db.FailSettlement(ctx, st.ID, err.Error())
+		return
+	}
+	s.db.FinishSettlement(ctx, st.ID)
+}
+
+func (s *Settler) apply(ctx context.Context, st models.Settlement) error {
+	key := st.ID.String()
+	if len(st.Payouts) == 0 {
+		return s.balance.Release(ctx, st.HoldID, st.Cash, key)
+	}
+	hold := st.HoldID
+	if hold == uuid.Nil {
+		var err error
+		if hold, err = s.balance.Reserve(ctx, uuid.Nil, st.Currency, st.Cash, st.Reference, key+"/reserve"); err != nil {
+			return err
+		}
+	}
+	return s.balance.Commit(ctx, hold, st.Payouts, st.Reference, key)
+}
+
+// Резерв остается брошенным, если сделку или заявку не удалось записать в БД, а вернуть резерв сразу тоже не удалось
+func (s *Settler) releaseOrphans(ctx context.Context) {
+	holds, err := s.db.GetOrphanHolds(ctx, time.Duration(s.cfg.OrphanAge)*time.Second)
+	if err != nil {
+		return
+	}
+	for _, hold := range holds {
+		if err := s.balance.Release(ctx, hold, 0, ""); err != nil {
+			continue
+		}
+		log.Printf("Orphan hold %v released\n", hold)
+	}
+}
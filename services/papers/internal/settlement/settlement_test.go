@@ -0,0 +1,107 @@
+package settlement
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"papers/internal/pkg/models"
+	"papers/internal/pkg/money"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDB struct {
+	pending  map[uuid.UUID]models.Settlement
+	attempts map[uuid.UUID]int
+}
+
+func (d *fakeDB) GetPendingSettlements(_ context.Context, _ int) ([]models.Settlement, error) {
+	settlements := []models.Settlement{}
+	for _, st := range d.pending {
+		settlements = append(settlements, st)
+	}
+	return settlements, nil
+}
+
+func (d *fakeDB) FinishSettlement(_ context.Context, id uuid.UUID) error {
+	delete(d.pending, id)
+	return nil
+}
+
+func (d *fakeDB) FailSettlement(_ context.Context, id uuid.UUID, _ string) error {
+	d.attempts[id]++
+	return nil
+}
+
+func (d *fakeDB) GetOrphanHolds(_ context.Context, _ time.Duration) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+// Balance, который проводит операцию с ключом один раз, а пока down - отвечает ошибкой
+type fakeBalance struct {
+	down     bool
+	holds    map[string]uuid.UUID
+	commits  map[string]int
+	releases map[string]money.Amount
+}
+
+func (b *fakeBalance) Reserve(_ context.Context, _ uuid.UUID, _ string, _ money.Amount, _ uuid.UUID, key string) (uuid.UUID, error) {
+	if b.down {
+		return uuid.Nil, errors.New("balance is down")
+	}
+	if _, ok := b.holds[key]; !ok {
+		b.holds[key] = uuid.New()
+	}
+	return b.holds[key], nil
+}
+
+func (b *fakeBalance) Commit(_ context.Context, _ uuid.UUID, _ []models.Payout, _ uuid.UUID, key string) error {
+	if b.down {
+		return errors.New("balance is down")
+	}
+	b.commits[key] = 1
+	return nil
+}
+
+func (b *fakeBalance) Release(_ context.Context, _ uuid.UUID, cash money.Amount, key string) error {
+	if b.down {
+		return errors.New("balance is down")
+	}
+	b.releases[key] = cash
+	return nil
+}
+
+func TestSettler(t *testing.T) {
+	db := &fakeDB{pending: map[uuid.UUID]models.Settlement{}, attempts: map[uuid.UUID]int{}}
+	balance := &fakeBalance{holds: map[string]uuid.UUID{}, commits: map[string]int{}, releases: map[string]money.Amount{}}
+	settler := New(&Config{Interval: 1, Batch: 10, OrphanAge: 1}, db, balance)
+	ctx := context.Background()
+
+	commit := models.Settlement{ID: uuid.New(), Currency: "USD", Cash: 500, Payouts: []models.Payout{{Account: models.AccountMarket, Amount: 500}}}
+	release := models.Settlement{ID: uuid.New(), HoldID: uuid.New(), Cash: 200}
+	db.pending[commit.ID], db.pending[release.ID] = commit, release
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tfailed settlements stay pending", testID)
+		balance.down = true
+		settler.Settle(ctx, []models.Settlement{commit, release})
+		require.Len(t, db.pending, 2)
+		require.Equal(t, 1, db.attempts[commit.ID])
+		require.Equal(t, 1, db.attempts[release.ID])
+	}
+	{
+		testID := 1
+		t.Logf("\tTest %d:\tretry settles them with the settlement id as idempotency key", testID)
+		balance.down = false
+		settlements, err := db.GetPendingSettlements(ctx, 10)
+		require.NoError(t, err)
+		settler.Settle(ctx, settlements)
+		require.Empty(t, db.pending)
+		require.Contains(t, balance.holds, commit.ID.String()+"/reserve", "Market hold must be reserved before commit")
+		require.Equal(t, 1, balance.commits[commit.ID.String()])
+		require.Equal(t, money.Amount(200), balance.releases[release.ID.String()])
+	}
+}